@@ -0,0 +1,65 @@
+package pool
+
+import (
+	"context"
+	"runtime/debug"
+)
+
+// AcquireSticky 与AcquireContext相同，但优先返回最近一次用同一个key获取、现在空闲的资源，
+// 没有这样的资源时获取任意一个资源，并把它与key关联
+// 用于让同一个调用者尽量重复使用同一个连接，例如利用服务端的会话缓存
+func (p *Pool[T]) AcquireSticky(ctx context.Context, key string) (T, error) {
+	if r, ok := p.acquireAffine(ctx, key); ok {
+		return r, nil
+	}
+	r, err := p.AcquireContext(ctx)
+	if err != nil {
+		return r, err
+	}
+	p.m.Lock()
+	if e, ok := p.inUse.Get(r); ok {
+		e.affinity = key
+	}
+	p.m.Unlock()
+	return r, nil
+}
+
+// acquireAffine 取出与key关联的空闲资源，没有这样的资源或有goroutine在排队时返回false
+func (p *Pool[T]) acquireAffine(ctx context.Context, key string) (T, bool) {
+	var stack []byte
+	if p.leakTimeout > 0 {
+		stack = debug.Stack()
+	}
+	for {
+		p.m.Lock()
+		var e *entry[T]
+		if !p.closed && !p.paused && len(p.waiters) == 0 && p.wakeups == 0 && !p.overReserve(PriorityNormal, 1) {
+			// 从最近放回的开始找，它最可能仍然可用
+			for i := p.idle.Len() - 1; i >= 0; i-- {
+				if p.idle.At(i).affinity == key {
+					e = p.checkout(i, stack)
+					break
+				}
+			}
+		}
+		p.m.Unlock()
+		if e == nil {
+			var zero T
+			return zero, false
+		}
+		if !p.checkIdle(ctx, e) {
+			continue
+		}
+		if ok, _ := p.runAcquireHook(ctx, e.r, nil); !ok {
+			continue
+		}
+		if p.abandon(e.r) {
+			// 池已经开始关闭，由AcquireContext返回ErrPoolClosed
+			var zero T
+			return zero, false
+		}
+		p.stats.hit()
+		p.logger.Println("AcquireSticky:", "Affine Resource")
+		return e.r, true
+	}
+}
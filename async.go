@@ -0,0 +1,37 @@
+package pool
+
+import "context"
+
+// AcquireResult 是AcquireAsync的结果
+type AcquireResult[T any] struct {
+	Resource T
+	Err      error
+}
+
+// AcquireAsync 在另一个goroutine中获取资源，调用者可以先做其它事情，需要资源时再从返回的通道接收
+//
+//	res := p.AcquireAsync(ctx)
+//	... // 其它工作
+//	r, ok := <-res
+//	if !ok || r.Err != nil { ... }
+//	defer p.Release(r.Resource)
+//
+// 通道最多收到一个结果，之后被关闭；ctx在结果被接收之前结束时，已经获取的资源被放回池里，
+// 通道直接被关闭，这时接收到的ok为false，错误见ctx.Err()
+// 不再需要资源时应当取消ctx，否则获取的资源会一直被占用
+func (p *Pool[T]) AcquireAsync(ctx context.Context) <-chan AcquireResult[T] {
+	ch := make(chan AcquireResult[T])
+	go func() {
+		defer close(ch)
+		r, err := p.AcquireContext(ctx)
+		select {
+		case ch <- AcquireResult[T]{Resource: r, Err: err}:
+		case <-ctx.Done():
+			if err == nil {
+				p.logger.Println("AcquireAsync:", "Abandoned")
+				p.Release(r)
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,89 @@
+package pool
+
+import "time"
+
+// DefaultAutoscaleInterval 是未设置间隔时自动调整MaxTotal的间隔
+const DefaultAutoscaleInterval = 10 * time.Second
+
+const (
+	// scaleUpUtilization 使用率达到这个值时增大MaxTotal
+	scaleUpUtilization = 0.9
+	// scaleDownUtilization 使用率低于这个值时才考虑减小MaxTotal
+	scaleDownUtilization = 0.5
+	// scaleDownSamples 连续这么多次采样使用率都很低时才减小MaxTotal，避免来回调整
+	scaleDownSamples = 3
+)
+
+// autoscaler 记录自动调整MaxTotal所需的状态
+type autoscaler struct {
+	min, max uint
+	waits    uint64 // 上一次采样时累计的等待次数
+	idleRuns int    // 使用率连续较低的采样次数
+}
+
+// autoscale 每隔interval根据等待次数和使用率调整一次MaxTotal，直到池被关闭
+func (p *Pool[T]) autoscale(interval time.Duration) {
+	ticker := p.clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			p.scale()
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// scale 在上一次采样后有Acquire等待过或使用率很高时增大MaxTotal，
+// 使用率连续scaleDownSamples次都很低时减小MaxTotal，结果限制在[min, max]之间
+func (p *Pool[T]) scale() {
+	a := p.scaler
+	waits := p.stats.waits.Load()
+	waited := waits > a.waits
+	a.waits = waits
+
+	p.m.Lock()
+	if p.closed {
+		p.m.Unlock()
+		return
+	}
+	inUse := p.numOpen - uint(p.idle.Len())
+	utilization := float64(inUse) / float64(p.maxTotal)
+	size := p.maxTotal
+	switch {
+	case waited || utilization >= scaleUpUtilization:
+		a.idleRuns = 0
+		step := size / 4
+		if step == 0 {
+			step = 1
+		}
+		size += step
+	case utilization < scaleDownUtilization:
+		a.idleRuns++
+		if a.idleRuns >= scaleDownSamples {
+			a.idleRuns = 0
+			size -= (size - inUse) / 2
+		}
+	default:
+		a.idleRuns = 0
+	}
+	if size > a.max {
+		size = a.max
+	}
+	if size < a.min {
+		size = a.min
+	}
+	if size == p.maxTotal {
+		p.m.Unlock()
+		return
+	}
+	p.maxTotal = size
+	// 关闭超出新上限的空闲资源
+	for p.numOpen > p.maxTotal && p.idle.Len() > 0 {
+		p.retire(p.takeIdle(0))
+	}
+	p.broadcast()
+	p.unlock()
+	p.logger.Println("Autoscale:", "MaxTotal", size)
+}
@@ -0,0 +1,230 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/lazysheep666/pool/internal/identity"
+)
+
+// ErrNoBackend 表示Balancer中没有可以用来创建资源的后端，所有后端都在排空或权重为0
+var ErrNoBackend = errors.New("No backend available")
+
+// BalanceStrategy 决定Balancer为每次创建选择哪个后端
+type BalanceStrategy int
+
+const (
+	// WeightedRoundRobin 按权重轮流选择后端，创建的资源数之比接近权重之比
+	WeightedRoundRobin BalanceStrategy = iota
+	// LeastConnections 选择打开的资源数与权重之比最小的后端
+	LeastConnections
+)
+
+// Backend 是Balancer中的一个后端
+type Backend[T any] struct {
+	Name    string                           // 后端的名字，在Balancer中唯一
+	Weight  uint                             // 后端的权重，0表示不用来创建资源
+	Factory func(context.Context) (T, error) // 在这个后端上创建资源的函数
+}
+
+// BackendStats 是一个后端的统计信息
+type BackendStats struct {
+	Name     string
+	Weight   uint
+	Draining bool   // 是否正在排空
+	Open     uint   // 由这个后端创建、还没有关闭的资源数
+	Created  uint64 // 累计创建的资源数
+	Failures uint64 // 累计创建失败的次数
+}
+
+// backend 是Balancer中一个后端的状态
+type backend[T any] struct {
+	Backend[T]
+	current  int // 平滑加权轮询的当前权重
+	draining bool
+	open     uint
+	created  uint64
+	failures uint64
+}
+
+// Balancer 按权重把资源的创建分散到多个后端，通过WithBalancer交给池使用
+// 一个Balancer只应被一个池使用
+type Balancer[T any] struct {
+	strategy BalanceStrategy
+
+	m        sync.Mutex
+	backends []*backend[T]
+	origin   identity.Map[T, *backend[T]] // 每个打开的资源由哪个后端创建
+}
+
+// NewBalancer 创建一个使用strategy在backends之间选择的Balancer
+func NewBalancer[T any](strategy BalanceStrategy, backends ...Backend[T]) (*Balancer[T], error) {
+	if strategy < WeightedRoundRobin || strategy > LeastConnections {
+		return nil, fmt.Errorf("%w: unknown BalanceStrategy %d", ErrInvalidConfig, strategy)
+	}
+	if len(backends) == 0 {
+		return nil, fmt.Errorf("%w: no backends", ErrInvalidConfig)
+	}
+	b := &Balancer[T]{strategy: strategy, origin: make(identity.Map[T, *backend[T]])}
+	names := make(map[string]bool, len(backends))
+	for _, be := range backends {
+		if be.Factory == nil {
+			return nil, fmt.Errorf("%w: backend %q has nil factory", ErrInvalidConfig, be.Name)
+		}
+		if names[be.Name] {
+			return nil, fmt.Errorf("%w: duplicate backend %q", ErrInvalidConfig, be.Name)
+		}
+		names[be.Name] = true
+		b.backends = append(b.backends, &backend[T]{Backend: be})
+	}
+	return b, nil
+}
+
+// SetWeight 修改名为name的后端的权重，后端不存在时返回false
+func (b *Balancer[T]) SetWeight(name string, weight uint) bool {
+	b.m.Lock()
+	defer b.m.Unlock()
+	be := b.lookup(name)
+	if be == nil {
+		return false
+	}
+	be.Weight = weight
+	be.current = 0
+	return true
+}
+
+// Drain 开始排空名为name的后端：不再在它上面创建资源，
+// 它创建的资源在放回池里或被Acquire取出时关闭，后端不存在时返回false
+func (b *Balancer[T]) Drain(name string) bool {
+	return b.setDraining(name, true)
+}
+
+// Undrain 停止排空名为name的后端，后端不存在时返回false
+func (b *Balancer[T]) Undrain(name string) bool {
+	return b.setDraining(name, false)
+}
+
+func (b *Balancer[T]) setDraining(name string, draining bool) bool {
+	b.m.Lock()
+	defer b.m.Unlock()
+	be := b.lookup(name)
+	if be == nil {
+		return false
+	}
+	be.draining = draining
+	be.current = 0
+	return true
+}
+
+// Stats 按NewBalancer中的顺序返回每个后端的统计信息
+func (b *Balancer[T]) Stats() []BackendStats {
+	b.m.Lock()
+	defer b.m.Unlock()
+	stats := make([]BackendStats, len(b.backends))
+	for i, be := range b.backends {
+		stats[i] = BackendStats{
+			Name:     be.Name,
+			Weight:   be.Weight,
+			Draining: be.draining,
+			Open:     be.open,
+			Created:  be.created,
+			Failures: be.failures,
+		}
+	}
+	return stats
+}
+
+// lookup 返回名为name的后端，调用者需持有b.m
+func (b *Balancer[T]) lookup(name string) *backend[T] {
+	for _, be := range b.backends {
+		if be.Name == name {
+			return be
+		}
+	}
+	return nil
+}
+
+// pick 按策略选择一个后端，没有可用的后端时返回nil，调用者需持有b.m
+func (b *Balancer[T]) pick() *backend[T] {
+	var best *backend[T]
+	total := 0
+	for _, be := range b.backends {
+		if be.draining || be.Weight == 0 {
+			continue
+		}
+		switch b.strategy {
+		case LeastConnections:
+			// 比较open/Weight，交叉相乘避免除法
+			if best == nil || uint64(be.open)*uint64(best.Weight) < uint64(best.open)*uint64(be.Weight) {
+				best = be
+			}
+		default:
+			// 平滑加权轮询，每次选择当前权重最大的后端
+			be.current += int(be.Weight)
+			total += int(be.Weight)
+			if best == nil || be.current > best.current {
+				best = be
+			}
+		}
+	}
+	if best != nil && b.strategy == WeightedRoundRobin {
+		best.current -= total
+	}
+	return best
+}
+
+// create 在选择的后端上创建一个资源
+func (b *Balancer[T]) create(ctx context.Context) (T, error) {
+	b.m.Lock()
+	be := b.pick()
+	if be == nil {
+		b.m.Unlock()
+		var zero T
+		return zero, ErrNoBackend
+	}
+	// 先计入打开的资源，让并发的创建看到这次选择
+	be.open++
+	b.m.Unlock()
+
+	r, err := be.Factory(ctx)
+	b.m.Lock()
+	defer b.m.Unlock()
+	if err != nil {
+		be.open--
+		be.failures++
+		return r, fmt.Errorf("backend %s: %w", be.Name, err)
+	}
+	be.created++
+	b.origin.Set(r, be)
+	return r, nil
+}
+
+// draining 判断r是否由正在排空的后端创建
+func (b *Balancer[T]) draining(r T) bool {
+	b.m.Lock()
+	defer b.m.Unlock()
+	be, ok := b.origin.Get(r)
+	return ok && be.draining
+}
+
+// backendOf 返回创建r的后端的名字，r不是由这个Balancer创建时返回空字符串
+func (b *Balancer[T]) backendOf(r T) string {
+	b.m.Lock()
+	defer b.m.Unlock()
+	if be, ok := b.origin.Get(r); ok {
+		return be.Name
+	}
+	return ""
+}
+
+// closed 在资源被关闭时减少它所属后端打开的资源数
+func (b *Balancer[T]) closed(r T) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	if be, ok := b.origin.Get(r); ok {
+		be.open--
+		b.origin.Delete(r)
+	}
+}
@@ -0,0 +1,244 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// AcquireN 从池中一次获取n个资源，要么全部获取成功，要么一个也不占用
+// 资源总数达到上限时排队等待，直到可以同时得到n个资源，避免多个goroutine
+// 各自持有一部分资源而互相等待。创建资源失败、ctx结束或超时时，
+// 已经取得的资源会被放回池里，错误的含义与AcquireContext相同
+func (p *Pool[T]) AcquireN(ctx context.Context, n int) (_ []T, err error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	p.checkClosedAcquire()
+	need := uint(n)
+	p.m.Lock()
+	maxTotal := p.maxTotal
+	p.m.Unlock()
+	if maxTotal > 0 && need > maxTotal {
+		return nil, fmt.Errorf("%w: cannot acquire %d resources with MaxTotal %d", ErrPoolExhausted, n, maxTotal)
+	}
+	if p.acquireTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = withTimeout(ctx, p.clock, p.acquireTimeout)
+		defer cancel()
+	}
+	var stack []byte
+	if p.leakTimeout > 0 {
+		stack = debug.Stack()
+	}
+	start := p.clock.Now()
+	var waitStart time.Time
+	defer func() { err = p.acquireDone(ctx, start, waitStart, err) }()
+	if p.overridable {
+		if err := p.checkOverride(ctx, false, &waitStart); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.lf != nil {
+		// 与acquire相同，让之后的Release改为加锁的路径，把资源交给排队的AcquireN
+		p.lf.contended.Add(1)
+		defer p.lf.contended.Add(-1)
+	}
+
+	var wait chan *entry[T]
+	var last waiter[T]
+	var binds uint // 本次获取中binder失败的次数
+	woken, queued := false, false
+	for {
+		if p.lf != nil && p.lf.parked.Load() > 0 {
+			p.m.Lock()
+			p.unpark()
+			p.unlock()
+		}
+		p.m.Lock()
+		if woken {
+			p.wakeups -= need
+			woken = false
+		}
+		// 池关闭后总是返回ErrPoolClosed，即使ctx也已经结束
+		if p.closed {
+			p.m.Unlock()
+			return nil, ErrPoolClosed
+		}
+		if err := ctx.Err(); err != nil {
+			p.wakeWaiters()
+			p.m.Unlock()
+			return nil, err
+		}
+		if p.paused && p.nonBlocking {
+			p.m.Unlock()
+			return nil, ErrPoolPaused
+		}
+		mustQueue := !queued && !p.nonBlocking &&
+			(p.wakeups > 0 || len(p.waiters) > 0 && p.waiters[0].prio >= PriorityNormal)
+		mustQueue = mustQueue || p.paused
+		if p.shutdown != nil {
+			if uint(p.idle.Len()) < need {
+				p.m.Unlock()
+				return nil, ErrPoolShuttingDown
+			}
+			mustQueue = false
+		}
+		if !mustQueue && p.batchAvailable(need) && !p.overReserve(PriorityNormal, need) {
+			var idle []*entry[T]
+			for uint(len(idle)) < need {
+				e := p.popIdle(stack)
+				if e == nil {
+					break
+				}
+				idle = append(idle, e)
+			}
+			p.numOpen += need - uint(len(idle))
+			p.m.Unlock()
+			rs, err := p.fillBatch(ctx, n, idle, stack, &binds)
+			if err != nil {
+				return nil, ctxError(ctx, err)
+			}
+			if p.abandonAll(rs) {
+				return nil, ErrPoolClosed
+			}
+			if rs != nil {
+				return rs, nil
+			}
+			// OnAcquire钩子或binder拒绝了其中的资源，重新获取
+			continue
+		}
+		if p.nonBlocking {
+			p.m.Unlock()
+			return nil, ErrPoolExhausted
+		}
+		if !queued && p.queueFull() {
+			p.m.Unlock()
+			return nil, ErrQueueFull
+		}
+		if wait == nil {
+			wait = make(chan *entry[T], 1)
+		}
+		last = p.enqueue(waiter[T]{ch: wait, prio: PriorityNormal, n: need, tag: last.tag, since: last.since}, queued)
+		queued = true
+		p.wakeWaiters()
+		p.m.Unlock()
+
+		if waitStart.IsZero() {
+			waitStart = p.clock.Now()
+		}
+		p.logger.Println("AcquireN:", "Waiting")
+		endWait := p.region(ctx, "pool.AcquireN.Wait")
+		select {
+		case <-wait:
+			endWait()
+			woken = true
+		case <-ctx.Done():
+			endWait()
+			p.m.Lock()
+			if !p.removeWaiter(wait) {
+				p.wakeups -= need
+				p.wakeWaiters()
+			}
+			p.m.Unlock()
+			// 回到循环开始，池同时被关闭时返回ErrPoolClosed而不是ctx的错误
+			continue
+		}
+	}
+}
+
+// fillBatch 检查AcquireN取出的空闲资源，并用已经占用的容量创建其余的资源，凑齐n个
+// 过期或不可用的空闲资源被关闭，它们占用的容量用来创建新资源。
+// 创建失败时放回所有资源并返回错误，OnAcquire钩子或binder拒绝了某个资源时放回其余的资源并返回nil，
+// binder失败的次数超过BindRetries时放回其余的资源并返回ErrBindFailed
+func (p *Pool[T]) fillBatch(ctx context.Context, n int, idle []*entry[T], stack []byte, binds *uint) ([]T, error) {
+	rs := make([]T, 0, n)
+	p.m.Lock()
+	for _, e := range idle {
+		if p.stale(ctx, e) {
+			// 与retire相同，但保留它占用的容量用来创建新资源
+			p.untrack(e)
+			p.usage.add(p.clock.Now().Sub(e.createdAt), e.busy)
+			p.totalWeight -= e.weight
+			p.pendingClose = append(p.pendingClose, e.r)
+			continue
+		}
+		rs = append(rs, e.r)
+	}
+	p.unlock()
+	hits := len(rs)
+
+	missing := n - len(rs)
+	if missing > 0 {
+		p.logger.Println("AcquireN:", "New Resources", missing)
+	}
+	created := make(chan createResult[T], missing)
+	for i := 0; i < missing; i++ {
+		go func() {
+			r, err := p.createInUse(ctx, stack)
+			created <- createResult[T]{r, err}
+		}()
+	}
+	var errs []error
+	for i := 0; i < missing; i++ {
+		res := <-created
+		if res.err != nil {
+			errs = append(errs, res.err)
+			continue
+		}
+		rs = append(rs, res.r)
+	}
+	if len(errs) > 0 {
+		p.ReleaseAll(rs)
+		return nil, errors.Join(errs...)
+	}
+
+	for i, r := range rs {
+		if ok, err := p.runAcquireHook(ctx, r, binds); !ok {
+			p.ReleaseAll(rs[:i])
+			p.ReleaseAll(rs[i+1:])
+			return nil, err
+		}
+	}
+	for i := range rs {
+		if i < hits {
+			p.stats.hit()
+		} else {
+			p.stats.miss()
+		}
+	}
+	return rs, nil
+}
+
+// batchAvailable 判断空闲资源数与剩余容量之和是否至少为n，调用者需持有p.m
+// 与available不同，AcquireN不共享资源，不计入可以共享的次数
+func (p *Pool[T]) batchAvailable(n uint) bool {
+	free := uint(p.idle.Len())
+	if free >= n {
+		return true
+	}
+	if p.weightFull() {
+		return false
+	}
+	if p.maxTotal == 0 {
+		return true
+	}
+	if p.numOpen < p.maxTotal+p.overflowN {
+		free += p.maxTotal + p.overflowN - p.numOpen
+	}
+	return free >= n
+}
+
+// ReleaseAll 把AcquireN获取的资源全部放回池里，返回所有Release错误的合并
+func (p *Pool[T]) ReleaseAll(rs []T) error {
+	var errs []error
+	for _, r := range rs {
+		if err := p.Release(r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
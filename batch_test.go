@@ -0,0 +1,168 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lazysheep666/pool"
+	"github.com/lazysheep666/pool/pooltest"
+)
+
+// startBatch 在新的goroutine中用AcquireN获取n个资源，排队后才返回，结果发送到返回的通道
+func startBatch(t *testing.T, p *pool.Pool[*tracked], n int) <-chan []*tracked {
+	t.Helper()
+	c := make(chan []*tracked, 1)
+	go func() {
+		rs, err := p.AcquireN(context.Background(), n)
+		if err != nil {
+			t.Error(err)
+		}
+		c <- rs
+	}()
+	eventually(t, "AcquireN to queue", func() bool { return p.Waiting() > 0 })
+	return c
+}
+
+// releaseAll 放回rs，失败时结束测试
+func releaseAll(t *testing.T, p *pool.Pool[*tracked], rs []*tracked) {
+	t.Helper()
+	if err := p.ReleaseAll(rs); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAcquireN(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		opts []pool.Option
+		run  func(t *testing.T, p *pool.Pool[*tracked], h *harness)
+		// 最后创建的资源数
+		wantCreated int64
+	}{
+		{"idle and new resources", nil, func(t *testing.T, p *pool.Pool[*tracked], h *harness) {
+			release(t, p, acquire(t, p))
+			rs, err := p.AcquireN(ctx, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rs[0].id != 1 {
+				t.Errorf("first resource %d, want the idle resource 1", rs[0].id)
+			}
+			if s := p.Stats(); s.Hits != 1 || s.Misses != 3 {
+				t.Errorf("Hits = %d, Misses = %d, want 1, 3", s.Hits, s.Misses)
+			}
+			releaseAll(t, p, rs)
+		}, 3},
+		{"more than MaxTotal", []pool.Option{pool.WithMaxTotal(2)}, func(t *testing.T, p *pool.Pool[*tracked], h *harness) {
+			if _, err := p.AcquireN(ctx, 3); !errors.Is(err, pool.ErrPoolExhausted) {
+				t.Errorf("AcquireN(3) = %v, want ErrPoolExhausted", err)
+			}
+		}, 0},
+		{"waits for all resources", []pool.Option{pool.WithMaxTotal(2)}, func(t *testing.T, p *pool.Pool[*tracked], h *harness) {
+			a, b := acquire(t, p), acquire(t, p)
+			c := startBatch(t, p, 2)
+			release(t, p, a)
+			// 只有一个空闲资源时不唤醒AcquireN，它不会先占用这一个
+			if p.Waiting() != 1 {
+				t.Fatalf("Waiting = %d with one idle resource, want 1", p.Waiting())
+			}
+			release(t, p, b)
+			releaseAll(t, p, <-c)
+		}, 2},
+		{"shared resources not counted as capacity", []pool.Option{
+			pool.WithMaxTotal(2), pool.WithSharing(4), pool.WithBlocking(false),
+		}, func(t *testing.T, p *pool.Pool[*tracked], h *harness) {
+			// 两次Acquire共享同一个资源，只剩一个容量
+			a, b := acquire(t, p), acquire(t, p)
+			if _, err := p.AcquireN(ctx, 2); !errors.Is(err, pool.ErrPoolExhausted) {
+				t.Errorf("AcquireN(2) with one free slot = %v, want ErrPoolExhausted", err)
+			}
+			release(t, p, a)
+			release(t, p, b)
+		}, 1},
+		{"waiter not woken by shareable resources", []pool.Option{pool.WithMaxTotal(2), pool.WithSharing(4)}, func(t *testing.T, p *pool.Pool[*tracked], h *harness) {
+			a := acquire(t, p)
+			c := startBatch(t, p, 2)
+			release(t, p, a)
+			releaseAll(t, p, <-c)
+		}, 2},
+		{"expired idle resources release their weight", []pool.Option{
+			pool.WithMaxLifetime(time.Minute),
+			pool.WithWeightFunc(func(*tracked) uint { return 2 }),
+			pool.WithMaxTotalWeight(4),
+		}, func(t *testing.T, p *pool.Pool[*tracked], h *harness) {
+			rs, err := p.AcquireN(ctx, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			releaseAll(t, p, rs)
+			h.clock.Advance(time.Minute + time.Second)
+			if rs, err = p.AcquireN(ctx, 2); err != nil {
+				t.Fatal(err)
+			}
+			// 两个关闭的资源和两个新资源各有一个样本
+			s := p.Stats()
+			if s.Weight != 4 || s.LifetimeClosed != 2 || s.Lifetime.Count != 4 {
+				t.Errorf("Weight = %d, LifetimeClosed = %d, Lifetime.Count = %d, want 4, 2, 4", s.Weight, s.LifetimeClosed, s.Lifetime.Count)
+			}
+			releaseAll(t, p, rs)
+			// 总权重没有泄漏，之后的Acquire仍然可以使用空闲资源
+			release(t, p, acquire(t, p))
+		}, 4},
+		{"lock-free parked resources", []pool.Option{pool.WithIdleStore(pool.LockFreeStore), pool.WithMaxTotal(2)}, func(t *testing.T, p *pool.Pool[*tracked], h *harness) {
+			a, b := acquire(t, p), acquire(t, p)
+			release(t, p, a)
+			release(t, p, b)
+			rs, err := p.AcquireN(ctx, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			releaseAll(t, p, rs)
+		}, 2},
+		{"lock-free release hands off to waiter", []pool.Option{pool.WithIdleStore(pool.LockFreeStore), pool.WithMaxTotal(2)}, func(t *testing.T, p *pool.Pool[*tracked], h *harness) {
+			a, b := acquire(t, p), acquire(t, p)
+			c := startBatch(t, p, 2)
+			release(t, p, a)
+			release(t, p, b)
+			releaseAll(t, p, <-c)
+		}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, h := newHarnessPool(t, tt.opts...)
+			tt.run(t, p, h)
+			if h.created.Load() != tt.wantCreated {
+				t.Errorf("created %d resources, want %d", h.created.Load(), tt.wantCreated)
+			}
+			if s := p.Stats(); s.InUse != 0 || s.Waiting != 0 {
+				t.Errorf("InUse = %d, Waiting = %d, want 0, 0", s.InUse, s.Waiting)
+			}
+		})
+	}
+}
+
+// TestAcquireNCreateFails 检查AcquireN创建资源失败时放回已经取得的资源，不占用任何资源
+func TestAcquireNCreateFails(t *testing.T) {
+	errCreate := errors.New("create failed")
+	var created atomic.Int64
+	p, err := pool.NewContext(func(ctx context.Context) (*tracked, error) {
+		if created.Add(1) == 3 {
+			return nil, errCreate
+		}
+		return &tracked{}, nil
+	}, pool.WithClock(pooltest.NewFakeClock(epoch)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if _, err := p.AcquireN(context.Background(), 3); !errors.Is(err, errCreate) {
+		t.Fatalf("AcquireN = %v, want %v", err, errCreate)
+	}
+	if s := p.Stats(); s.InUse != 0 || s.Idle != 2 {
+		t.Errorf("InUse = %d, Idle = %d, want 0, 2", s.InUse, s.Idle)
+	}
+}
@@ -0,0 +1,250 @@
+package pool
+
+// 这些基准测试模拟真实的使用方式，用来客观地评估分片、资源存储结构等对性能敏感的修改，
+// 除了时间和分配，每个基准测试还按每次操作报告池的创建、等待和未命中次数，
+// 多次运行的结果可以用benchstat比较
+//
+//	go test -run NONE -bench . -count 10 > old.txt
+//	go test -run NONE -bench . -count 10 > new.txt
+//	benchstat old.txt new.txt
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// benchResource 是基准测试中使用的资源
+type benchResource struct{ n int }
+
+// benchFactory 创建一个资源，没有任何开销，测量的只是池本身
+func benchFactory() (*benchResource, error) {
+	return &benchResource{}, nil
+}
+
+// contentionLevels 是争用场景使用的goroutine数
+var contentionLevels = []int{1, 4, 16, 64}
+
+// BenchmarkHotPool 在一个goroutine中反复获取和放回资源，资源总是空闲的，测量最快路径的开销
+func BenchmarkHotPool(b *testing.B) {
+	p := benchPool(b, WithMaxIdle(4))
+	defer p.Close()
+	benchWarm(b, p, 4)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r, err := p.Acquire()
+		if err != nil {
+			b.Fatal(err)
+		}
+		r.n++
+		p.Release(r)
+	}
+	b.StopTimer()
+	reportStats(b, p.Stats())
+}
+
+// BenchmarkColdPool 每次都创建新资源并销毁，测量创建和关闭资源时池的开销
+func BenchmarkColdPool(b *testing.B) {
+	p := benchPool(b)
+	defer p.Close()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r, err := p.Acquire()
+		if err != nil {
+			b.Fatal(err)
+		}
+		p.Discard(r)
+	}
+	b.StopTimer()
+	reportStats(b, p.Stats())
+}
+
+// BenchmarkContention 用不同数量的goroutine争用最多8个资源，goroutine超过8个时Acquire需要排队等待
+func BenchmarkContention(b *testing.B) {
+	for _, n := range contentionLevels {
+		n := n
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			p := benchPool(b, WithMaxTotal(8), WithMaxIdle(8))
+			defer p.Close()
+			benchWarm(b, p, 8)
+			b.ReportAllocs()
+			b.ResetTimer()
+			benchParallel(b, n, func() error { return benchCycle(p) })
+			b.StopTimer()
+			reportStats(b, p.Stats())
+		})
+	}
+}
+
+// BenchmarkAcquireReleaseParallel 用b.RunParallel在每个CPU上反复获取和放回资源，
+// 资源足够每个goroutine使用，比较各种空闲资源的存储方式在没有排队时的锁争用
+func BenchmarkAcquireReleaseParallel(b *testing.B) {
+	for _, kind := range []IdleStore{SliceStore, RingStore, LockFreeStore} {
+		kind := kind
+		b.Run(kind.String(), func(b *testing.B) {
+			n := 4 * runtime.GOMAXPROCS(0)
+			p := benchPool(b, WithIdleStore(kind), WithMaxIdle(uint(n)))
+			defer p.Close()
+			benchWarm(b, p, n)
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if err := benchCycle(p); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+			b.StopTimer()
+			reportStats(b, p.Stats())
+		})
+	}
+}
+
+// BenchmarkSharded 与BenchmarkContention相同，但使用ShardedPool，用来比较分片减少的锁争用
+func BenchmarkSharded(b *testing.B) {
+	for _, n := range contentionLevels {
+		n := n
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			sp, err := NewSharded(benchFactory, 0, WithMaxTotal(8), WithMaxIdle(8))
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer sp.Close()
+			b.ReportAllocs()
+			b.ResetTimer()
+			benchParallel(b, n, func() error { return benchCycle(sp) })
+			b.StopTimer()
+			reportStats(b, sp.Stats())
+		})
+	}
+}
+
+// BenchmarkKeyed 用16个goroutine在16个键之间获取和放回资源
+func BenchmarkKeyed(b *testing.B) {
+	kp, err := NewKeyed(func(ctx context.Context, key int) (*benchResource, error) { return benchFactory() },
+		WithPerKeyLimits(4, 4))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer kp.Close()
+	const keys = 16
+	var m sync.Mutex
+	next := 0
+	b.ReportAllocs()
+	b.ResetTimer()
+	benchParallel(b, keys, func() error {
+		m.Lock()
+		key := next % keys
+		next++
+		m.Unlock()
+		r, err := kp.Acquire(context.Background(), key)
+		if err != nil {
+			return err
+		}
+		r.n++
+		return kp.Release(key, r)
+	})
+	b.StopTimer()
+	var s Stats
+	for _, ks := range kp.StatsByKey() {
+		s.TotalCreated += ks.TotalCreated
+		s.AcquireWaitCount += ks.AcquireWaitCount
+		s.Misses += ks.Misses
+	}
+	reportStats(b, s)
+}
+
+// BenchmarkChurn 用8个goroutine使用一个资源很快过期、后台频繁回收和补充空闲资源的池，
+// 测量资源不断被创建和销毁时的开销
+func BenchmarkChurn(b *testing.B) {
+	p := benchPool(b,
+		WithMaxTotal(16),
+		WithMaxIdle(8),
+		WithMinIdle(2),
+		WithMaxLifetime(200*time.Microsecond),
+		WithIdleTimeout(100*time.Microsecond),
+		WithReapInterval(time.Millisecond),
+	)
+	defer p.Close()
+	b.ReportAllocs()
+	b.ResetTimer()
+	benchParallel(b, 8, func() error { return benchCycle(p) })
+	b.StopTimer()
+	reportStats(b, p.Stats())
+}
+
+// reportStats 按每次操作报告池的累计计数，不受b.N的影响
+func reportStats(b *testing.B, s Stats) {
+	perOp := func(v uint64) float64 { return float64(v) / float64(b.N) }
+	b.ReportMetric(perOp(s.TotalCreated), "creates/op")
+	b.ReportMetric(perOp(s.AcquireWaitCount), "waits/op")
+	b.ReportMetric(perOp(s.Misses), "misses/op")
+}
+
+// benchPool 创建一个池，失败时结束基准测试
+func benchPool(b *testing.B, opts ...Option) *Pool[*benchResource] {
+	p, err := New(benchFactory, opts...)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return p
+}
+
+// benchWarm 让池中有n个空闲资源
+func benchWarm(b *testing.B, p *Pool[*benchResource], n int) {
+	rs := make([]*benchResource, 0, n)
+	for i := 0; i < n; i++ {
+		r, err := p.Acquire()
+		if err != nil {
+			b.Fatal(err)
+		}
+		rs = append(rs, r)
+	}
+	for _, r := range rs {
+		p.Release(r)
+	}
+}
+
+// benchCycle 获取一个资源，使用后放回
+func benchCycle(p Pooler[*benchResource]) error {
+	r, err := p.Acquire()
+	if err != nil {
+		return err
+	}
+	r.n++
+	return p.Release(r)
+}
+
+// benchParallel 用n个goroutine一共执行b.N次fn，第一个错误会结束基准测试
+func benchParallel(b *testing.B, n int, fn func() error) {
+	var wg sync.WaitGroup
+	var once sync.Once
+	var failure error
+	for g := 0; g < n; g++ {
+		count := b.N / n
+		if g < b.N%n {
+			count++
+		}
+		wg.Add(1)
+		go func(count int) {
+			defer wg.Done()
+			for i := 0; i < count; i++ {
+				if err := fn(); err != nil {
+					once.Do(func() { failure = err })
+					return
+				}
+			}
+		}(count)
+	}
+	wg.Wait()
+	if failure != nil {
+		b.Fatal(failure)
+	}
+}
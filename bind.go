@@ -0,0 +1,10 @@
+package pool
+
+import "errors"
+
+// DefaultBindRetries 是没有设置BindRetries时binder失败后最多重新获取的次数
+const DefaultBindRetries = 3
+
+// ErrBindFailed 表示WithBinder设置的函数在一次获取中失败的次数超过了BindRetries，
+// 返回的错误同时包含binder最后一次返回的错误
+var ErrBindFailed = errors.New("Failed to bind resource")
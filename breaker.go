@@ -0,0 +1,60 @@
+package pool
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrFactoryUnavailable 表示factory连续失败，熔断器已经断开，
+// 在冷却时间结束并且一次试探成功之前不会再调用factory
+var ErrFactoryUnavailable = errors.New("Resource factory is unavailable")
+
+// breaker 是factory的熔断器，连续失败threshold次后断开cooldown时间，
+// 冷却结束后只允许一次试探调用，成功时闭合，失败时重新断开
+type breaker struct {
+	mu        sync.Mutex
+	threshold uint
+	cooldown  time.Duration
+	failures  uint      // 连续失败的次数
+	openUntil time.Time // 断开状态持续到这个时间
+	probing   bool      // 是否有试探调用正在进行
+}
+
+// allow 判断现在是否可以调用factory
+func (b *breaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true
+	}
+	if now.Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// isOpen 判断熔断器现在是否处于断开状态
+func (b *breaker) isOpen(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures >= b.threshold && now.Before(b.openUntil)
+}
+
+// record 记录一次factory调用的结果，ignore为true时(例如调用者取消了ctx)不计入失败
+func (b *breaker) record(err error, ignore bool, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	switch {
+	case err == nil:
+		b.failures = 0
+	case ignore:
+	default:
+		b.failures++
+		if b.failures >= b.threshold {
+			b.openUntil = now.Add(b.cooldown)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package pool_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lazysheep666/pool"
+)
+
+func TestBreaker(t *testing.T) {
+	const threshold, cooldown = 2, time.Minute
+	// step 推进时钟wait后按fail设置factory并获取一次资源，成功时销毁资源，使下一次获取调用factory
+	type step struct {
+		wait time.Duration
+		fail bool
+		want error
+	}
+	tests := []struct {
+		name      string
+		steps     []step
+		wantCalls int64 // factory被调用的次数
+	}{
+		{"opens after threshold failures", []step{
+			{0, true, errFlaky},
+			{0, true, errFlaky},
+			{0, false, pool.ErrFactoryUnavailable},
+		}, 2},
+		{"success resets failures", []step{
+			{0, true, errFlaky},
+			{0, false, nil},
+			{0, true, errFlaky},
+			{0, false, nil},
+		}, 4},
+		{"stays open during cooldown", []step{
+			{0, true, errFlaky},
+			{0, true, errFlaky},
+			{cooldown - time.Second, false, pool.ErrFactoryUnavailable},
+		}, 2},
+		{"successful probe closes", []step{
+			{0, true, errFlaky},
+			{0, true, errFlaky},
+			{cooldown, false, nil},
+			{0, true, errFlaky},
+			{0, false, nil},
+		}, 5},
+		{"failed probe reopens", []step{
+			{0, true, errFlaky},
+			{0, true, errFlaky},
+			{cooldown, true, errFlaky},
+			{0, false, pool.ErrFactoryUnavailable},
+			{cooldown, false, nil},
+		}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, f := newFlakyPool(t, pool.WithBreaker(threshold, cooldown))
+			for i, s := range tt.steps {
+				f.clock.Advance(s.wait)
+				f.fail.Store(s.fail)
+				r, err := p.Acquire()
+				if !errors.Is(err, s.want) || (s.want == nil) != (err == nil) {
+					t.Fatalf("step %d: Acquire = %v, want %v", i, err, s.want)
+				}
+				if err == nil {
+					if err := p.Discard(r); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			if f.calls.Load() != tt.wantCalls {
+				t.Errorf("factory called %d times, want %d", f.calls.Load(), tt.wantCalls)
+			}
+		})
+	}
+}
+
+// TestBreakerOpenInCreateError 检查使熔断器断开的失败在CreateError中报告熔断器已经断开
+func TestBreakerOpenInCreateError(t *testing.T) {
+	p, f := newFlakyPool(t, pool.WithBreaker(2, time.Minute))
+	f.fail.Store(true)
+	for i, want := range []bool{false, true} {
+		var ce *pool.CreateError
+		if _, err := p.Acquire(); !errors.As(err, &ce) || ce.BreakerOpen != want {
+			t.Errorf("failure %d: Acquire = %v, want a CreateError with BreakerOpen %v", i+1, err, want)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// Package bufpool 提供按大小分级复用字节缓冲区的资源池
+//
+//	p, err := bufpool.New(512, 64<<10)
+//	b, err := p.Get(1500) // len(b.B) == 1500, cap(b.B) == 2048
+//	defer p.Put(b)
+package bufpool
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/lazysheep666/pool"
+)
+
+// ErrForeignBuffer 表示放回的缓冲区不是从本池获取的
+var ErrForeignBuffer = errors.New("bufpool: buffer does not belong to this pool")
+
+// Buffer 是池中的一个缓冲区，B的容量不小于它所在级别的大小
+// 使用者可以修改B，放回时B的容量小于级别的大小则它不再被复用；
+// 池记录的是*Buffer而不是B，append让B换了底层数组后Put仍然能找到它所在的级别
+type Buffer struct {
+	B []byte
+
+	owner *Pool
+	class int // 所在级别的下标，-1表示超过最大级别、不放回池里
+}
+
+var _ pool.Sized = (*Buffer)(nil)
+
+// Size 返回B的容量，实现pool.Sized，让pool.WithMaxIdleBytes可以限制每一级空闲缓冲区占用的内存
+func (b *Buffer) Size() int {
+	return cap(b.B)
+}
+
+// ClassStats 是一个大小级别的统计信息
+type ClassStats struct {
+	Size int // 这一级缓冲区的容量
+	pool.Stats
+}
+
+// Pool 是按大小分级的缓冲区池，每一级是一个pool.Pool，级别的大小从minSize开始每级翻倍直到maxSize
+// 超过maxSize的请求直接分配，放回时丢弃
+type Pool struct {
+	sizes     []int
+	classes   []*pool.Pool[*Buffer]
+	oversized atomic.Uint64
+}
+
+// New 创建一个缓冲区池，minSize和maxSize会向上取整为2的幂
+// opts应用到每一级的池上，默认每一级最多保留pool.DefaultMaxIdle个空闲缓冲区、不限制总数
+func New(minSize, maxSize int, opts ...pool.Option) (*Pool, error) {
+	if minSize <= 0 || maxSize < minSize {
+		return nil, fmt.Errorf("%w: bufpool sizes must satisfy 0 < minSize <= maxSize", pool.ErrInvalidConfig)
+	}
+	bp := &Pool{}
+	max := roundUp(maxSize)
+	for size := roundUp(minSize); size <= max; size *= 2 {
+		class := len(bp.sizes)
+		size := size
+		p, err := pool.New(func() (*Buffer, error) {
+			return &Buffer{B: make([]byte, 0, size), owner: bp, class: class}, nil
+		}, opts...)
+		if err != nil {
+			bp.Close()
+			return nil, err
+		}
+		bp.sizes = append(bp.sizes, size)
+		bp.classes = append(bp.classes, p)
+	}
+	return bp, nil
+}
+
+// roundUp 返回不小于n的最小的2的幂
+func roundUp(n int) int {
+	size := 1
+	for size < n {
+		size *= 2
+	}
+	return size
+}
+
+// classOf 返回能容纳n个字节的最小级别，超过最大级别时返回-1
+func (bp *Pool) classOf(n int) int {
+	for i, size := range bp.sizes {
+		if n <= size {
+			return i
+		}
+	}
+	return -1
+}
+
+// Get 获取一个长度为n的缓冲区，它的内容是上一个使用者留下的，使用完后调用Put放回
+func (bp *Pool) Get(n int) (*Buffer, error) {
+	if n < 0 {
+		return nil, errors.New("bufpool: negative size")
+	}
+	class := bp.classOf(n)
+	if class < 0 {
+		bp.oversized.Add(1)
+		return &Buffer{B: make([]byte, n), owner: bp, class: -1}, nil
+	}
+	b, err := bp.classes[class].Acquire()
+	if err != nil {
+		return nil, err
+	}
+	b.B = b.B[:n]
+	return b, nil
+}
+
+// Put 把缓冲区放回池里，b的容量已经小于所在级别的大小时把它从池中移除
+func (bp *Pool) Put(b *Buffer) error {
+	if b == nil || b.owner != bp {
+		return ErrForeignBuffer
+	}
+	if b.class < 0 {
+		return nil
+	}
+	p := bp.classes[b.class]
+	if cap(b.B) < bp.sizes[b.class] {
+		return p.Discard(b)
+	}
+	b.B = b.B[:0]
+	return p.Release(b)
+}
+
+// Oversized 返回超过最大级别、没有经过池直接分配的次数
+func (bp *Pool) Oversized() uint64 {
+	return bp.oversized.Load()
+}
+
+// Stats 返回每一级的统计信息，按大小从小到大排列
+func (bp *Pool) Stats() []ClassStats {
+	stats := make([]ClassStats, len(bp.classes))
+	for i, p := range bp.classes {
+		stats[i] = ClassStats{Size: bp.sizes[i], Stats: p.Stats()}
+	}
+	return stats
+}
+
+// Close 关闭所有级别的池
+func (bp *Pool) Close() error {
+	errs := make([]error, 0, len(bp.classes))
+	for _, p := range bp.classes {
+		errs = append(errs, p.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,208 @@
+package bufpool
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lazysheep666/pool"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		min, max  int
+		wantSizes []int
+		wantErr   error
+	}{
+		{"powers of two", 512, 4096, []int{512, 1024, 2048, 4096}, nil},
+		{"rounded up", 500, 3000, []int{512, 1024, 2048, 4096}, nil},
+		{"single class", 100, 100, []int{128}, nil},
+		{"zero min", 0, 4096, nil, pool.ErrInvalidConfig},
+		{"max below min", 4096, 512, nil, pool.ErrInvalidConfig},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bp, err := New(tt.min, tt.max)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("New = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer bp.Close()
+			stats := bp.Stats()
+			if len(stats) != len(tt.wantSizes) {
+				t.Fatalf("%d classes, want %v", len(stats), tt.wantSizes)
+			}
+			for i, s := range stats {
+				if s.Size != tt.wantSizes[i] {
+					t.Errorf("class %d size %d, want %d", i, s.Size, tt.wantSizes[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		name      string
+		n         int
+		wantCap   int
+		wantClass int // -1表示直接分配
+	}{
+		{"empty", 0, 512, 0},
+		{"below min", 100, 512, 0},
+		{"exact class", 1024, 1024, 1},
+		{"between classes", 1500, 2048, 2},
+		{"max class", 4096, 4096, 3},
+		{"oversized", 5000, 5000, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bp, err := New(512, 4096)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bp.Close()
+			b, err := bp.Get(tt.n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(b.B) != tt.n || cap(b.B) != tt.wantCap {
+				t.Errorf("len = %d, cap = %d, want %d, %d", len(b.B), cap(b.B), tt.n, tt.wantCap)
+			}
+			for i, s := range bp.Stats() {
+				want := uint(0)
+				if i == tt.wantClass {
+					want = 1
+				}
+				if s.InUse != want {
+					t.Errorf("class %d InUse = %d, want %d", s.Size, s.InUse, want)
+				}
+			}
+			if err := bp.Put(b); err != nil {
+				t.Fatal(err)
+			}
+			wantOversized := uint64(0)
+			if tt.wantClass < 0 {
+				wantOversized = 1
+			}
+			if bp.Oversized() != wantOversized {
+				t.Errorf("Oversized = %d, want %d", bp.Oversized(), wantOversized)
+			}
+		})
+	}
+}
+
+func TestPut(t *testing.T) {
+	other, err := New(512, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	tests := []struct {
+		name string
+		// use 获取一个缓冲区并修改它，返回要放回的缓冲区
+		use      func(t *testing.T, bp *Pool) *Buffer
+		wantErr  error
+		wantIdle uint // 1024这一级放回后的空闲数
+	}{
+		{"reused", func(t *testing.T, bp *Pool) *Buffer {
+			return get(t, bp, 1000)
+		}, nil, 1},
+		{"grown by append still found", func(t *testing.T, bp *Pool) *Buffer {
+			b := get(t, bp, 1000)
+			b.B = append(b.B, make([]byte, 5000)...)
+			return b
+		}, nil, 1},
+		{"shrunk capacity discarded", func(t *testing.T, bp *Pool) *Buffer {
+			b := get(t, bp, 1000)
+			b.B = b.B[:10:10]
+			return b
+		}, nil, 0},
+		{"nil", func(t *testing.T, bp *Pool) *Buffer {
+			return nil
+		}, ErrForeignBuffer, 0},
+		{"other pool", func(t *testing.T, bp *Pool) *Buffer {
+			b := get(t, other, 100)
+			t.Cleanup(func() { other.Put(b) })
+			return b
+		}, ErrForeignBuffer, 0},
+		{"not from a pool", func(t *testing.T, bp *Pool) *Buffer {
+			return &Buffer{B: make([]byte, 1024)}
+		}, ErrForeignBuffer, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bp, err := New(512, 2048)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bp.Close()
+			if err := bp.Put(tt.use(t, bp)); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Put = %v, want %v", err, tt.wantErr)
+			}
+			s := bp.Stats()[1]
+			if s.Idle != tt.wantIdle || s.InUse != 0 {
+				t.Errorf("Idle = %d, InUse = %d, want %d, 0", s.Idle, s.InUse, tt.wantIdle)
+			}
+			if tt.wantIdle == 0 {
+				return
+			}
+			// 复用的缓冲区长度重新设置为n，容量不小于级别的大小
+			b := get(t, bp, 600)
+			if len(b.B) != 600 || cap(b.B) < 1024 {
+				t.Errorf("reused buffer len = %d, cap = %d", len(b.B), cap(b.B))
+			}
+			if s := bp.Stats()[1]; s.Hits != 1 {
+				t.Errorf("Hits = %d, want 1", s.Hits)
+			}
+			bp.Put(b)
+		})
+	}
+}
+
+// TestMaxIdleBytes 检查pool.WithMaxIdleBytes按缓冲区容量限制每一级的空闲内存
+func TestMaxIdleBytes(t *testing.T) {
+	bp, err := New(1024, 1024, pool.WithMaxIdleBytes(2048))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bp.Close()
+	bufs := []*Buffer{get(t, bp, 1), get(t, bp, 1), get(t, bp, 1)}
+	for _, b := range bufs {
+		if err := bp.Put(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s := bp.Stats()[0]; s.Idle != 2 {
+		t.Errorf("Idle = %d, want 2", s.Idle)
+	}
+}
+
+// TestGetErrors 检查负数大小和关闭之后的Get
+func TestGetErrors(t *testing.T) {
+	bp, err := New(512, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bp.Get(-1); err == nil {
+		t.Error("Get(-1) succeeded")
+	}
+	if err := bp.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bp.Get(10); !errors.Is(err, pool.ErrPoolClosed) {
+		t.Errorf("Get after Close = %v, want ErrPoolClosed", err)
+	}
+}
+
+// get 从bp获取一个长度为n的缓冲区，失败时结束测试
+func get(t *testing.T, bp *Pool, n int) *Buffer {
+	t.Helper()
+	b, err := bp.Get(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
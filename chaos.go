@@ -0,0 +1,104 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrChaos 是WithChaos注入的factory失败返回的错误
+var ErrChaos = errors.New("Chaos: injected failure")
+
+// ChaosConfig 设置WithChaos注入故障的概率，概率在0到1之间，0表示不注入
+type ChaosConfig struct {
+	// AcquireLatency 注入的Acquire延迟的上限，每次注入的延迟在0到AcquireLatency之间均匀分布
+	AcquireLatency time.Duration
+	// AcquireLatencyRate 每次Acquire被注入延迟的概率
+	AcquireLatencyRate float64
+	// FactoryFailureRate 每次调用factory时不调用它而直接返回ErrChaos的概率
+	FactoryFailureRate float64
+	// ValidationFailureRate 取出的空闲资源被当作验证失败而销毁的概率
+	ValidationFailureRate float64
+	// DeathRate 取出的空闲资源在交给调用者前被closer关闭的概率，模拟对端断开了连接，
+	// 调用者之后应该Discard这个资源，closer需要能够处理重复关闭
+	DeathRate float64
+	// Seed 随机数的种子，0表示使用当前时间，固定种子可以重现同样的故障序列
+	Seed int64
+}
+
+// validate 检查概率是否合法
+func (c ChaosConfig) validate() error {
+	for _, r := range []float64{c.AcquireLatencyRate, c.FactoryFailureRate, c.ValidationFailureRate, c.DeathRate} {
+		if r < 0 || r > 1 {
+			return fmt.Errorf("%w: chaos rate %v out of range [0, 1]", ErrInvalidConfig, r)
+		}
+	}
+	if c.AcquireLatency < 0 {
+		return fmt.Errorf("%w: negative chaos AcquireLatency %v", ErrInvalidConfig, c.AcquireLatency)
+	}
+	return nil
+}
+
+// chaos 按ChaosConfig随机注入故障
+type chaos struct {
+	ChaosConfig
+	m    sync.Mutex
+	rand *rand.Rand
+}
+
+// newChaos 创建注入故障的状态
+func newChaos(c ChaosConfig) *chaos {
+	seed := c.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &chaos{ChaosConfig: c, rand: rand.New(rand.NewSource(seed))}
+}
+
+// hit 以rate的概率返回true
+func (c *chaos) hit(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.rand.Float64() < rate
+}
+
+// latency 返回这次Acquire要注入的延迟，不注入时返回0
+func (c *chaos) latency() time.Duration {
+	if c.AcquireLatency <= 0 || !c.hit(c.AcquireLatencyRate) {
+		return 0
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+	return time.Duration(c.rand.Int63n(int64(c.AcquireLatency) + 1))
+}
+
+// chaosDelay 在Acquire开始时注入延迟，ctx在延迟期间结束时返回ctx.Err()
+func (p *Pool[T]) chaosDelay(ctx context.Context) error {
+	d := p.chaos.latency()
+	if d == 0 {
+		return nil
+	}
+	p.logger.Println("Chaos:", "Acquire Latency", d)
+	t := p.clock.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// chaosKill 按DeathRate在交给调用者前关闭一个空闲资源
+func (p *Pool[T]) chaosKill(r T) {
+	if p.chaos.hit(p.chaos.DeathRate) {
+		p.logger.Println("Chaos:", "Killing Resource")
+		p.closer(r)
+	}
+}
@@ -0,0 +1,56 @@
+package pool
+
+import "time"
+
+// CheckoutViolation 描述一个持有时间超过MaxCheckoutDuration的资源，在资源放回或销毁时报告
+type CheckoutViolation struct {
+	AcquiredAt time.Time     // 获取资源的时间
+	Held       time.Duration // 持有的时间
+	Stack      []byte        // 获取资源时的调用栈，只在设置了LeakTimeout时记录
+	Discarded  bool          // 资源是否被销毁而不是放回池里
+}
+
+// checkin 在资源放回或销毁时记录它被持有的时间，超过maxCheckout时返回违规的描述
+// r不在使用中时返回nil，调用者需持有p.m
+func (p *Pool[T]) checkin(r T) *CheckoutViolation {
+	e, ok := p.inUse.Get(r)
+	if !ok {
+		return nil
+	}
+	if e.profKey != nil {
+		p.unprofile(e)
+	}
+	if e.untimed {
+		return nil
+	}
+	held := p.clock.Now().Sub(e.acquiredAt)
+	e.busy += held
+	p.stats.checkoutNanos.Add(int64(held))
+	if p.maxCheckout == 0 || held <= p.maxCheckout {
+		return nil
+	}
+	p.stats.overdue.Add(1)
+	return &CheckoutViolation{AcquiredAt: e.acquiredAt, Held: held, Stack: e.stack}
+}
+
+// busyTime 返回使用中的资源e到now为止被持有的时间，包括这一次借出，调用者需持有p.m
+// 不加锁地借出或暂存的资源没有记录获取的时间，只返回之前累计的时间
+func (p *Pool[T]) busyTime(e *entry[T], now time.Time) time.Duration {
+	if e.untimed || e.lingering || p.lf != nil && e.lf.Load() != lfHeld {
+		return e.busy
+	}
+	return e.busy + now.Sub(e.acquiredAt)
+}
+
+// reportOverdue 报告一个持有时间过长的资源，未设置回调时写入日志
+func (p *Pool[T]) reportOverdue(v *CheckoutViolation) {
+	if p.onOverdue != nil {
+		p.onOverdue(*v)
+		return
+	}
+	if v.Stack != nil {
+		p.logger.Println("Checkout:", "Resource held for", v.Held, "acquired at\n"+string(v.Stack))
+		return
+	}
+	p.logger.Println("Checkout:", "Resource held for", v.Held)
+}
@@ -0,0 +1,114 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock 是池使用的时钟，空闲超时、最长使用时间、后台回收等与时间有关的逻辑都通过它获取时间
+// 默认使用系统时钟，测试时可以用WithClock换成pooltest.FakeClock
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer 是Clock创建的定时器，含义与time.Timer相同
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// Ticker 是Clock创建的周期定时器，含义与time.Ticker相同
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// realClock 是使用time包的系统时钟
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTimer(d time.Duration) Timer { return realTimer{time.NewTimer(d)} }
+
+func (realClock) NewTicker(d time.Duration) Ticker { return realTicker{time.NewTicker(d)} }
+
+type realTimer struct{ t *time.Timer }
+
+func (t realTimer) C() <-chan time.Time        { return t.t.C }
+func (t realTimer) Stop() bool                 { return t.t.Stop() }
+func (t realTimer) Reset(d time.Duration) bool { return t.t.Reset(d) }
+
+type realTicker struct{ t *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.t.C }
+func (t realTicker) Stop()               { t.t.Stop() }
+
+// withTimeout 与context.WithTimeout相同，但用clock计时
+func withTimeout(ctx context.Context, clock Clock, d time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := clock.(realClock); ok {
+		return context.WithTimeout(ctx, d)
+	}
+	c := &timeoutCtx{
+		Context:  ctx,
+		deadline: clock.Now().Add(d),
+		done:     make(chan struct{}),
+		stop:     make(chan struct{}),
+	}
+	t := clock.NewTimer(d)
+	go func() {
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			c.finish(ctx.Err())
+		case <-t.C():
+			c.finish(context.DeadlineExceeded)
+		case <-c.stop:
+			c.finish(context.Canceled)
+		}
+	}()
+	var once sync.Once
+	return c, func() {
+		once.Do(func() {
+			// 同步停止定时器，让调用者返回后不再留下这个定时器
+			t.Stop()
+			close(c.stop)
+		})
+	}
+}
+
+// timeoutCtx 是withTimeout在非系统时钟下返回的ctx
+type timeoutCtx struct {
+	context.Context
+	deadline time.Time
+	done     chan struct{}
+	stop     chan struct{}
+
+	m   sync.Mutex
+	err error
+}
+
+func (c *timeoutCtx) Deadline() (time.Time, bool) {
+	if d, ok := c.Context.Deadline(); ok && d.Before(c.deadline) {
+		return d, true
+	}
+	return c.deadline, true
+}
+
+func (c *timeoutCtx) Done() <-chan struct{} { return c.done }
+
+func (c *timeoutCtx) Err() error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.err
+}
+
+func (c *timeoutCtx) finish(err error) {
+	c.m.Lock()
+	c.err = err
+	c.m.Unlock()
+	close(c.done)
+}
@@ -0,0 +1,373 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stressResource 是压力测试使用的资源，closed记录它是否已经被关闭
+type stressResource struct{ closed atomic.Bool }
+
+func (r *stressResource) Close() error {
+	if r.closed.Swap(true) {
+		return errors.New("resource closed twice")
+	}
+	return nil
+}
+
+// TestCloseConcurrentAcquire 让多个goroutine在Acquire、Release和Discard的同时反复Close池，
+// 检查Close可以重复调用、关闭后各种Acquire只返回ErrPoolClosed、所有资源都恰好被关闭一次，应该开启竞争检测运行
+func TestCloseConcurrentAcquire(t *testing.T) {
+	rounds := 100
+	if testing.Short() {
+		rounds = 10
+	}
+	// one 把只返回一个资源的Acquire转换为acquireFunc
+	one := func(acquire func(p *Pool[*stressResource], ctx context.Context, i int) (*stressResource, error)) acquireFunc {
+		return func(p *Pool[*stressResource], ctx context.Context, i int) ([]*stressResource, error) {
+			r, err := acquire(p, ctx, i)
+			if err != nil {
+				return nil, err
+			}
+			return []*stressResource{r}, nil
+		}
+	}
+	// 除了lock-free以外都使用验证函数，检查不会借出已经关闭的资源
+	validated := []Option{WithValidator(func(r *stressResource) bool { return !r.closed.Load() })}
+	tests := []struct {
+		name    string
+		acquire acquireFunc
+		opts    []Option
+	}{
+		{"AcquireContext", one(func(p *Pool[*stressResource], ctx context.Context, i int) (*stressResource, error) {
+			return p.AcquireContext(ctx)
+		}), validated},
+		{"AcquireN", func(p *Pool[*stressResource], ctx context.Context, i int) ([]*stressResource, error) {
+			return p.AcquireN(ctx, 2)
+		}, validated},
+		{"AcquireSticky", one(func(p *Pool[*stressResource], ctx context.Context, i int) (*stressResource, error) {
+			return p.AcquireSticky(ctx, fmt.Sprint(i%3))
+		}), validated},
+		{"AcquireHashed", one(func(p *Pool[*stressResource], ctx context.Context, i int) (*stressResource, error) {
+			return p.AcquireHashed(ctx, fmt.Sprint(i%3))
+		}), validated},
+		{"AcquireWithMinWeight", one(func(p *Pool[*stressResource], ctx context.Context, i int) (*stressResource, error) {
+			return p.AcquireWithMinWeight(ctx, 1)
+		}), validated},
+		{"lock-free", one(func(p *Pool[*stressResource], ctx context.Context, i int) (*stressResource, error) {
+			return p.AcquireContext(ctx)
+		}), []Option{WithIdleStore(LockFreeStore)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < rounds; i++ {
+				if err := closeRound(16, 3, tt.acquire, tt.opts...); err != nil {
+					t.Fatalf("round %d: %v", i, err)
+				}
+			}
+		})
+	}
+}
+
+// acquireFunc 是closeRound中第i个goroutine获取资源的方法
+type acquireFunc func(p *Pool[*stressResource], ctx context.Context, i int) ([]*stressResource, error)
+
+// closeRound 创建一个池，让n个goroutine用acquire使用它，同时用closers个goroutine关闭它，返回发现的第一个问题
+func closeRound(n, closers int, acquire acquireFunc, opts ...Option) error {
+	var created, closed atomic.Int64
+	factory := func(ctx context.Context) (*stressResource, error) {
+		time.Sleep(50 * time.Microsecond)
+		created.Add(1)
+		return &stressResource{}, nil
+	}
+	p, err := NewContext(factory, append([]Option{
+		WithMaxTotal(uint(n/4 + 1)),
+		WithMinIdle(1),
+		WithIdleTimeout(time.Millisecond),
+		WithCloser(func(r *stressResource) error { closed.Add(1); return r.Close() }),
+	}, opts...)...)
+	if err != nil {
+		return err
+	}
+
+	var (
+		wg       sync.WaitGroup
+		isClosed atomic.Bool
+		failure  atomic.Value
+	)
+	fail := func(err error) { failure.CompareAndSwap(nil, err) }
+	guard := func() {
+		if v := recover(); v != nil {
+			fail(fmt.Errorf("panic: %v", v))
+		}
+	}
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer guard()
+			for j := 0; j < 50; j++ {
+				after := isClosed.Load()
+				ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+				rs, err := acquire(p, ctx, i)
+				cancel()
+				switch {
+				case err == nil && after:
+					fail(errors.New("Acquire returned a resource after Close"))
+				case err != nil && after && !errors.Is(err, ErrPoolClosed):
+					fail(fmt.Errorf("Acquire after Close returned %v", err))
+				}
+				for k, r := range rs {
+					if r.closed.Load() {
+						fail(errors.New("Acquire returned a closed resource"))
+					}
+					if (i+j+k)%3 == 0 {
+						p.Discard(r)
+					} else {
+						p.Release(r)
+					}
+				}
+			}
+		}(i)
+	}
+	for i := 0; i < closers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer guard()
+			time.Sleep(time.Millisecond)
+			if err := p.Close(); err != nil {
+				fail(err)
+			}
+			isClosed.Store(true)
+			p.BeginShutdown()
+			if err := p.Close(); err != nil {
+				fail(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err, ok := failure.Load().(error); ok {
+		return err
+	}
+	if s := p.Stats(); s.Idle != 0 || s.InUse != 0 {
+		return fmt.Errorf("%d idle and %d in-use resources left after Close", s.Idle, s.InUse)
+	}
+	if created.Load() != closed.Load() {
+		return fmt.Errorf("%d resources created but %d closed", created.Load(), closed.Load())
+	}
+	return nil
+}
+
+// TestAcquireDuringClose 检查在取得资源之后、返回之前池开始关闭时，各种Acquire都销毁资源并返回ErrPoolClosed
+func TestAcquireDuringClose(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		acquire acquireFunc
+	}{
+		{"AcquireContext", func(p *Pool[*stressResource], ctx context.Context, i int) ([]*stressResource, error) {
+			r, err := p.AcquireContext(ctx)
+			return []*stressResource{r}, err
+		}},
+		{"AcquireN", func(p *Pool[*stressResource], ctx context.Context, i int) ([]*stressResource, error) {
+			return p.AcquireN(ctx, 2)
+		}},
+		{"AcquireSticky", func(p *Pool[*stressResource], ctx context.Context, i int) ([]*stressResource, error) {
+			r, err := p.AcquireSticky(ctx, "key")
+			return []*stressResource{r}, err
+		}},
+		{"AcquireHashed", func(p *Pool[*stressResource], ctx context.Context, i int) ([]*stressResource, error) {
+			r, err := p.AcquireHashed(ctx, "key")
+			return []*stressResource{r}, err
+		}},
+		{"AcquireWithMinWeight", func(p *Pool[*stressResource], ctx context.Context, i int) ([]*stressResource, error) {
+			r, err := p.AcquireWithMinWeight(ctx, 1)
+			return []*stressResource{r}, err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var closing atomic.Bool
+			var p *Pool[*stressResource]
+			closed := make(chan error, 1)
+			// 第一次在closing之后执行的钩子开始关闭池，等Close开始之后才返回
+			hook := func(r *stressResource, s Stats) error {
+				if closing.CompareAndSwap(true, false) {
+					go func() { closed <- p.Close() }()
+					<-p.done
+				}
+				return nil
+			}
+			var err error
+			p, err = New(func() (*stressResource, error) { return &stressResource{}, nil }, WithOnAcquire(hook))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 先放回一次，让之后的获取使用空闲资源
+			rs, err := tt.acquire(p, ctx, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.ReleaseAll(rs)
+			closing.Store(true)
+			if rs, err := tt.acquire(p, ctx, 0); !errors.Is(err, ErrPoolClosed) {
+				t.Errorf("Acquire during Close = %v, %v, want ErrPoolClosed", rs, err)
+			}
+			select {
+			case err := <-closed:
+				if err != nil {
+					t.Fatal(err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Close did not return, a resource acquired during Close was not destroyed")
+			}
+			if s := p.Stats(); s.TotalCreated != s.TotalClosed {
+				t.Errorf("TotalCreated = %d, TotalClosed = %d, want all resources closed", s.TotalCreated, s.TotalClosed)
+			}
+		})
+	}
+}
+
+// TestAcquireAfterCloseIgnoresContext 检查池关闭后即使ctx已经结束，各种Acquire也返回ErrPoolClosed
+func TestAcquireAfterCloseIgnoresContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name    string
+		acquire func(p *Pool[int]) error
+	}{
+		{"AcquireContext", func(p *Pool[int]) error { _, err := p.AcquireContext(ctx); return err }},
+		{"AcquireWithMinWeight", func(p *Pool[int]) error { _, err := p.AcquireWithMinWeight(ctx, 1); return err }},
+		{"AcquireN", func(p *Pool[int]) error { _, err := p.AcquireN(ctx, 2); return err }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(func() (int, error) { return 1, nil })
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.Close()
+			if err := tt.acquire(p); !errors.Is(err, ErrPoolClosed) {
+				t.Fatalf("got %v, want ErrPoolClosed", err)
+			}
+		})
+	}
+}
+
+// TestCloseWakesQueuedAcquire 检查排队等待的Acquire在池关闭时返回ErrPoolClosed
+func TestCloseWakesQueuedAcquire(t *testing.T) {
+	p, err := New(func() (*int, error) { return new(int), nil }, WithMaxTotal(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _ := p.Acquire()
+	done := make(chan error)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_, err := p.AcquireContext(ctx)
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	closed := make(chan struct{})
+	go func() { p.Close(); close(closed) }()
+	if err := <-done; !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("got %v, want ErrPoolClosed", err)
+	}
+	p.Release(r)
+	<-closed
+}
+
+// TestCloseContext 检查Close立即关闭空闲资源、等待借出的资源放回，ctx结束时强制关闭它们
+func TestCloseContext(t *testing.T) {
+	tests := []struct {
+		name string
+		// finish 在CloseContext开始后放回借出的资源held或用cancel结束CloseContext的ctx，nil表示关闭时没有借出的资源
+		finish  func(p *Pool[*stressResource], held *stressResource, cancel context.CancelFunc)
+		wantErr error // CloseContext返回的错误
+	}{
+		{"nothing checked out", nil, nil},
+		{"waits for release", func(p *Pool[*stressResource], held *stressResource, cancel context.CancelFunc) {
+			if err := p.Release(held); err != nil {
+				t.Errorf("Release during Close: %v", err)
+			}
+		}, nil},
+		{"waits for discard", func(p *Pool[*stressResource], held *stressResource, cancel context.CancelFunc) {
+			if err := p.Discard(held); err != nil {
+				t.Errorf("Discard during Close: %v", err)
+			}
+		}, nil},
+		{"forced when ctx ends", func(p *Pool[*stressResource], held *stressResource, cancel context.CancelFunc) {
+			cancel()
+		}, context.Canceled},
+	}
+	for _, kind := range []IdleStore{SliceStore, LockFreeStore} {
+		for _, tt := range tests {
+			t.Run(kind.String()+"/"+tt.name, func(t *testing.T) {
+				var created, closed atomic.Int64
+				p, err := New(func() (*stressResource, error) {
+					created.Add(1)
+					return &stressResource{}, nil
+				}, WithIdleStore(kind), WithCloser(func(r *stressResource) error {
+					closed.Add(1)
+					return r.Close()
+				}))
+				if err != nil {
+					t.Fatal(err)
+				}
+				rs := make([]*stressResource, 3)
+				for i := range rs {
+					rs[i], _ = p.Acquire()
+				}
+				p.Release(rs[0])
+				p.Release(rs[1])
+				if tt.finish == nil {
+					p.Release(rs[2])
+				}
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+				done := make(chan error, 1)
+				go func() { done <- p.CloseContext(ctx) }()
+				if tt.finish != nil {
+					// 空闲资源立即关闭，借出的资源在放回或ctx结束之前保持打开
+					for closed.Load() < 2 {
+						time.Sleep(time.Millisecond)
+					}
+					select {
+					case err := <-done:
+						t.Fatalf("CloseContext returned %v with a resource checked out", err)
+					case <-time.After(10 * time.Millisecond):
+					}
+					if rs[2].closed.Load() {
+						t.Fatal("checked out resource closed before it was returned")
+					}
+					if _, err := p.Acquire(); !errors.Is(err, ErrPoolClosed) {
+						t.Errorf("Acquire during Close = %v, want ErrPoolClosed", err)
+					}
+					tt.finish(p, rs[2], cancel)
+				}
+				select {
+				case err := <-done:
+					if !errors.Is(err, tt.wantErr) {
+						t.Errorf("CloseContext = %v, want %v", err, tt.wantErr)
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatal("CloseContext did not return")
+				}
+				if created.Load() != 3 || closed.Load() != 3 {
+					t.Errorf("created %d and closed %d resources, want 3 and 3", created.Load(), closed.Load())
+				}
+				if err := p.Close(); err != nil {
+					t.Errorf("second Close = %v, want nil", err)
+				}
+			})
+		}
+	}
+}
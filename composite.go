@@ -0,0 +1,337 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/lazysheep666/pool/internal/identity"
+)
+
+// ErrNoHealthyMember 表示Composite中所有子池都被标记为不健康或权重为0
+var ErrNoHealthyMember = errors.New("No healthy member pool")
+
+// Member 是Composite中的一个子池，例如连接到一个可用区后端的池
+type Member[T any] struct {
+	Name   string    // 子池的名字，在Composite中唯一
+	Pool   Pooler[T] // 子池，Composite关闭时被关闭
+	Tier   int       // 优先级，Tier小的子池先被使用，例如本地为0、远端为1
+	Weight uint      // 同一Tier中按权重轮流选择子池，0表示不使用
+}
+
+// MemberStats 是Composite中一个子池的统计信息
+type MemberStats struct {
+	Name     string
+	Tier     int
+	Weight   uint
+	Healthy  bool
+	Stats    Stats  // 子池自己的统计信息
+	Acquired uint64 // 通过Composite从这个子池获取的次数
+	Spilled  uint64 // 更靠前的子池饱和时溢出到这个子池获取的次数
+}
+
+// member 是Composite中一个子池的状态
+type member[T any] struct {
+	Member[T]
+	current  int // 平滑加权轮询的当前权重
+	healthy  bool
+	acquired uint64
+	spilled  uint64
+}
+
+// tryAcquirer 是可以不等待地获取资源的子池
+type tryAcquirer[T any] interface {
+	TryAcquire() (T, error)
+}
+
+// ctxTryAcquirer 是创建资源时可以使用调用者的ctx的tryAcquirer，
+// *Pool、*ShardedPool、*Partition和*Composite实现了它
+type ctxTryAcquirer[T any] interface {
+	tryAcquireContext(ctx context.Context) (T, error)
+}
+
+// Composite 按策略在多个独立的子池之间分配获取：优先使用Tier最小的子池，同一Tier中按权重轮流选择，
+// 子池饱和时溢出到下一个子池，被标记为不健康的子池不再被获取，并在资源放回时被排空
+// 判断饱和需要子池实现TryAcquire，没有实现的子池总是被当作未饱和，所有子池都饱和时在最优先的子池上等待
+type Composite[T any] struct {
+	m       sync.Mutex
+	members []*member[T]
+	owner   identity.Map[T, *member[T]] // 使用中的资源来自哪个子池
+}
+
+// NewComposite 创建一个在members之间分配获取的Composite，所有子池都默认是健康的
+func NewComposite[T any](members ...Member[T]) (*Composite[T], error) {
+	if len(members) == 0 {
+		return nil, fmt.Errorf("%w: no members", ErrInvalidConfig)
+	}
+	c := &Composite[T]{owner: make(identity.Map[T, *member[T]])}
+	names := make(map[string]bool, len(members))
+	for _, m := range members {
+		if m.Pool == nil {
+			return nil, fmt.Errorf("%w: member %q has nil pool", ErrInvalidConfig, m.Name)
+		}
+		if names[m.Name] {
+			return nil, fmt.Errorf("%w: duplicate member %q", ErrInvalidConfig, m.Name)
+		}
+		names[m.Name] = true
+		c.members = append(c.members, &member[T]{Member: m, healthy: true})
+	}
+	return c, nil
+}
+
+// Acquire 从子池中获取一个资源
+func (c *Composite[T]) Acquire() (T, error) {
+	return c.AcquireContext(context.Background())
+}
+
+// AcquireContext 按Tier和权重依次尝试不等待地从每个健康的子池获取资源，
+// 都饱和时在第一个尝试的子池上等待，ctx的含义与Pool.AcquireContext相同
+func (c *Composite[T]) AcquireContext(ctx context.Context) (T, error) {
+	order := c.order()
+	if len(order) == 0 {
+		var zero T
+		return zero, ErrNoHealthyMember
+	}
+	r, ok, err := c.tryEach(ctx, order, true)
+	if ok || err != nil {
+		return r, err
+	}
+	return c.acquireFrom(ctx, order[0], false)
+}
+
+// TryAcquire 与AcquireContext相同，但所有子池都饱和时返回ErrPoolExhausted，不等待，
+// 跳过没有实现TryAcquire的子池
+func (c *Composite[T]) TryAcquire() (T, error) {
+	return c.tryAcquireContext(context.Background())
+}
+
+// tryAcquireContext 与TryAcquire相同，但子池创建资源时使用ctx
+func (c *Composite[T]) tryAcquireContext(ctx context.Context) (T, error) {
+	var zero T
+	order := c.order()
+	if len(order) == 0 {
+		return zero, ErrNoHealthyMember
+	}
+	r, ok, err := c.tryEach(ctx, order, false)
+	if ok || err != nil {
+		return r, err
+	}
+	return zero, ErrPoolExhausted
+}
+
+// tryEach 按order依次不等待地从子池获取资源，成功时ok为true，子池返回饱和以外的错误时返回该错误
+// 子池创建资源时使用ctx，wait为true时遇到没有实现TryAcquire的子池就在它上面等待，否则跳过它
+func (c *Composite[T]) tryEach(ctx context.Context, order []*member[T], wait bool) (r T, ok bool, err error) {
+	for i, m := range order {
+		if cta, can := m.Pool.(ctxTryAcquirer[T]); can {
+			r, err = cta.tryAcquireContext(ctx)
+		} else if ta, can := m.Pool.(tryAcquirer[T]); can {
+			r, err = ta.TryAcquire()
+		} else {
+			if wait {
+				r, err = c.acquireFrom(ctx, m, i > 0)
+				return r, err == nil, err
+			}
+			continue
+		}
+		if err == nil {
+			c.track(r, m, i > 0)
+			return r, true, nil
+		}
+		if !saturated(err) {
+			return r, false, fmt.Errorf("member %s: %w", m.Name, err)
+		}
+	}
+	return r, false, nil
+}
+
+// saturated 判断子池返回的err是否表示它暂时不能提供资源，这时溢出到下一个子池
+func saturated(err error) bool {
+	return errors.Is(err, ErrPoolExhausted) || errors.Is(err, ErrPartitionFull) || errors.Is(err, ErrPoolPaused) ||
+		errors.Is(err, ErrPoolShuttingDown) || errors.Is(err, ErrPoolClosed) || errors.Is(err, ErrNoHealthyMember)
+}
+
+// acquireFrom 在子池m上等待获取一个资源，spilled表示这是一次溢出
+func (c *Composite[T]) acquireFrom(ctx context.Context, m *member[T], spilled bool) (T, error) {
+	r, err := m.Pool.AcquireContext(ctx)
+	if err != nil {
+		return r, fmt.Errorf("member %s: %w", m.Name, err)
+	}
+	c.track(r, m, spilled)
+	return r, nil
+}
+
+// order 返回这次获取尝试子池的顺序：按Tier从小到大，同一Tier中先是平滑加权轮询选中的子池，其余按声明的顺序
+func (c *Composite[T]) order() []*member[T] {
+	c.m.Lock()
+	defer c.m.Unlock()
+	tiers := make(map[int][]*member[T])
+	var keys []int
+	for _, m := range c.members {
+		if !m.healthy || m.Weight == 0 {
+			continue
+		}
+		if _, ok := tiers[m.Tier]; !ok {
+			keys = append(keys, m.Tier)
+		}
+		tiers[m.Tier] = append(tiers[m.Tier], m)
+	}
+	// Tier通常只有几个，插入排序即可
+	for i := 1; i < len(keys); i++ {
+		for j := i; j > 0 && keys[j] < keys[j-1]; j-- {
+			keys[j], keys[j-1] = keys[j-1], keys[j]
+		}
+	}
+	order := make([]*member[T], 0, len(c.members))
+	for _, k := range keys {
+		ms := tiers[k]
+		best := pickWeighted(ms)
+		order = append(order, best)
+		for _, m := range ms {
+			if m != best {
+				order = append(order, m)
+			}
+		}
+	}
+	return order
+}
+
+// pickWeighted 用平滑加权轮询从ms中选择一个子池，ms不为空，调用者需持有c.m
+func pickWeighted[T any](ms []*member[T]) *member[T] {
+	var best *member[T]
+	total := 0
+	for _, m := range ms {
+		m.current += int(m.Weight)
+		total += int(m.Weight)
+		if best == nil || m.current > best.current {
+			best = m
+		}
+	}
+	best.current -= total
+	return best
+}
+
+// track 记录r来自子池m
+func (c *Composite[T]) track(r T, m *member[T], spilled bool) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.owner.Set(r, m)
+	m.acquired++
+	if spilled {
+		m.spilled++
+	}
+}
+
+// untrack 返回r来自的子池并删除记录，r不是从Composite获取的资源时返回nil
+func (c *Composite[T]) untrack(r T) (*member[T], bool) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	m, ok := c.owner.Get(r)
+	if !ok {
+		return nil, false
+	}
+	c.owner.Delete(r)
+	return m, m.healthy
+}
+
+// Release 把资源放回它来自的子池，子池被标记为不健康时销毁资源，
+// 不是从Composite获取或已经放回的资源返回ErrForeignResource
+func (c *Composite[T]) Release(r T) error {
+	m, healthy := c.untrack(r)
+	if m == nil {
+		return ErrForeignResource
+	}
+	if !healthy {
+		return m.Pool.Discard(r)
+	}
+	return m.Pool.Release(r)
+}
+
+// Discard 销毁一个使用中的资源
+func (c *Composite[T]) Discard(r T) error {
+	m, _ := c.untrack(r)
+	if m == nil {
+		return ErrForeignResource
+	}
+	return m.Pool.Discard(r)
+}
+
+// SetHealthy 标记名为name的子池是否健康，不健康的子池不再被获取，从它获取的资源放回时被销毁
+// 子池不存在时返回false
+func (c *Composite[T]) SetHealthy(name string, healthy bool) bool {
+	c.m.Lock()
+	defer c.m.Unlock()
+	m := c.lookup(name)
+	if m == nil {
+		return false
+	}
+	m.healthy = healthy
+	m.current = 0
+	return true
+}
+
+// SetWeight 修改名为name的子池的权重，子池不存在时返回false
+func (c *Composite[T]) SetWeight(name string, weight uint) bool {
+	c.m.Lock()
+	defer c.m.Unlock()
+	m := c.lookup(name)
+	if m == nil {
+		return false
+	}
+	m.Weight = weight
+	m.current = 0
+	return true
+}
+
+// lookup 返回名为name的子池，调用者需持有c.m
+func (c *Composite[T]) lookup(name string) *member[T] {
+	for _, m := range c.members {
+		if m.Name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// Stats 返回所有子池的统计信息的合计
+func (c *Composite[T]) Stats() Stats {
+	var s Stats
+	for _, m := range c.members {
+		s = s.add(m.Pool.Stats())
+	}
+	return s
+}
+
+// Members 按NewComposite中的顺序返回每个子池的统计信息
+func (c *Composite[T]) Members() []MemberStats {
+	c.m.Lock()
+	stats := make([]MemberStats, len(c.members))
+	for i, m := range c.members {
+		stats[i] = MemberStats{
+			Name:     m.Name,
+			Tier:     m.Tier,
+			Weight:   m.Weight,
+			Healthy:  m.healthy,
+			Acquired: m.acquired,
+			Spilled:  m.spilled,
+		}
+	}
+	c.m.Unlock()
+	// 子池的Stats可能需要它自己的锁，在c.m之外获取
+	for i, m := range c.members {
+		stats[i].Stats = m.Pool.Stats()
+	}
+	return stats
+}
+
+// Close 关闭所有子池，返回所有子池的错误的合并
+func (c *Composite[T]) Close() error {
+	var errs []error
+	for _, m := range c.members {
+		if err := m.Pool.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("member %s: %w", m.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,165 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lazysheep666/pool"
+	"github.com/lazysheep666/pool/pooltest"
+)
+
+func TestComposite(t *testing.T) {
+	// local 是Tier 0的子池，remote是Tier 1的子池，都最多有一个资源，
+	// 测试结束时调用undo中的函数放回借出的资源
+	type pools struct {
+		local, remote *pool.Pool[*tracked]
+		undo          *[]func()
+	}
+	// hold 从p借出一个资源，测试结束时放回
+	hold := func(t *testing.T, ps pools, p pool.Pooler[*tracked]) {
+		r, err := p.Acquire()
+		if err != nil {
+			t.Fatal(err)
+		}
+		*ps.undo = append(*ps.undo, func() { p.Release(r) })
+	}
+	tests := []struct {
+		name string
+		// member 返回作为Tier 0子池使用的Pooler，可以在其中改变local的状态
+		member func(t *testing.T, ps pools) pool.Pooler[*tracked]
+		want   string // 资源来自的子池，空表示TryAcquire返回ErrPoolExhausted
+	}{
+		{"local first", func(t *testing.T, ps pools) pool.Pooler[*tracked] { return ps.local }, "local"},
+		{"spills when exhausted", func(t *testing.T, ps pools) pool.Pooler[*tracked] {
+			hold(t, ps, ps.local)
+			return ps.local
+		}, "remote"},
+		{"spills when partition full", func(t *testing.T, ps pools) pool.Pooler[*tracked] {
+			pt := ps.local.Partition("tenant", 1)
+			hold(t, ps, pt)
+			return pt
+		}, "remote"},
+		{"spills when paused", func(t *testing.T, ps pools) pool.Pooler[*tracked] {
+			ps.local.Pause()
+			return ps.local
+		}, "remote"},
+		{"spills when shutting down", func(t *testing.T, ps pools) pool.Pooler[*tracked] {
+			hold(t, ps, ps.local)
+			ps.local.BeginShutdown()
+			return ps.local
+		}, "remote"},
+		{"all saturated", func(t *testing.T, ps pools) pool.Pooler[*tracked] {
+			hold(t, ps, ps.local)
+			hold(t, ps, ps.remote)
+			return ps.local
+		}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local, _ := newHarnessPool(t, pool.WithMaxTotal(1))
+			remote, _ := newHarnessPool(t, pool.WithMaxTotal(1))
+			var undo []func()
+			defer func() {
+				for _, f := range undo {
+					f()
+				}
+			}()
+			c, err := pool.NewComposite(
+				pool.Member[*tracked]{Name: "local", Pool: tt.member(t, pools{local, remote, &undo}), Tier: 0, Weight: 1},
+				pool.Member[*tracked]{Name: "remote", Pool: remote, Tier: 1, Weight: 1},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r, err := c.TryAcquire()
+			if tt.want == "" {
+				if !errors.Is(err, pool.ErrPoolExhausted) {
+					t.Fatalf("TryAcquire = %v, want ErrPoolExhausted", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Release(r)
+			for _, m := range c.Members() {
+				if got := m.Acquired == 1; got != (m.Name == tt.want) {
+					t.Errorf("member %s: Acquired = %d, want the resource from %s", m.Name, m.Acquired, tt.want)
+				}
+				if m.Name == "remote" && m.Spilled != m.Acquired {
+					t.Errorf("remote: Spilled = %d, Acquired = %d, want every remote acquire to be a spill", m.Spilled, m.Acquired)
+				}
+			}
+		})
+	}
+}
+
+// TestCompositeWeights 检查同一Tier中按权重轮流选择子池，不健康的子池不被使用，放回时销毁它的资源
+func TestCompositeWeights(t *testing.T) {
+	a, ha := newHarnessPool(t)
+	b, _ := newHarnessPool(t)
+	c, err := pool.NewComposite(
+		pool.Member[*tracked]{Name: "a", Pool: a, Weight: 2},
+		pool.Member[*tracked]{Name: "b", Pool: b, Weight: 1},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 6; i++ {
+		r, err := c.Acquire()
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Release(r)
+	}
+	ms := c.Members()
+	if ms[0].Acquired != 4 || ms[1].Acquired != 2 {
+		t.Fatalf("Acquired = %d, %d, want 4, 2", ms[0].Acquired, ms[1].Acquired)
+	}
+	held, err := c.Acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetHealthy("a", false)
+	c.SetHealthy("b", false)
+	if _, err := c.Acquire(); !errors.Is(err, pool.ErrNoHealthyMember) {
+		t.Errorf("Acquire with no healthy member = %v, want ErrNoHealthyMember", err)
+	}
+	if err := c.Release(held); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Release(held); !errors.Is(err, pool.ErrForeignResource) {
+		t.Errorf("second Release = %v, want ErrForeignResource", err)
+	}
+	// 被选中的是a，它不健康时放回的资源被销毁
+	if ha.closed.Load() != 1 {
+		t.Errorf("closed %d resources of a, want 1", ha.closed.Load())
+	}
+}
+
+// TestCompositeTryUsesContext 检查不等待地尝试子池时，子池用调用者的ctx创建资源
+func TestCompositeTryUsesContext(t *testing.T) {
+	type key struct{}
+	var got any
+	p, err := pool.NewContext(func(ctx context.Context) (*tracked, error) {
+		got = ctx.Value(key{})
+		return &tracked{}, nil
+	}, pool.WithClock(pooltest.NewFakeClock(epoch)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := pool.NewComposite(pool.Member[*tracked]{Name: "only", Pool: p, Weight: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	r, err := c.AcquireContext(context.WithValue(context.Background(), key{}, "caller"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Release(r)
+	if got != "caller" {
+		t.Errorf("factory saw ctx value %v, want the caller's", got)
+	}
+}
@@ -0,0 +1,200 @@
+package pool
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadConfig 从r读取JSON或YAML格式的配置，字段名与ParseQuery的参数名相同，例如
+//
+//	max_total: 50
+//	idle_timeout: 30s
+//	overflow_policy: block
+//
+// 时间使用time.ParseDuration的格式，读取后会用Validate检查配置，
+// 未知的字段、不合法的值和不合法的配置都返回ErrInvalidConfig，并指出出错的字段和行号
+func LoadConfig(r io.Reader) (Config, error) {
+	var cfg Config
+	dec := yaml.NewDecoder(r)
+	if err := dec.Decode(&cfg); err != nil && err != io.EOF {
+		return Config{}, configError(err)
+	}
+	if err := cfg.withDefaults().Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// configError 把解码时的错误包装为ErrInvalidConfig
+func configError(err error) error {
+	if errors.Is(err, ErrInvalidConfig) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+}
+
+// UnmarshalJSON 从JSON对象中读取配置，时间可以写成"30s"这样的字符串，
+// OverflowPolicy、ReuseStrategy和IdleStore可以写成名字，未知的字段返回ErrInvalidConfig
+func (c *Config) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for key, raw := range fields {
+		s := string(raw)
+		if s == "null" {
+			continue
+		}
+		if strings.HasPrefix(s, `"`) {
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return err
+			}
+		}
+		if err := c.set(key, s, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnmarshalYAML 从YAML映射中读取配置，规则与UnmarshalJSON相同
+func (c *Config) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("%w: line %d: config must be a mapping", ErrInvalidConfig, node.Line)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if value.Kind != yaml.ScalarNode {
+			return fmt.Errorf("%w: line %d: field %q must be a scalar", ErrInvalidConfig, value.Line, key.Value)
+		}
+		if value.Tag == "!!null" {
+			continue
+		}
+		if err := c.set(key.Value, value.Value, key.Line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// set 把名为key的字段设置为s，line不为0时出错信息中带上行号
+func (c *Config) set(key, s string, line int) error {
+	where := ""
+	if line > 0 {
+		where = fmt.Sprintf("line %d: ", line)
+	}
+	fields := queryFields()
+	i, ok := fields[key]
+	if !ok {
+		if near := nearest(key, fields); near != "" {
+			return fmt.Errorf("%w: %sunknown field %q, did you mean %q?", ErrInvalidConfig, where, key, near)
+		}
+		return fmt.Errorf("%w: %sunknown field %q", ErrInvalidConfig, where, key)
+	}
+	v, err := parseQueryValue(reflect.TypeOf(*c).Field(i).Type, s)
+	if err != nil {
+		return fmt.Errorf("%w: %sfield %q: %v", ErrInvalidConfig, where, key, err)
+	}
+	reflect.ValueOf(c).Elem().Field(i).Set(v)
+	return nil
+}
+
+// MarshalJSON 把配置编码为UnmarshalJSON可以读取的JSON对象，省略零值的字段
+func (c Config) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.values())
+}
+
+// MarshalYAML 把配置编码为UnmarshalYAML可以读取的YAML映射，省略零值的字段
+func (c Config) MarshalYAML() (any, error) {
+	return c.values(), nil
+}
+
+// values 返回配置中不为零值的字段，时间和枚举被转换为字符串
+func (c Config) values() map[string]any {
+	v := reflect.ValueOf(c)
+	out := make(map[string]any)
+	for key, i := range queryFields() {
+		f := v.Field(i)
+		if f.IsZero() {
+			continue
+		}
+		switch x := f.Interface().(type) {
+		case time.Duration:
+			out[key] = x.String()
+		case OverflowPolicy:
+			out[key] = enumName(overflowNames, x)
+		case ReuseStrategy:
+			out[key] = enumName(reuseNames, x)
+		case IdleStore:
+			out[key] = enumName(storeNames, x)
+		default:
+			out[key] = x
+		}
+	}
+	return out
+}
+
+// enumName 返回v在names中的名字，没有名字时返回它的数值
+func enumName[E comparable](names map[string]E, v E) string {
+	for name, x := range names {
+		if x == v {
+			return name
+		}
+	}
+	return fmt.Sprint(v)
+}
+
+// nearest 返回fields中与key编辑距离最小、并且不超过2的字段名，用于提示拼写错误
+func nearest(key string, fields map[string]int) string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	best, dist := "", 3
+	for _, name := range names {
+		if d := editDistance(key, name); d < dist {
+			best, dist = name, d
+		}
+	}
+	return best
+}
+
+// editDistance 返回a和b之间的Levenshtein距离
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
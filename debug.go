@@ -0,0 +1,200 @@
+package pool
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/lazysheep666/pool/internal/identity"
+)
+
+// DebugInfo 是池内部状态的快照，用于调试
+type DebugInfo struct {
+	Stats  Stats          `json:"stats"`
+	Config Config         `json:"config"` // 当前生效的配置
+	Idle   []ResourceInfo `json:"idle"`
+	InUse  []ResourceInfo `json:"in_use"`
+}
+
+// ResourceInfo 描述池中的一个资源
+type ResourceInfo struct {
+	CreatedAt time.Time     `json:"created_at"`
+	Age       time.Duration `json:"age"`  // 从创建起经过的时间
+	Uses      uint          `json:"uses"` // 被获取的次数
+	// LastUsed 最近一次被获取的时间，从未被获取时为零值
+	LastUsed time.Time `json:"last_used"`
+	// Idle 空闲资源自放回池中起经过的时间
+	Idle time.Duration `json:"idle,omitempty"`
+	// Held 使用中的资源自被获取起经过的时间
+	Held time.Duration `json:"held,omitempty"`
+	// Stack 开启泄漏检测时获取资源的调用栈
+	Stack string `json:"stack,omitempty"`
+	// Tags SetTag设置的标签
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// Debug 返回池内部状态的快照
+func (p *Pool[T]) Debug() DebugInfo {
+	stats := p.Stats()
+	now := p.clock.Now()
+	p.m.Lock()
+	defer p.m.Unlock()
+	info := DebugInfo{
+		Stats:  stats,
+		Config: p.liveConfig(),
+		Idle:   make([]ResourceInfo, 0, p.idle.Len()),
+		InUse:  make([]ResourceInfo, 0, len(p.inUse)),
+	}
+	for i := 0; i < p.idle.Len(); i++ {
+		e := p.idle.At(i)
+		info.Idle = append(info.Idle, e.info(now, true))
+	}
+	for _, e := range p.inUse {
+		info.InUse = append(info.InUse, e.info(now, false))
+	}
+	return info
+}
+
+// Inspect 对每个空闲资源调用一次fn，顺序与空闲资源的顺序相同
+// fn看到的是调用Inspect时的快照，调用fn时不持有池的锁
+func (p *Pool[T]) Inspect(fn func(ResourceInfo)) {
+	now := p.clock.Now()
+	p.m.Lock()
+	infos := make([]ResourceInfo, 0, p.idle.Len())
+	for i := 0; i < p.idle.Len(); i++ {
+		e := p.idle.At(i)
+		infos = append(infos, e.info(now, true))
+	}
+	p.m.Unlock()
+	for _, info := range infos {
+		fn(info)
+	}
+}
+
+// CheckoutInfo 描述一个借出的资源，由InUse提供
+type CheckoutInfo struct {
+	ResourceInfo
+	AcquiredAt time.Time // 本次被获取的时间
+	Backend    string    // 使用WithBalancer时创建资源的后端的名字
+	Affinity   string    // 最近一次用AcquireSticky获取时的键
+	Borrowers  uint      // 同时持有资源的借用者数，只在WithSharing的共享模式下大于1
+}
+
+// InUse 按获取的时间从早到晚对每个借出的资源调用一次fn，fn返回false时停止，用于列出当前的借出情况
+// 只有开启泄漏检测时CheckoutInfo.Stack才有获取资源的调用栈
+// fn看到的是调用InUse时的快照，调用fn时不持有池的锁，不影响池的其它操作
+func (p *Pool[T]) InUse(fn func(info CheckoutInfo) bool) {
+	now := p.clock.Now()
+	p.m.Lock()
+	infos := make([]CheckoutInfo, 0, len(p.inUse))
+	rs := make([]T, 0, len(p.inUse))
+	for _, e := range p.inUse {
+		infos = append(infos, CheckoutInfo{
+			ResourceInfo: e.info(now, false),
+			AcquiredAt:   e.acquiredAt,
+			Affinity:     e.affinity,
+			Borrowers:    e.refs,
+		})
+		rs = append(rs, e.r)
+	}
+	p.m.Unlock()
+	if p.balancer != nil {
+		for i, r := range rs {
+			infos[i].Backend = p.balancer.backendOf(r)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].AcquiredAt.Before(infos[j].AcquiredAt) })
+	for _, info := range infos {
+		if !fn(info) {
+			return
+		}
+	}
+}
+
+// SetTag 给使用中或空闲的资源r设置一个标签，r不在池里时返回false
+// 可以在OnAcquire和OnRelease钩子中调用，OnCreate钩子执行时资源还没有放进池里
+func (p *Pool[T]) SetTag(r T, key, value string) bool {
+	p.m.Lock()
+	defer p.m.Unlock()
+	e := p.lookupEntry(r)
+	if e == nil {
+		return false
+	}
+	if e.tags == nil {
+		e.tags = make(map[string]string)
+	}
+	e.tags[key] = value
+	return true
+}
+
+// Tags 返回资源r的标签的副本，r不在池里或没有标签时返回nil
+func (p *Pool[T]) Tags(r T) map[string]string {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if e := p.lookupEntry(r); e != nil {
+		return copyTags(e.tags)
+	}
+	return nil
+}
+
+// lookupEntry 返回使用中或空闲的资源r的记录，调用者需持有p.m
+func (p *Pool[T]) lookupEntry(r T) *entry[T] {
+	if e, ok := p.inUse.Get(r); ok {
+		return e
+	}
+	for i := 0; i < p.idle.Len(); i++ {
+		e := p.idle.At(i)
+		if identity.Same(e.r, r) {
+			return e
+		}
+	}
+	return nil
+}
+
+// info 返回e在now时的ResourceInfo，调用者需持有池的锁
+func (e *entry[T]) info(now time.Time, idle bool) ResourceInfo {
+	info := ResourceInfo{
+		CreatedAt: e.createdAt,
+		Age:       now.Sub(e.createdAt),
+		Uses:      e.uses,
+		LastUsed:  e.acquiredAt,
+		Tags:      copyTags(e.tags),
+	}
+	if idle {
+		info.Idle = now.Sub(e.returnedAt)
+	} else {
+		info.Held = now.Sub(e.acquiredAt)
+		info.Stack = string(e.stack)
+	}
+	return info
+}
+
+// copyTags 返回tags的副本，tags为空时返回nil
+func copyTags(tags map[string]string) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+	c := make(map[string]string, len(tags))
+	for k, v := range tags {
+		c[k] = v
+	}
+	return c
+}
+
+// Handler 返回一个以JSON格式输出Debug结果的http.Handler
+func (p *Pool[T]) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(p.Debug())
+	})
+}
+
+// PublishExpvar 以name为名字通过expvar发布Debug的结果
+// 与expvar.Publish一样，name已被使用时会panic
+func (p *Pool[T]) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() any { return p.Debug() }))
+}
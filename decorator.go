@@ -0,0 +1,142 @@
+package pool
+
+import (
+	"context"
+	"time"
+)
+
+// Decorator 包装一个Pooler，返回添加了额外行为的Pooler，用Chain组合多个Decorator
+type Decorator[T any] func(Pooler[T]) Pooler[T]
+
+// Chain 用ds依次包装p，ds[0]在最外层，最先看到每次调用
+//
+//	p := pool.Chain[*Conn](base, pool.WithLoggingDecorator[*Conn](logger), pool.WithRateLimitDecorator[*Conn](limiter))
+func Chain[T any](p Pooler[T], ds ...Decorator[T]) Pooler[T] {
+	for i := len(ds) - 1; i >= 0; i-- {
+		p = ds[i](p)
+	}
+	return p
+}
+
+// Intercept 返回一个拦截获取和放回的Decorator，其它方法直接交给被包装的Pooler
+// acquire收到ctx和被包装的AcquireContext，release收到资源、是否是Discard以及被包装的Release或Discard，
+// 为nil的函数不拦截
+func Intercept[T any](
+	acquire func(ctx context.Context, next func(context.Context) (T, error)) (T, error),
+	release func(r T, discard bool, next func(T) error) error,
+) Decorator[T] {
+	return func(p Pooler[T]) Pooler[T] {
+		return &intercepted[T]{Pooler: p, acquire: acquire, release: release}
+	}
+}
+
+// intercepted 是Intercept返回的Decorator包装后的Pooler
+type intercepted[T any] struct {
+	Pooler[T]
+	acquire func(ctx context.Context, next func(context.Context) (T, error)) (T, error)
+	release func(r T, discard bool, next func(T) error) error
+}
+
+func (w *intercepted[T]) Acquire() (T, error) {
+	return w.AcquireContext(context.Background())
+}
+
+func (w *intercepted[T]) AcquireContext(ctx context.Context) (T, error) {
+	if w.acquire == nil {
+		return w.Pooler.AcquireContext(ctx)
+	}
+	return w.acquire(ctx, w.Pooler.AcquireContext)
+}
+
+func (w *intercepted[T]) Release(r T) error {
+	if w.release == nil {
+		return w.Pooler.Release(r)
+	}
+	return w.release(r, false, w.Pooler.Release)
+}
+
+func (w *intercepted[T]) Discard(r T) error {
+	if w.release == nil {
+		return w.Pooler.Discard(r)
+	}
+	return w.release(r, true, w.Pooler.Discard)
+}
+
+// WithLoggingDecorator 返回一个用l记录每次获取、放回和销毁的耗时与错误的Decorator
+func WithLoggingDecorator[T any](l Logger) Decorator[T] {
+	return Intercept(
+		func(ctx context.Context, next func(context.Context) (T, error)) (T, error) {
+			start := time.Now()
+			r, err := next(ctx)
+			if err != nil {
+				l.Println("Acquire:", "Failed after", time.Since(start), err)
+			} else {
+				l.Println("Acquire:", "Took", time.Since(start))
+			}
+			return r, err
+		},
+		func(r T, discard bool, next func(T) error) error {
+			op := "Release"
+			if discard {
+				op = "Discard"
+			}
+			if err := next(r); err != nil {
+				l.Println(op, err)
+				return err
+			}
+			l.Println(op, "Done")
+			return nil
+		},
+	)
+}
+
+// Operation 是WithMetricsDecorator报告的操作
+type Operation string
+
+const (
+	OpAcquire Operation = "acquire"
+	OpRelease Operation = "release"
+	OpDiscard Operation = "discard"
+)
+
+// WithMetricsDecorator 返回一个在每次获取、放回和销毁后用操作、耗时和错误调用record的Decorator，
+// 用来把这些操作接入任意的指标系统
+func WithMetricsDecorator[T any](record func(op Operation, d time.Duration, err error)) Decorator[T] {
+	return Intercept(
+		func(ctx context.Context, next func(context.Context) (T, error)) (T, error) {
+			start := time.Now()
+			r, err := next(ctx)
+			record(OpAcquire, time.Since(start), err)
+			return r, err
+		},
+		func(r T, discard bool, next func(T) error) error {
+			op := OpRelease
+			if discard {
+				op = OpDiscard
+			}
+			start := time.Now()
+			err := next(r)
+			record(op, time.Since(start), err)
+			return err
+		},
+	)
+}
+
+// Limiter 限制获取资源的速率，golang.org/x/time/rate.Limiter实现了这个接口
+type Limiter interface {
+	Wait(ctx context.Context) error
+}
+
+// WithRateLimitDecorator 返回一个在每次获取前等待l的Decorator，等待期间ctx结束时返回l的错误
+func WithRateLimitDecorator[T any](l Limiter) Decorator[T] {
+	return Intercept[T](
+		func(ctx context.Context, next func(context.Context) (T, error)) (T, error) {
+			if err := l.Wait(ctx); err != nil {
+				var zero T
+				return zero, err
+			}
+			return next(ctx)
+		},
+		nil,
+	)
+}
@@ -0,0 +1,90 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// ErrNoDefaultPool 表示调用包级别的Acquire等函数时没有用SetDefault设置对应资源类型的默认池
+var ErrNoDefaultPool = errors.New("No default pool for resource type")
+
+// defaults 保存每种资源类型的默认池，键是资源类型的reflect.Type
+var defaults sync.Map
+
+// SetDefault 把p设置为资源类型T的默认池，供包级别的Acquire、Release、Discard和With使用，
+// 每种资源类型有各自的默认池，p为nil时清除默认池，适合不想传递池对象的小程序
+//
+//	pool.SetDefault[net.Conn](p)
+//	c, err := pool.Acquire[net.Conn](ctx)
+//	defer pool.Release(c)
+func SetDefault[T any](p Pooler[T]) {
+	key := reflect.TypeOf((*T)(nil)).Elem()
+	if p == nil {
+		defaults.Delete(key)
+		return
+	}
+	defaults.Store(key, p)
+}
+
+// Default 返回资源类型T的默认池，没有设置时返回nil
+func Default[T any]() Pooler[T] {
+	v, ok := defaults.Load(reflect.TypeOf((*T)(nil)).Elem())
+	if !ok {
+		return nil
+	}
+	return v.(Pooler[T])
+}
+
+// Acquire 从资源类型T的默认池中获取一个资源
+func Acquire[T any](ctx context.Context) (T, error) {
+	p := Default[T]()
+	if p == nil {
+		var zero T
+		return zero, ErrNoDefaultPool
+	}
+	return p.AcquireContext(ctx)
+}
+
+// Release 把资源放回资源类型T的默认池
+func Release[T any](r T) error {
+	p := Default[T]()
+	if p == nil {
+		return ErrNoDefaultPool
+	}
+	return p.Release(r)
+}
+
+// Discard 销毁从资源类型T的默认池中获取的资源
+func Discard[T any](r T) error {
+	p := Default[T]()
+	if p == nil {
+		return ErrNoDefaultPool
+	}
+	return p.Discard(r)
+}
+
+// With 从资源类型T的默认池中获取一个资源并用它调用fn，含义与Pool.With相同
+func With[T any](ctx context.Context, fn func(r T) error) (err error) {
+	p := Default[T]()
+	if p == nil {
+		return ErrNoDefaultPool
+	}
+	r, err := p.AcquireContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			p.Discard(r)
+			panic(v)
+		}
+		if err != nil {
+			p.Discard(r)
+			return
+		}
+		p.Release(r)
+	}()
+	return fn(r)
+}
@@ -0,0 +1,91 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+)
+
+// DefaultRetries 是没有设置WithRetries时Do在失败后重试的次数
+const DefaultRetries = 1
+
+// DoOption 用于配置一次Do调用
+type DoOption func(*doSettings)
+
+type doSettings struct {
+	retryable func(error) bool
+	retries   int
+}
+
+// WithRetryOn 设置哪些错误说明资源已经损坏，需要销毁它并用另一个资源重试，默认使用IsConnError
+func WithRetryOn(retryable func(error) bool) DoOption {
+	return func(s *doSettings) { s.retryable = retryable }
+}
+
+// WithRetries 设置Do在可以重试的错误之后最多重试的次数，默认为DefaultRetries，0表示不重试
+func WithRetries(n int) DoOption {
+	return func(s *doSettings) { s.retries = n }
+}
+
+// Do 获取一个资源执行fn，执行成功时把资源放回池里
+// fn返回可以重试的错误(见WithRetryOn)时销毁资源，再获取一个资源重试，用于连接被对端关闭等偶发的错误；
+// 返回其它错误时资源仍然放回池里并返回这个错误，fn panic时销毁资源并继续panic
+// 重试次数用完或ctx结束时返回最后一次的错误，获取资源失败时返回获取的错误
+func (p *Pool[T]) Do(ctx context.Context, fn func(r T) error, opts ...DoOption) error {
+	s := doSettings{retryable: IsConnError, retries: DefaultRetries}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	var last error
+	for attempt := 0; ; attempt++ {
+		r, err := p.AcquireContext(ctx)
+		if err != nil {
+			if last != nil {
+				return errors.Join(last, err)
+			}
+			return err
+		}
+		err = p.do(r, fn, s.retryable)
+		if err == nil || !s.retryable(err) {
+			return err
+		}
+		last = err
+		if attempt >= s.retries || ctx.Err() != nil {
+			return err
+		}
+		p.logger.Println("Do:", "Retrying:", err)
+	}
+}
+
+// do 用r执行fn，按fn的结果放回或销毁r
+func (p *Pool[T]) do(r T, fn func(r T) error, retryable func(error) bool) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			p.Discard(r)
+			panic(v)
+		}
+		if err != nil && retryable(err) {
+			p.Discard(r)
+			return
+		}
+		p.Release(r)
+	}()
+	return fn(r)
+}
+
+// IsConnError 判断err是否说明连接已经断开或不可用，例如对端关闭了连接、连接被重置或已经被关闭
+// 超时不被视为连接错误，因为重试同样可能超时
+func IsConnError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && !opErr.Timeout()
+}
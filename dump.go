@@ -0,0 +1,158 @@
+package pool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+// StateDump 是DumpState输出的池状态的完整快照，在DebugInfo的基础上增加了排队等待的Acquire
+type StateDump struct {
+	Name    string       `json:"name,omitempty"`
+	Time    time.Time    `json:"time"`
+	Closed  bool         `json:"closed"`
+	Paused  bool         `json:"paused"`
+	Waiters []WaiterInfo `json:"waiters"`
+	DebugInfo
+}
+
+// WaiterInfo 描述一个排队等待资源的Acquire
+type WaiterInfo struct {
+	Priority Priority      `json:"priority"`
+	Consumer string        `json:"consumer,omitempty"` // AcquireAs的消费者
+	N        uint          `json:"n"`                  // 需要的资源数，AcquireN时大于1
+	Waiting  time.Duration `json:"waiting"`            // 已经排队的时间
+	// Stack 开启泄漏检测时等待者的调用栈
+	Stack string `json:"stack,omitempty"`
+}
+
+// StateEncoder 把StateDump写入w，用于DumpStateWith选择输出的格式
+type StateEncoder func(w io.Writer, s StateDump) error
+
+// State 返回池当前状态的快照，使用中的资源按获取的时间从早到晚排列
+func (p *Pool[T]) State() StateDump {
+	info := p.Debug()
+	now := p.clock.Now()
+	p.m.Lock()
+	s := StateDump{
+		Name:      p.name,
+		Time:      now,
+		Closed:    p.closed,
+		Paused:    p.paused,
+		Waiters:   make([]WaiterInfo, 0, len(p.waiters)),
+		DebugInfo: info,
+	}
+	for _, w := range p.waiters {
+		s.Waiters = append(s.Waiters, WaiterInfo{Priority: w.prio, Consumer: w.consumer, N: w.n, Waiting: now.Sub(w.since), Stack: string(w.stack)})
+	}
+	p.m.Unlock()
+	sort.Slice(s.InUse, func(i, j int) bool { return s.InUse[i].Held > s.InUse[j].Held })
+	return s
+}
+
+// DumpState 把池当前的状态先以便于阅读的文本、再以JSON写入w，用于排查死锁等问题，例如在收到SIGQUIT时调用，见DumpOnSignal
+func (p *Pool[T]) DumpState(w io.Writer) error {
+	return p.DumpStateWith(w, TextState, JSONState)
+}
+
+// DumpStateWith 依次用encoders把池当前的状态写入w
+func (p *Pool[T]) DumpStateWith(w io.Writer, encoders ...StateEncoder) error {
+	s := p.State()
+	for _, enc := range encoders {
+		if err := enc(w, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// JSONState 以缩进的JSON格式写入s
+func JSONState(w io.Writer, s StateDump) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// TextState 以便于阅读的文本格式写入s，调用栈附在每个资源和等待者之后
+func TextState(w io.Writer, s StateDump) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	name := s.Name
+	if name == "" {
+		name = "(unnamed)"
+	}
+	fmt.Fprintf(tw, "pool %s at %s closed=%v paused=%v\n", name, s.Time.Format(time.RFC3339Nano), s.Closed, s.Paused)
+	st := s.Stats
+	fmt.Fprintf(tw, "idle=%d in_use=%d waiting=%d quarantined=%d lingering=%d created=%d closed=%d\n",
+		st.Idle, st.InUse, st.Waiting, st.Quarantined, st.Lingering, st.TotalCreated, st.TotalClosed)
+	fmt.Fprintf(tw, "max_total=%d max_idle=%d min_idle=%d\n", s.Config.MaxTotal, s.Config.MaxIdle, s.Config.MinIdle)
+
+	fmt.Fprintf(tw, "\nwaiters (%d)\n", len(s.Waiters))
+	for i, wi := range s.Waiters {
+		fmt.Fprintf(tw, "  #%d\tpriority=%d\tconsumer=%q\tn=%d\twaiting=%v\n", i, wi.Priority, wi.Consumer, wi.N, wi.Waiting)
+		stack(tw, wi.Stack)
+	}
+	fmt.Fprintf(tw, "\nin use (%d)\n", len(s.InUse))
+	for i, r := range s.InUse {
+		fmt.Fprintf(tw, "  #%d\theld=%v\tage=%v\tuses=%d\n", i, r.Held, r.Age, r.Uses)
+		stack(tw, r.Stack)
+	}
+	fmt.Fprintf(tw, "\nidle (%d)\n", len(s.Idle))
+	for i, r := range s.Idle {
+		fmt.Fprintf(tw, "  #%d\tidle=%v\tage=%v\tuses=%d\n", i, r.Idle, r.Age, r.Uses)
+	}
+	fmt.Fprintln(tw)
+	return tw.Flush()
+}
+
+// stack 缩进写入一个调用栈，没有调用栈时不写入
+func stack(w io.Writer, s string) {
+	if s == "" {
+		return
+	}
+	fmt.Fprintf(w, "    %s\n", indent(s))
+}
+
+// indent 在s的每个换行之后加上缩进
+func indent(s string) string {
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		out = append(out, s[i])
+		if s[i] == '\n' && i < len(s)-1 {
+			out = append(out, "    "...)
+		}
+	}
+	return string(out)
+}
+
+// Dumper 是可以输出自己状态的池，*Pool实现了这个接口
+type Dumper interface {
+	DumpState(w io.Writer) error
+}
+
+// DumpOnSignal 每次收到signals中的信号时把p的状态写入w，没有指定signals时使用SIGQUIT，直到ctx结束
+// 监听SIGQUIT后Go运行时不再在收到它时输出所有goroutine的调用栈并退出
+func DumpOnSignal(ctx context.Context, p Dumper, w io.Writer, signals ...os.Signal) {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGQUIT}
+	}
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, signals...)
+	go func() {
+		defer signal.Stop(sig)
+		for {
+			select {
+			case <-sig:
+				p.DumpState(w)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
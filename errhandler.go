@@ -0,0 +1,56 @@
+package pool
+
+import "time"
+
+// ErrorSource 是产生后台错误的操作
+type ErrorSource int
+
+const (
+	// SourceClose 表示closer关闭资源失败或超时
+	SourceClose ErrorSource = iota
+	// SourceKeepalive 表示后台保活时ping失败，资源会被关闭
+	SourceKeepalive
+	// SourceReplenish 表示后台补充空闲资源时创建失败
+	SourceReplenish
+	// SourceSchedule 表示WithSchedule的时间表要求的容量不合法
+	SourceSchedule
+	// SourceStats 表示WithStatsSink设置的StatsSink推送统计信息失败
+	SourceStats
+	// SourceState 表示WithStateStore设置的StateStore读取或保存容量提示失败
+	SourceState
+)
+
+// String 返回操作的名字
+func (s ErrorSource) String() string {
+	switch s {
+	case SourceClose:
+		return "Close"
+	case SourceKeepalive:
+		return "Keepalive"
+	case SourceReplenish:
+		return "Replenish"
+	case SourceSchedule:
+		return "Schedule"
+	case SourceStats:
+		return "Stats"
+	case SourceState:
+		return "State"
+	}
+	return "Unknown"
+}
+
+// ErrorContext 描述一个后台错误发生的位置
+type ErrorContext struct {
+	Source ErrorSource
+	Time   time.Time // 错误发生的时间
+
+	Pool   string            // WithName设置的池的名字
+	Labels map[string]string // WithLabels设置的池的标签，不应修改
+}
+
+// reportError 把后台错误交给WithErrorHandler设置的函数
+func (p *Pool[T]) reportError(err error, source ErrorSource) {
+	if p.onError != nil {
+		p.onError(err, ErrorContext{Source: source, Time: p.clock.Now(), Pool: p.name, Labels: p.labels})
+	}
+}
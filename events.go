@@ -0,0 +1,89 @@
+package pool
+
+import (
+	"context"
+	"time"
+)
+
+// EventType 是池中发生的事件的类型
+type EventType int
+
+const (
+	// ResourceCreated 表示创建了一个资源，Duration是调用factory(包括重试)的时间
+	ResourceCreated EventType = iota
+	// ResourceDestroyed 表示关闭了一个资源，Err是closer返回的错误
+	ResourceDestroyed
+	// AcquireWaited 表示一次Acquire因资源达到上限而等待过，Duration是等待的时间，
+	// Err是这次Acquire最终的错误
+	AcquireWaited
+	// AcquireTimedOut 表示一次Acquire超时，Duration是这次Acquire用去的时间
+	AcquireTimedOut
+	// PoolClosed 表示池已经关闭，Duration是等待使用中的资源被放回的时间，
+	// Err是CloseContext返回的错误
+	PoolClosed
+	// ReaperRun 表示后台goroutine完成了一次回收，Duration是回收用去的时间，
+	// Count是关闭的空闲资源数
+	ReaperRun
+	// ResourceLeaked 表示一个没有放回就被丢弃的资源被WithReclaimAbandoned回收，
+	// Duration是它被持有的时间
+	ResourceLeaked
+	// ResourceClosedOutside 表示Release时发现资源已经被调用者直接关闭，池不再关闭它，
+	// Duration是它存活的时间
+	ResourceClosedOutside
+	// SanitizeFailed 表示WithSanitizer设置的函数没能清理放回的资源，资源被销毁，
+	// Err是清理函数返回的错误
+	SanitizeFailed
+	// AcquireStarved 表示一次Acquire排队等待超过了StarvationThreshold并且仍在等待，Duration是已经等待的时间
+	AcquireStarved
+)
+
+// String 返回事件类型的名字
+func (t EventType) String() string {
+	switch t {
+	case ResourceCreated:
+		return "ResourceCreated"
+	case ResourceDestroyed:
+		return "ResourceDestroyed"
+	case AcquireWaited:
+		return "AcquireWaited"
+	case AcquireTimedOut:
+		return "AcquireTimedOut"
+	case PoolClosed:
+		return "PoolClosed"
+	case ReaperRun:
+		return "ReaperRun"
+	case ResourceLeaked:
+		return "ResourceLeaked"
+	case ResourceClosedOutside:
+		return "ResourceClosedOutside"
+	case SanitizeFailed:
+		return "SanitizeFailed"
+	case AcquireStarved:
+		return "AcquireStarved"
+	}
+	return "Unknown"
+}
+
+// Event 描述池中发生的一个事件
+type Event struct {
+	Type     EventType
+	Time     time.Time     // 事件发生的时间
+	Duration time.Duration // 含义由Type决定
+	Count    int           // 含义由Type决定
+	Err      error
+
+	// Context 是触发事件的Acquire的ctx，可以从中读取请求ID、租户等值，只有AcquireWaited、
+	// AcquireTimedOut和ResourceCreated有，后台补充资源时的ResourceCreated是context.Background()，其它事件为nil
+	Context context.Context
+
+	Pool   string            // WithName设置的池的名字
+	Labels map[string]string // WithLabels设置的池的标签，不应修改
+}
+
+// emit 把事件交给WithEventSink设置的函数
+func (p *Pool[T]) emit(e Event) {
+	if p.onEvent != nil {
+		e.Pool, e.Labels = p.name, p.labels
+		p.onEvent(e)
+	}
+}
@@ -0,0 +1,107 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lazysheep666/pool/internal/identity"
+)
+
+// DefaultFailbackInterval 是切换到备用factory后，默认重新尝试更靠前的factory的间隔
+const DefaultFailbackInterval = 30 * time.Second
+
+// failover 按顺序使用WithFactories设置的多个factory创建资源
+// 当前的factory创建失败，或它创建的资源健康检查失败时切换到下一个，
+// 之后每隔interval从第一个factory开始重新尝试，成功时切换回去
+type failover[T any] struct {
+	factories []func(context.Context) (T, error)
+	interval  time.Duration
+	logger    Logger
+	clock     Clock
+
+	m       sync.Mutex
+	active  int                  // 当前使用的factory
+	probeAt time.Time            // 下一次尝试更靠前的factory的时间
+	origin  identity.Map[T, int] // 每个资源由哪个factory创建
+}
+
+func newFailover[T any](factories []func(context.Context) (T, error), interval time.Duration, logger Logger, clock Clock) *failover[T] {
+	return &failover[T]{
+		factories: factories,
+		interval:  interval,
+		logger:    logger,
+		clock:     clock,
+		origin:    make(identity.Map[T, int]),
+	}
+}
+
+// create 用当前的factory创建资源，失败时依次尝试后面的factory，全部失败时返回所有错误的合并
+// 到了尝试的时间时先从第一个factory开始尝试
+func (f *failover[T]) create(ctx context.Context) (T, error) {
+	now := f.clock.Now()
+	f.m.Lock()
+	start := f.active
+	if start > 0 && !now.Before(f.probeAt) {
+		start = 0
+		f.probeAt = now.Add(f.interval)
+	}
+	f.m.Unlock()
+
+	var errs []error
+	for i := start; i < len(f.factories); i++ {
+		r, err := f.factories[i](ctx)
+		if err == nil {
+			f.m.Lock()
+			f.origin.Set(r, i)
+			f.switchTo(i)
+			f.m.Unlock()
+			return r, nil
+		}
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			// ctx结束导致的失败不说明factory不可用
+			break
+		}
+	}
+	var zero T
+	return zero, errors.Join(errs...)
+}
+
+// unhealthy 报告r的健康检查失败，r由当前的factory创建时切换到下一个
+func (f *failover[T]) unhealthy(r T) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	if i, ok := f.origin.Get(r); ok && i == f.active && i+1 < len(f.factories) {
+		f.switchTo(i + 1)
+	}
+}
+
+// forget 在资源被关闭时删除它的记录
+func (f *failover[T]) forget(r T) {
+	f.m.Lock()
+	f.origin.Delete(r)
+	f.m.Unlock()
+}
+
+// switchTo 把当前的factory切换为第i个，调用者需持有f.m
+func (f *failover[T]) switchTo(i int) {
+	if i == f.active {
+		return
+	}
+	if i > f.active {
+		f.logger.Println("Failover:", "Switching To Fallback", i)
+		f.probeAt = f.clock.Now().Add(f.interval)
+	} else {
+		f.logger.Println("Failover:", "Switching Back To", i)
+	}
+	f.active = i
+}
+
+// unhealthy 在资源的健康检查失败时通知failover
+func (p *Pool[T]) unhealthy(r T) {
+	if p.failover != nil {
+		p.failover.unhealthy(r)
+	}
+}
@@ -0,0 +1,110 @@
+package pool
+
+import (
+	"context"
+	"time"
+)
+
+// ConsumerStats 是用AcquireAs获取资源的一个消费者的统计信息
+type ConsumerStats struct {
+	Acquired uint64 `json:"acquired"` // 成功获取的次数
+	Failed   uint64 `json:"failed"`   // 超时、取消或池关闭等获取失败的次数
+	// WaitCount 和WaitDuration 是排队等待过的获取次数和等待的总时间
+	WaitCount    uint64        `json:"wait_count"`
+	WaitDuration time.Duration `json:"wait_duration"`
+	Waiting      uint          `json:"waiting"` // 正在排队等待的获取数
+	InUse        uint          `json:"in_use"`  // 正在持有的资源数，共享的资源只计入第一个借用者
+}
+
+// consumer 是一个消费者的公平调度状态和统计信息，由p.m保护
+type consumer struct {
+	finish uint64 // 最近一次排队的虚拟完成时间
+	stats  ConsumerStats
+}
+
+// AcquireAs 与AcquireContext相同，但以消费者id的身份获取资源，id通常是组件或租户的名字，应当是有限的几个值
+// 资源不足需要排队时，池在同一优先级的消费者之间公平地分配资源(自计时公平排队，类似于按获取次数的赤字轮询)：
+// 每个消费者的等待者按自己的顺序排在一起，不同消费者轮流得到资源，获取频繁的消费者不能靠数量占满队列，
+// 不用AcquireAs的Acquire作为同一个匿名的消费者参与分配；每个消费者的统计信息见ConsumerStats
+func (p *Pool[T]) AcquireAs(ctx context.Context, id string) (T, error) {
+	return p.acquire(ctx, PriorityNormal, id, acquireWait)
+}
+
+// ConsumerStats 返回每个用AcquireAs获取过资源的消费者的统计信息，以消费者的id为键
+func (p *Pool[T]) ConsumerStats() map[string]ConsumerStats {
+	p.m.Lock()
+	defer p.m.Unlock()
+	out := make(map[string]ConsumerStats, len(p.consumers))
+	for id, c := range p.consumers {
+		if id != "" {
+			out[id] = c.stats
+		}
+	}
+	for _, w := range p.waiters {
+		if s, ok := out[w.consumer]; ok {
+			s.Waiting++
+			out[w.consumer] = s
+		}
+	}
+	for _, e := range p.inUse {
+		if s, ok := out[e.consumer]; ok {
+			s.InUse++
+			out[e.consumer] = s
+		}
+	}
+	return out
+}
+
+// fairTag 返回消费者id新排队的、需要n个资源的等待者的虚拟完成时间，
+// 同一个消费者的等待者依次排列，消费者在空闲后重新排队时从当前的虚拟时间开始，调用者需持有p.m
+func (p *Pool[T]) fairTag(id string, n uint) uint64 {
+	c := p.consumer(id)
+	start := c.finish
+	if start < p.vclock {
+		start = p.vclock
+	}
+	c.finish = start + uint64(n)
+	return c.finish
+}
+
+// served 在等待者w被唤醒或直接收到资源时推进虚拟时间，调用者需持有p.m
+func (p *Pool[T]) served(w waiter[T]) {
+	if w.tag > p.vclock {
+		p.vclock = w.tag
+	}
+}
+
+// consumer 返回消费者id的状态，不存在时创建，调用者需持有p.m
+func (p *Pool[T]) consumer(id string) *consumer {
+	c := p.consumers[id]
+	if c == nil {
+		if p.consumers == nil {
+			p.consumers = make(map[string]*consumer)
+		}
+		c = &consumer{}
+		p.consumers[id] = c
+	}
+	return c
+}
+
+// consumed 记录消费者id一次从waitStart开始等待、结果为r和err的获取
+func (p *Pool[T]) consumed(id string, r T, waitStart time.Time, err error) {
+	now := p.clock.Now()
+	p.m.Lock()
+	defer p.m.Unlock()
+	s := &p.consumer(id).stats
+	if !waitStart.IsZero() {
+		s.WaitCount++
+		s.WaitDuration += now.Sub(waitStart)
+	}
+	if err != nil {
+		s.Failed++
+		return
+	}
+	s.Acquired++
+	if e, _ := p.inUse.Get(r); e != nil && e.refs == 1 {
+		e.consumer = id
+		// 放回时需要在加锁的路径中更新消费者的统计
+		p.claim(e)
+	}
+}
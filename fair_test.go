@@ -0,0 +1,87 @@
+package pool_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lazysheep666/pool"
+)
+
+// startAs 在新的goroutine中以消费者id获取一个资源，排队后才返回，结果发送到返回的通道，id为空时用Acquire
+func startAs(t *testing.T, p *pool.Pool[*tracked], id string) <-chan acquired {
+	t.Helper()
+	if id == "" {
+		return startWaiter(t, p, context.Background())
+	}
+	before := p.Waiting()
+	c := make(chan acquired, 1)
+	go func() {
+		r, err := p.AcquireAs(context.Background(), id)
+		c <- acquired{r, err}
+	}()
+	eventually(t, "AcquireAs to queue", func() bool { return p.Waiting() > before })
+	return c
+}
+
+func TestAcquireAsFairness(t *testing.T) {
+	tests := []struct {
+		name      string
+		consumers []string // 依次排队的等待者的消费者，空表示匿名的Acquire
+		want      []int    // 依次得到资源的等待者
+	}{
+		{"one consumer in arrival order", []string{"a", "a", "a"}, []int{0, 1, 2}},
+		{"late consumer not behind a burst", []string{"a", "a", "a", "b"}, []int{0, 3, 1, 2}},
+		{"consumers alternate", []string{"a", "a", "b", "b"}, []int{0, 2, 1, 3}},
+		{"anonymous acquires share one consumer", []string{"", "", "a"}, []int{0, 2, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := newHarnessPool(t, pool.WithMaxTotal(1))
+			r := acquire(t, p)
+			results := make([]<-chan acquired, len(tt.consumers))
+			for i, id := range tt.consumers {
+				results[i] = startAs(t, p, id)
+			}
+			// 只有一个资源，每个等待者得到资源后放回，交给下一个，顺序错误时等待者得不到资源
+			for _, i := range tt.want {
+				release(t, p, r)
+				res := result(t, results[i])
+				if res.err != nil {
+					t.Fatalf("waiter %d: %v", i, res.err)
+				}
+				r = res.r
+			}
+			release(t, p, r)
+		})
+	}
+}
+
+func TestConsumerStats(t *testing.T) {
+	ctx := context.Background()
+	p, _ := newHarnessPool(t, pool.WithMaxTotal(1))
+	a, err := p.AcquireAs(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := startAs(t, p, "b")
+	s := p.ConsumerStats()
+	if s["a"].InUse != 1 || s["a"].Acquired != 1 || s["b"].Waiting != 1 {
+		t.Errorf("a: InUse = %d, Acquired = %d, b: Waiting = %d, want 1, 1, 1", s["a"].InUse, s["a"].Acquired, s["b"].Waiting)
+	}
+	release(t, p, a)
+	res := result(t, c)
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	release(t, p, res.r)
+	s = p.ConsumerStats()
+	if len(s) != 2 {
+		t.Errorf("got stats for %d consumers, want 2", len(s))
+	}
+	if b := s["b"]; b.Acquired != 1 || b.WaitCount != 1 || b.Waiting != 0 || b.InUse != 0 {
+		t.Errorf("b: Acquired = %d, WaitCount = %d, Waiting = %d, InUse = %d, want 1, 1, 0, 0", b.Acquired, b.WaitCount, b.Waiting, b.InUse)
+	}
+	if s["a"].InUse != 0 {
+		t.Errorf("a: InUse = %d after Release, want 0", s["a"].InUse)
+	}
+}
@@ -0,0 +1,45 @@
+package pool
+
+import "context"
+
+// flight 是一次正在进行的共享创建
+type flight struct {
+	done chan struct{} // 创建结束时关闭
+	err  error         // 创建的错误，发起者的ctx结束导致的失败不共享，此时为nil
+}
+
+// newResourceShared 在没有正在进行的创建时自己创建资源，否则等待那次创建结束
+// 那次创建失败时返回同一个错误，成功时再尝试自己创建
+func (p *Pool[T]) newResourceShared(ctx context.Context) (T, error) {
+	for {
+		p.m.Lock()
+		f := p.flight
+		if f == nil {
+			f = &flight{done: make(chan struct{})}
+			p.flight = f
+			p.m.Unlock()
+
+			r, err := p.makeResource(ctx)
+			if ctx.Err() == nil {
+				f.err = err
+			}
+			p.m.Lock()
+			p.flight = nil
+			p.m.Unlock()
+			close(f.done)
+			return r, err
+		}
+		p.m.Unlock()
+
+		select {
+		case <-f.done:
+			if f.err != nil {
+				var zero T
+				return zero, f.err
+			}
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Package grpcpool 提供管理gRPC连接的资源池
+//
+//	p, err := grpcpool.NewConnPool("127.0.0.1:50051",
+//		[]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+//		pool.WithMaxTotal(8))
+//	cc, err := p.Get(ctx)
+//	defer cc.Close() // 把连接放回池里
+//	client := pb.NewGreeterClient(cc)
+package grpcpool
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lazysheep666/pool"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// ConnPool 是一个*grpc.ClientConn的资源池
+// 从池中取出空闲连接时会检查它的连接状态，处于TransientFailure或Shutdown的连接会被关闭并重新获取
+type ConnPool struct {
+	p *pool.Pool[*grpc.ClientConn]
+}
+
+// NewConnPool 创建一个用dialOpts连接target的连接池
+// opts用来设置池的其它配置，其中的WithValidator会替换默认的状态检查
+func NewConnPool(target string, dialOpts []grpc.DialOption, opts ...pool.Option) (*ConnPool, error) {
+	if target == "" {
+		return nil, errors.New("grpcpool: empty target")
+	}
+	dial := func(ctx context.Context) (*grpc.ClientConn, error) {
+		return grpc.DialContext(ctx, target, dialOpts...)
+	}
+	opts = append([]pool.Option{
+		pool.WithCloser(func(cc *grpc.ClientConn) error { return cc.Close() }),
+		pool.WithValidator(Healthy),
+	}, opts...)
+	p, err := pool.NewContext(dial, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &ConnPool{p: p}, nil
+}
+
+// Get 从池中获取一个连接，使用完后调用它的Close放回池里
+func (cp *ConnPool) Get(ctx context.Context) (*ClientConn, error) {
+	pr, err := cp.p.AcquireResource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientConn{ClientConn: pr.Value(), pr: pr}, nil
+}
+
+// Stats 返回连接池的统计信息
+func (cp *ConnPool) Stats() pool.Stats {
+	return cp.p.Stats()
+}
+
+// Close 关闭连接池，并等待使用中的连接被放回后关闭它们
+func (cp *ConnPool) Close() error {
+	return cp.p.Close()
+}
+
+// Pool 返回底层的资源池
+func (cp *ConnPool) Pool() *pool.Pool[*grpc.ClientConn] {
+	return cp.p
+}
+
+// ClientConn 是从ConnPool中获取的连接，实现了grpc.ClientConnInterface，可以直接传给生成的客户端
+// Close会把连接放回池里而不是关闭它
+type ClientConn struct {
+	*grpc.ClientConn
+	pr *pool.PooledResource[*grpc.ClientConn]
+}
+
+var _ grpc.ClientConnInterface = (*ClientConn)(nil)
+
+// Close 把连接放回池里，重复调用时返回pool.ErrResourceReleased
+func (c *ClientConn) Close() error {
+	return c.pr.Close()
+}
+
+// Destroy 关闭连接而不是放回池里，用于连接出错、状态不可预期的情况
+func (c *ClientConn) Destroy() error {
+	return c.pr.Destroy()
+}
+
+// Healthy 检查一个空闲连接是否仍然可用，处于TransientFailure或Shutdown时返回false
+// 处于Idle的连接会被要求开始连接
+func Healthy(cc *grpc.ClientConn) bool {
+	switch s := cc.GetState(); s {
+	case connectivity.TransientFailure, connectivity.Shutdown:
+		return false
+	case connectivity.Idle:
+		cc.Connect()
+	}
+	return true
+}
@@ -0,0 +1,237 @@
+package grpcpool
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lazysheep666/pool"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// server 是运行健康检查服务的内存gRPC服务器，restart之后新的连接连到新的服务器
+type server struct {
+	mu  sync.Mutex
+	lis *bufconn.Listener
+	srv *grpc.Server
+}
+
+func newServer(t *testing.T) *server {
+	t.Helper()
+	s := &server{}
+	s.start()
+	t.Cleanup(s.stop)
+	return s
+}
+
+// start 启动一个新的服务器
+func (s *server) start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lis = bufconn.Listen(1 << 20)
+	s.srv = grpc.NewServer()
+	healthpb.RegisterHealthServer(s.srv, health.NewServer())
+	go s.srv.Serve(s.lis)
+}
+
+// stop 停止服务器并断开所有连接，之后的拨号失败
+func (s *server) stop() {
+	s.mu.Lock()
+	srv := s.srv
+	s.mu.Unlock()
+	srv.Stop()
+}
+
+// dialOpts 返回通过内存连接访问s的DialOption
+func (s *server) dialOpts() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			s.mu.Lock()
+			lis := s.lis
+			s.mu.Unlock()
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}
+}
+
+// newPool 创建连接s的连接池
+func newPool(t *testing.T, s *server, opts ...pool.Option) *ConnPool {
+	t.Helper()
+	cp, err := NewConnPool("passthrough:///bufnet", s.dialOpts(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cp.Close() })
+	return cp
+}
+
+// get 从cp获取一个连接并通过它调用一次健康检查
+func get(t *testing.T, cp *ConnPool) *ClientConn {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	cc, err := cp.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := healthpb.NewHealthClient(cc).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("health status %v, want SERVING", resp.Status)
+	}
+	return cc
+}
+
+// waitFor 等待cc进入状态want
+func waitFor(t *testing.T, cc *grpc.ClientConn, want connectivity.State) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for s := cc.GetState(); s != want; s = cc.GetState() {
+		if !cc.WaitForStateChange(ctx, s) {
+			t.Fatalf("connection %v, want %v", s, want)
+		}
+	}
+}
+
+// fail 停止s并让cc重新连接，cc进入TransientFailure
+func fail(t *testing.T, s *server, cc *grpc.ClientConn) {
+	t.Helper()
+	s.stop()
+	waitFor(t, cc, connectivity.Idle)
+	cc.Connect()
+	waitFor(t, cc, connectivity.TransientFailure)
+}
+
+func TestConnPool(t *testing.T) {
+	tests := []struct {
+		name string
+		// run 使用s和cp，wantMisses是期望的创建次数
+		run        func(t *testing.T, s *server, cp *ConnPool)
+		wantMisses uint64
+	}{
+		{"reused after Close", func(t *testing.T, s *server, cp *ConnPool) {
+			a := get(t, cp)
+			if err := a.Close(); err != nil {
+				t.Fatal(err)
+			}
+			b := get(t, cp)
+			if b.ClientConn != a.ClientConn {
+				t.Error("Get created a new connection with one idle")
+			}
+			b.Close()
+		}, 1},
+		{"double Close", func(t *testing.T, s *server, cp *ConnPool) {
+			cc := get(t, cp)
+			cc.Close()
+			if err := cc.Close(); !errors.Is(err, pool.ErrResourceReleased) {
+				t.Errorf("second Close = %v, want ErrResourceReleased", err)
+			}
+		}, 1},
+		{"Destroy closes the connection", func(t *testing.T, s *server, cp *ConnPool) {
+			a := get(t, cp)
+			if err := a.Destroy(); err != nil {
+				t.Fatal(err)
+			}
+			if st := a.ClientConn.GetState(); st != connectivity.Shutdown {
+				t.Errorf("destroyed connection %v, want Shutdown", st)
+			}
+			get(t, cp).Close()
+		}, 2},
+		{"failed idle connection replaced", func(t *testing.T, s *server, cp *ConnPool) {
+			a := get(t, cp)
+			a.Close()
+			fail(t, s, a.ClientConn)
+			s.start()
+			b := get(t, cp)
+			if b.ClientConn == a.ClientConn {
+				t.Error("Get reused a failed connection")
+			}
+			b.Close()
+		}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t)
+			cp := newPool(t, s)
+			tt.run(t, s, cp)
+			if st := cp.Stats(); st.Misses != tt.wantMisses || st.InUse != 0 {
+				t.Errorf("Misses = %d, InUse = %d, want %d, 0", st.Misses, st.InUse, tt.wantMisses)
+			}
+		})
+	}
+}
+
+func TestHealthy(t *testing.T) {
+	s := newServer(t)
+	cc := get(t, newPool(t, s))
+	raw := cc.ClientConn
+	steps := []struct {
+		name  string
+		do    func()
+		state connectivity.State
+		want  bool
+	}{
+		{"ready", func() {}, connectivity.Ready, true},
+		// Idle的连接被要求开始连接，服务器已经停止，连接失败
+		{"idle", func() {
+			s.stop()
+			waitFor(t, raw, connectivity.Idle)
+		}, connectivity.Idle, true},
+		{"transient failure", func() {
+			waitFor(t, raw, connectivity.TransientFailure)
+		}, connectivity.TransientFailure, false},
+		{"shutdown", func() { cc.Destroy() }, connectivity.Shutdown, false},
+	}
+	for _, st := range steps {
+		st.do()
+		if got := raw.GetState(); got != st.state {
+			t.Fatalf("%s: state %v, want %v", st.name, got, st.state)
+		}
+		if got := Healthy(raw); got != st.want {
+			t.Errorf("%s: Healthy = %v, want %v", st.name, got, st.want)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{"insecure", "grpc://bufnet?insecure=true&max_total=2", false},
+		{"pool options", "grpc://bufnet?insecure=1&idle_timeout=1m", false},
+		{"wrong scheme", "http://bufnet?insecure=true", true},
+		{"invalid insecure", "grpc://bufnet?insecure=maybe", true},
+		{"unknown parameter", "grpc://bufnet?insecure=true&bogus=1", true},
+		{"empty target", "grpc://?insecure=true", true},
+		{"malformed", "grpc://%zz", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t)
+			// 只替换拨号，TLS设置来自连接串的insecure参数
+			cp, err := Open(tt.url, s.dialOpts()[:1])
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Open = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer cp.Close()
+			get(t, cp).Close()
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package grpcpool
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/lazysheep666/pool"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Open 用连接串创建连接池，连接串的格式是grpc://host:port?参数，
+// 参数insecure=true表示不使用TLS，其它参数用pool.ParseQuery解析，
+// 例如grpc://127.0.0.1:50051?insecure=true&max_total=8&idle_timeout=1m，opts在连接串中的参数之后生效
+func Open(rawURL string, dialOpts []grpc.DialOption, opts ...pool.Option) (*ConnPool, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("grpcpool: %w", err)
+	}
+	if u.Scheme != "grpc" {
+		return nil, fmt.Errorf("grpcpool: unsupported scheme %q", u.Scheme)
+	}
+	q := u.Query()
+	if s := q.Get("insecure"); s != "" {
+		ok, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, fmt.Errorf("grpcpool: invalid insecure parameter %q", s)
+		}
+		if ok {
+			dialOpts = append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, dialOpts...)
+		}
+		q.Del("insecure")
+	}
+	opt, err := pool.ParseQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	return NewConnPool(u.Host, dialOpts, append([]pool.Option{opt}, opts...)...)
+}
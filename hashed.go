@@ -0,0 +1,104 @@
+package pool
+
+import (
+	"context"
+	"hash/fnv"
+	"math/rand"
+	"runtime/debug"
+)
+
+// AcquireHashed 与AcquireContext相同，但按key确定地选择资源，用于按键路由以利用服务端的缓存
+// 选择使用最高随机权重(rendezvous)的一致性哈希：每个资源有一个固定的随机标识，key对应分数最高的资源，
+// 资源增加或减少时只有原本对应被移除或新增资源的key改变映射，其它key仍然使用同一个资源
+// 对应的资源正在使用时，按分数依次使用下一个空闲资源，共享模式下可以共享时直接共享它；
+// 都不可用时与AcquireContext相同地创建资源或排队等待
+func (p *Pool[T]) AcquireHashed(ctx context.Context, key string) (T, error) {
+	if r, ok := p.acquireHashed(ctx, key); ok {
+		return r, nil
+	}
+	return p.AcquireContext(ctx)
+}
+
+// acquireHashed 按key的分数取出空闲资源或共享使用中的资源，没有可用的资源或有goroutine在排队时返回false
+func (p *Pool[T]) acquireHashed(ctx context.Context, key string) (T, bool) {
+	var zero T
+	var stack []byte
+	if p.leakTimeout > 0 {
+		stack = debug.Stack()
+	}
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	kh := h.Sum64()
+	for {
+		p.m.Lock()
+		if p.closed || p.paused || len(p.waiters) > 0 || p.wakeups > 0 || p.overReserve(PriorityNormal, 1) {
+			p.m.Unlock()
+			return zero, false
+		}
+		idle, idleScore := -1, uint64(0)
+		for i := 0; i < p.idle.Len(); i++ {
+			if s := p.hashScore(p.idle.At(i), kh); idle < 0 || s > idleScore {
+				idle, idleScore = i, s
+			}
+		}
+		// 对应的资源正在使用时，共享它或使用下一个空闲资源
+		var busy *entry[T]
+		busyScore := uint64(0)
+		for _, e := range p.inUse {
+			if s := p.hashScore(e, kh); (idle < 0 || s > idleScore) && (busy == nil || s > busyScore) {
+				busy, busyScore = e, s
+			}
+		}
+		if busy != nil && p.canShare(busy, p.clock.Now()) {
+			busy.refs++
+			busy.uses++
+			p.m.Unlock()
+			if ok, _ := p.runAcquireHook(ctx, busy.r, nil); !ok {
+				continue
+			}
+			if p.abandon(busy.r) {
+				// 池已经开始关闭，由AcquireContext返回ErrPoolClosed
+				return zero, false
+			}
+			p.stats.hit()
+			p.logger.Println("AcquireHashed:", "Shared Resource")
+			return busy.r, true
+		}
+		if idle < 0 {
+			p.m.Unlock()
+			return zero, false
+		}
+		e := p.checkout(idle, stack)
+		p.m.Unlock()
+		if !p.checkIdle(ctx, e) {
+			continue
+		}
+		if ok, _ := p.runAcquireHook(ctx, e.r, nil); !ok {
+			continue
+		}
+		if p.abandon(e.r) {
+			return zero, false
+		}
+		p.stats.hit()
+		p.logger.Println("AcquireHashed:", "Hashed Resource")
+		return e.r, true
+	}
+}
+
+// hashScore 返回资源e对于哈希值为kh的key的分数，第一次使用时为资源分配随机标识，调用者需持有p.m
+func (p *Pool[T]) hashScore(e *entry[T], kh uint64) uint64 {
+	for e.hashID == 0 {
+		e.hashID = rand.Uint64()
+	}
+	return mix64(kh ^ e.hashID)
+}
+
+// mix64 是splitmix64的终结函数，把输入的每一位均匀地扩散到输出中
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
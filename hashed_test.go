@@ -0,0 +1,105 @@
+package pool_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/lazysheep666/pool"
+)
+
+func TestAcquireHashed(t *testing.T) {
+	const resources, keys = 4, 32
+	ctx := context.Background()
+	// hashed 用AcquireHashed获取key对应的资源，失败时结束测试
+	hashed := func(t *testing.T, p *pool.Pool[*tracked], key string) *tracked {
+		t.Helper()
+		r, err := p.AcquireHashed(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	// owners 返回每个key对应的资源的id
+	owners := func(t *testing.T, p *pool.Pool[*tracked]) map[string]int64 {
+		t.Helper()
+		m := make(map[string]int64, keys)
+		for i := 0; i < keys; i++ {
+			key := fmt.Sprint("key", i)
+			r := hashed(t, p, key)
+			m[key] = r.id
+			release(t, p, r)
+		}
+		return m
+	}
+	tests := []struct {
+		name    string
+		sharers uint
+		run     func(t *testing.T, p *pool.Pool[*tracked], h *harness)
+	}{
+		{"same key same resource", 0, func(t *testing.T, p *pool.Pool[*tracked], h *harness) {
+			first := owners(t, p)
+			used := make(map[int64]bool)
+			for key, id := range owners(t, p) {
+				if first[key] != id {
+					t.Errorf("%s: got resource %d, then %d", key, first[key], id)
+				}
+				used[id] = true
+			}
+			if len(used) < 2 {
+				t.Errorf("%d keys all map to %d resource(s), want them spread over several", keys, len(used))
+			}
+		}},
+		{"removing a resource only moves its keys", 0, func(t *testing.T, p *pool.Pool[*tracked], h *harness) {
+			before := owners(t, p)
+			gone := hashed(t, p, "key0")
+			if err := p.Discard(gone); err != nil {
+				t.Fatal(err)
+			}
+			for key, id := range owners(t, p) {
+				if was := before[key]; was != gone.id && id != was {
+					t.Errorf("%s moved from resource %d to %d", key, was, id)
+				}
+				if id == gone.id {
+					t.Errorf("%s still maps to the discarded resource", key)
+				}
+			}
+		}},
+		{"busy resource falls back to an idle one", 0, func(t *testing.T, p *pool.Pool[*tracked], h *harness) {
+			held := hashed(t, p, "key0")
+			r := hashed(t, p, "key0")
+			if r == held {
+				t.Fatal("got the checked out resource")
+			}
+			if h.created.Load() != resources {
+				t.Errorf("created %d resources, want the idle ones reused", h.created.Load())
+			}
+			release(t, p, r)
+			release(t, p, held)
+		}},
+		{"busy resource shared", 2, func(t *testing.T, p *pool.Pool[*tracked], h *harness) {
+			held := hashed(t, p, "key0")
+			if r := hashed(t, p, "key0"); r != held {
+				t.Errorf("got resource %d, want the shared resource %d", r.id, held.id)
+			}
+			release(t, p, held)
+			release(t, p, held)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, h := newHarnessPool(t, pool.WithSharing(tt.sharers))
+			rs := make([]*tracked, resources)
+			for i := range rs {
+				rs[i] = acquire(t, p)
+			}
+			for _, r := range rs {
+				release(t, p, r)
+			}
+			tt.run(t, p, h)
+			if s := p.Stats(); s.InUse != 0 {
+				t.Errorf("InUse = %d, want 0", s.InUse)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DefaultHealthTimeout 是ctx没有截止时间时Health最长的等待时间
+const DefaultHealthTimeout = 5 * time.Second
+
+// ErrUnhealthy 表示Health取得的资源没有通过验证函数
+var ErrUnhealthy = errors.New("Resource is unhealthy")
+
+// Health 检查池能否提供一个可用的资源：获取(必要时创建)一个资源，
+// 并用验证函数和WithKeepalive设置的ping检查它，通过时放回池里，否则销毁它并返回错误
+// ctx没有截止时间时最多等待DefaultHealthTimeout，池被Pause暂停时直接返回ErrPoolPaused
+func (p *Pool[T]) Health(ctx context.Context) error {
+	if p.Paused() {
+		return ErrPoolPaused
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = withTimeout(ctx, p.clock, DefaultHealthTimeout)
+		defer cancel()
+	}
+	r, err := p.AcquireContext(ctx)
+	if err != nil {
+		return err
+	}
+	if p.validator != nil && !p.validator(ctx, r) {
+		p.Discard(r)
+		return ErrUnhealthy
+	}
+	if p.ping != nil {
+		if err := p.ping(r); err != nil {
+			p.Discard(r)
+			return fmt.Errorf("%w: %w", ErrUnhealthy, err)
+		}
+	}
+	return p.Release(r)
+}
+
+// NewAndVerify 与NewContext相同，但在返回前创建MinIdle个(至少一个)资源放入池中，
+// 并像Health一样用验证函数和ping检查它们，用于在启动时就发现factory的配置错误
+// 创建或检查失败时关闭池并返回错误，检查失败的错误满足errors.Is(err, ErrUnhealthy)
+func NewAndVerify[T any](ctx context.Context, fn func(context.Context) (T, error), opts ...Option) (*Pool[T], error) {
+	p, err := NewContext(fn, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.verify(ctx); err != nil {
+		p.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// verify 创建MinIdle个(至少一个)空闲资源并检查所有空闲资源
+func (p *Pool[T]) verify(ctx context.Context) error {
+	p.m.Lock()
+	n := p.minIdle
+	if n == 0 {
+		n = 1
+	}
+	if idle := uint(p.idle.Len()) + p.creatingIdle; idle < n {
+		n -= idle
+	} else {
+		n = 0
+	}
+	p.m.Unlock()
+	if err := p.fill(ctx, n); err != nil {
+		return err
+	}
+	p.m.Lock()
+	rs := make([]T, 0, p.idle.Len())
+	for i := 0; i < p.idle.Len(); i++ {
+		e := p.idle.At(i)
+		rs = append(rs, e.r)
+	}
+	p.m.Unlock()
+	if len(rs) == 0 {
+		return fmt.Errorf("%w: no resource was created", ErrUnhealthy)
+	}
+	for _, r := range rs {
+		if p.validator != nil && !p.validator(ctx, r) {
+			return ErrUnhealthy
+		}
+		if p.ping != nil {
+			if err := p.ping(r); err != nil {
+				return fmt.Errorf("%w: %w", ErrUnhealthy, err)
+			}
+		}
+	}
+	return nil
+}
+
+// HealthHandler 返回一个调用Health的http.Handler，可以用作readiness探针，
+// 健康时返回200，否则返回503和错误信息
+func (p *Pool[T]) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if err := p.Health(r.Context()); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintln(w, err)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+}
@@ -0,0 +1,130 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lazysheep666/pool"
+	"github.com/lazysheep666/pool/pooltest"
+)
+
+// epoch 是测试中FakeClock开始的时间
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// tracked 是测试使用的资源，id是创建的顺序，从1开始
+type tracked struct {
+	id     int64
+	weight uint // 使用WithWeightFunc的测试中资源的权重
+	closed atomic.Bool
+}
+
+// harness 记录一个测试池创建和关闭的资源
+type harness struct {
+	clock   *pooltest.FakeClock
+	created atomic.Int64
+	closed  atomic.Int64
+}
+
+// newHarnessPool 创建一个使用FakeClock的池，测试结束时关闭它，
+// 仍被借出的资源在1秒后被强制关闭
+func newHarnessPool(t *testing.T, opts ...pool.Option) (*pool.Pool[*tracked], *harness) {
+	t.Helper()
+	h := &harness{clock: pooltest.NewFakeClock(epoch)}
+	opts = append([]pool.Option{
+		pool.WithClock(h.clock),
+		pool.WithCloser(h.close),
+	}, opts...)
+	p, err := pool.New(h.create, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		p.CloseContext(ctx)
+	})
+	return p, h
+}
+
+func (h *harness) create() (*tracked, error) {
+	return &tracked{id: h.created.Add(1)}, nil
+}
+
+func (h *harness) close(r *tracked) error {
+	if r.closed.Swap(true) {
+		return errors.New("resource closed twice")
+	}
+	h.closed.Add(1)
+	return nil
+}
+
+// acquire 获取一个资源，失败时结束测试
+func acquire[T any](t *testing.T, p *pool.Pool[T]) T {
+	t.Helper()
+	r, err := p.Acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// release 放回r，失败时结束测试
+func release[T any](t *testing.T, p *pool.Pool[T], r T) {
+	t.Helper()
+	if err := p.Release(r); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// eventually 等待cond成立，用于等待后台goroutine，5秒后仍不成立时结束测试
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// errFlaky 是flaky factory失败时返回的错误
+var errFlaky = errors.New("flaky factory failed")
+
+// flaky 是可以设置为失败的factory，calls是调用的次数
+type flaky struct {
+	harness
+	fail  atomic.Bool
+	calls atomic.Int64
+}
+
+func (f *flaky) create(context.Context) (*tracked, error) {
+	f.calls.Add(1)
+	if f.fail.Load() {
+		return nil, errFlaky
+	}
+	return f.harness.create()
+}
+
+// newFlakyPool 与newHarnessPool相同，但使用flaky factory
+func newFlakyPool(t *testing.T, opts ...pool.Option) (*pool.Pool[*tracked], *flaky) {
+	t.Helper()
+	f := &flaky{harness: harness{clock: pooltest.NewFakeClock(epoch)}}
+	opts = append([]pool.Option{
+		pool.WithClock(f.clock),
+		pool.WithCloser(f.close),
+	}, opts...)
+	p, err := pool.NewContext(f.create, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		p.CloseContext(ctx)
+	})
+	return p, f
+}
@@ -0,0 +1,77 @@
+// Package identity 用来区分池中的资源，资源的类型不需要可比较
+// 可比较的资源用它自己的值识别，切片、map和chan用底层的指针识别
+package identity
+
+import "reflect"
+
+// pointerKey 是用底层指针识别的资源的键，同一个指针的不同类型的值互不相同
+type pointerKey struct {
+	t reflect.Type
+	p uintptr
+}
+
+// Key 返回识别r的键，r的动态类型既不可比较也不是切片、map或chan时返回false
+func Key[T any](r T) (any, bool) {
+	v := any(r)
+	t := reflect.TypeOf(v)
+	if t == nil || t.Comparable() {
+		return v, true
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Chan:
+		return pointerKey{t, reflect.ValueOf(v).Pointer()}, true
+	}
+	return nil, false
+}
+
+// Supported 返回类型t的值是否可以用Key识别，接口类型总是返回true，要到运行时再检查它的动态类型
+func Supported(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Interface, reflect.Slice, reflect.Map, reflect.Chan:
+		return true
+	}
+	return t.Comparable()
+}
+
+// Of 返回类型参数T对应的reflect.Type
+func Of[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// Same 返回a和b是否是同一个资源
+func Same[T any](a, b T) bool {
+	ka, ok := Key(a)
+	if !ok {
+		return false
+	}
+	kb, ok := Key(b)
+	return ok && ka == kb
+}
+
+// Map 是以资源为键的map，可以像普通的map一样用len和range
+type Map[T, V any] map[any]V
+
+// Get 返回r对应的值
+func (m Map[T, V]) Get(r T) (V, bool) {
+	k, ok := Key(r)
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	v, ok := m[k]
+	return v, ok
+}
+
+// Set 把r对应的值设为v，r无法识别时什么也不做
+func (m Map[T, V]) Set(r T, v V) {
+	if k, ok := Key(r); ok {
+		m[k] = v
+	}
+}
+
+// Delete 删除r对应的值
+func (m Map[T, V]) Delete(r T) {
+	if k, ok := Key(r); ok {
+		delete(m, k)
+	}
+}
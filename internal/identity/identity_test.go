@@ -0,0 +1,109 @@
+package identity
+
+import (
+	"errors"
+	"testing"
+)
+
+// holder 含有切片，不可比较，也没有可以用来识别的指针
+type holder struct{ b []byte }
+
+func TestKey(t *testing.T) {
+	a, b := make([]byte, 1, 8), make([]byte, 1, 8)
+	m1, m2 := map[string]int{}, map[string]int{}
+	p1, p2 := new(int), new(int)
+	tests := []struct {
+		name      string
+		x, y      any
+		ok        bool
+		wantEqual bool
+	}{
+		{"same pointer", p1, p1, true, true},
+		{"different pointers", p1, p2, true, false},
+		{"equal ints", 3, 3, true, true},
+		{"same slice", a, a, true, true},
+		{"resliced", a, a[:0], true, true},
+		{"different slices", a, b, true, false},
+		{"same map", m1, m1, true, true},
+		{"different maps", m1, m2, true, false},
+		{"nil", nil, nil, true, true},
+		{"struct with slice", holder{a}, holder{a}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kx, ok := Key(tt.x)
+			if ok != tt.ok {
+				t.Fatalf("Key(%v) ok = %v, want %v", tt.x, ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			ky, _ := Key(tt.y)
+			if got := kx == ky; got != tt.wantEqual {
+				t.Errorf("Key(x) == Key(y) = %v, want %v", got, tt.wantEqual)
+			}
+			if got := Same(tt.x, tt.y); got != tt.wantEqual {
+				t.Errorf("Same = %v, want %v", got, tt.wantEqual)
+			}
+		})
+	}
+}
+
+func TestSupported(t *testing.T) {
+	tests := []struct {
+		name string
+		ok   bool
+		got  bool
+	}{
+		{"pointer", true, Supported(Of[*int]())},
+		{"int", true, Supported(Of[int]())},
+		{"slice", true, Supported(Of[[]byte]())},
+		{"map", true, Supported(Of[map[string]int]())},
+		{"chan", true, Supported(Of[chan int]())},
+		{"interface", true, Supported(Of[error]())},
+		{"func", false, Supported(Of[func()]())},
+		{"struct with slice", false, Supported(Of[holder]())},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.ok {
+			t.Errorf("Supported(%s) = %v, want %v", tt.name, tt.got, tt.ok)
+		}
+	}
+}
+
+func TestMap(t *testing.T) {
+	a, b := make([]byte, 0, 8), make([]byte, 0, 8)
+	m := make(Map[[]byte, int])
+	m.Set(a, 1)
+	m.Set(b, 2)
+	m.Set(a[:4], 3) // 与a是同一个资源
+	if len(m) != 2 {
+		t.Fatalf("len = %d, want 2", len(m))
+	}
+	if v, ok := m.Get(a); !ok || v != 3 {
+		t.Errorf("Get(a) = %d, %v, want 3, true", v, ok)
+	}
+	m.Delete(a)
+	if _, ok := m.Get(a); ok {
+		t.Error("Get(a) after Delete found a value")
+	}
+	if v, ok := m.Get(b); !ok || v != 2 {
+		t.Errorf("Get(b) = %d, %v, want 2, true", v, ok)
+	}
+
+	// 无法识别的资源不会被加入
+	errs := make(Map[error, int])
+	errs.Set(sliceError{}, 1)
+	if len(errs) != 0 {
+		t.Errorf("len = %d after setting an unidentifiable key, want 0", len(errs))
+	}
+	errs.Set(errors.New("x"), 1)
+	if len(errs) != 1 {
+		t.Errorf("len = %d, want 1", len(errs))
+	}
+}
+
+// sliceError 是不可比较的错误类型
+type sliceError struct{ causes []string }
+
+func (sliceError) Error() string { return "slice error" }
@@ -0,0 +1,62 @@
+package pool
+
+import "time"
+
+// keepalive 每隔interval对空闲资源执行一次ping，直到池被关闭
+func (p *Pool[T]) keepalive(interval time.Duration) {
+	ticker := p.clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			p.pingIdle(p.clock.Now().Add(-interval))
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// pingIdle 对before之前放回池中的空闲资源执行ping，关闭ping失败的资源，并刷新其余资源的分数
+// ping期间这些资源被暂时移出空闲资源，但仍然占用容量
+func (p *Pool[T]) pingIdle(before time.Time) {
+	p.m.Lock()
+	var pinging []*entry[T]
+	p.idle.Retain(func(e *entry[T]) bool {
+		if e.returnedAt.Before(before) {
+			pinging = append(pinging, e)
+			return false
+		}
+		return true
+	})
+	p.m.Unlock()
+	if len(pinging) == 0 {
+		return
+	}
+
+	failed := make([]bool, len(pinging))
+	scores := make([]float64, len(pinging))
+	scored := make([]bool, len(pinging))
+	for i, e := range pinging {
+		if err := p.ping(e.r); err != nil {
+			p.logger.Println("Keepalive:", "Ping Failed:", err)
+			p.reportError(err, SourceKeepalive)
+			p.unhealthy(e.r)
+			failed[i] = true
+			continue
+		}
+		// ping之后刷新分数，让RTT等变化及时影响选择
+		scores[i], scored[i] = p.scoreOf(e.r)
+	}
+
+	p.m.Lock()
+	defer p.unlock()
+	for i, e := range pinging {
+		if failed[i] || p.closed || uint(p.idle.Len()) >= p.maxIdle || e.gen != p.generation.Load() {
+			p.retire(e)
+			continue
+		}
+		p.setScore(e, scores[i], scored[i])
+		p.idle.Put(e)
+	}
+	p.broadcast()
+}
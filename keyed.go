@@ -0,0 +1,409 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// KeyedPool 按键管理一组子池，每个键对应的子池在第一次使用时创建
+// 每个子池的资源数受WithPerKeyLimits限制，所有子池的资源总数受全局上限限制，
+// 达到全局上限时按最近最少使用的顺序关闭其它键的空闲子池，长时间未使用的键对应的子池会被关闭
+type KeyedPool[K comparable, T any] struct {
+	m       sync.Mutex
+	pools   map[K]*keyedEntry[T]
+	factory func(context.Context, K) (T, error)
+	s       settings // 每个子池的设置
+	closed  bool
+
+	maxTotal   uint          // 所有子池资源总数的上限，0表示不限制
+	numOpen    uint          // 所有子池中已创建且尚未销毁的资源数
+	notify     chan struct{} // 有资源被销毁或池关闭时关闭，用来唤醒等待全局容量的goroutine
+	keyIdleTTL time.Duration // 键超过这个时间未被使用且没有使用中的资源时关闭其子池
+	done       chan struct{}
+	clock      Clock
+}
+
+// keyedEntry 是KeyedPool中的一个子池
+type keyedEntry[T any] struct {
+	pool     *Pool[T]
+	lastUsed time.Time
+}
+
+// KeyedOption 用于配置NewKeyed创建的KeyedPool
+type KeyedOption func(*keyedSettings)
+
+type keyedSettings struct {
+	poolOpts    []Option
+	maxTotal    uint
+	keyMaxTotal uint
+	keyMaxIdle  uint
+	keyIdleTTL  time.Duration
+}
+
+// WithPoolOptions 设置每个子池的Option，例如用WithMaxTotal限制每个键的资源数
+func WithPoolOptions(opts ...Option) KeyedOption {
+	return func(s *keyedSettings) { s.poolOpts = append(s.poolOpts, opts...) }
+}
+
+// WithPerKeyLimits 设置每个键的资源总数和空闲资源数的上限，避免一个键占用所有的全局容量
+// 0表示使用WithPoolOptions中的设置
+func WithPerKeyLimits(maxTotal, maxIdle uint) KeyedOption {
+	return func(s *keyedSettings) {
+		s.keyMaxTotal = maxTotal
+		s.keyMaxIdle = maxIdle
+	}
+}
+
+// WithGlobalMaxTotal 设置所有子池资源总数的上限，0表示不限制
+func WithGlobalMaxTotal(n uint) KeyedOption {
+	return func(s *keyedSettings) { s.maxTotal = n }
+}
+
+// WithKeyIdleTimeout 设置键的最长空闲时间，超过这个时间未被使用、
+// 并且没有使用中资源的键对应的子池会被关闭，0表示不关闭
+func WithKeyIdleTimeout(d time.Duration) KeyedOption {
+	return func(s *keyedSettings) { s.keyIdleTTL = d }
+}
+
+// NewKeyed 创建一个KeyedPool，factory为指定的键创建新资源
+func NewKeyed[K comparable, T any](factory func(ctx context.Context, key K) (T, error), opts ...KeyedOption) (*KeyedPool[K, T], error) {
+	if factory == nil {
+		return nil, fmt.Errorf("%w: nil factory", ErrInvalidConfig)
+	}
+	var ks keyedSettings
+	for _, opt := range opts {
+		opt(&ks)
+	}
+	if ks.keyIdleTTL < 0 {
+		return nil, fmt.Errorf("%w: negative key idle timeout %v", ErrInvalidConfig, ks.keyIdleTTL)
+	}
+	var s settings
+	for _, opt := range ks.poolOpts {
+		opt(&s)
+	}
+	if ks.keyMaxTotal > 0 {
+		s.MaxTotal = ks.keyMaxTotal
+	}
+	if ks.keyMaxIdle > 0 {
+		s.MaxIdle = ks.keyMaxIdle
+	}
+	cfg := s.Config.withDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := funcOption[func(T) error](s.closer, "closer"); err != nil {
+		return nil, err
+	}
+
+	kp := &KeyedPool[K, T]{
+		pools:      make(map[K]*keyedEntry[T]),
+		factory:    factory,
+		s:          s,
+		maxTotal:   ks.maxTotal,
+		notify:     make(chan struct{}),
+		keyIdleTTL: ks.keyIdleTTL,
+		done:       make(chan struct{}),
+		clock:      s.clock,
+	}
+	if kp.clock == nil {
+		kp.clock = realClock{}
+	}
+	if ks.keyIdleTTL > 0 {
+		go kp.sweeper()
+	}
+	return kp, nil
+}
+
+// Acquire 从key对应的子池中获取一个资源，子池不存在时会先创建它
+func (kp *KeyedPool[K, T]) Acquire(ctx context.Context, key K) (T, error) {
+	for {
+		p, err := kp.pool(key)
+		if err != nil {
+			var zero T
+			return zero, err
+		}
+		r, err := p.AcquireContext(ctx)
+		if errors.Is(err, ErrPoolClosed) {
+			// 子池刚好因为空闲被关闭，重新创建一个
+			kp.m.Lock()
+			closed := kp.closed
+			kp.m.Unlock()
+			if !closed {
+				continue
+			}
+		}
+		return r, err
+	}
+}
+
+// Release 把从key对应的子池中获取的资源放回去，key对应的子池不存在时返回ErrForeignResource
+func (kp *KeyedPool[K, T]) Release(key K, r T) error {
+	if p := kp.lookup(key); p != nil {
+		return p.Release(r)
+	}
+	return ErrForeignResource
+}
+
+// Discard 销毁从key对应的子池中获取的资源
+func (kp *KeyedPool[K, T]) Discard(key K, r T) error {
+	if p := kp.lookup(key); p != nil {
+		return p.Discard(r)
+	}
+	return ErrForeignResource
+}
+
+// Stats 返回key对应的子池的统计信息，子池不存在时返回零值
+func (kp *KeyedPool[K, T]) Stats(key K) Stats {
+	if p := kp.lookup(key); p != nil {
+		return p.Stats()
+	}
+	return Stats{}
+}
+
+// StatsByKey 返回当前每个子池的统计信息
+func (kp *KeyedPool[K, T]) StatsByKey() map[K]Stats {
+	kp.m.Lock()
+	pools := make(map[K]*Pool[T], len(kp.pools))
+	for k, e := range kp.pools {
+		pools[k] = e.pool
+	}
+	kp.m.Unlock()
+
+	stats := make(map[K]Stats, len(pools))
+	for k, p := range pools {
+		stats[k] = p.Stats()
+	}
+	return stats
+}
+
+// Keys 返回当前存在子池的所有键
+func (kp *KeyedPool[K, T]) Keys() []K {
+	kp.m.Lock()
+	defer kp.m.Unlock()
+	keys := make([]K, 0, len(kp.pools))
+	for k := range kp.pools {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Close 关闭所有子池，并等待使用中的资源被放回，返回所有子池的错误的合并
+func (kp *KeyedPool[K, T]) Close() error {
+	return kp.CloseContext(context.Background())
+}
+
+// CloseContext 与Close相同，但最多等待到ctx结束，
+// 之后仍未放回的资源会被强制关闭，返回的错误满足errors.Is(err, ctx.Err())
+func (kp *KeyedPool[K, T]) CloseContext(ctx context.Context) error {
+	kp.m.Lock()
+	if !kp.closed {
+		kp.closed = true
+		close(kp.done)
+		kp.broadcast()
+	}
+	// 关闭期间子池仍然留在kp.pools中，以便使用中的资源可以被放回
+	pools := make([]*Pool[T], 0, len(kp.pools))
+	for _, e := range kp.pools {
+		pools = append(pools, e.pool)
+	}
+	kp.m.Unlock()
+
+	errs := make([]error, 0, len(pools))
+	for _, p := range pools {
+		errs = append(errs, p.CloseContext(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// pool 返回key对应的子池，不存在时创建它
+func (kp *KeyedPool[K, T]) pool(key K) (*Pool[T], error) {
+	kp.m.Lock()
+	defer kp.m.Unlock()
+	if kp.closed {
+		return nil, ErrPoolClosed
+	}
+	e, ok := kp.pools[key]
+	if !ok {
+		p, err := kp.newSubPool(key)
+		if err != nil {
+			return nil, err
+		}
+		e = &keyedEntry[T]{pool: p}
+		kp.pools[key] = e
+	}
+	e.lastUsed = kp.clock.Now()
+	return e.pool, nil
+}
+
+// lookup 返回key对应的子池，不存在时返回nil
+func (kp *KeyedPool[K, T]) lookup(key K) *Pool[T] {
+	kp.m.Lock()
+	defer kp.m.Unlock()
+	e, ok := kp.pools[key]
+	if !ok {
+		return nil
+	}
+	e.lastUsed = kp.clock.Now()
+	return e.pool
+}
+
+// newSubPool 为key创建一个子池，子池创建和销毁资源时会占用和释放全局容量
+func (kp *KeyedPool[K, T]) newSubPool(key K) (*Pool[T], error) {
+	s := kp.s
+	closer, _ := funcOption[func(T) error](s.closer, "closer")
+	s.closer = func(r T) error {
+		defer kp.releaseGlobal()
+		if closer != nil {
+			return closer(r)
+		}
+		return closeIfCloser(r)
+	}
+	return newPool(func(ctx context.Context) (T, error) {
+		if err := kp.acquireGlobal(ctx, key); err != nil {
+			var zero T
+			return zero, err
+		}
+		r, err := kp.factory(ctx, key)
+		if err != nil {
+			kp.releaseGlobal()
+		}
+		return r, err
+	}, s)
+}
+
+// acquireGlobal 占用一个全局容量，达到上限时按最近最少使用的顺序关闭其它键的空闲子池，
+// 其它键都有使用中的资源时关闭它们的空闲资源，都没有空闲资源时等待资源被销毁
+func (kp *KeyedPool[K, T]) acquireGlobal(ctx context.Context, key K) error {
+	for {
+		kp.m.Lock()
+		if kp.closed {
+			kp.m.Unlock()
+			return ErrPoolClosed
+		}
+		if err := ctx.Err(); err != nil {
+			// 放弃等待的Acquire不再占用容量，被唤醒时ctx可能已经结束
+			kp.m.Unlock()
+			return err
+		}
+		if kp.maxTotal == 0 || kp.numOpen < kp.maxTotal {
+			kp.numOpen++
+			kp.m.Unlock()
+			return nil
+		}
+		victims := kp.lru(key)
+		notify := kp.notify
+		kp.m.Unlock()
+
+		if kp.evictPool(victims) {
+			continue
+		}
+		evicted := false
+		for _, v := range victims {
+			if v.pool.evictIdle() {
+				evicted = true
+				break
+			}
+		}
+		if evicted {
+			continue
+		}
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// keyedVictim 是acquireGlobal可以关闭的一个子池
+type keyedVictim[K comparable, T any] struct {
+	key      K
+	pool     *Pool[T]
+	lastUsed time.Time
+}
+
+// lru 按最近使用的时间从早到晚返回除key以外的子池，调用者需持有kp.m
+func (kp *KeyedPool[K, T]) lru(key K) []keyedVictim[K, T] {
+	victims := make([]keyedVictim[K, T], 0, len(kp.pools))
+	for k, e := range kp.pools {
+		if k != key {
+			victims = append(victims, keyedVictim[K, T]{key: k, pool: e.pool, lastUsed: e.lastUsed})
+		}
+	}
+	sort.Slice(victims, func(i, j int) bool { return victims[i].lastUsed.Before(victims[j].lastUsed) })
+	return victims
+}
+
+// evictPool 关闭victims中第一个有空闲资源并且没有使用中资源的子池，没有这样的子池时返回false
+func (kp *KeyedPool[K, T]) evictPool(victims []keyedVictim[K, T]) bool {
+	for _, v := range victims {
+		s := v.pool.Stats()
+		if s.InUse > 0 || s.Idle == 0 {
+			continue
+		}
+		kp.m.Lock()
+		e, ok := kp.pools[v.key]
+		if ok && e.pool == v.pool {
+			delete(kp.pools, v.key)
+		}
+		kp.m.Unlock()
+		if ok && e.pool == v.pool {
+			v.pool.Close()
+			return true
+		}
+	}
+	return false
+}
+
+// releaseGlobal 释放一个全局容量
+func (kp *KeyedPool[K, T]) releaseGlobal() {
+	kp.m.Lock()
+	kp.numOpen--
+	kp.broadcast()
+	kp.m.Unlock()
+}
+
+// broadcast 唤醒等待全局容量的goroutine，调用者需持有kp.m
+func (kp *KeyedPool[K, T]) broadcast() {
+	close(kp.notify)
+	kp.notify = make(chan struct{})
+}
+
+// sweeper 定期关闭长时间未使用的键对应的子池，直到KeyedPool被关闭
+func (kp *KeyedPool[K, T]) sweeper() {
+	interval := kp.keyIdleTTL / 2
+	if interval <= 0 {
+		interval = kp.keyIdleTTL
+	}
+	ticker := kp.clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			kp.sweep(kp.clock.Now())
+		case <-kp.done:
+			return
+		}
+	}
+}
+
+// sweep 关闭超过keyIdleTTL未被使用并且没有使用中资源的子池
+func (kp *KeyedPool[K, T]) sweep(now time.Time) {
+	var idle []*Pool[T]
+	kp.m.Lock()
+	for k, e := range kp.pools {
+		if now.Sub(e.lastUsed) > kp.keyIdleTTL && e.pool.Stats().InUse == 0 {
+			delete(kp.pools, k)
+			idle = append(idle, e.pool)
+		}
+	}
+	kp.m.Unlock()
+
+	for _, p := range idle {
+		p.Close()
+	}
+}
@@ -0,0 +1,133 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/lazysheep666/pool"
+	"github.com/lazysheep666/pool/pooltest"
+)
+
+// newHarnessKeyed 创建一个使用FakeClock的KeyedPool，测试结束时关闭它
+func newHarnessKeyed(t *testing.T, opts ...pool.KeyedOption) (*pool.KeyedPool[string, *tracked], *harness) {
+	t.Helper()
+	h := &harness{clock: pooltest.NewFakeClock(epoch)}
+	opts = append([]pool.KeyedOption{
+		pool.WithPoolOptions(pool.WithClock(h.clock), pool.WithCloser(h.close)),
+	}, opts...)
+	kp, err := pool.NewKeyed(func(ctx context.Context, key string) (*tracked, error) { return h.create() }, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		kp.CloseContext(ctx)
+	})
+	return kp, h
+}
+
+// keys 返回kp中按字母顺序排列的键
+func keys(kp *pool.KeyedPool[string, *tracked]) []string {
+	ks := kp.Keys()
+	sort.Strings(ks)
+	return ks
+}
+
+func TestKeyedLimits(t *testing.T) {
+	ctx := context.Background()
+	// use 获取key的一个资源，失败时结束测试
+	use := func(t *testing.T, kp *pool.KeyedPool[string, *tracked], key string) *tracked {
+		t.Helper()
+		r, err := kp.Acquire(ctx, key)
+		if err != nil {
+			t.Fatalf("Acquire(%q): %v", key, err)
+		}
+		return r
+	}
+	tests := []struct {
+		name string
+		opts []pool.KeyedOption
+		run  func(t *testing.T, kp *pool.KeyedPool[string, *tracked], h *harness)
+		// 最后剩下的键和关闭的资源数
+		wantKeys   []string
+		wantClosed int64
+	}{
+		{"per-key MaxTotal", []pool.KeyedOption{
+			pool.WithPerKeyLimits(2, 2), pool.WithPoolOptions(pool.WithBlocking(false)),
+		}, func(t *testing.T, kp *pool.KeyedPool[string, *tracked], h *harness) {
+			a1, a2 := use(t, kp, "a"), use(t, kp, "a")
+			if _, err := kp.Acquire(ctx, "a"); !errors.Is(err, pool.ErrPoolExhausted) {
+				t.Errorf("third Acquire(a) = %v, want ErrPoolExhausted", err)
+			}
+			// 其它键不受影响
+			b := use(t, kp, "b")
+			for _, r := range []*tracked{a1, a2} {
+				kp.Release("a", r)
+			}
+			kp.Release("b", b)
+		}, []string{"a", "b"}, 0},
+		{"per-key MaxIdle", []pool.KeyedOption{pool.WithPerKeyLimits(0, 1)}, func(t *testing.T, kp *pool.KeyedPool[string, *tracked], h *harness) {
+			rs := []*tracked{use(t, kp, "a"), use(t, kp, "a"), use(t, kp, "a")}
+			for _, r := range rs {
+				kp.Release("a", r)
+			}
+			if s := kp.Stats("a"); s.Idle != 1 {
+				t.Errorf("Idle = %d, want 1", s.Idle)
+			}
+		}, []string{"a"}, 2},
+		{"global ceiling evicts least recently used key", []pool.KeyedOption{pool.WithGlobalMaxTotal(2)}, func(t *testing.T, kp *pool.KeyedPool[string, *tracked], h *harness) {
+			kp.Release("a", use(t, kp, "a"))
+			h.clock.Advance(time.Second)
+			kp.Release("b", use(t, kp, "b"))
+			h.clock.Advance(time.Second)
+			// a最久没有使用，它的子池被关闭
+			kp.Release("c", use(t, kp, "c"))
+		}, []string{"b", "c"}, 1},
+		{"global ceiling waits for busy keys", []pool.KeyedOption{pool.WithGlobalMaxTotal(1)}, func(t *testing.T, kp *pool.KeyedPool[string, *tracked], h *harness) {
+			a := use(t, kp, "a")
+			short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+			defer cancel()
+			if _, err := kp.Acquire(short, "b"); !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("Acquire(b) at the ceiling = %v, want context.DeadlineExceeded", err)
+			}
+			// a放回后成为空闲子池，可以被关闭
+			kp.Release("a", a)
+			kp.Release("b", use(t, kp, "b"))
+		}, []string{"b"}, 1},
+		{"idle keys swept", []pool.KeyedOption{pool.WithKeyIdleTimeout(time.Minute)}, func(t *testing.T, kp *pool.KeyedPool[string, *tracked], h *harness) {
+			kp.Release("a", use(t, kp, "a"))
+			b := use(t, kp, "b")
+			h.clock.BlockUntil(1)
+			h.clock.Advance(time.Minute + time.Second)
+			// b有使用中的资源，不会被关闭
+			eventually(t, "idle key to be swept", func() bool { return h.closed.Load() == 1 })
+			kp.Release("b", b)
+		}, []string{"b"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kp, h := newHarnessKeyed(t, tt.opts...)
+			tt.run(t, kp, h)
+			if got := keys(kp); !reflect.DeepEqual(got, tt.wantKeys) {
+				t.Errorf("Keys = %v, want %v", got, tt.wantKeys)
+			}
+			if h.closed.Load() != tt.wantClosed {
+				t.Errorf("closed %d resources, want %d", h.closed.Load(), tt.wantClosed)
+			}
+			// 放弃的Acquire的创建在后台结束，之后不再有使用中的资源
+			eventually(t, "no resources in use", func() bool {
+				for _, s := range kp.StatsByKey() {
+					if s.InUse != 0 {
+						return false
+					}
+				}
+				return true
+			})
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package pool
+
+import (
+	"sort"
+	"strings"
+)
+
+// PoolError 给池返回的错误附加池的名字和标签，只在设置了WithName或WithLabels时使用
+// errors.Is和errors.As可以穿过它检查原始错误
+type PoolError struct {
+	Pool   string            // WithName设置的名字
+	Labels map[string]string // WithLabels设置的标签，不应修改
+	Err    error
+}
+
+func (e *PoolError) Error() string {
+	return "pool " + describe(e.Pool, e.Labels) + ": " + e.Err.Error()
+}
+
+func (e *PoolError) Unwrap() error { return e.Err }
+
+// Name 返回WithName设置的名字
+func (p *Pool[T]) Name() string {
+	return p.name
+}
+
+// Labels 返回WithLabels设置的标签的副本
+func (p *Pool[T]) Labels() map[string]string {
+	return copyTags(p.labels)
+}
+
+// wrapErr 在设置了名字或标签时用PoolError包装err
+func (p *Pool[T]) wrapErr(err error) error {
+	if err == nil || p.name == "" && len(p.labels) == 0 {
+		return err
+	}
+	if _, ok := err.(*PoolError); ok {
+		return err
+	}
+	return &PoolError{Pool: p.name, Labels: p.labels, Err: err}
+}
+
+// describe 把名字和标签格式化为name{k1=v1,k2=v2}，标签按键排序
+func describe(name string, labels map[string]string) string {
+	if len(labels) == 0 {
+		return name
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(labels[k])
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// labeledLogger 在每条日志前加上池的名字和标签
+type labeledLogger struct {
+	Logger
+	prefix string
+}
+
+func (l labeledLogger) Println(v ...any) {
+	l.Logger.Println(append([]any{l.prefix}, v...)...)
+}
+
+// newLabeledLogger 在设置了名字或标签时返回加上前缀的logger，否则直接返回logger
+func newLabeledLogger(logger Logger, name string, labels map[string]string) Logger {
+	if name == "" && len(labels) == 0 {
+		return logger
+	}
+	return labeledLogger{Logger: logger, prefix: "[" + describe(name, labels) + "]"}
+}
@@ -0,0 +1,90 @@
+package pool
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// Leak 描述一个被持有时间过长、可能忘记放回池里的资源
+type Leak struct {
+	AcquiredAt time.Time     // 获取资源的时间
+	Held       time.Duration // 已经持有的时间
+	Stack      []byte        // 获取资源时的调用栈
+}
+
+// reclaim 关闭一个被丢弃的使用中资源并释放它占用的容量，报告一次泄漏并发出ResourceLeaked事件
+// 资源还有其它借用者时与Discard相同，只放弃这一次借用，最后一个借用者放回时再销毁
+func (p *Pool[T]) reclaim(r T) {
+	now := p.clock.Now()
+	p.m.Lock()
+	e, ok := p.inUse.Get(r)
+	if !ok {
+		p.m.Unlock()
+		return
+	}
+	if e.refs > 1 {
+		e.refs--
+		e.broken = true
+		p.m.Unlock()
+	} else {
+		p.untrack(e)
+		p.retire(e)
+		p.unlock()
+	}
+
+	l := Leak{AcquiredAt: e.acquiredAt, Held: now.Sub(e.acquiredAt), Stack: e.stack}
+	p.emit(Event{Type: ResourceLeaked, Time: now, Duration: l.Held})
+	if p.onLeak != nil {
+		p.onLeak(l)
+	} else {
+		p.logger.Println("Leak:", "Reclaimed resource abandoned after", l.Held)
+	}
+}
+
+// leakDetector 定期检查被持有过久的资源，直到池被关闭
+func (p *Pool[T]) leakDetector() {
+	interval := p.leakTimeout / 2
+	if interval <= 0 {
+		interval = p.leakTimeout
+	}
+	ticker := p.clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			p.checkLeaks(p.clock.Now())
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// checkLeaks 报告持有时间超过leakTimeout的资源，每次获取只报告一次
+func (p *Pool[T]) checkLeaks(now time.Time) {
+	var leaks []Leak
+	p.m.Lock()
+	for _, e := range p.inUse {
+		if e.leakReported || e.lingering || e.leased || now.Sub(e.acquiredAt) <= p.leakTimeout {
+			continue
+		}
+		e.leakReported = true
+		leaks = append(leaks, Leak{
+			AcquiredAt: e.acquiredAt,
+			Held:       now.Sub(e.acquiredAt),
+			Stack:      e.stack,
+		})
+	}
+	p.m.Unlock()
+
+	for _, l := range leaks {
+		if p.onLeak != nil {
+			p.onLeak(l)
+		} else {
+			p.logger.Println("Leak:", "Resource held for", l.Held, "acquired at\n"+string(l.Stack))
+		}
+		if p.strict {
+			panic(&MisuseError{Err: fmt.Errorf("resource held for %v, longer than LeakTimeout", l.Held), Stack: debug.Stack(), Acquired: l.Stack})
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package pool_test
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+	"testing"
+
+	"github.com/lazysheep666/pool"
+)
+
+// TestReclaimAbandoned 检查没有放回就被垃圾回收的PooledResource的资源被回收，共享的资源留给其它借用者
+func TestReclaimAbandoned(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		sharers uint
+		// run 借出资源并丢弃其中一些，返回仍然持有的PooledResource
+		run        func(t *testing.T, p *pool.Pool[*tracked]) []*pool.PooledResource[*tracked]
+		wantClosed int64 // 回收之后关闭的资源数
+	}{
+		{"unshared resource reclaimed", 0, func(t *testing.T, p *pool.Pool[*tracked]) []*pool.PooledResource[*tracked] {
+			abandon(t, p)
+			return nil
+		}, 1},
+		{"shared resource kept for other borrowers", 2, func(t *testing.T, p *pool.Pool[*tracked]) []*pool.PooledResource[*tracked] {
+			kept, err := p.AcquireResource(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			abandon(t, p)
+			return []*pool.PooledResource[*tracked]{kept}
+		}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var leaks atomic.Int64
+			p, h := newHarnessPool(t, pool.WithReclaimAbandoned(), pool.WithSharing(tt.sharers),
+				pool.WithLeakHandler(func(pool.Leak) { leaks.Add(1) }))
+			kept := tt.run(t, p)
+			eventually(t, "abandoned resource to be reclaimed", func() bool {
+				runtime.GC()
+				return leaks.Load() == 1
+			})
+			if h.closed.Load() != tt.wantClosed {
+				t.Errorf("closed %d resources after reclaiming, want %d", h.closed.Load(), tt.wantClosed)
+			}
+			for _, pr := range kept {
+				if pr.Value().closed.Load() {
+					t.Error("resource closed while another borrower holds it")
+				}
+				if err := pr.Close(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// 被丢弃过的共享资源在最后一个借用者放回时销毁
+			if s := p.Stats(); s.InUse != 0 || s.Idle != 0 || h.closed.Load() != 1 {
+				t.Errorf("InUse = %d, Idle = %d, closed = %d, want 0, 0, 1", s.InUse, s.Idle, h.closed.Load())
+			}
+		})
+	}
+}
+
+// abandon 借出一个资源并丢弃PooledResource，不调用Close或Destroy
+func abandon(t *testing.T, p *pool.Pool[*tracked]) {
+	t.Helper()
+	if _, err := p.AcquireResource(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
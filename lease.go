@@ -0,0 +1,203 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrLeaseExpired 表示租约没有及时续期，资源已经被池收回并销毁
+var ErrLeaseExpired = errors.New("Lease has expired")
+
+// Lease 是AcquireLease返回的租约，持有者需要在到期之前用Renew续期，否则池收回并销毁资源、关闭Done
+// 用于需要长时间持有资源的任务，让长时间的持有成为显式的约定，持有租约的资源不会被报告为泄漏
+type Lease[T any] struct {
+	p          *Pool[T]
+	r          T
+	ttl        time.Duration
+	e          *entry[T]
+	acquiredAt time.Time // 区分同一个资源的不同借出
+
+	m        sync.Mutex
+	deadline time.Time
+	ended    bool // 已经到期或被放回
+	expired  bool
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// AcquireLease 获取一个资源并返回期限为ttl的租约，获取的行为与AcquireContext相同
+func (p *Pool[T]) AcquireLease(ctx context.Context, ttl time.Duration) (*Lease[T], error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("%w: non-positive lease ttl %v", ErrInvalidConfig, ttl)
+	}
+	r, err := p.AcquireContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.m.Lock()
+	e, ok := p.inUse.Get(r)
+	if ok {
+		e.leased = true
+		// 租约的资源只通过加锁的路径放回，到期时才能区分不同的借出
+		p.claim(e)
+	}
+	p.m.Unlock()
+	if !ok {
+		// 池已经关闭，资源已被强制关闭
+		return nil, ErrPoolClosed
+	}
+	l := &Lease[T]{
+		p:          p,
+		r:          r,
+		ttl:        ttl,
+		e:          e,
+		acquiredAt: e.acquiredAt,
+		deadline:   p.clock.Now().Add(ttl),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go l.watch()
+	return l, nil
+}
+
+// Resource 返回租约持有的资源
+func (l *Lease[T]) Resource() T {
+	return l.r
+}
+
+// Deadline 返回租约到期的时间
+func (l *Lease[T]) Deadline() time.Time {
+	l.m.Lock()
+	defer l.m.Unlock()
+	return l.deadline
+}
+
+// Done 返回在租约到期或被放回时关闭的通道
+func (l *Lease[T]) Done() <-chan struct{} {
+	return l.done
+}
+
+// Err 在租约到期后返回ErrLeaseExpired，否则返回nil
+func (l *Lease[T]) Err() error {
+	l.m.Lock()
+	defer l.m.Unlock()
+	if l.expired {
+		return ErrLeaseExpired
+	}
+	return nil
+}
+
+// Renew 把租约的期限延长到从现在开始的ttl，租约已经到期时返回ErrLeaseExpired，已经放回时返回ErrDoubleRelease
+func (l *Lease[T]) Renew(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	l.m.Lock()
+	defer l.m.Unlock()
+	if err := l.endedErr(); err != nil {
+		return err
+	}
+	l.deadline = l.p.clock.Now().Add(l.ttl)
+	return nil
+}
+
+// Release 结束租约并把资源放回池里，租约已经到期时返回ErrLeaseExpired
+func (l *Lease[T]) Release() error {
+	if err := l.end(); err != nil {
+		return err
+	}
+	return l.p.Release(l.r)
+}
+
+// Discard 结束租约并销毁资源，租约已经到期时返回ErrLeaseExpired
+func (l *Lease[T]) Discard() error {
+	if err := l.end(); err != nil {
+		return err
+	}
+	return l.p.Discard(l.r)
+}
+
+// endedErr 返回租约已经结束时的错误，调用者需持有l.m
+func (l *Lease[T]) endedErr() error {
+	if l.expired {
+		return ErrLeaseExpired
+	}
+	if l.ended {
+		return l.p.wrapErr(ErrDoubleRelease)
+	}
+	return nil
+}
+
+// end 在放回资源之前结束租约
+func (l *Lease[T]) end() error {
+	l.m.Lock()
+	if err := l.endedErr(); err != nil {
+		l.m.Unlock()
+		return err
+	}
+	l.ended = true
+	l.m.Unlock()
+	close(l.stop)
+	close(l.done)
+	l.p.m.Lock()
+	if l.current() {
+		l.e.leased = false
+	}
+	l.p.m.Unlock()
+	return nil
+}
+
+// current 判断租约的资源是否仍是这次借出，调用者需持有l.p.m
+func (l *Lease[T]) current() bool {
+	e, ok := l.p.inUse.Get(l.r)
+	return ok && e == l.e && e.acquiredAt.Equal(l.acquiredAt)
+}
+
+// watch 在租约到期时收回资源，续期后重新等待
+func (l *Lease[T]) watch() {
+	p := l.p
+	t := p.clock.NewTimer(l.ttl)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C():
+		case <-l.stop:
+			return
+		}
+		l.m.Lock()
+		if l.ended {
+			l.m.Unlock()
+			return
+		}
+		if now := p.clock.Now(); now.Before(l.deadline) {
+			t.Reset(l.deadline.Sub(now))
+			l.m.Unlock()
+			continue
+		}
+		l.ended, l.expired = true, true
+		l.m.Unlock()
+		l.expire()
+		close(l.done)
+		return
+	}
+}
+
+// expire 收回并销毁到期的租约持有的资源
+func (l *Lease[T]) expire() {
+	p := l.p
+	p.m.Lock()
+	if !l.current() {
+		p.m.Unlock()
+		return
+	}
+	e := l.e
+	e.leased = false
+	p.checkin(l.r)
+	p.untrack(e)
+	p.retire(e)
+	p.unlock()
+	p.logger.Println("Lease:", "Expired after", p.clock.Now().Sub(l.acquiredAt))
+}
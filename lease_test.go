@@ -0,0 +1,111 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lazysheep666/pool"
+)
+
+func TestAcquireLease(t *testing.T) {
+	const ttl = time.Minute
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		// run 推进时钟并续期、放回或销毁租约
+		run func(t *testing.T, p *pool.Pool[*tracked], h *harness, l *pool.Lease[*tracked])
+		// 最后关闭的资源数和空闲资源数
+		wantClosed int64
+		wantIdle   uint
+	}{
+		{"released before deadline", func(t *testing.T, p *pool.Pool[*tracked], h *harness, l *pool.Lease[*tracked]) {
+			h.clock.Advance(ttl / 2)
+			if err := l.Release(); err != nil {
+				t.Fatal(err)
+			}
+			waitDone(t, l)
+			if err := l.Err(); err != nil {
+				t.Errorf("Err = %v after Release, want nil", err)
+			}
+			if err := l.Release(); !errors.Is(err, pool.ErrDoubleRelease) {
+				t.Errorf("second Release = %v, want ErrDoubleRelease", err)
+			}
+			if err := l.Renew(ctx); !errors.Is(err, pool.ErrDoubleRelease) {
+				t.Errorf("Renew after Release = %v, want ErrDoubleRelease", err)
+			}
+		}, 0, 1},
+		{"discarded", func(t *testing.T, p *pool.Pool[*tracked], h *harness, l *pool.Lease[*tracked]) {
+			if err := l.Discard(); err != nil {
+				t.Fatal(err)
+			}
+			waitDone(t, l)
+		}, 1, 0},
+		{"expires at deadline", func(t *testing.T, p *pool.Pool[*tracked], h *harness, l *pool.Lease[*tracked]) {
+			h.clock.Advance(ttl)
+			waitDone(t, l)
+			if err := l.Err(); !errors.Is(err, pool.ErrLeaseExpired) {
+				t.Errorf("Err = %v, want ErrLeaseExpired", err)
+			}
+			if err := l.Renew(ctx); !errors.Is(err, pool.ErrLeaseExpired) {
+				t.Errorf("Renew = %v, want ErrLeaseExpired", err)
+			}
+			if err := l.Release(); !errors.Is(err, pool.ErrLeaseExpired) {
+				t.Errorf("Release = %v, want ErrLeaseExpired", err)
+			}
+		}, 1, 0},
+		{"renew extends deadline", func(t *testing.T, p *pool.Pool[*tracked], h *harness, l *pool.Lease[*tracked]) {
+			h.clock.Advance(ttl / 2)
+			if err := l.Renew(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if want := epoch.Add(ttl + ttl/2); !l.Deadline().Equal(want) {
+				t.Errorf("Deadline = %v, want %v", l.Deadline(), want)
+			}
+			// 原来的期限到了，租约重新等待到续期后的期限
+			h.clock.Advance(ttl / 2)
+			h.clock.BlockUntil(1)
+			if l.Err() != nil {
+				t.Fatalf("lease expired at its original deadline after Renew")
+			}
+			h.clock.Advance(ttl / 2)
+			waitDone(t, l)
+			if err := l.Err(); !errors.Is(err, pool.ErrLeaseExpired) {
+				t.Errorf("Err = %v, want ErrLeaseExpired", err)
+			}
+		}, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, h := newHarnessPool(t)
+			l, err := p.AcquireLease(ctx, ttl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			h.clock.BlockUntil(1)
+			tt.run(t, p, h, l)
+			eventually(t, "lease to end", func() bool { return p.Stats().InUse == 0 })
+			if s := p.Stats(); h.closed.Load() != tt.wantClosed || s.Idle != tt.wantIdle {
+				t.Errorf("closed = %d, Idle = %d, want %d, %d", h.closed.Load(), s.Idle, tt.wantClosed, tt.wantIdle)
+			}
+		})
+	}
+}
+
+// waitDone 等待租约的Done被关闭，5秒后仍未关闭时结束测试
+func waitDone(t *testing.T, l *pool.Lease[*tracked]) {
+	t.Helper()
+	select {
+	case <-l.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("lease Done not closed")
+	}
+}
+
+func TestAcquireLeaseInvalidTTL(t *testing.T) {
+	p, _ := newHarnessPool(t)
+	if _, err := p.AcquireLease(context.Background(), 0); !errors.Is(err, pool.ErrInvalidConfig) {
+		t.Fatalf("AcquireLease with zero ttl = %v, want ErrInvalidConfig", err)
+	}
+}
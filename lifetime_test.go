@@ -0,0 +1,83 @@
+package pool_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lazysheep666/pool"
+)
+
+func TestMaxLifetime(t *testing.T) {
+	const lifetime = time.Minute
+	tests := []struct {
+		name string
+		// run 使用资源并推进时钟，返回最后一次Acquire得到的资源
+		run         func(t *testing.T, p *pool.Pool[*tracked], h *harness) *tracked
+		wantID      int64 // 最后一次Acquire得到的资源的id
+		wantExpired uint64
+	}{
+		{"reused within lifetime", func(t *testing.T, p *pool.Pool[*tracked], h *harness) *tracked {
+			release(t, p, acquire(t, p))
+			h.clock.Advance(lifetime - time.Second)
+			return acquire(t, p)
+		}, 1, 0},
+		{"expired idle replaced", func(t *testing.T, p *pool.Pool[*tracked], h *harness) *tracked {
+			release(t, p, acquire(t, p))
+			h.clock.Advance(lifetime + time.Second)
+			return acquire(t, p)
+		}, 2, 1},
+		{"expired while checked out", func(t *testing.T, p *pool.Pool[*tracked], h *harness) *tracked {
+			r := acquire(t, p)
+			h.clock.Advance(lifetime + time.Second)
+			release(t, p, r)
+			return acquire(t, p)
+		}, 2, 1},
+		{"counted from creation", func(t *testing.T, p *pool.Pool[*tracked], h *harness) *tracked {
+			r := acquire(t, p)
+			h.clock.Advance(lifetime / 2)
+			release(t, p, r)
+			h.clock.Advance(lifetime/2 + time.Second)
+			return acquire(t, p)
+		}, 2, 1},
+		{"exactly at lifetime", func(t *testing.T, p *pool.Pool[*tracked], h *harness) *tracked {
+			release(t, p, acquire(t, p))
+			h.clock.Advance(lifetime)
+			return acquire(t, p)
+		}, 1, 0},
+	}
+	for _, kind := range []pool.IdleStore{pool.SliceStore, pool.LockFreeStore} {
+		for _, tt := range tests {
+			t.Run(kind.String()+"/"+tt.name, func(t *testing.T) {
+				// 回收间隔很长，只测试Acquire和Release时的检查
+				p, h := newHarnessPool(t, pool.WithIdleStore(kind), pool.WithMaxLifetime(lifetime),
+					pool.WithReapInterval(24*time.Hour))
+				r := tt.run(t, p, h)
+				defer release(t, p, r)
+				if r.id != tt.wantID {
+					t.Errorf("got resource %d, want %d", r.id, tt.wantID)
+				}
+				s := p.Stats()
+				if s.LifetimeClosed != tt.wantExpired || uint64(h.closed.Load()) != tt.wantExpired {
+					t.Errorf("LifetimeClosed = %d, closed = %d, want %d", s.LifetimeClosed, h.closed.Load(), tt.wantExpired)
+				}
+			})
+		}
+	}
+}
+
+// TestMaxLifetimeReaper 检查后台回收在没有Acquire时关闭过期的空闲资源
+func TestMaxLifetimeReaper(t *testing.T) {
+	for _, kind := range []pool.IdleStore{pool.SliceStore, pool.LockFreeStore} {
+		t.Run(kind.String(), func(t *testing.T) {
+			p, h := newHarnessPool(t, pool.WithIdleStore(kind), pool.WithMaxLifetime(time.Minute),
+				pool.WithReapInterval(30*time.Second))
+			release(t, p, acquire(t, p))
+			h.clock.BlockUntil(1)
+			h.clock.Advance(61 * time.Second)
+			eventually(t, "expired resource to be closed", func() bool { return h.closed.Load() == 1 })
+			if s := p.Stats(); s.Idle != 0 || s.LifetimeClosed != 1 {
+				t.Errorf("Idle = %d, LifetimeClosed = %d, want 0, 1", s.Idle, s.LifetimeClosed)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package pool
+
+// ReleaseDeferred 放回一个需要异步收尾才能知道是否可以复用的资源，例如还要读完响应体的连接
+// 池在另一个goroutine中调用decide，按它返回的结果用ReleaseWith放回或销毁资源；
+// decide超过LingerTimeout没有返回或panic时资源被销毁，之后decide的结果被忽略
+// 等待期间资源仍然占用容量，计入Stats.Lingering，调用者不应再使用它，再次放回返回ErrDoubleRelease
+func (p *Pool[T]) ReleaseDeferred(r T, decide func() Outcome) error {
+	p.m.Lock()
+	err := p.checkOwned(r)
+	var e *entry[T]
+	if err == nil {
+		e, _ = p.inUse.Get(r)
+	}
+	if e != nil {
+		e.lingering = true
+		p.lingering++
+	}
+	p.m.Unlock()
+	if err != nil {
+		p.logger.Println("ReleaseDeferred", err)
+		return p.misuse(p.wrapErr(err))
+	}
+	if e == nil {
+		// 池已经关闭，资源已被强制关闭
+		return nil
+	}
+
+	decided := make(chan Outcome, 1)
+	go func() {
+		outcome := OutcomeDiscard
+		defer func() {
+			if v := recover(); v != nil {
+				p.logger.Println("ReleaseDeferred:", "Decide Panicked:", v)
+				outcome = OutcomeDiscard
+			}
+			decided <- outcome
+		}()
+		outcome = decide()
+	}()
+	go func() {
+		t := p.clock.NewTimer(p.lingerTimeout)
+		defer t.Stop()
+		var outcome Outcome
+		select {
+		case outcome = <-decided:
+		case <-t.C():
+			p.logger.Println("ReleaseDeferred:", "Linger Timeout")
+			outcome = OutcomeDiscard
+		}
+		if outcome < OutcomeCommit || outcome > OutcomeDiscard {
+			outcome = OutcomeDiscard
+		}
+		p.m.Lock()
+		p.stopLingering(e)
+		p.m.Unlock()
+		if err := p.ReleaseWith(r, outcome); err != nil {
+			p.logger.Println("ReleaseDeferred", err)
+		}
+	}()
+	return nil
+}
+
+// stopLingering 结束资源的等待状态，调用者需持有p.m
+func (p *Pool[T]) stopLingering(e *entry[T]) {
+	if e.lingering {
+		e.lingering = false
+		p.lingering--
+	}
+}
@@ -0,0 +1,67 @@
+package pool_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lazysheep666/pool"
+)
+
+func TestReleaseDeferred(t *testing.T) {
+	const timeout = 10 * time.Second
+	tests := []struct {
+		name    string
+		outcome pool.Outcome
+		panics  bool
+		expire  bool // 是否在decide返回之前让LingerTimeout到期
+		// 决定之后关闭的资源数和空闲资源数
+		wantClosed int64
+		wantIdle   uint
+	}{
+		{"commit keeps resource", pool.OutcomeCommit, false, false, 0, 1},
+		{"discard closes resource", pool.OutcomeDiscard, false, false, 1, 0},
+		{"unknown outcome closes resource", pool.Outcome(7), false, false, 1, 0},
+		{"panic closes resource", pool.OutcomeCommit, true, false, 1, 0},
+		{"timeout closes resource", pool.OutcomeCommit, false, true, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, h := newHarnessPool(t, pool.WithLingerTimeout(timeout))
+			r := acquire(t, p)
+			proceed, returned := make(chan struct{}), make(chan struct{})
+			err := p.ReleaseDeferred(r, func() pool.Outcome {
+				defer close(returned)
+				<-proceed
+				if tt.panics {
+					panic("decide failed")
+				}
+				return tt.outcome
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 等待决定期间资源仍然占用容量，不能再次放回
+			if s := p.Stats(); s.Lingering != 1 || s.InUse != 0 || s.Idle != 0 {
+				t.Errorf("Lingering = %d, InUse = %d, Idle = %d while deciding, want 1, 0, 0", s.Lingering, s.InUse, s.Idle)
+			}
+			if err := p.Release(r); !errors.Is(err, pool.ErrDoubleRelease) {
+				t.Errorf("Release while lingering = %v, want ErrDoubleRelease", err)
+			}
+			if tt.expire {
+				h.clock.BlockUntil(1)
+				h.clock.Advance(timeout)
+				eventually(t, "linger timeout", func() bool { return p.Stats().Lingering == 0 })
+			}
+			close(proceed)
+			<-returned
+			eventually(t, "decision to apply", func() bool { return p.Stats().Lingering == 0 })
+			eventually(t, "resource to be put back or closed", func() bool {
+				return h.closed.Load() == tt.wantClosed && p.Stats().Idle == tt.wantIdle
+			})
+			if s := p.Stats(); s.InUse != 0 {
+				t.Errorf("InUse = %d, want 0", s.InUse)
+			}
+		})
+	}
+}
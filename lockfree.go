@@ -0,0 +1,307 @@
+package pool
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lazysheep666/pool/internal/identity"
+)
+
+// maxParked 是LockFreeStore的环形缓冲区最多的槽数
+const maxParked = 1024
+
+// maxPopRetries 是暂存的资源不为空但队首还没有写入时acquireParked重试的次数
+const maxPopRetries = 4
+
+// 使用LockFreeStore时entry.lf的取值
+const (
+	lfClaimed int32 = iota // 由加锁的路径管理：空闲、正在创建或正在被加锁的Release处理
+	lfHeld                 // 由加锁的路径借出给调用者，可以不加锁地放回
+	lfLent                 // 不加锁地借出给调用者，acquiredAt没有更新
+	lfParked               // 不加锁地放回，暂存在环形缓冲区中
+)
+
+// lockFreeStore 是LockFreeStore，store的方法仍然在持有p.m时调用，由嵌入的sliceStore实现，
+// 另外用一个无锁的环形缓冲区暂存不加锁放回的资源，它们在池看来仍然是使用中的资源，
+// 不加锁的Acquire从中取出资源，其它操作在持有p.m时先用unpark把它们移回空闲资源
+type lockFreeStore[T any] struct {
+	sliceStore[T]
+	ring      lockFreeRing[T]
+	index     sync.Map     // 借出的资源的identity.Key到entry，不加锁的Release用它找到entry
+	idle      atomic.Int64 // sliceStore中的空闲资源数，不加锁的Release用它检查MaxIdle
+	parked    atomic.Int64 // 暂存的资源数
+	limit     atomic.Int64 // 最多暂存的资源数，即MaxIdle
+	contended atomic.Int64 // 正在走加锁的路径的Acquire数，大于0时不再暂存资源或从暂存的资源中获取
+	slowOnly  atomic.Bool  // 池暂停、开始关闭或已经关闭，只使用加锁的路径
+}
+
+// newLockFreeStore 返回一个最多暂存maxIdle个资源的lockFreeStore
+func newLockFreeStore[T any](maxIdle uint) *lockFreeStore[T] {
+	size := 1
+	for size < int(maxIdle) && size < maxParked {
+		size *= 2
+	}
+	s := &lockFreeStore[T]{}
+	s.ring.init(size)
+	s.limit.Store(int64(maxIdle))
+	return s
+}
+
+func (s *lockFreeStore[T]) Put(e *entry[T]) {
+	s.sliceStore.Put(e)
+	s.idle.Store(int64(s.sliceStore.Len()))
+}
+
+func (s *lockFreeStore[T]) Get(i int) *entry[T] {
+	e := s.sliceStore.Get(i)
+	s.idle.Store(int64(s.sliceStore.Len()))
+	return e
+}
+
+func (s *lockFreeStore[T]) Drain() []*entry[T] {
+	s.idle.Store(0)
+	return s.sliceStore.Drain()
+}
+
+func (s *lockFreeStore[T]) Retain(keep func(e *entry[T]) bool) {
+	s.sliceStore.Retain(keep)
+	s.idle.Store(int64(s.sliceStore.Len()))
+}
+
+// lockFreeRing 是有界的多生产者多消费者无锁队列(Dmitry Vyukov的算法)，
+// 每个槽的序号说明它是否可以写入或读取，push和pop各自只在head或tail上竞争一次CAS
+type lockFreeRing[T any] struct {
+	slots []ringSlot[T]
+	mask  uint64
+	_     [56]byte // 把head和tail放在不同的缓存行上
+	head  atomic.Uint64
+	_     [56]byte
+	tail  atomic.Uint64
+}
+
+// ringSlot 是lockFreeRing的一个槽，seq等于位置时可以写入，等于位置+1时可以读取
+type ringSlot[T any] struct {
+	seq atomic.Uint64
+	e   *entry[T]
+}
+
+// init 初始化有size个槽的队列，size必须是2的幂
+func (q *lockFreeRing[T]) init(size int) {
+	q.slots = make([]ringSlot[T], size)
+	q.mask = uint64(size - 1)
+	for i := range q.slots {
+		q.slots[i].seq.Store(uint64(i))
+	}
+}
+
+// push 把e放到队尾，队列已满时返回false
+func (q *lockFreeRing[T]) push(e *entry[T]) bool {
+	pos := q.tail.Load()
+	for {
+		slot := &q.slots[pos&q.mask]
+		seq := slot.seq.Load()
+		switch diff := int64(seq - pos); {
+		case diff == 0:
+			if q.tail.CompareAndSwap(pos, pos+1) {
+				slot.e = e
+				slot.seq.Store(pos + 1)
+				return true
+			}
+			pos = q.tail.Load()
+		case diff < 0:
+			return false
+		default:
+			pos = q.tail.Load()
+		}
+	}
+}
+
+// pop 取出队首的资源，队列为空时返回nil
+func (q *lockFreeRing[T]) pop() *entry[T] {
+	pos := q.head.Load()
+	for {
+		slot := &q.slots[pos&q.mask]
+		seq := slot.seq.Load()
+		switch diff := int64(seq - (pos + 1)); {
+		case diff == 0:
+			if q.head.CompareAndSwap(pos, pos+1) {
+				e := slot.e
+				slot.e = nil
+				slot.seq.Store(pos + q.mask + 1)
+				return e
+			}
+			pos = q.head.Load()
+		case diff < 0:
+			return nil
+		default:
+			pos = q.head.Load()
+		}
+	}
+}
+
+// lockFreeEligible 判断池的设置是否允许不加锁地获取和放回，
+// 获取和放回时需要执行检查、钩子或记录每次借出的设置都只能使用加锁的路径
+func (p *Pool[T]) lockFreeEligible() bool {
+	return p.validator == nil && p.onAcquire == nil && p.onRelease == nil && p.reset == nil &&
+		p.sanitize == nil && p.binder == nil && p.weight == nil && p.maxWeight == 0 && p.score == nil &&
+		p.isClosed == nil && p.selection == nil && p.balancer == nil && p.chaos == nil && !p.overridable &&
+		p.maxUses == 0 && p.maxSharers <= 1 && p.leakTimeout == 0 && p.maxCheckout == 0 && !p.reclaimAbandoned &&
+		p.maxIdleBytes == 0 && p.reserved == 0 && p.onEvent == nil && p.tracer == nil && !p.logging &&
+		!p.runtimeTrace && p.profile == nil && p.overflow == DiscardOverflow
+}
+
+// track 把e记为借出的资源，调用者需持有p.m
+func (p *Pool[T]) track(e *entry[T]) {
+	p.inUse.Set(e.r, e)
+	if p.lf != nil {
+		e.lf.Store(lfHeld)
+		e.untimed = false
+		if k, ok := identity.Key(e.r); ok {
+			p.lf.index.Store(k, e)
+		}
+	}
+}
+
+// untrack 把e从借出的资源中移除，调用者需持有p.m
+func (p *Pool[T]) untrack(e *entry[T]) {
+	p.inUse.Delete(e.r)
+	if p.lf != nil {
+		e.lf.Store(lfClaimed)
+		if k, ok := identity.Key(e.r); ok {
+			p.lf.index.Delete(k)
+		}
+	}
+}
+
+// claim 让加锁的路径接管借出的资源e，之后它只能通过加锁的路径放回，
+// e已经被不加锁地放回时返回false，调用者需持有p.m
+func (p *Pool[T]) claim(e *entry[T]) bool {
+	if p.lf == nil {
+		return true
+	}
+	for {
+		switch s := e.lf.Load(); s {
+		case lfParked:
+			return false
+		case lfClaimed:
+			return true
+		default:
+			if e.lf.CompareAndSwap(s, lfClaimed) {
+				// 不加锁的借出没有记录获取的时间，放回时不计算持有的时间
+				e.untimed = e.untimed || s == lfLent
+				return true
+			}
+		}
+	}
+}
+
+// acquireParked 不加锁地从暂存的资源中获取一个，没有可用的资源、有Acquire在排队或池暂停、正在关闭时返回false
+func (p *Pool[T]) acquireParked(ctx context.Context) (T, bool) {
+	var zero T
+	lf := p.lf
+	for retries := 0; lf.contended.Load() == 0 && !lf.slowOnly.Load() && ctx.Err() == nil; {
+		e := lf.ring.pop()
+		if e == nil {
+			// 正在放入的Release已经占用了队首的槽但还没有写入，稍等它写入，避免不必要地创建资源
+			if lf.parked.Load() == 0 || retries == maxPopRetries {
+				return zero, false
+			}
+			retries++
+			runtime.Gosched()
+			continue
+		}
+		lf.parked.Add(-1)
+		expired := p.maxLifetime > 0 && p.expired(e, p.clock.Now())
+		if expired || lf.slowOnly.Load() || e.gen != p.generation.Load() {
+			// 由加锁的路径处理Pause、Close和失效的资源，在这之前e仍然标记为暂存，Close不会强制关闭它
+			p.m.Lock()
+			p.untrack(e)
+			if expired {
+				p.stats.lifetimeClosed.Add(1)
+				p.retire(e)
+			} else {
+				p.putBack(e)
+			}
+			p.unlock()
+			continue
+		}
+		e.lf.Store(lfLent)
+		if p.abandon(e.r) {
+			// 池在取出e之后开始关闭，由加锁的路径返回ErrPoolClosed
+			return zero, false
+		}
+		p.stats.hit()
+		return e.r, true
+	}
+	return zero, false
+}
+
+// park 不加锁地把借出的资源r暂存起来，不能这样放回时返回false，由调用者走加锁的路径
+func (p *Pool[T]) park(r T) bool {
+	lf := p.lf
+	if lf.contended.Load() > 0 || lf.slowOnly.Load() {
+		return false
+	}
+	k, ok := identity.Key(r)
+	if !ok {
+		return false
+	}
+	v, ok := lf.index.Load(k)
+	if !ok {
+		return false
+	}
+	e := v.(*entry[T])
+	s := e.lf.Load()
+	if s != lfHeld && s != lfLent || !e.lf.CompareAndSwap(s, lfParked) {
+		return false
+	}
+	if lf.parked.Add(1)+lf.idle.Load() > lf.limit.Load() || !lf.ring.push(e) {
+		lf.parked.Add(-1)
+		e.lf.Store(s)
+		return false
+	}
+	// 排队的Acquire、Pause和Close可能在上面的检查之后开始，它们在设置标记之后才会调用unpark，
+	// 这里再检查一次，保证暂存的资源总会被其中一方交给加锁的路径
+	if lf.contended.Load() > 0 || lf.slowOnly.Load() {
+		p.m.Lock()
+		p.unpark()
+		p.unlock()
+	}
+	return true
+}
+
+// idleCount 返回空闲资源数，包括暂存的资源，调用者需持有p.m
+func (p *Pool[T]) idleCount() int {
+	n := p.idle.Len()
+	if p.lf != nil {
+		n += int(p.lf.parked.Load())
+	}
+	return n
+}
+
+// unpark 把所有暂存的资源交给排队的Acquire或移回空闲资源，调用者需持有p.m，并用p.unlock解锁
+func (p *Pool[T]) unpark() {
+	if p.lf == nil {
+		return
+	}
+	for {
+		e := p.lf.ring.pop()
+		if e == nil {
+			return
+		}
+		p.lf.parked.Add(-1)
+		p.untrack(e)
+		p.putBack(e)
+	}
+}
+
+// slowPath 让之后的Acquire和Release只使用加锁的路径，on为false时恢复，调用者需持有p.m，并用p.unlock解锁
+func (p *Pool[T]) slowPath(on bool) {
+	if p.lf == nil {
+		return
+	}
+	p.lf.slowOnly.Store(on)
+	p.unpark()
+}
@@ -0,0 +1,341 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLockFreeRing(t *testing.T) {
+	var q lockFreeRing[int]
+	q.init(4)
+	es := make([]*entry[int], 5)
+	for i := range es {
+		es[i] = &entry[int]{r: i}
+	}
+	if e := q.pop(); e != nil {
+		t.Fatalf("pop on an empty ring = %v, want nil", e.r)
+	}
+	for i := 0; i < 4; i++ {
+		if !q.push(es[i]) {
+			t.Fatalf("push %d failed", i)
+		}
+	}
+	if q.push(es[4]) {
+		t.Fatal("push on a full ring succeeded")
+	}
+	// 取出一个之后可以再放入一个，顺序是FIFO
+	for round := 0; round < 3; round++ {
+		e := q.pop()
+		if e == nil || e.r != round%len(es) {
+			t.Fatalf("round %d: pop = %v, want %d", round, e, round%len(es))
+		}
+		if !q.push(e) {
+			t.Fatalf("round %d: push after pop failed", round)
+		}
+	}
+}
+
+// TestLockFreeRingConcurrent 检查多个生产者和消费者同时使用时每个元素恰好被取出一次
+func TestLockFreeRingConcurrent(t *testing.T) {
+	const producers, consumers, perProducer = 4, 4, 2000
+	var q lockFreeRing[int]
+	q.init(64)
+	var seen [producers * perProducer]atomic.Int32
+	var remaining atomic.Int64
+	remaining.Store(producers * perProducer)
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < perProducer; i++ {
+				e := &entry[int]{r: p*perProducer + i}
+				for !q.push(e) {
+					runtime.Gosched()
+				}
+			}
+		}(p)
+	}
+	for c := 0; c < consumers; c++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for remaining.Load() > 0 {
+				if e := q.pop(); e != nil {
+					seen[e.r].Add(1)
+					remaining.Add(-1)
+				} else {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for i := range seen {
+		if n := seen[i].Load(); n != 1 {
+			t.Fatalf("element %d popped %d times, want 1", i, n)
+		}
+	}
+}
+
+// lfResource 记录是否同时被多个调用者持有
+type lfResource struct {
+	held   atomic.Bool
+	closed atomic.Bool
+}
+
+// newLockFreePool 创建一个使用LockFreeStore的池，closed统计被关闭的资源数
+func newLockFreePool(t *testing.T, closed *atomic.Int64, opts ...Option) *Pool[*lfResource] {
+	t.Helper()
+	opts = append([]Option{
+		WithIdleStore(LockFreeStore),
+		WithCloser(func(r *lfResource) error {
+			if r.closed.Swap(true) {
+				t.Error("resource closed twice")
+			}
+			closed.Add(1)
+			return nil
+		}),
+	}, opts...)
+	p, err := New(func() (*lfResource, error) { return &lfResource{}, nil }, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLockFreeEligible(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want bool
+	}{
+		{"plain", nil, true},
+		{"slice store", []Option{WithIdleStore(SliceStore)}, false},
+		{"validator", []Option{WithValidator(func(*lfResource) bool { return true })}, false},
+		{"max uses", []Option{WithMaxUses(3)}, false},
+		{"events", []Option{WithEventSink(func(Event) {})}, false},
+		{"max lifetime", []Option{WithMaxLifetime(time.Hour)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var closed atomic.Int64
+			p := newLockFreePool(t, &closed, tt.opts...)
+			defer p.Close()
+			if got := p.lf != nil; got != tt.want {
+				t.Errorf("lock-free path enabled = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLockFreeStore(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, p *Pool[*lfResource], closed *atomic.Int64)
+	}{
+		{"release parks and acquire reuses", func(t *testing.T, p *Pool[*lfResource], closed *atomic.Int64) {
+			r := mustAcquire(t, p)
+			if err := p.Release(r); err != nil {
+				t.Fatal(err)
+			}
+			if n := p.lf.parked.Load(); n != 1 {
+				t.Fatalf("parked = %d, want 1", n)
+			}
+			if s := p.Stats(); s.Idle != 1 || s.InUse != 0 {
+				t.Errorf("Idle = %d, InUse = %d, want 1, 0", s.Idle, s.InUse)
+			}
+			r2 := mustAcquire(t, p)
+			defer p.Release(r2)
+			if r2 != r {
+				t.Error("Acquire did not reuse the parked resource")
+			}
+			if s := p.Stats(); s.TotalCreated != 1 || s.Hits != 1 {
+				t.Errorf("TotalCreated = %d, Hits = %d, want 1, 1", s.TotalCreated, s.Hits)
+			}
+		}},
+		{"double release", func(t *testing.T, p *Pool[*lfResource], closed *atomic.Int64) {
+			r := mustAcquire(t, p)
+			p.Release(r)
+			if err := p.Release(r); !errors.Is(err, ErrDoubleRelease) {
+				t.Errorf("second Release = %v, want ErrDoubleRelease", err)
+			}
+			if err := p.Discard(r); !errors.Is(err, ErrDoubleRelease) {
+				t.Errorf("Discard after Release = %v, want ErrDoubleRelease", err)
+			}
+		}},
+		{"foreign", func(t *testing.T, p *Pool[*lfResource], closed *atomic.Int64) {
+			if err := p.Release(&lfResource{}); !errors.Is(err, ErrForeignResource) {
+				t.Errorf("Release = %v, want ErrForeignResource", err)
+			}
+		}},
+		{"discard after lock-free acquire", func(t *testing.T, p *Pool[*lfResource], closed *atomic.Int64) {
+			p.Release(mustAcquire(t, p))
+			r := mustAcquire(t, p)
+			if err := p.Discard(r); err != nil {
+				t.Fatal(err)
+			}
+			if closed.Load() != 1 {
+				t.Errorf("closed = %d, want 1", closed.Load())
+			}
+		}},
+		{"close closes parked", func(t *testing.T, p *Pool[*lfResource], closed *atomic.Int64) {
+			a, b := mustAcquire(t, p), mustAcquire(t, p)
+			p.Release(a)
+			p.Release(b)
+			if err := p.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if closed.Load() != 2 {
+				t.Errorf("closed = %d, want 2", closed.Load())
+			}
+			if _, err := p.Acquire(); !errors.Is(err, ErrPoolClosed) {
+				t.Errorf("Acquire after Close = %v, want ErrPoolClosed", err)
+			}
+		}},
+		{"release after close", func(t *testing.T, p *Pool[*lfResource], closed *atomic.Int64) {
+			r := mustAcquire(t, p)
+			done := make(chan error)
+			go func() { done <- p.Close() }()
+			for !p.lf.slowOnly.Load() {
+				runtime.Gosched()
+			}
+			if err := p.Release(r); err != nil {
+				t.Fatal(err)
+			}
+			if err := <-done; err != nil {
+				t.Fatal(err)
+			}
+			if closed.Load() != 1 {
+				t.Errorf("closed = %d, want 1", closed.Load())
+			}
+		}},
+		{"pause", func(t *testing.T, p *Pool[*lfResource], closed *atomic.Int64) {
+			p.Release(mustAcquire(t, p))
+			p.Pause()
+			if _, err := p.TryAcquire(); !errors.Is(err, ErrPoolPaused) {
+				t.Errorf("TryAcquire while paused = %v, want ErrPoolPaused", err)
+			}
+			p.Resume()
+			p.Release(mustAcquire(t, p))
+			if s := p.Stats(); s.TotalCreated != 1 {
+				t.Errorf("TotalCreated = %d, want 1", s.TotalCreated)
+			}
+		}},
+		{"invalidate", func(t *testing.T, p *Pool[*lfResource], closed *atomic.Int64) {
+			p.Release(mustAcquire(t, p))
+			p.InvalidateAll()
+			if closed.Load() != 1 {
+				t.Errorf("closed = %d, want 1", closed.Load())
+			}
+			p.Release(mustAcquire(t, p))
+			if s := p.Stats(); s.TotalCreated != 2 {
+				t.Errorf("TotalCreated = %d, want 2", s.TotalCreated)
+			}
+		}},
+		{"max idle", func(t *testing.T, p *Pool[*lfResource], closed *atomic.Int64) {
+			rs := []*lfResource{mustAcquire(t, p), mustAcquire(t, p), mustAcquire(t, p)}
+			for _, r := range rs {
+				p.Release(r)
+			}
+			// MaxIdle为2，第三个资源走加锁的路径并被关闭
+			if s := p.Stats(); s.Idle != 2 || closed.Load() != 1 {
+				t.Errorf("Idle = %d, closed = %d, want 2, 1", s.Idle, closed.Load())
+			}
+		}},
+		{"waiter gets resource", func(t *testing.T, p *Pool[*lfResource], closed *atomic.Int64) {
+			p.SetMaxTotal(1)
+			r := mustAcquire(t, p)
+			got := make(chan *lfResource)
+			go func() {
+				r, err := p.Acquire()
+				if err != nil {
+					t.Error(err)
+				}
+				got <- r
+			}()
+			for p.Waiting() == 0 {
+				runtime.Gosched()
+			}
+			p.Release(r)
+			r2 := <-got
+			defer p.Release(r2)
+			if r2 != r {
+				t.Error("waiter did not get the released resource")
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var closed atomic.Int64
+			p := newLockFreePool(t, &closed, WithMaxIdle(2))
+			defer p.Close()
+			tt.run(t, p, &closed)
+		})
+	}
+}
+
+// TestLockFreeConcurrent 检查多个goroutine同时获取和放回时一个资源不会同时借给两个调用者，
+// 关闭后所有资源都被关闭
+func TestLockFreeConcurrent(t *testing.T) {
+	const goroutines, rounds = 16, 2000
+	var closed atomic.Int64
+	p := newLockFreePool(t, &closed, WithMaxTotal(4), WithMaxIdle(4))
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				r, err := p.AcquireContext(ctx)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if r.held.Swap(true) {
+					t.Error("resource lent to two callers at once")
+				}
+				r.held.Store(false)
+				if (g+i)%97 == 0 {
+					err = p.Discard(r)
+				} else {
+					err = p.Release(r)
+				}
+				if err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	// Acquire用了别人放回的资源时，它创建的资源在后台放回
+	for deadline := time.Now().Add(5 * time.Second); p.Stats().InUse != 0; {
+		if time.Now().After(deadline) {
+			t.Fatalf("InUse = %d after all releases, want 0", p.Stats().InUse)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if s := p.Stats(); closed.Load() != int64(s.TotalCreated) {
+		t.Errorf("closed %d of %d resources", closed.Load(), s.TotalCreated)
+	}
+}
+
+// mustAcquire 获取一个资源，失败时结束测试
+func mustAcquire[T any](t *testing.T, p *Pool[T]) T {
+	t.Helper()
+	r, err := p.Acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
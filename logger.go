@@ -0,0 +1,11 @@
+package pool
+
+// Logger 是池内部使用的日志接口，*log.Logger实现了这个接口
+type Logger interface {
+	Println(v ...any)
+}
+
+// nopLogger 丢弃所有日志，是池默认使用的Logger
+type nopLogger struct{}
+
+func (nopLogger) Println(...any) {}
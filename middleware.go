@@ -0,0 +1,77 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// ctxKey 是Middleware把资源保存到请求ctx中使用的key，每个资源类型对应一个key
+type ctxKey[T any] struct{}
+
+// Middleware 返回一个http中间件，为每个请求从p获取一个资源并保存到请求的ctx中，
+// handler中用FromContext取出，handler返回时把资源放回池里，响应状态码为5xx或handler panic时销毁资源
+// 获取资源失败时返回503，不调用handler
+// 同一个请求上嵌套使用资源类型相同的多个Middleware时，FromContext返回最内层的资源
+func Middleware[T any](p Pooler[T]) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			r, err := p.AcquireContext(req.Context())
+			if err != nil {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				fmt.Fprintln(w, err)
+				return
+			}
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				if v := recover(); v != nil {
+					p.Discard(r)
+					panic(v)
+				}
+				if sw.status >= http.StatusInternalServerError {
+					p.Discard(r)
+					return
+				}
+				p.Release(r)
+			}()
+			next.ServeHTTP(sw, req.WithContext(contextWith(req.Context(), r)))
+		})
+	}
+}
+
+// contextWith 返回保存了资源r的ctx
+func contextWith[T any](ctx context.Context, r T) context.Context {
+	return context.WithValue(ctx, ctxKey[T]{}, r)
+}
+
+// FromContext 取出Middleware保存在ctx中的资源，没有时ok为false
+func FromContext[T any](ctx context.Context) (r T, ok bool) {
+	r, ok = ctx.Value(ctxKey[T]{}).(T)
+	return r, ok
+}
+
+// statusWriter 记录handler写入的响应状态码
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap 让http.ResponseController可以使用底层ResponseWriter的Flush等功能
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
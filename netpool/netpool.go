@@ -0,0 +1,107 @@
+// Package netpool 提供管理网络连接的资源池
+//
+//	p, err := netpool.NewConnPool(&net.Dialer{}, "127.0.0.1:6379", pool.WithMaxTotal(16))
+//	c, err := p.Get(ctx)
+//	defer c.Close() // 把连接放回池里
+package netpool
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/lazysheep666/pool"
+	"github.com/lazysheep666/pool/validators"
+)
+
+// DefaultKeepAlive 是对TCP连接开启的keepalive探测间隔
+const DefaultKeepAlive = 15 * time.Second
+
+// Dialer 建立网络连接，*net.Dialer和*tls.Dialer都实现了这个接口
+type Dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// ConnPool 是一个TCP或TLS连接的资源池
+// 从池中取出空闲连接时会检查对端是否已经关闭连接，已断开的连接会被关闭并重新获取
+type ConnPool struct {
+	p *pool.Pool[net.Conn]
+}
+
+// NewConnPool 创建一个用dialer连接addr的连接池
+// opts用来设置池的其它配置，其中的WithValidator会替换默认的断线检查
+func NewConnPool(dialer Dialer, addr string, opts ...pool.Option) (*ConnPool, error) {
+	if dialer == nil {
+		return nil, errors.New("netpool: nil dialer")
+	}
+	dial := func(ctx context.Context) (net.Conn, error) {
+		c, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		if tc, ok := c.(*net.TCPConn); ok {
+			tc.SetKeepAlive(true)
+			tc.SetKeepAlivePeriod(DefaultKeepAlive)
+		}
+		return c, nil
+	}
+	opts = append([]pool.Option{
+		pool.WithCloser(func(c net.Conn) error { return c.Close() }),
+		pool.WithValidator(Alive),
+	}, opts...)
+	p, err := pool.NewContext(dial, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &ConnPool{p: p}, nil
+}
+
+// Get 从池中获取一个连接，使用完后调用它的Close放回池里
+func (cp *ConnPool) Get(ctx context.Context) (*Conn, error) {
+	pr, err := cp.p.AcquireResource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c := pr.Value()
+	// 清除上一个使用者设置的超时
+	c.SetDeadline(time.Time{})
+	return &Conn{Conn: c, pr: pr}, nil
+}
+
+// Stats 返回连接池的统计信息
+func (cp *ConnPool) Stats() pool.Stats {
+	return cp.p.Stats()
+}
+
+// Close 关闭连接池，并等待使用中的连接被放回后关闭它们
+func (cp *ConnPool) Close() error {
+	return cp.p.Close()
+}
+
+// Pool 返回底层的资源池
+func (cp *ConnPool) Pool() *pool.Pool[net.Conn] {
+	return cp.p
+}
+
+// Conn 是从ConnPool中获取的连接，Close会把连接放回池里
+type Conn struct {
+	net.Conn
+	pr *pool.PooledResource[net.Conn]
+}
+
+// Close 把连接放回池里，重复调用时返回pool.ErrResourceReleased
+func (c *Conn) Close() error {
+	return c.pr.Close()
+}
+
+// Destroy 关闭连接而不是放回池里，用于连接出错、状态不可预期的情况
+func (c *Conn) Destroy() error {
+	return c.pr.Destroy()
+}
+
+// Alive 检查一个空闲连接是否仍然可用，与validators.ConnAlive相同
+// 对端已经关闭连接、连接出错或连接上有未读的数据时返回false
+func Alive(c net.Conn) bool {
+	return validators.ConnAlive(c)
+}
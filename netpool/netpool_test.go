@@ -0,0 +1,215 @@
+package netpool
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lazysheep666/pool"
+)
+
+// server 是接受回环连接的测试服务端，conns依次收到接受的连接
+type server struct {
+	ln    net.Listener
+	conns chan net.Conn
+}
+
+// countingDialer 是记录连接次数的Dialer
+type countingDialer struct {
+	net.Dialer
+	dials atomic.Int64
+}
+
+func (d *countingDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	d.dials.Add(1)
+	return d.Dialer.DialContext(ctx, network, address)
+}
+
+// newServer 在回环地址上启动一个server，测试结束时关闭它和它接受的连接
+func newServer(t *testing.T) *server {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &server{ln: ln, conns: make(chan net.Conn, 16)}
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.conns <- c
+		}
+	}()
+	t.Cleanup(func() {
+		ln.Close()
+		for {
+			select {
+			case c := <-s.conns:
+				c.Close()
+			default:
+				return
+			}
+		}
+	})
+	return s
+}
+
+// next 返回服务端接受的下一个连接
+func (s *server) next(t *testing.T) net.Conn {
+	t.Helper()
+	select {
+	case c := <-s.conns:
+		t.Cleanup(func() { c.Close() })
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatal("no connection accepted")
+		return nil
+	}
+}
+
+// get 从cp获取一个连接，失败时结束测试
+func get(t *testing.T, cp *ConnPool) *Conn {
+	t.Helper()
+	c, err := cp.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// waitDead 等待Alive发现c已经不可用
+func waitDead(t *testing.T, c net.Conn) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); Alive(c); {
+		if time.Now().After(deadline) {
+			t.Fatal("connection still alive")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConnPool(t *testing.T) {
+	tests := []struct {
+		name string
+		// run 获取并放回连接，可以在其中操作服务端的连接
+		run       func(t *testing.T, cp *ConnPool, s *server)
+		wantDials int64
+	}{
+		{"reuses live connection", func(t *testing.T, cp *ConnPool, s *server) {
+			c := get(t, cp)
+			raw := c.Conn
+			c.Close()
+			if c = get(t, cp); c.Conn != raw {
+				t.Error("got a new connection, want the idle one")
+			}
+			c.Close()
+		}, 1},
+		{"replaces connection closed by peer", func(t *testing.T, cp *ConnPool, s *server) {
+			c := get(t, cp)
+			raw := c.Conn
+			c.Close()
+			s.next(t).Close()
+			waitDead(t, raw)
+			if c = get(t, cp); c.Conn == raw {
+				t.Error("got the connection closed by the peer")
+			}
+			c.Close()
+		}, 2},
+		{"replaces connection with unread data", func(t *testing.T, cp *ConnPool, s *server) {
+			c := get(t, cp)
+			raw := c.Conn
+			c.Close()
+			if _, err := s.next(t).Write([]byte("stale")); err != nil {
+				t.Fatal(err)
+			}
+			waitDead(t, raw)
+			if c = get(t, cp); c.Conn == raw {
+				t.Error("got the connection with a stale reply")
+			}
+			c.Close()
+		}, 2},
+		{"destroyed connection not reused", func(t *testing.T, cp *ConnPool, s *server) {
+			c := get(t, cp)
+			raw := c.Conn
+			if err := c.Destroy(); err != nil {
+				t.Fatal(err)
+			}
+			if c = get(t, cp); c.Conn == raw {
+				t.Error("got the destroyed connection")
+			}
+			c.Close()
+		}, 2},
+		{"deadline cleared for next user", func(t *testing.T, cp *ConnPool, s *server) {
+			c := get(t, cp)
+			c.SetDeadline(time.Now().Add(-time.Second))
+			c.Close()
+			c = get(t, cp)
+			defer c.Close()
+			if _, err := c.Write([]byte("x")); err != nil {
+				t.Fatalf("Write = %v, want the previous deadline cleared", err)
+			}
+		}, 1},
+		{"double close", func(t *testing.T, cp *ConnPool, s *server) {
+			c := get(t, cp)
+			c.Close()
+			if err := c.Close(); !errors.Is(err, pool.ErrResourceReleased) {
+				t.Errorf("second Close = %v, want ErrResourceReleased", err)
+			}
+		}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t)
+			d := &countingDialer{}
+			cp, err := NewConnPool(d, s.ln.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cp.Close()
+			tt.run(t, cp, s)
+			if got := d.dials.Load(); got != tt.wantDials {
+				t.Errorf("dialed %d connections, want %d", got, tt.wantDials)
+			}
+		})
+	}
+}
+
+func TestOpen(t *testing.T) {
+	s := newServer(t)
+	tests := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{"tcp with options", "tcp://" + s.ln.Addr().String() + "?max_total=2", false},
+		{"unsupported scheme", "udp://" + s.ln.Addr().String(), true},
+		{"missing host", "tcp://", true},
+		{"bad option", "tcp://" + s.ln.Addr().String() + "?max_total=lots", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cp, err := Open(tt.url)
+			if tt.wantErr {
+				if err == nil {
+					cp.Close()
+					t.Fatalf("Open(%q) succeeded, want an error", tt.url)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cp.Close()
+			c := get(t, cp)
+			c.Close()
+			if s := cp.Stats(); s.Idle != 1 {
+				t.Errorf("Idle = %d, want 1", s.Idle)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package netpool
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+
+	"github.com/lazysheep666/pool"
+)
+
+// Open 用连接串创建连接池，连接串的格式是scheme://host:port?参数，scheme是tcp或tls，
+// 参数用pool.ParseQuery解析，例如tcp://127.0.0.1:6379?max_total=50&idle_timeout=30s
+// tls连接用host验证服务端证书，opts在连接串中的参数之后生效
+func Open(rawURL string, opts ...pool.Option) (*ConnPool, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("netpool: %w", err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("netpool: missing host in %q", rawURL)
+	}
+	var dialer Dialer
+	switch u.Scheme {
+	case "tcp":
+		dialer = &net.Dialer{}
+	case "tls":
+		dialer = &tls.Dialer{Config: &tls.Config{ServerName: u.Hostname()}}
+	default:
+		return nil, fmt.Errorf("netpool: unsupported scheme %q", u.Scheme)
+	}
+	opt, err := pool.ParseQuery(u.Query())
+	if err != nil {
+		return nil, err
+	}
+	return NewConnPool(dialer, u.Host, append([]pool.Option{opt}, opts...)...)
+}
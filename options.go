@@ -0,0 +1,896 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// DefaultMaxIdle 是未设置MaxIdle时池中最多保留的空闲资源数
+const DefaultMaxIdle = 2
+
+// DefaultReapInterval 是只设置了MinIdle时后台补充空闲资源的间隔
+const DefaultReapInterval = time.Minute
+
+// DefaultQuarantineBackoff 是设置了QuarantineRetries而没有设置QuarantineBackoff时第一次重试前的等待时间
+const DefaultQuarantineBackoff = time.Second
+
+// DefaultLingerTimeout 是没有设置LingerTimeout时ReleaseDeferred等待决定的最长时间
+const DefaultLingerTimeout = 30 * time.Second
+
+// maxRetryBackoff 是factory重试前最长的等待时间
+const maxRetryBackoff = 30 * time.Second
+
+// ErrInvalidConfig 表示创建池时传入了不合法的配置
+var ErrInvalidConfig = errors.New("Invalid pool config")
+
+// OverflowPolicy 决定空闲资源已满时Release如何处理放回的资源
+type OverflowPolicy int
+
+const (
+	// DiscardOverflow 关闭放回的资源，这是默认的行为
+	DiscardOverflow OverflowPolicy = iota
+	// BlockOnOverflow 阻塞Release直到池中有空闲位置或池被关闭
+	BlockOnOverflow
+	// PanicOnOverflow 关闭放回的资源后panic，用于调试
+	PanicOnOverflow
+)
+
+// ReuseStrategy 决定Acquire优先使用哪个空闲资源
+type ReuseStrategy int
+
+const (
+	// FIFO 优先使用最早放回的空闲资源，让所有空闲资源轮流被使用，这是默认的行为
+	FIFO ReuseStrategy = iota
+	// LIFO 优先使用最近放回的空闲资源，很少用到的资源可以因为IdleTimeout被回收
+	LIFO
+)
+
+// Config 是资源池的配置，零值表示使用默认值
+type Config struct {
+	// MaxIdle 池中最多保留的空闲资源数，0表示使用DefaultMaxIdle
+	MaxIdle uint `json:"max_idle,omitempty" yaml:"max_idle,omitempty"`
+	// MinIdle 至少保持的空闲资源数，回收时保留，并由后台goroutine定期补足
+	MinIdle uint `json:"min_idle,omitempty" yaml:"min_idle,omitempty"`
+	// MaxTotal 资源总数(空闲+使用中)的上限，0表示不限制
+	MaxTotal uint `json:"max_total,omitempty" yaml:"max_total,omitempty"`
+	// AcquireTimeout 每次Acquire最长的等待时间，0表示不限制
+	AcquireTimeout time.Duration `json:"acquire_timeout,omitempty" yaml:"acquire_timeout,omitempty"`
+	// MaxWaiters 排队等待资源的Acquire数的上限，超过时Acquire立即返回ErrQueueFull，0表示不限制
+	MaxWaiters uint `json:"max_waiters,omitempty" yaml:"max_waiters,omitempty"`
+	// NonBlocking 为true时，资源达到上限后Acquire立即返回ErrPoolExhausted
+	NonBlocking bool `json:"non_blocking,omitempty" yaml:"non_blocking,omitempty"`
+	// IdleTimeout 空闲资源的最长空闲时间，超过后由后台goroutine关闭，0表示不回收
+	IdleTimeout time.Duration `json:"idle_timeout,omitempty" yaml:"idle_timeout,omitempty"`
+	// MaxLifetime 资源从创建起的最长使用时间，超过后放回或回收时被关闭，0表示不限制
+	MaxLifetime time.Duration `json:"max_lifetime,omitempty" yaml:"max_lifetime,omitempty"`
+	// LifetimeJitter 在[0, 1)之间，不为0时每个资源的IdleTimeout和MaxLifetime随机缩短至多这个比例，
+	// 让同时创建的资源分散地过期和重建，0表示不加抖动
+	LifetimeJitter float64 `json:"lifetime_jitter,omitempty" yaml:"lifetime_jitter,omitempty"`
+	// MaxUses 每个资源最多被获取的次数，达到后放回时被关闭，0表示不限制
+	MaxUses uint `json:"max_uses,omitempty" yaml:"max_uses,omitempty"`
+	// ReapInterval 后台回收和补充空闲资源的间隔，0表示使用IdleTimeout和MaxLifetime中较小的一个，
+	// 两者都未设置时使用DefaultReapInterval
+	ReapInterval time.Duration `json:"reap_interval,omitempty" yaml:"reap_interval,omitempty"`
+	// CloseTimeout 每次调用closer的最长时间，超时后不再等待它返回，0表示一直等待
+	CloseTimeout time.Duration `json:"close_timeout,omitempty" yaml:"close_timeout,omitempty"`
+	// CloseConcurrency 一次关闭多个资源时(Close、收缩空闲资源等)同时调用closer的上限，0和1表示依次关闭
+	CloseConcurrency uint `json:"close_concurrency,omitempty" yaml:"close_concurrency,omitempty"`
+	// ReplaceDiscarded 为true时，Discard后在后台创建新资源把空闲资源补足到MinIdle
+	ReplaceDiscarded bool `json:"replace_discarded,omitempty" yaml:"replace_discarded,omitempty"`
+	// EagerReplenish 为true时，任何资源被销毁(回收、Discard、验证失败等)后都在后台创建新资源，
+	// 把空闲资源补足到MinIdle，而不是等到下一次回收
+	EagerReplenish bool `json:"eager_replenish,omitempty" yaml:"eager_replenish,omitempty"`
+	// KeepaliveInterval 对空闲资源执行WithKeepalive设置的ping的间隔，0表示不执行
+	KeepaliveInterval time.Duration `json:"keepalive_interval,omitempty" yaml:"keepalive_interval,omitempty"`
+	// SlowAcquireThreshold Acquire花费的时间超过这个值时调用WithSlowAcquireThreshold设置的函数，
+	// 0表示不检查
+	SlowAcquireThreshold time.Duration `json:"slow_acquire_threshold,omitempty" yaml:"slow_acquire_threshold,omitempty"`
+	// StarvationThreshold 有Acquire排队等待超过这个时间时调用WithStarvationAlert设置的函数，0表示不检查
+	StarvationThreshold time.Duration `json:"starvation_threshold,omitempty" yaml:"starvation_threshold,omitempty"`
+	// LeakTimeout 资源被持有超过这个时间时报告泄漏，并附上获取资源时的调用栈，0表示不检测
+	// 开启后每次Acquire都会记录调用栈，只建议在调试时使用
+	LeakTimeout time.Duration `json:"leak_timeout,omitempty" yaml:"leak_timeout,omitempty"`
+	// ReclaimAbandoned 为true时，AcquireResource返回的PooledResource没有Close或Destroy就被垃圾回收时，
+	// 关闭其中的资源、释放它占用的容量并报告泄漏，只对AcquireResource获取的资源有效
+	ReclaimAbandoned bool `json:"reclaim_abandoned,omitempty" yaml:"reclaim_abandoned,omitempty"`
+	// MaxCheckoutDuration 资源被持有超过这个时间时，在放回或销毁时报告，0表示不检查
+	MaxCheckoutDuration time.Duration `json:"max_checkout_duration,omitempty" yaml:"max_checkout_duration,omitempty"`
+	// DiscardOverdue 为true时，持有时间超过MaxCheckoutDuration的资源放回时被销毁而不是放回池里
+	DiscardOverdue bool `json:"discard_overdue,omitempty" yaml:"discard_overdue,omitempty"`
+	// OverflowPolicy 空闲资源已满时Release的行为
+	OverflowPolicy OverflowPolicy `json:"overflow_policy,omitempty" yaml:"overflow_policy,omitempty"`
+	// ReuseStrategy Acquire优先使用哪个空闲资源
+	ReuseStrategy ReuseStrategy `json:"reuse_strategy,omitempty" yaml:"reuse_strategy,omitempty"`
+	// BreakerThreshold factory连续失败这么多次后熔断，BreakerCooldown时间内
+	// 需要创建资源的Acquire直接返回ErrFactoryUnavailable，0表示不熔断
+	BreakerThreshold uint `json:"breaker_threshold,omitempty" yaml:"breaker_threshold,omitempty"`
+	// BreakerCooldown 熔断持续的时间，结束后允许一次试探，成功时恢复
+	BreakerCooldown time.Duration `json:"breaker_cooldown,omitempty" yaml:"breaker_cooldown,omitempty"`
+	// OverflowSize 资源总数达到MaxTotal后还可以临时创建的溢出资源数，只在设置了MaxTotal时有效
+	// 溢出资源不受MaxIdle限制，放回后空闲超过OverflowTTL时被关闭
+	OverflowSize uint `json:"overflow_size,omitempty" yaml:"overflow_size,omitempty"`
+	// OverflowTTL 溢出资源放回后最长的空闲时间，0表示溢出资源放回时直接关闭
+	OverflowTTL time.Duration `json:"overflow_ttl,omitempty" yaml:"overflow_ttl,omitempty"`
+	// HighPriorityReserve 只留给PriorityHigh的容量，使用中的资源数加上这个值达到MaxTotal后，
+	// 其它优先级的Acquire需要等待，只在设置了MaxTotal时有效
+	HighPriorityReserve uint `json:"high_priority_reserve,omitempty" yaml:"high_priority_reserve,omitempty"`
+	// AutoscaleMin 和AutoscaleMax 是自动调整MaxTotal的范围，AutoscaleMax为0表示不自动调整
+	// 开启后MaxTotal从AutoscaleMin开始，有Acquire等待或使用率很高时增大，
+	// 使用率持续较低时减小
+	AutoscaleMin uint `json:"autoscale_min,omitempty" yaml:"autoscale_min,omitempty"`
+	AutoscaleMax uint `json:"autoscale_max,omitempty" yaml:"autoscale_max,omitempty"`
+	// AutoscaleInterval 自动调整MaxTotal的间隔，0表示使用DefaultAutoscaleInterval
+	AutoscaleInterval time.Duration `json:"autoscale_interval,omitempty" yaml:"autoscale_interval,omitempty"`
+	// FactoryAttempts factory失败时最多调用的次数，0和1表示不重试
+	FactoryAttempts uint `json:"factory_attempts,omitempty" yaml:"factory_attempts,omitempty"`
+	// FactoryBackoff 第一次重试前等待的时间，之后每次翻倍，实际等待时间带有随机抖动
+	FactoryBackoff time.Duration `json:"factory_backoff,omitempty" yaml:"factory_backoff,omitempty"`
+	// MaxConcurrentCreates 同时进行的factory调用的上限，0表示不限制
+	MaxConcurrentCreates uint `json:"max_concurrent_creates,omitempty" yaml:"max_concurrent_creates,omitempty"`
+	// WarmupConcurrency Warmup和Fill同时创建资源的上限，0表示不限制，同时还受MaxConcurrentCreates限制
+	WarmupConcurrency uint `json:"warmup_concurrency,omitempty" yaml:"warmup_concurrency,omitempty"`
+	// CreateRate 每秒最多调用factory的次数，0表示不限制
+	// 达到限制时阻塞模式下等待，非阻塞模式下返回ErrCreateRateLimited
+	CreateRate rate.Limit `json:"create_rate,omitempty" yaml:"create_rate,omitempty"`
+	// CreateBurst 可以连续调用factory的次数，CreateRate不为0时默认为1
+	CreateBurst int `json:"create_burst,omitempty" yaml:"create_burst,omitempty"`
+	// SingleflightCreates 为true时同一时刻只进行一次创建，其它需要创建资源的Acquire等待它结束，
+	// 创建失败时它们直接返回同一个错误，成功时资源只属于发起创建的Acquire，其它Acquire再依次创建
+	SingleflightCreates bool `json:"singleflight_creates,omitempty" yaml:"singleflight_creates,omitempty"`
+	// FailbackInterval 切换到WithFactories设置的备用factory后，重新尝试更靠前的factory的间隔，
+	// 0表示使用DefaultFailbackInterval
+	FailbackInterval time.Duration `json:"failback_interval,omitempty" yaml:"failback_interval,omitempty"`
+	// ValidateIfIdleLongerThan 不为0时，Acquire只用验证函数检查空闲时间超过这个值的资源
+	ValidateIfIdleLongerThan time.Duration `json:"validate_if_idle_longer_than,omitempty" yaml:"validate_if_idle_longer_than,omitempty"`
+	// ValidationBudget 每次Acquire检查空闲资源(验证函数等)的总时间上限，用完后直接创建新资源，
+	// 资源总数已达上限时仍继续检查空闲资源，0表示不限制
+	ValidationBudget time.Duration `json:"validation_budget,omitempty" yaml:"validation_budget,omitempty"`
+	// QuarantineRetries 不为0时，没有通过验证函数的资源先被隔离，而不是立即关闭，
+	// 之后按QuarantineBackoff重新验证至多这么多次，通过时放回池里，都失败时才关闭
+	QuarantineRetries uint `json:"quarantine_retries,omitempty" yaml:"quarantine_retries,omitempty"`
+	// QuarantineBackoff 隔离后第一次重新验证前等待的时间，之后每次翻倍，0表示使用DefaultQuarantineBackoff
+	QuarantineBackoff time.Duration `json:"quarantine_backoff,omitempty" yaml:"quarantine_backoff,omitempty"`
+	// BindRetries 是一次获取中WithBinder设置的函数失败后最多重新获取的次数，0表示使用DefaultBindRetries
+	BindRetries uint `json:"bind_retries,omitempty" yaml:"bind_retries,omitempty"`
+	// CompactIdle 为true时，后台回收时按最近的需求收缩空闲资源：记录每个回收间隔内同时使用的资源数的峰值，
+	// 空闲资源超过峰值的移动平均减去使用中的资源数(至少保留MinIdle)时，每次关闭超出部分的一半，最早放回的先关闭
+	CompactIdle bool `json:"compact_idle,omitempty" yaml:"compact_idle,omitempty"`
+	// Strict 为true时开启严格模式，在开发和测试中尽早发现错误的用法：放回零值、重复放回、放回不属于池的资源、
+	// 池关闭之后再Acquire都会立即panic，值是带有调用栈的*MisuseError，持有时间超过LeakTimeout的资源也会在后台panic
+	Strict bool `json:"strict,omitempty" yaml:"strict,omitempty"`
+	// LingerTimeout ReleaseDeferred等待决定函数的最长时间，超时后资源被销毁，0表示使用DefaultLingerTimeout
+	LingerTimeout time.Duration `json:"linger_timeout,omitempty" yaml:"linger_timeout,omitempty"`
+	// ValidateOnRelease 为true时，Release也会用验证函数检查资源
+	ValidateOnRelease bool `json:"validate_on_release,omitempty" yaml:"validate_on_release,omitempty"`
+	// MaxTotalWeight 资源总权重的上限，已创建资源的总权重达到这个值后不再创建新资源，0表示不限制
+	// 资源的权重由WithWeightFunc或资源实现的Weighted决定，默认为1；创建前无法知道新资源的权重，
+	// 所以最后创建的资源可能让总权重超过这个值
+	MaxTotalWeight uint `json:"max_total_weight,omitempty" yaml:"max_total_weight,omitempty"`
+	// IdleStore 保存空闲资源的数据结构，默认为SliceStore，见IdleStore
+	IdleStore IdleStore `json:"idle_store,omitempty" yaml:"idle_store,omitempty"`
+	// MaxIdleBytes 空闲资源总大小的上限，超过时先关闭最大的空闲资源，用于大小不同的缓冲区等资源，0表示不限制
+	// 资源的大小由WithSizeFunc或资源实现的Sized决定，在资源放回空闲资源时测量；空闲资源不超过MinIdle个时不再关闭
+	MaxIdleBytes uint64 `json:"max_idle_bytes,omitempty" yaml:"max_idle_bytes,omitempty"`
+	// MaxSharers 大于1时开启共享模式，一个资源最多同时借给这么多个Acquire，
+	// 所有借用者都放回后资源才回到空闲资源中，0和1表示不共享
+	MaxSharers uint `json:"max_sharers,omitempty" yaml:"max_sharers,omitempty"`
+	// RuntimeTrace 为true时，在runtime/trace的执行跟踪中为每次Acquire记录一个任务，
+	// 并把排队等待和factory调用记录为区域，用go tool trace查看池造成的等待
+	RuntimeTrace bool `json:"runtime_trace,omitempty" yaml:"runtime_trace,omitempty"`
+	// CheckoutProfile 为true时，在pprof.Profile中记录每个借出的资源和获取它的调用栈，见Pool.Profile
+	CheckoutProfile bool `json:"checkout_profile,omitempty" yaml:"checkout_profile,omitempty"`
+	// Name 池的名字，附加在日志、事件、错误和指标上，用于区分多个池
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Logger 池内部使用的日志，nil表示不输出日志
+	Logger Logger `json:"-" yaml:"-"`
+}
+
+// Validate 检查配置是否合法
+func (c Config) Validate() error {
+	if c.MaxTotal > 0 && c.MaxIdle > c.MaxTotal {
+		return fmt.Errorf("%w: MaxIdle %d exceeds MaxTotal %d", ErrInvalidConfig, c.MaxIdle, c.MaxTotal)
+	}
+	if c.MinIdle > c.MaxIdle {
+		return fmt.Errorf("%w: MinIdle %d exceeds MaxIdle %d", ErrInvalidConfig, c.MinIdle, c.MaxIdle)
+	}
+	if c.OverflowPolicy < DiscardOverflow || c.OverflowPolicy > PanicOnOverflow {
+		return fmt.Errorf("%w: unknown OverflowPolicy %d", ErrInvalidConfig, c.OverflowPolicy)
+	}
+	if c.ReuseStrategy < FIFO || c.ReuseStrategy > LIFO {
+		return fmt.Errorf("%w: unknown ReuseStrategy %d", ErrInvalidConfig, c.ReuseStrategy)
+	}
+	if c.IdleStore < SliceStore || c.IdleStore > LockFreeStore {
+		return fmt.Errorf("%w: unknown IdleStore %d", ErrInvalidConfig, c.IdleStore)
+	}
+	if c.AcquireTimeout < 0 {
+		return fmt.Errorf("%w: negative AcquireTimeout %v", ErrInvalidConfig, c.AcquireTimeout)
+	}
+	if c.IdleTimeout < 0 {
+		return fmt.Errorf("%w: negative IdleTimeout %v", ErrInvalidConfig, c.IdleTimeout)
+	}
+	if c.MaxLifetime < 0 {
+		return fmt.Errorf("%w: negative MaxLifetime %v", ErrInvalidConfig, c.MaxLifetime)
+	}
+	if c.LifetimeJitter < 0 || c.LifetimeJitter >= 1 {
+		return fmt.Errorf("%w: LifetimeJitter %v not in [0, 1)", ErrInvalidConfig, c.LifetimeJitter)
+	}
+	if c.FailbackInterval < 0 {
+		return fmt.Errorf("%w: negative FailbackInterval %v", ErrInvalidConfig, c.FailbackInterval)
+	}
+	if c.SlowAcquireThreshold < 0 {
+		return fmt.Errorf("%w: negative SlowAcquireThreshold %v", ErrInvalidConfig, c.SlowAcquireThreshold)
+	}
+	if c.StarvationThreshold < 0 {
+		return fmt.Errorf("%w: negative StarvationThreshold %v", ErrInvalidConfig, c.StarvationThreshold)
+	}
+	if c.KeepaliveInterval < 0 {
+		return fmt.Errorf("%w: negative KeepaliveInterval %v", ErrInvalidConfig, c.KeepaliveInterval)
+	}
+	if c.LeakTimeout < 0 {
+		return fmt.Errorf("%w: negative LeakTimeout %v", ErrInvalidConfig, c.LeakTimeout)
+	}
+	if c.ValidateIfIdleLongerThan < 0 {
+		return fmt.Errorf("%w: negative ValidateIfIdleLongerThan %v", ErrInvalidConfig, c.ValidateIfIdleLongerThan)
+	}
+	if c.ValidationBudget < 0 {
+		return fmt.Errorf("%w: negative ValidationBudget %v", ErrInvalidConfig, c.ValidationBudget)
+	}
+	if c.MaxCheckoutDuration < 0 {
+		return fmt.Errorf("%w: negative MaxCheckoutDuration %v", ErrInvalidConfig, c.MaxCheckoutDuration)
+	}
+	if c.BreakerThreshold > 0 && c.BreakerCooldown <= 0 {
+		return fmt.Errorf("%w: BreakerCooldown must be positive, got %v", ErrInvalidConfig, c.BreakerCooldown)
+	}
+	if c.FactoryBackoff < 0 {
+		return fmt.Errorf("%w: negative FactoryBackoff %v", ErrInvalidConfig, c.FactoryBackoff)
+	}
+	if c.CreateRate < 0 || c.CreateBurst < 0 {
+		return fmt.Errorf("%w: invalid create rate limit %v, burst %d", ErrInvalidConfig, c.CreateRate, c.CreateBurst)
+	}
+	if c.OverflowSize > 0 && c.MaxTotal == 0 {
+		return fmt.Errorf("%w: OverflowSize requires MaxTotal", ErrInvalidConfig)
+	}
+	if c.OverflowTTL < 0 {
+		return fmt.Errorf("%w: negative OverflowTTL %v", ErrInvalidConfig, c.OverflowTTL)
+	}
+	if c.MaxTotal > 0 && c.HighPriorityReserve >= c.MaxTotal {
+		return fmt.Errorf("%w: HighPriorityReserve %d must be less than MaxTotal %d", ErrInvalidConfig, c.HighPriorityReserve, c.MaxTotal)
+	}
+	if c.AutoscaleMax > 0 {
+		if c.AutoscaleMin == 0 || c.AutoscaleMin > c.AutoscaleMax {
+			return fmt.Errorf("%w: invalid autoscale range [%d, %d]", ErrInvalidConfig, c.AutoscaleMin, c.AutoscaleMax)
+		}
+		if c.MaxTotal < c.AutoscaleMin || c.MaxTotal > c.AutoscaleMax {
+			return fmt.Errorf("%w: MaxTotal %d outside autoscale range [%d, %d]", ErrInvalidConfig, c.MaxTotal, c.AutoscaleMin, c.AutoscaleMax)
+		}
+	}
+	if c.AutoscaleInterval < 0 {
+		return fmt.Errorf("%w: negative AutoscaleInterval %v", ErrInvalidConfig, c.AutoscaleInterval)
+	}
+	if c.CloseTimeout < 0 {
+		return fmt.Errorf("%w: negative CloseTimeout %v", ErrInvalidConfig, c.CloseTimeout)
+	}
+	if c.ReapInterval < 0 {
+		return fmt.Errorf("%w: negative ReapInterval %v", ErrInvalidConfig, c.ReapInterval)
+	}
+	if c.QuarantineBackoff < 0 {
+		return fmt.Errorf("%w: negative QuarantineBackoff %v", ErrInvalidConfig, c.QuarantineBackoff)
+	}
+	if c.LingerTimeout < 0 {
+		return fmt.Errorf("%w: negative LingerTimeout %v", ErrInvalidConfig, c.LingerTimeout)
+	}
+	return nil
+}
+
+// withDefaults 返回填充了默认值的配置
+func (c Config) withDefaults() Config {
+	if c.AutoscaleMax > 0 {
+		if c.MaxTotal == 0 {
+			c.MaxTotal = c.AutoscaleMin
+		}
+		if c.AutoscaleInterval == 0 {
+			c.AutoscaleInterval = DefaultAutoscaleInterval
+		}
+	}
+	if c.CreateRate > 0 && c.CreateBurst == 0 {
+		c.CreateBurst = 1
+	}
+	if c.QuarantineRetries > 0 && c.QuarantineBackoff == 0 {
+		c.QuarantineBackoff = DefaultQuarantineBackoff
+	}
+	if c.BindRetries == 0 {
+		c.BindRetries = DefaultBindRetries
+	}
+	if c.FailbackInterval == 0 {
+		c.FailbackInterval = DefaultFailbackInterval
+	}
+	if c.LingerTimeout == 0 {
+		c.LingerTimeout = DefaultLingerTimeout
+	}
+	if c.MaxIdle == 0 {
+		c.MaxIdle = DefaultMaxIdle
+		if c.MinIdle > c.MaxIdle {
+			c.MaxIdle = c.MinIdle
+		}
+		if c.MaxTotal > 0 && c.MaxIdle > c.MaxTotal {
+			c.MaxIdle = c.MaxTotal
+		}
+	}
+	if c.ReapInterval == 0 {
+		c.ReapInterval = c.IdleTimeout
+		if c.MaxLifetime > 0 && (c.ReapInterval == 0 || c.MaxLifetime < c.ReapInterval) {
+			c.ReapInterval = c.MaxLifetime
+		}
+		if c.OverflowSize > 0 && c.OverflowTTL > 0 && (c.ReapInterval == 0 || c.OverflowTTL < c.ReapInterval) {
+			c.ReapInterval = c.OverflowTTL
+		}
+		if c.ReapInterval == 0 {
+			c.ReapInterval = DefaultReapInterval
+		}
+	}
+	if c.Logger == nil {
+		c.Logger = nopLogger{}
+	}
+	return c
+}
+
+// Option 用于配置New创建的资源池
+type Option func(*settings)
+
+// settings 保存所有Option设置的值
+// 与资源类型相关的函数以any保存，在创建池时检查类型
+type settings struct {
+	Config
+	closer    any
+	validator any
+	onLeak    func(Leak)
+	onEvent   func(Event)
+	onError   func(error, ErrorContext)
+	onSlow    func(time.Duration, int)
+	onOverdue func(CheckoutViolation)
+	onStarved func(StarvedWaiter)
+
+	testOverrides bool
+	labels        map[string]string
+	selection     SelectionPolicy
+	schedule      Schedule
+	chaos         *ChaosConfig
+	schedInt      time.Duration
+	statsSink     StatsSink
+	statsInt      time.Duration
+
+	stateStore StateStore
+	onCreate   any
+	onAcquire  any
+	onRelease  any
+	onClose    any
+	onOutcome  any
+	ping       any
+	reset      any
+	sanitize   any
+	binder     any
+	score      any
+	weight     any
+	size       any
+	isClosed   any
+	factories  any
+	balancer   any
+	tracer     Tracer
+	clock      Clock
+	createSem  chan struct{} // ShardedPool的所有分片共用的factory调用限制
+	createLim  *rate.Limiter // ShardedPool的所有分片共用的factory调用速率限制
+}
+
+// WithMaxIdle 设置池中最多保留的空闲资源数
+func WithMaxIdle(n uint) Option {
+	return func(s *settings) { s.MaxIdle = n }
+}
+
+// WithMinIdle 设置至少保持的空闲资源数，回收时保留，并由后台goroutine定期补足
+// 使用Warmup可以在启动时预先创建这些资源
+func WithMinIdle(n uint) Option {
+	return func(s *settings) { s.MinIdle = n }
+}
+
+// WithMaxTotal 设置资源总数(空闲+使用中)的上限，0表示不限制
+func WithMaxTotal(n uint) Option {
+	return func(s *settings) { s.MaxTotal = n }
+}
+
+// WithMaxWaiters 设置排队等待资源的Acquire数的上限，超过时Acquire立即返回ErrQueueFull，
+// 用于在池饱和时快速失败，把压力反馈给上游
+func WithMaxWaiters(n uint) Option {
+	return func(s *settings) { s.MaxWaiters = n }
+}
+
+// WithAcquireTimeout 设置每次Acquire最长的等待时间，作为调用者传入的ctx之外的上限，
+// 超时时返回的错误同时满足errors.Is(err, ErrAcquireTimeout)和errors.Is(err, context.DeadlineExceeded)
+func WithAcquireTimeout(d time.Duration) Option {
+	return func(s *settings) { s.AcquireTimeout = d }
+}
+
+// WithIdleTimeout 设置空闲资源的最长空闲时间，超过后由后台goroutine关闭
+func WithIdleTimeout(d time.Duration) Option {
+	return func(s *settings) { s.IdleTimeout = d }
+}
+
+// WithMaxLifetime 设置资源从创建起的最长使用时间，超过后放回或回收时被关闭
+func WithMaxLifetime(d time.Duration) Option {
+	return func(s *settings) { s.MaxLifetime = d }
+}
+
+// WithLifetimeJitter 设置每个资源的IdleTimeout和MaxLifetime随机缩短的最大比例，见LifetimeJitter
+func WithLifetimeJitter(fraction float64) Option {
+	return func(s *settings) { s.LifetimeJitter = fraction }
+}
+
+// WithMaxUses 设置每个资源最多被获取的次数，达到后放回时被关闭，
+// 之后的Acquire会创建新资源代替它
+func WithMaxUses(n uint) Option {
+	return func(s *settings) { s.MaxUses = n }
+}
+
+// WithSharing 开启共享模式，一个资源最多同时借给n个Acquire，用于可以并发使用的无状态资源
+// Acquire优先使用借用者最少、还没有达到n的使用中资源，其次才使用空闲资源或创建新资源
+// 每个借用者都需要Release或Discard一次，最后一个借用者放回时资源才回到空闲资源中，
+// 共享的资源被Discard后不再借出，在最后一个借用者放回时销毁
+func WithSharing(n uint) Option {
+	return func(s *settings) { s.MaxSharers = n }
+}
+
+// WithReapInterval 设置后台回收和补充空闲资源的间隔，默认取IdleTimeout和MaxLifetime中较小的一个
+func WithReapInterval(d time.Duration) Option {
+	return func(s *settings) { s.ReapInterval = d }
+}
+
+// WithCloseTimeout 限制每次调用closer的时间，超时后放弃等待并返回ErrCloseTimeout，
+// closer仍在后台goroutine中运行，用于防止卡住的Close阻塞池的关闭
+func WithCloseTimeout(d time.Duration) Option {
+	return func(s *settings) { s.CloseTimeout = d }
+}
+
+// WithBlocking 设置资源达到上限时Acquire是否阻塞等待，默认阻塞
+func WithBlocking(blocking bool) Option {
+	return func(s *settings) { s.NonBlocking = !blocking }
+}
+
+// WithEagerReplenish 设置资源被销毁后是否立即在后台创建新资源把空闲资源补足到MinIdle
+func WithEagerReplenish(enabled bool) Option {
+	return func(s *settings) { s.EagerReplenish = enabled }
+}
+
+// WithReplaceDiscarded 设置Discard后是否在后台创建新资源把空闲资源补足到MinIdle
+func WithReplaceDiscarded(replace bool) Option {
+	return func(s *settings) { s.ReplaceDiscarded = replace }
+}
+
+// WithOverflowPolicy 设置空闲资源已满时Release的行为，默认DiscardOverflow
+func WithOverflowPolicy(policy OverflowPolicy) Option {
+	return func(s *settings) { s.OverflowPolicy = policy }
+}
+
+// WithReuseStrategy 设置Acquire优先使用哪个空闲资源，默认FIFO
+func WithReuseStrategy(strategy ReuseStrategy) Option {
+	return func(s *settings) { s.ReuseStrategy = strategy }
+}
+
+// WithLeakDetection 开启泄漏检测，资源被持有超过timeout时报告泄漏
+// 开启后每次Acquire都会记录调用栈，只建议在调试时使用
+func WithLeakDetection(timeout time.Duration) Option {
+	return func(s *settings) { s.LeakTimeout = timeout }
+}
+
+// WithLeakHandler 设置报告泄漏的函数，默认写入日志
+func WithLeakHandler(fn func(Leak)) Option {
+	return func(s *settings) { s.onLeak = fn }
+}
+
+// WithBreaker 设置factory的熔断器，连续失败threshold次后熔断cooldown时间，
+// 期间需要创建资源的Acquire直接返回ErrFactoryUnavailable，冷却结束后的一次试探成功时恢复
+func WithBreaker(threshold uint, cooldown time.Duration) Option {
+	return func(s *settings) {
+		s.BreakerThreshold = threshold
+		s.BreakerCooldown = cooldown
+	}
+}
+
+// WithOverflow 允许资源总数达到MaxTotal后再临时创建n个溢出资源，用于应对突发流量，
+// 溢出资源放回后空闲超过ttl时被关闭，ttl为0时放回时直接关闭，只在设置了MaxTotal时有效
+func WithOverflow(n uint, ttl time.Duration) Option {
+	return func(s *settings) {
+		s.OverflowSize = n
+		s.OverflowTTL = ttl
+	}
+}
+
+// WithHighPriorityReserve 设置只留给PriorityHigh的容量，只在设置了MaxTotal时有效
+func WithHighPriorityReserve(n uint) Option {
+	return func(s *settings) { s.HighPriorityReserve = n }
+}
+
+// WithAutoscale 根据负载在[min, max]之间自动调整MaxTotal，每隔interval调整一次，
+// interval为0时使用DefaultAutoscaleInterval
+func WithAutoscale(min, max uint, interval time.Duration) Option {
+	return func(s *settings) {
+		s.AutoscaleMin = min
+		s.AutoscaleMax = max
+		s.AutoscaleInterval = interval
+	}
+}
+
+// WithFactoryRetry 设置factory失败时最多调用attempts次，
+// 第一次重试前等待backoff，之后每次翻倍，实际等待时间带有随机抖动
+func WithFactoryRetry(attempts uint, backoff time.Duration) Option {
+	return func(s *settings) {
+		s.FactoryAttempts = attempts
+		s.FactoryBackoff = backoff
+	}
+}
+
+// WithFactories 设置按顺序使用的多个factory，设置后New的fn不再使用，可以为nil
+// primary创建失败，或它创建的资源未通过验证函数或keepalive的ping时，新资源改由下一个factory创建，
+// 之后每隔FailbackInterval重新尝试primary，成功时切换回去
+func WithFactories[T any](primary func(context.Context) (T, error), fallbacks ...func(context.Context) (T, error)) Option {
+	factories := append([]func(context.Context) (T, error){primary}, fallbacks...)
+	return func(s *settings) { s.factories = factories }
+}
+
+// WithBalancer 设置用b在多个后端之间分配新资源的创建，设置后New的fn不再使用，可以为nil
+// b中正在排空的后端创建的资源在放回池里或被Acquire取出时关闭
+func WithBalancer[T any](b *Balancer[T]) Option {
+	return func(s *settings) { s.balancer = b }
+}
+
+// WithFailbackInterval 设置切换到备用factory后重新尝试更靠前的factory的间隔
+func WithFailbackInterval(d time.Duration) Option {
+	return func(s *settings) { s.FailbackInterval = d }
+}
+
+// WithCreateRateLimit 限制每秒最多调用r次factory，最多可以连续调用burst次，避免启动时大量创建资源触发后端的限流
+// 达到限制时阻塞模式下等待，非阻塞模式下返回ErrCreateRateLimited
+func WithCreateRateLimit(r rate.Limit, burst int) Option {
+	return func(s *settings) {
+		s.CreateRate = r
+		s.CreateBurst = burst
+	}
+}
+
+// WithWarmupConcurrency 限制Warmup和Fill同时创建的资源不超过n个，0表示不限制
+func WithWarmupConcurrency(n uint) Option {
+	return func(s *settings) { s.WarmupConcurrency = n }
+}
+
+// WithCloseConcurrency 设置一次关闭多个资源时最多同时关闭n个，让有大量资源的池可以很快关闭，
+// 资源仍按创建时间从早到晚开始关闭，但可能不按这个顺序完成
+func WithCloseConcurrency(n uint) Option {
+	return func(s *settings) { s.CloseConcurrency = n }
+}
+
+// WithMaxConcurrentCreates 限制同时进行的factory调用不超过n个，避免大量Acquire同时创建资源压垮后端
+func WithMaxConcurrentCreates(n uint) Option {
+	return func(s *settings) { s.MaxConcurrentCreates = n }
+}
+
+// WithSingleflightCreates 设置同一时刻只进行一次创建，等待中的Acquire共享失败的结果，
+// 后端不可用时只有一次factory调用返回错误，而不是每个Acquire都调用一次
+func WithSingleflightCreates(enabled bool) Option {
+	return func(s *settings) { s.SingleflightCreates = enabled }
+}
+
+// WithEventSink 设置接收池中事件的函数，函数在产生事件的goroutine中同步调用，应尽快返回
+func WithEventSink(fn func(Event)) Option {
+	return func(s *settings) { s.onEvent = fn }
+}
+
+// WithErrorHandler 设置处理后台错误的函数，包括关闭资源失败、保活ping失败、后台补充资源失败等，
+// 这些错误没有调用者可以返回，默认只写入日志，fn可以用来报警，fn应该尽快返回
+func WithErrorHandler(fn func(err error, ec ErrorContext)) Option {
+	return func(s *settings) { s.onError = fn }
+}
+
+// WithSlowAcquireThreshold 设置慢Acquire的阈值，Acquire花费的时间(包括等待和创建资源)超过d时，
+// 在返回前用花费的时间和仍在等待的Acquire数调用fn
+func WithSlowAcquireThreshold(d time.Duration, fn func(wait time.Duration, waiters int)) Option {
+	return func(s *settings) {
+		s.SlowAcquireThreshold = d
+		s.onSlow = fn
+	}
+}
+
+// WithStarvationAlert 设置饥饿的阈值，有Acquire排队等待超过maxWait时用它的描述调用fn，每次排队只报告一次
+// fn为nil时写入日志，fn在后台goroutine中调用，应该尽快返回
+func WithStarvationAlert(maxWait time.Duration, fn func(StarvedWaiter)) Option {
+	return func(s *settings) {
+		s.StarvationThreshold = maxWait
+		s.onStarved = fn
+	}
+}
+
+// WithTestOverrides 允许用SetTestOverride让池模拟被占满或获取失败，只应该在集成测试中使用
+// 没有设置时SetTestOverride不起作用，Acquire只多一次原子读取
+func WithTestOverrides() Option {
+	return func(s *settings) { s.testOverrides = true }
+}
+
+// WithValidateIfIdleLongerThan 让Acquire只验证空闲时间超过d的资源，刚放回不久的资源直接使用，
+// 减少验证函数带来的延迟
+func WithValidateIfIdleLongerThan(d time.Duration) Option {
+	return func(s *settings) { s.ValidateIfIdleLongerThan = d }
+}
+
+// WithValidationBudget 设置每次Acquire检查空闲资源的总时间上限，
+// 用完后不再检查剩下的空闲资源而是直接创建新资源，资源总数已达上限时仍继续检查
+func WithValidationBudget(d time.Duration) Option {
+	return func(s *settings) { s.ValidationBudget = d }
+}
+
+// WithReclaimAbandoned 为AcquireResource返回的PooledResource设置finalizer，
+// 它们没有Close或Destroy就被垃圾回收时关闭其中的资源、释放容量，并用WithLeakHandler设置的函数报告泄漏
+func WithReclaimAbandoned() Option {
+	return func(s *settings) { s.ReclaimAbandoned = true }
+}
+
+// WithMaxCheckoutDuration 设置资源最长的持有时间，持有超过d的资源在放回或销毁时调用fn报告，
+// fn为nil时写入日志，设置了LeakTimeout时报告中带有获取资源时的调用栈
+func WithMaxCheckoutDuration(d time.Duration, fn func(CheckoutViolation)) Option {
+	return func(s *settings) {
+		s.MaxCheckoutDuration = d
+		s.onOverdue = fn
+	}
+}
+
+// WithDiscardOverdue 让持有时间超过MaxCheckoutDuration的资源放回时被销毁而不是放回池里
+func WithDiscardOverdue() Option {
+	return func(s *settings) { s.DiscardOverdue = true }
+}
+
+// WithChaos 按c中的概率随机注入Acquire延迟、factory失败、验证失败和资源断开，
+// 用于测试使用者在池出现故障时的表现，不应在生产环境使用
+func WithChaos(c ChaosConfig) Option {
+	return func(s *settings) { s.chaos = &c }
+}
+
+// WithSchedule 每隔interval用s计算一次容量，变化时调整MinIdle、MaxIdle和MaxTotal并关闭超出的空闲资源，
+// 例如白天保持较大的池、夜间缩小，interval为0时使用DefaultScheduleInterval，创建池时会立即应用一次
+func WithSchedule(s Schedule, interval time.Duration) Option {
+	return func(st *settings) {
+		st.schedule = s
+		st.schedInt = interval
+	}
+}
+
+// WithSelectionPolicy 设置Acquire选择空闲资源的策略，代替ReuseStrategy，
+// 可以使用NewestFirst、LeastUsed、RoundRobin()或自己的实现
+func WithSelectionPolicy(sp SelectionPolicy) Option {
+	return func(s *settings) { s.selection = sp }
+}
+
+// WithName 设置池的名字，池输出的日志、事件和返回的错误都会带上它
+func WithName(name string) Option {
+	return func(s *settings) { s.Name = name }
+}
+
+// WithLabels 设置池的标签，与WithName一样附加在日志、事件和错误上，poolprom也用它们作为指标的标签
+// labels会被复制
+func WithLabels(labels map[string]string) Option {
+	return func(s *settings) { s.labels = copyTags(labels) }
+}
+
+// WithClock 设置池使用的时钟，默认使用系统时钟，主要用于测试
+func WithClock(c Clock) Option {
+	return func(s *settings) { s.clock = c }
+}
+
+// WithLogger 设置池内部使用的日志，默认不输出日志
+func WithLogger(l Logger) Option {
+	return func(s *settings) { s.Logger = l }
+}
+
+// WithTracer 设置观察获取、创建和放回操作的Tracer
+func WithTracer(t Tracer) Option {
+	return func(s *settings) { s.tracer = t }
+}
+
+// WithCloser 设置销毁资源的函数，未设置时实现了io.Closer的资源用Close关闭，其它资源不做任何处理
+func WithCloser[T any](fn func(T) error) Option {
+	return func(s *settings) { s.closer = fn }
+}
+
+// WithDestroyFunc 与WithCloser相同，用于没有实现io.Closer的资源，例如结构体、缓冲区或cgo句柄
+func WithDestroyFunc[T any](fn func(T) error) Option {
+	return WithCloser(fn)
+}
+
+// WithValidator 设置检查资源是否可用的函数
+// Acquire从池中取出空闲资源时会先检查它，不可用的资源被销毁后重新获取
+func WithValidator[T any](fn func(T) bool) Option {
+	return func(s *settings) { s.validator = fn }
+}
+
+// WithValidatorContext 与WithValidator相同，但验证函数接收调用者的ctx，
+// Acquire时是AcquireContext的ctx，ReleaseContext时是它的ctx，验证函数应该在ctx结束时尽快返回false
+func WithValidatorContext[T any](fn func(ctx context.Context, r T) bool) Option {
+	return func(s *settings) { s.validator = fn }
+}
+
+// WithOnCreate 设置资源创建后执行的钩子，钩子返回错误时资源被关闭，
+// 这次创建视为失败，s是当时的统计信息，但不包括Lifetime、BusyTime、WaitTime和Utilization，
+// 其它钩子收到的s也是这样，避免每次获取和放回都计算分布
+func WithOnCreate[T any](fn func(r T, s Stats) error) Option {
+	return func(s *settings) { s.onCreate = fn }
+}
+
+// WithOnCreateContext 与WithOnCreate相同，但钩子接收传给factory的ctx，
+// 为Acquire创建资源时是AcquireContext的ctx，可以从中读取请求ID等值，后台补充资源时是context.Background()
+func WithOnCreateContext[T any](fn func(ctx context.Context, r T, s Stats) error) Option {
+	return func(s *settings) { s.onCreate = fn }
+}
+
+// WithOnAcquire 设置资源交给调用者之前执行的钩子，钩子返回错误时资源被销毁，
+// Acquire会重新获取一个资源
+func WithOnAcquire[T any](fn func(r T, s Stats) error) Option {
+	return func(s *settings) { s.onAcquire = fn }
+}
+
+// WithOnAcquireContext 与WithOnAcquire相同，但钩子接收AcquireContext的ctx
+func WithOnAcquireContext[T any](fn func(ctx context.Context, r T, s Stats) error) Option {
+	return func(s *settings) { s.onAcquire = fn }
+}
+
+// WithBinder 设置资源交给调用者之前绑定它的函数，例如选择数据库或设置租户，在OnAcquire钩子之后执行
+// 返回错误时资源被销毁并重新获取，一次获取中失败超过BindRetries次时返回ErrBindFailed
+func WithBinder[T any](fn func(ctx context.Context, r T) error) Option {
+	return func(s *settings) { s.binder = fn }
+}
+
+// WithBindRetries 设置一次获取中binder失败后最多重新获取的次数
+func WithBindRetries(n uint) Option {
+	return func(s *settings) { s.BindRetries = n }
+}
+
+// WithOnRelease 设置资源放回池里之前执行的钩子，钩子返回错误时资源被销毁
+func WithOnRelease[T any](fn func(r T, s Stats) error) Option {
+	return func(s *settings) { s.onRelease = fn }
+}
+
+// WithOnOutcome 设置ReleaseWith在放回资源之前执行的钩子，用来按使用结果收尾，例如对Rollback的事务执行ROLLBACK
+// 钩子返回错误时资源被销毁
+func WithOnOutcome[T any](fn func(r T, o Outcome) error) Option {
+	return func(s *settings) { s.onOutcome = fn }
+}
+
+// WithOnClose 设置资源被关闭之前执行的钩子
+func WithOnClose[T any](fn func(r T, s Stats)) Option {
+	return func(s *settings) { s.onClose = fn }
+}
+
+// WithKeepalive 设置后台每隔interval对空闲了至少interval的资源执行一次ping，
+// ping返回错误的资源被关闭，用于避免空闲连接被NAT或防火墙悄悄断开
+func WithKeepalive[T any](interval time.Duration, ping func(T) error) Option {
+	return func(s *settings) {
+		s.KeepaliveInterval = interval
+		s.ping = ping
+	}
+}
+
+// WithResetFunc 设置资源放回池里之前重置它的函数，返回错误时资源被销毁
+// 设置后不再调用资源的Resetter.Reset
+func WithResetFunc[T any](fn func(T) error) Option {
+	return func(s *settings) { s.reset = fn }
+}
+
+// WithResetFuncContext 与WithResetFunc相同，但重置函数接收ReleaseContext的ctx，Release时是context.Background()
+func WithResetFuncContext[T any](fn func(ctx context.Context, r T) error) Option {
+	return func(s *settings) { s.reset = fn }
+}
+
+// WithSanitizer 设置资源放回池里之前清除敏感状态的函数，例如认证令牌和临时表，在重置之后执行
+// 返回错误时资源被销毁，并发出Err为该错误的SanitizeFailed事件
+func WithSanitizer[T any](fn func(r T) error) Option {
+	return func(s *settings) { s.sanitize = fn }
+}
+
+// WithWeightFunc 设置计算资源权重的函数，在资源创建后调用一次，用于容量不同的资源，
+// 设置后不再调用资源的Weighted.Weight，权重计入MaxTotalWeight和Stats.Weight，
+// 并用于AcquireWithMinWeight和IdleResource.Weight
+func WithWeightFunc[T any](fn func(T) uint) Option {
+	return func(s *settings) { s.weight = fn }
+}
+
+// WithScoreFunc 设置计算资源当前分数的函数，分数越高越好，设置后不再调用资源的Scorer.Score，见Scorer
+func WithScoreFunc[T any](fn func(T) float64) Option {
+	return func(s *settings) { s.score = fn }
+}
+
+// WithIdleStore 设置保存空闲资源的数据结构，见IdleStore，用于比较不同实现在具体负载下的开销
+func WithIdleStore(kind IdleStore) Option {
+	return func(s *settings) { s.IdleStore = kind }
+}
+
+// WithSizeFunc 设置计算资源占用的字节数的函数，在资源放回空闲资源时调用，应该尽快返回，
+// 设置后不再调用资源的Sized.Size，大小计入MaxIdleBytes和Stats.IdleBytes
+func WithSizeFunc[T any](fn func(T) int) Option {
+	return func(s *settings) { s.size = fn }
+}
+
+// WithMaxIdleBytes 限制空闲资源的总大小不超过n个字节，见Config.MaxIdleBytes，
+// 因此关闭的资源数计入Stats.SizeClosed
+func WithMaxIdleBytes(n uint64) Option {
+	return func(s *settings) { s.MaxIdleBytes = n }
+}
+
+// WithClosedCheck 设置判断资源是否已经被调用者直接关闭的函数，在Release时调用，
+// 设置后不再调用资源的ClosedChecker.IsClosed，见ClosedChecker
+func WithClosedCheck[T any](fn func(T) bool) Option {
+	return func(s *settings) { s.isClosed = fn }
+}
+
+// WithMaxTotalWeight 设置资源总权重的上限，已创建资源的总权重达到n后不再创建新资源
+func WithMaxTotalWeight(n uint) Option {
+	return func(s *settings) { s.MaxTotalWeight = n }
+}
+
+// WithQuarantine 设置没有通过验证函数的资源先被隔离，等待backoff后重新验证，每次重试的等待时间翻倍，
+// 至多重试retries次，通过时放回池里，都失败时才关闭，用于验证偶尔因为后端短暂的停顿而失败的情况
+// 隔离的资源仍然占用容量，计入Stats.Quarantined
+func WithQuarantine(retries uint, backoff time.Duration) Option {
+	return func(s *settings) { s.QuarantineRetries, s.QuarantineBackoff = retries, backoff }
+}
+
+// WithIdleCompaction 设置是否按最近的需求收缩空闲资源，见Config.CompactIdle，
+// 关闭的资源数计入Stats.CompactionClosed
+func WithIdleCompaction(compact bool) Option {
+	return func(s *settings) { s.CompactIdle = compact }
+}
+
+// WithStrictMode 设置是否开启严格模式，见Config.Strict，生产环境应当关闭，错误的用法只返回错误
+func WithStrictMode(strict bool) Option {
+	return func(s *settings) { s.Strict = strict }
+}
+
+// WithLingerTimeout 设置ReleaseDeferred等待决定函数的最长时间，超时后资源被销毁
+func WithLingerTimeout(d time.Duration) Option {
+	return func(s *settings) { s.LingerTimeout = d }
+}
+
+// WithValidateOnRelease 设置Release时是否也检查资源，不可用的资源直接销毁
+func WithValidateOnRelease(validate bool) Option {
+	return func(s *settings) { s.ValidateOnRelease = validate }
+}
+
+// WithCheckoutProfile 设置是否在pprof.Profile中记录借出的资源和获取它的调用栈，见Pool.Profile，
+// 开启后每次Acquire都要记录调用栈
+func WithCheckoutProfile(enabled bool) Option {
+	return func(s *settings) { s.CheckoutProfile = enabled }
+}
+
+// WithRuntimeTrace 设置是否在runtime/trace的执行跟踪中记录Acquire任务、排队等待和factory调用，
+// 只在正在记录执行跟踪时有开销
+func WithRuntimeTrace(enabled bool) Option {
+	return func(s *settings) { s.RuntimeTrace = enabled }
+}
+
+// ctxFuncOption 与funcOption相同，但v也可以是不接收ctx的形式F，这时用adapt把它转换为接收ctx的形式C
+func ctxFuncOption[C, F any](v any, name string, adapt func(F) C) (C, error) {
+	if fn, ok := v.(F); ok {
+		return adapt(fn), nil
+	}
+	return funcOption[C](v, name)
+}
+
+// funcOption 取出一个与资源类型相关的函数，类型不匹配时返回错误
+func funcOption[F any](v any, name string) (F, error) {
+	var zero F
+	if v == nil {
+		return zero, nil
+	}
+	fn, ok := v.(F)
+	if !ok {
+		return zero, fmt.Errorf("%w: %s has type %T, want %T", ErrInvalidConfig, name, v, zero)
+	}
+	return fn, nil
+}
@@ -0,0 +1,59 @@
+package pool
+
+import "fmt"
+
+// Outcome 是ReleaseWith报告的资源使用结果，用于代表事务等需要收尾的资源
+type Outcome int
+
+const (
+	// OutcomeCommit 使用成功，资源可以直接放回池里
+	OutcomeCommit Outcome = iota
+	// OutcomeRollback 使用失败，资源需要回滚后才能放回池里
+	OutcomeRollback
+	// OutcomeDiscard 资源已经不可用，应该被销毁
+	OutcomeDiscard
+)
+
+// String 返回结果的名字
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeCommit:
+		return "Commit"
+	case OutcomeRollback:
+		return "Rollback"
+	case OutcomeDiscard:
+		return "Discard"
+	}
+	return "Unknown"
+}
+
+// ReleaseWith 按使用结果放回资源：先用outcome调用WithOnOutcome设置的钩子，
+// 钩子成功时Commit和Rollback的资源被放回池里，钩子返回错误或结果为Discard时资源被销毁
+// 没有设置钩子时Commit的资源被放回，Rollback和Discard的资源被销毁
+func (p *Pool[T]) ReleaseWith(r T, outcome Outcome) error {
+	if outcome < OutcomeCommit || outcome > OutcomeDiscard {
+		return fmt.Errorf("pool: unknown Outcome %d", outcome)
+	}
+	// 先检查一次，避免对不属于调用者的资源执行钩子
+	p.m.Lock()
+	err := p.checkOwned(r)
+	p.m.Unlock()
+	if err != nil {
+		p.logger.Println("ReleaseWith", err)
+		return p.misuse(p.wrapErr(err))
+	}
+
+	keep := outcome == OutcomeCommit
+	if p.onOutcome != nil {
+		if err := p.onOutcome(r, outcome); err != nil {
+			p.logger.Println("ReleaseWith", outcome, "Failed:", err)
+			keep = false
+		} else {
+			keep = outcome != OutcomeDiscard
+		}
+	}
+	if !keep {
+		return p.Discard(r)
+	}
+	return p.Release(r)
+}
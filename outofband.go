@@ -0,0 +1,50 @@
+package pool
+
+// ClosedChecker 是可以报告自己是否已经被关闭的资源，例如在Close时设置标志的连接包装类型
+// 没有设置WithClosedCheck时，池在Release时调用IsClosed，调用者没有放回而是直接关闭的资源
+// 不会再被关闭或放回池里，而是计为已销毁、记入Stats.ClosedOutside，并在后台补足MinIdle个空闲资源
+type ClosedChecker interface {
+	IsClosed() bool
+}
+
+// closedOutside 判断资源r是否已经被调用者直接关闭，判断的函数panic时认为没有关闭
+func (p *Pool[T]) closedOutside(r T) (closed bool) {
+	var err error
+	defer func() {
+		if err != nil {
+			closed = false
+		}
+	}()
+	defer catch(p.logger, "closed check", &err)
+	if p.isClosed != nil {
+		return p.isClosed(r)
+	}
+	if c, ok := any(r).(ClosedChecker); ok {
+		return c.IsClosed()
+	}
+	return false
+}
+
+// forget 释放已经被调用者关闭的资源占用的容量，但不再关闭它，解锁后补足MinIdle个空闲资源
+// 调用者需持有p.m，并用p.unlock解锁
+func (p *Pool[T]) forget(e *entry[T]) {
+	p.logger.Println("Release", "Closed Outside")
+	p.usage.add(p.clock.Now().Sub(e.createdAt), e.busy)
+	p.totalWeight -= e.weight
+	p.forgotten = append(p.forgotten, e)
+	p.releaseSlot()
+	p.destroyed = true
+}
+
+// dropResource 把已经被调用者关闭的资源计为已销毁，不调用closer，在p.unlock解锁后执行
+func (p *Pool[T]) dropResource(e *entry[T]) {
+	p.stats.closed.Add(1)
+	p.stats.closedOutside.Add(1)
+	p.emit(Event{Type: ResourceClosedOutside, Time: p.clock.Now(), Duration: p.clock.Now().Sub(e.createdAt)})
+	if p.failover != nil {
+		p.failover.forget(e.r)
+	}
+	if p.balancer != nil {
+		p.balancer.closed(e.r)
+	}
+}
@@ -0,0 +1,130 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError 表示factory、验证函数或钩子发生了panic，池把它转换为错误，
+// 资源占用的容量会像普通的失败一样被释放
+type PanicError struct {
+	Value any    // recover得到的值
+	Stack []byte // 发生panic时的调用栈
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("pool: panic: %v", e.Value)
+}
+
+// Unwrap 在panic的值是error时返回它
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// catch 在defer中调用，把panic转换为*PanicError保存到err中并写入日志
+func catch(logger Logger, name string, err *error) {
+	if v := recover(); v != nil {
+		pe := &PanicError{Value: v, Stack: debug.Stack()}
+		logger.Println("Panic:", name, pe.Value)
+		*err = pe
+	}
+}
+
+// recoverPanics 包装用户提供的函数，让其中的panic变成错误而不是让调用的goroutine崩溃
+// 在newPool中启动后台goroutine之前调用
+func (p *Pool[T]) recoverPanics() {
+	logger := p.logger
+	p.factory = recoverFactory(logger, p.factory)
+	if validator := p.validator; validator != nil {
+		p.validator = func(ctx context.Context, r T) (ok bool) {
+			var err error
+			defer func() {
+				if err != nil {
+					ok = false
+				}
+			}()
+			defer catch(logger, "validator", &err)
+			return validator(ctx, r)
+		}
+	}
+	p.closer = recoverFunc(logger, "closer", p.closer)
+	p.ping = recoverFunc(logger, "keepalive ping", p.ping)
+	p.sanitize = recoverFunc(logger, "sanitizer", p.sanitize)
+	if reset := p.reset; reset != nil {
+		p.reset = func(ctx context.Context, r T) (err error) {
+			defer catch(logger, "reset func", &err)
+			return reset(ctx, r)
+		}
+	}
+	if onCreate := p.onCreate; onCreate != nil {
+		p.onCreate = func(ctx context.Context, r T, s Stats) (err error) {
+			defer catch(logger, "OnCreate hook", &err)
+			return onCreate(ctx, r, s)
+		}
+	}
+	if binder := p.binder; binder != nil {
+		p.binder = func(ctx context.Context, r T) (err error) {
+			defer catch(logger, "binder", &err)
+			return binder(ctx, r)
+		}
+	}
+	if onAcquire := p.onAcquire; onAcquire != nil {
+		p.onAcquire = func(ctx context.Context, r T, s Stats) (err error) {
+			defer catch(logger, "OnAcquire hook", &err)
+			return onAcquire(ctx, r, s)
+		}
+	}
+	p.onRelease = recoverHook(logger, "OnRelease hook", p.onRelease)
+	if onOutcome := p.onOutcome; onOutcome != nil {
+		p.onOutcome = func(r T, o Outcome) (err error) {
+			defer catch(logger, "OnOutcome hook", &err)
+			return onOutcome(r, o)
+		}
+	}
+	if onClose := p.onClose; onClose != nil {
+		p.onClose = func(r T, s Stats) {
+			var err error
+			defer catch(logger, "OnClose hook", &err)
+			onClose(r, s)
+		}
+	}
+	if onError := p.onError; onError != nil {
+		p.onError = func(e error, ec ErrorContext) {
+			var err error
+			defer catch(logger, "Error handler", &err)
+			onError(e, ec)
+		}
+	}
+}
+
+// recoverFactory 包装factory
+func recoverFactory[T any](logger Logger, fn func(context.Context) (T, error)) func(context.Context) (T, error) {
+	return func(ctx context.Context) (r T, err error) {
+		defer catch(logger, "factory", &err)
+		return fn(ctx)
+	}
+}
+
+// recoverFunc 包装一个接收资源并返回错误的函数，fn为nil时返回nil
+func recoverFunc[T any](logger Logger, name string, fn func(T) error) func(T) error {
+	if fn == nil {
+		return nil
+	}
+	return func(r T) (err error) {
+		defer catch(logger, name, &err)
+		return fn(r)
+	}
+}
+
+// recoverHook 包装一个钩子函数，fn为nil时返回nil
+func recoverHook[T any](logger Logger, name string, fn func(T, Stats) error) func(T, Stats) error {
+	if fn == nil {
+		return nil
+	}
+	return func(r T, s Stats) (err error) {
+		defer catch(logger, name, &err)
+		return fn(r, s)
+	}
+}
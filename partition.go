@@ -0,0 +1,216 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lazysheep666/pool/internal/identity"
+)
+
+// ErrPartitionFull 表示非阻塞模式下或TryAcquire时分区借出的资源数已达到上限
+var ErrPartitionFull = errors.New("Partition has reached its share")
+
+// Partition 是Pool.Partition返回的视图，与池共用同一组资源，但限制通过它同时借出的资源数，
+// 用于在多个租户之间分配一个共享的池，每个分区有自己的统计信息
+type Partition[T any] struct {
+	p    *Pool[T]
+	name string
+	sem  chan struct{} // 每个借出的资源占用一个位置，nil表示不限制
+
+	m    sync.Mutex
+	held identity.Map[T, uint] // 通过这个分区借出的资源，共享模式下同一个资源可以借出多次
+
+	waiting   atomic.Int64
+	acquired  atomic.Uint64
+	waits     atomic.Uint64
+	waitNanos atomic.Int64
+	timeouts  atomic.Uint64
+}
+
+var _ Pooler[int] = (*Partition[int])(nil)
+
+// Partition 返回名为name的分区，通过它同时借出的资源最多为maxShare个，0表示不限制
+// 同一个name总是返回同一个分区，之后调用时的maxShare被忽略
+// 分区借出的资源要通过分区放回，分区的Close不做任何事情
+func (p *Pool[T]) Partition(name string, maxShare uint) *Partition[T] {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if pt, ok := p.partitions[name]; ok {
+		return pt
+	}
+	pt := &Partition[T]{p: p, name: name, held: make(identity.Map[T, uint])}
+	if maxShare > 0 {
+		pt.sem = make(chan struct{}, maxShare)
+	}
+	if p.partitions == nil {
+		p.partitions = make(map[string]*Partition[T])
+	}
+	p.partitions[name] = pt
+	return pt
+}
+
+// PartitionStats 返回每个分区的统计信息，以分区的名字为键
+func (p *Pool[T]) PartitionStats() map[string]Stats {
+	p.m.Lock()
+	pts := make([]*Partition[T], 0, len(p.partitions))
+	for _, pt := range p.partitions {
+		pts = append(pts, pt)
+	}
+	p.m.Unlock()
+	stats := make(map[string]Stats, len(pts))
+	for _, pt := range pts {
+		stats[pt.name] = pt.Stats()
+	}
+	return stats
+}
+
+// Name 返回分区的名字
+func (pt *Partition[T]) Name() string {
+	return pt.name
+}
+
+// Acquire 通过分区从池中获取一个资源
+func (pt *Partition[T]) Acquire() (T, error) {
+	return pt.AcquireContext(context.Background())
+}
+
+// AcquireContext 通过分区从池中获取一个资源，分区借出的资源已达到上限时等待其它资源通过分区放回，
+// 池处于非阻塞模式时返回ErrPartitionFull，之后的行为与Pool.AcquireContext相同
+func (pt *Partition[T]) AcquireContext(ctx context.Context) (T, error) {
+	return pt.acquire(ctx, false)
+}
+
+// TryAcquire 与Pool.TryAcquire相同，分区借出的资源已达到上限时立即返回ErrPartitionFull
+func (pt *Partition[T]) TryAcquire() (T, error) {
+	return pt.tryAcquireContext(context.Background())
+}
+
+// tryAcquireContext 与TryAcquire相同，但创建资源时使用ctx
+func (pt *Partition[T]) tryAcquireContext(ctx context.Context) (T, error) {
+	return pt.acquire(ctx, true)
+}
+
+func (pt *Partition[T]) acquire(ctx context.Context, try bool) (T, error) {
+	var zero T
+	if err := pt.take(ctx, try); err != nil {
+		return zero, err
+	}
+	var r T
+	var err error
+	if try {
+		r, err = pt.p.tryAcquireContext(ctx)
+	} else {
+		r, err = pt.p.AcquireContext(ctx)
+	}
+	if err != nil {
+		pt.give()
+		return zero, err
+	}
+	pt.m.Lock()
+	n, _ := pt.held.Get(r)
+	pt.held.Set(r, n+1)
+	pt.m.Unlock()
+	pt.acquired.Add(1)
+	return r, nil
+}
+
+// take 占用分区中的一个位置，try为true或池处于非阻塞模式时不等待
+func (pt *Partition[T]) take(ctx context.Context, try bool) error {
+	if pt.sem == nil {
+		return nil
+	}
+	select {
+	case pt.sem <- struct{}{}:
+		return nil
+	default:
+	}
+	pt.p.m.Lock()
+	nonBlocking := pt.p.nonBlocking
+	pt.p.m.Unlock()
+	if try || nonBlocking {
+		return ErrPartitionFull
+	}
+	start := pt.p.clock.Now()
+	pt.waiting.Add(1)
+	defer func() {
+		pt.waiting.Add(-1)
+		pt.waits.Add(1)
+		pt.waitNanos.Add(int64(pt.p.clock.Now().Sub(start)))
+	}()
+	select {
+	case pt.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			pt.timeouts.Add(1)
+		}
+		return ctx.Err()
+	}
+}
+
+// give 释放take占用的位置
+func (pt *Partition[T]) give() {
+	if pt.sem != nil {
+		<-pt.sem
+	}
+}
+
+// Release 把通过分区借出的资源放回池里，不是通过这个分区借出的资源返回ErrForeignResource
+func (pt *Partition[T]) Release(r T) error {
+	if err := pt.untrack(r); err != nil {
+		return err
+	}
+	defer pt.give()
+	return pt.p.Release(r)
+}
+
+// Discard 销毁通过分区借出的资源，不是通过这个分区借出的资源返回ErrForeignResource
+func (pt *Partition[T]) Discard(r T) error {
+	if err := pt.untrack(r); err != nil {
+		return err
+	}
+	defer pt.give()
+	return pt.p.Discard(r)
+}
+
+// untrack 把r从分区借出的资源中去掉一次
+func (pt *Partition[T]) untrack(r T) error {
+	pt.m.Lock()
+	defer pt.m.Unlock()
+	n, _ := pt.held.Get(r)
+	switch n {
+	case 0:
+		return pt.p.wrapErr(ErrForeignResource)
+	case 1:
+		pt.held.Delete(r)
+	default:
+		pt.held.Set(r, n-1)
+	}
+	return nil
+}
+
+// Stats 返回分区的统计信息，只有InUse、Waiting和与获取相关的计数，资源数等其它信息见池的Stats
+func (pt *Partition[T]) Stats() Stats {
+	pt.m.Lock()
+	var inUse uint
+	for _, n := range pt.held {
+		inUse += n
+	}
+	pt.m.Unlock()
+	return Stats{
+		InUse:               inUse,
+		Waiting:             uint(pt.waiting.Load()),
+		AcquireCount:        pt.acquired.Load(),
+		AcquireWaitCount:    pt.waits.Load(),
+		AcquireWaitDuration: time.Duration(pt.waitNanos.Load()),
+		AcquireTimeoutCount: pt.timeouts.Load(),
+	}
+}
+
+// Close 不做任何事情，池需要用它自己的Close关闭
+func (pt *Partition[T]) Close() error {
+	return nil
+}
@@ -0,0 +1,148 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lazysheep666/pool"
+)
+
+func TestPartition(t *testing.T) {
+	// tenants 是测试中的两个分区，a最多借出2个资源，b不限制
+	type tenants struct {
+		a, b *pool.Partition[*tracked]
+	}
+	tests := []struct {
+		name string
+		opts []pool.Option
+		run  func(t *testing.T, p *pool.Pool[*tracked], ts tenants) []func()
+	}{
+		{"limits its share", nil, func(t *testing.T, p *pool.Pool[*tracked], ts tenants) []func() {
+			undo := holdN(t, ts.a, 2)
+			if _, err := ts.a.TryAcquire(); !errors.Is(err, pool.ErrPartitionFull) {
+				t.Errorf("TryAcquire on a full partition = %v, want ErrPartitionFull", err)
+			}
+			// 其它分区和池本身不受影响
+			undo = append(undo, holdN(t, ts.b, 3)...)
+			return append(undo, holdN(t, p, 1)...)
+		}},
+		{"release frees a slot", nil, func(t *testing.T, p *pool.Pool[*tracked], ts tenants) []func() {
+			undo := holdN(t, ts.a, 2)
+			undo[0]()
+			return append(undo[1:], holdN(t, ts.a, 1)...)
+		}},
+		{"non-blocking pool", []pool.Option{pool.WithBlocking(false)}, func(t *testing.T, p *pool.Pool[*tracked], ts tenants) []func() {
+			undo := holdN(t, ts.a, 2)
+			if _, err := ts.a.Acquire(); !errors.Is(err, pool.ErrPartitionFull) {
+				t.Errorf("Acquire on a full partition = %v, want ErrPartitionFull", err)
+			}
+			return undo
+		}},
+		{"pool limit applies", []pool.Option{pool.WithMaxTotal(1), pool.WithBlocking(false)}, func(t *testing.T, p *pool.Pool[*tracked], ts tenants) []func() {
+			undo := holdN(t, ts.b, 1)
+			if _, err := ts.a.Acquire(); !errors.Is(err, pool.ErrPoolExhausted) {
+				t.Errorf("Acquire on an exhausted pool = %v, want ErrPoolExhausted", err)
+			}
+			// 失败的Acquire不占用分区的位置
+			if s := ts.a.Stats(); s.InUse != 0 {
+				t.Errorf("a: InUse = %d after a failed Acquire, want 0", s.InUse)
+			}
+			return undo
+		}},
+		{"foreign resources rejected", nil, func(t *testing.T, p *pool.Pool[*tracked], ts tenants) []func() {
+			r := acquire(t, p)
+			if err := ts.a.Release(r); !errors.Is(err, pool.ErrForeignResource) {
+				t.Errorf("releasing a pool resource to a = %v, want ErrForeignResource", err)
+			}
+			rb, err := ts.b.Acquire()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ts.a.Discard(rb); !errors.Is(err, pool.ErrForeignResource) {
+				t.Errorf("discarding b's resource through a = %v, want ErrForeignResource", err)
+			}
+			return []func(){func() { p.Release(r) }, func() { ts.b.Release(rb) }}
+		}},
+		{"same name same partition", nil, func(t *testing.T, p *pool.Pool[*tracked], ts tenants) []func() {
+			if again := p.Partition("a", 10); again != ts.a {
+				t.Error("Partition returned a new partition for an existing name")
+			}
+			undo := holdN(t, ts.a, 2)
+			if _, err := p.Partition("a", 10).TryAcquire(); !errors.Is(err, pool.ErrPartitionFull) {
+				t.Errorf("TryAcquire = %v, want the original share of 2 to apply", err)
+			}
+			return undo
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := newHarnessPool(t, tt.opts...)
+			ts := tenants{p.Partition("a", 2), p.Partition("b", 0)}
+			undo := tt.run(t, p, ts)
+			for _, f := range undo {
+				f()
+			}
+			for name, s := range p.PartitionStats() {
+				if s.InUse != 0 {
+					t.Errorf("%s: InUse = %d after releasing everything, want 0", name, s.InUse)
+				}
+			}
+		})
+	}
+}
+
+// holdN 通过pl借出n个资源，返回放回它们的函数
+func holdN(t *testing.T, pl pool.Pooler[*tracked], n int) []func() {
+	t.Helper()
+	undo := make([]func(), 0, n)
+	for i := 0; i < n; i++ {
+		r, err := pl.Acquire()
+		if err != nil {
+			t.Fatal(err)
+		}
+		undo = append(undo, func() {
+			if err := pl.Release(r); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	return undo
+}
+
+// TestPartitionWait 检查分区满时Acquire等待通过分区放回的资源，并计入分区的统计信息
+func TestPartitionWait(t *testing.T) {
+	p, _ := newHarnessPool(t)
+	pt := p.Partition("a", 1)
+	r, err := pt.Acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := pt.AcquireContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("AcquireContext on a full partition = %v, want DeadlineExceeded", err)
+	}
+	c := make(chan acquired, 1)
+	go func() {
+		r, err := pt.Acquire()
+		c <- acquired{r, err}
+	}()
+	eventually(t, "Acquire to wait on the partition", func() bool { return pt.Stats().Waiting == 1 })
+	if err := pt.Release(r); err != nil {
+		t.Fatal(err)
+	}
+	res := result(t, c)
+	if res.err != nil || res.r != r {
+		t.Fatalf("waiter got %v, %v, want the released resource", res.r, res.err)
+	}
+	if err := pt.Release(res.r); err != nil {
+		t.Fatal(err)
+	}
+	s := pt.Stats()
+	if s.AcquireCount != 2 || s.AcquireWaitCount != 2 || s.AcquireTimeoutCount != 1 || s.Waiting != 0 {
+		t.Errorf("AcquireCount = %d, AcquireWaitCount = %d, AcquireTimeoutCount = %d, Waiting = %d, want 2, 2, 1, 0",
+			s.AcquireCount, s.AcquireWaitCount, s.AcquireTimeoutCount, s.Waiting)
+	}
+}
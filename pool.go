@@ -1,81 +1,2130 @@
 package pool
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
-	"log"
+	"math/rand"
+	"runtime/debug"
+	"runtime/pprof"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
+
+	"github.com/lazysheep666/pool/internal/identity"
 )
 
 // Pool 管理一组可以安全地在多个goroutines间
-// 共享的资源。被管理的资源必须实现io.Closer接口
-type Pool struct {
-	m         sync.Mutex
-	resources chan io.Closer
-	factory   func() (io.Closer, error)
-	closed    bool
+// 共享的资源。资源的类型由类型参数T决定，
+// 销毁资源时调用WithCloser设置的函数。
+// 池通过资源识别被放回的资源，可比较的资源用它的值识别，不同的资源必须互不相等，
+// 切片、map和chan用底层的指针识别，不同的资源不能共用底层的数组(例如容量为0的切片)，
+// 其它不可比较的类型(例如含有切片的结构体)无法区分，New返回ErrInvalidConfig
+type Pool[T any] struct {
+	m            sync.Mutex
+	idle         store[T]                         // 空闲资源，最早放回的在最前面，按reuse从队首或队尾取出
+	lf           *lockFreeStore[T]                // 使用LockFreeStore时与idle相同，提供不加锁的获取和放回，nil表示不使用，见lockfree.go
+	inUse        identity.Map[T, *entry[T]]       // 使用中的资源
+	pendingClose []T                              // 等待解锁后关闭的资源
+	forgotten    []*entry[T]                      // 已经被调用者关闭、等待解锁后记录的资源
+	closeErrs    []error                          // 池关闭后关闭资源时closer返回的错误，由CloseContext返回
+	factory      func(context.Context) (T, error) // 由m保护，SwapFactory会替换它
+	closer       func(T) error
+	closeTimeout time.Duration                // 每次调用closer的最长时间，0表示不限制
+	chaos        *chaos                       // WithChaos注入故障的状态，nil表示不注入
+	overridable  bool                         // 是否设置了WithTestOverrides
+	override     atomic.Pointer[testOverride] // SetTestOverride设置的模拟状态，nil表示不模拟
+	overrideWait atomic.Int64                 // 因ForceExhausted等待的获取数
+	validator    func(context.Context, T) bool
+	onCreate     func(context.Context, T, Stats) error
+	onAcquire    func(context.Context, T, Stats) error
+	onRelease    func(T, Stats) error
+	onClose      func(T, Stats)
+	onOutcome    func(T, Outcome) error
+	ping         func(T) error
+	reset        func(context.Context, T) error
+	sanitize     func(T) error
+	binder       func(context.Context, T) error
+	bindRetries  uint // binder失败后最多重新获取的次数
+	weight       func(T) uint
+	size         func(T) int
+	score        func(T) float64
+	scoring      bool // 有资源报告过分数，之后popIdle优先使用分数最高的空闲资源
+	isClosed     func(T) bool
+	closed       bool
+	paused       bool // Pause之后为true，Acquire排队等待Resume
+
+	maxIdle     uint          // 池中最多保留的空闲资源数
+	minIdle     uint          // 至少保持的空闲资源数
+	maxTotal    uint          // 资源总数(空闲+使用中)的上限，0表示不限制
+	numOpen     uint          // 已创建且尚未销毁的资源数
+	nonBlocking bool          // 达到上限时Acquire立即返回ErrPoolExhausted
+	notify      chan struct{} // 有资源放回、容量释放或池关闭时关闭，用来唤醒等待的goroutine，由notifier按需创建
+	waiters     []waiter[T]   // 等待资源的Acquire，按优先级从高到低、同一优先级内按到达的顺序排列
+	wakeups     uint          // 留给已被唤醒、但还没有重新检查池的等待者的资源数
+	reserved    uint          // 只留给PriorityHigh的容量
+	overflowN   uint          // 达到maxTotal后还可以创建的溢出资源数
+	overflowTTL time.Duration // 溢出资源放回后最长的空闲时间，0表示放回时关闭
+	maxWaiters  uint          // 排队等待的Acquire数的上限，0表示不限制
+
+	replaceDiscarded bool // Discard后在后台补足MinIdle个空闲资源
+	replenishing     bool // 是否有goroutine正在补充空闲资源
+	eagerReplenish   bool // 任何资源被销毁后都在后台补足MinIdle个空闲资源
+	destroyed        bool // 本次持有锁期间有资源被销毁，p.unlock时补充空闲资源
+	creatingIdle     uint // 正在创建、准备放入空闲资源中的资源数
+
+	overflow        OverflowPolicy // 空闲资源已满时Release的行为
+	overflowWaiters int            // 因BlockOnOverflow等待空闲位置的Release数
+
+	acquireTimeout    time.Duration // 每次Acquire最长的等待时间，0表示不限制
+	validateOnRelease bool          // Release时也用validator检查资源
+	validateIdle      time.Duration // 只验证空闲时间超过这个值的资源，0表示总是验证
+	validationBudget  time.Duration // 每次Acquire检查空闲资源的总时间上限，0表示不限制
+	idleTimeout       time.Duration // 空闲资源的最长空闲时间，0表示不回收
+	compactIdle       bool          // 按最近的需求收缩空闲资源
+	strict            bool          // 严格模式，错误的用法直接panic
+	peakInUse         uint          // 上一次回收之后同时使用的资源数的峰值
+	demand            float64       // peakInUse的移动平均
+	maxInUse          uint          // 池存在期间同时使用的资源数的峰值，见WarmHints
+	warmHint          uint          // WithStateStore读取的提示要求Warmup预热的空闲资源数
+	stateStore        StateStore    // 保存容量提示的StateStore，nil表示不保存
+	maxLifetime       time.Duration // 资源从创建起的最长使用时间，0表示不限制
+	lifetimeJitter    float64       // 每个资源的idleTimeout和maxLifetime随机缩短的最大比例
+	maxUses           uint          // 每个资源最多被获取的次数，0表示不限制
+	maxSharers        uint          // 共享模式下一个资源同时借出的次数上限，0和1表示不共享
+	reuse             ReuseStrategy // 取出空闲资源的顺序
+	selection         SelectionPolicy
+	selectBuf         []IdleResource // selectIdle复用的缓冲区
+	done              chan struct{}  // 池关闭时关闭，通知后台goroutine退出
+	leakTimeout       time.Duration  // 资源被持有超过这个时间时报告泄漏，0表示不检测
+	maxCheckout       time.Duration  // 资源被持有超过这个时间时在放回时报告，0表示不检查
+	reclaimAbandoned  bool           // 回收没有放回就被垃圾回收的PooledResource
+	discardOverdue    bool           // 持有时间超过maxCheckout的资源放回时被销毁
+	onOverdue         func(CheckoutViolation)
+	onLeak            func(Leak)                // 报告泄漏的函数，nil表示写入日志
+	onEvent           func(Event)               // 接收事件的函数，nil表示不发出事件
+	onError           func(error, ErrorContext) // 处理后台错误的函数，nil表示只写入日志
+	slowAcquire       time.Duration             // Acquire超过这个时间时调用onSlowAcquire，0表示不检查
+	onSlowAcquire     func(wait time.Duration, waiters int)
+	starvation        time.Duration // 等待者排队超过这个时间时调用onStarved，0表示不检查
+	onStarved         func(StarvedWaiter)
+	logger            Logger
+	logging           bool              // 是否设置了WithLogger，没有设置时热路径上不构造日志的参数，避免分配
+	name              string            // WithName设置的名字，附加在日志、事件和错误上
+	labels            map[string]string // WithLabels设置的标签，创建后不再修改
+	tracer            Tracer
+	clock             Clock
+	breaker           *breaker                   // factory的熔断器，nil表示不熔断
+	scaler            *autoscaler                // 自动调整MaxTotal的状态，nil表示不调整
+	schedule          Schedule                   // WithSchedule设置的时间表，nil表示不按时间调整
+	scheduled         *Capacity                  // 最近一次按时间表应用的容量，只由applySchedule访问
+	factoryAttempts   uint                       // factory失败时最多调用的次数
+	failover          *failover[T]               // WithFactories设置的多个factory，nil表示只有一个
+	balancer          *Balancer[T]               // WithBalancer设置的多个后端，nil表示不使用
+	createSem         chan struct{}              // 限制同时进行的factory调用，nil表示不限制
+	warmupParallel    uint                       // fill同时创建的资源数上限，0表示不限制
+	closeParallel     uint                       // unlock同时关闭的资源数上限，0和1表示依次关闭
+	createLim         *rate.Limiter              // 限制factory调用的速率，nil表示不限制
+	singleflight      bool                       // 同一时刻只进行一次创建，失败时等待者共享错误
+	runtimeTrace      bool                       // 在runtime/trace中记录Acquire任务、等待和factory调用
+	profile           *pprof.Profile             // 记录借出的资源的Profile，nil表示不记录
+	profiled          map[*checkoutKey]*entry[T] // 已经加入profile的借出
+	quarantineN       uint                       // 隔离的资源最多重新验证的次数，0表示不隔离
+	quarantineBackoff time.Duration              // 隔离后第一次重新验证前等待的时间
+	lingerTimeout     time.Duration              // ReleaseDeferred等待决定的最长时间
+	lingering         uint                       // ReleaseDeferred放回、还在等待决定的资源数
+	quarantined       uint                       // 正在隔离的资源数
+	maxWeight         uint                       // 资源总权重的上限，0表示不限制
+	maxIdleBytes      uint64                     // 空闲资源总大小的上限，0表示不限制
+	totalWeight       uint                       // 已创建且尚未销毁的资源的总权重
+	partitions        map[string]*Partition[T]   // Partition创建的分区，以名字为键
+	consumers         map[string]*consumer       // 公平调度的消费者，以AcquireAs的id为键，匿名的Acquire为空字符串
+	vclock            uint64                     // 公平调度的虚拟时间，最近一个被服务的等待者的完成时间
+	flight            *flight                    // 正在进行的共享创建，nil表示没有
+	retryBackoff      time.Duration              // 第一次重试前等待的时间
+
+	reaping  bool          // 后台回收goroutine是否已经启动
+	reconfig sync.Mutex    // 串行化Resize和UpdateConfig
+	shutdown chan struct{} // BeginShutdown返回的通道，nil表示没有开始逐渐关闭
+
+	generation atomic.Uint64 // 每次InvalidateAll加一，早于当前generation创建的资源不再放回池里
+
+	stats     counters
+	usage     usageRing  // 最近销毁的资源的存活时间和使用时间，由m保护
+	waitTimes sampleRing // 最近的等待时间，由m保护
+	config    Config     // 创建池时使用的配置，调试时展示
+}
+
+// entry 记录池中一个资源的状态
+type entry[T any] struct {
+	r          T
+	createdAt  time.Time         // 创建的时间
+	returnedAt time.Time         // 最近一次放回池中的时间
+	uses       uint              // 被获取的次数
+	gen        uint64            // 开始创建时池的generation
+	tags       map[string]string // SetTag设置的标签
+	affinity   string            // 最近一次用AcquireSticky获取时的键
+	overflow   bool              // 是否是资源总数超过maxTotal时创建的溢出资源
+	busy       time.Duration     // 累计被持有的时间
+	refs       uint              // 同时持有资源的借用者数，只在共享模式下大于1
+	invalid    bool              // 最近一次检查时没有通过验证函数
+	weight     uint              // 资源的权重，创建后不再改变
+	broken     bool              // 共享的资源被Discard过，不再借出
+	lingering  bool              // 被ReleaseDeferred放回，正在等待决定
+	leased     bool              // 被AcquireLease借出，到期时由租约收回
+	consumer   string            // 用AcquireAs借出时的消费者
+	profKey    *checkoutKey      // 这次借出在Profile中的键
+	hashID     uint64            // AcquireHashed使用的随机标识，0表示尚未分配
+	size       uint64            // 最近一次放回空闲资源时测量的大小
+	jitter     float64           // idleTimeout和maxLifetime缩短的比例，创建后不再改变
+	score      float64           // 最近一次计算的分数，见Scorer
+	sized      bool              // 这次放回之后是否已经测量过大小
+
+	acquiredAt   time.Time // 最近一次被获取的时间
+	stack        []byte    // 开启泄漏检测时获取资源的调用栈
+	leakReported bool      // 本次获取是否已经报告过泄漏
+
+	lf      atomic.Int32 // 使用LockFreeStore时在无锁路径中的状态，见lfHeld
+	untimed bool         // 这次借出是不加锁的，acquiredAt没有更新，放回时不计算持有的时间
 }
 
 // ErrPoolClosed 表示请求(Acquire) 了一个已经关闭的池
 var ErrPoolClosed = errors.New("Pool has been closed")
 
+// ErrPoolExhausted 表示非阻塞模式下或TryAcquire时池中资源已达到上限
+var ErrPoolExhausted = errors.New("Pool has been exhausted")
+
+// ErrAcquireTimeout 表示Acquire等待资源超时，
+// 返回的错误同时满足errors.Is(err, context.DeadlineExceeded)
+var ErrAcquireTimeout = errors.New("Acquire timed out")
+
+// ErrPoolPaused 表示池被Pause暂停时，非阻塞模式下或TryAcquire时无法获取资源
+var ErrPoolPaused = errors.New("Pool has been paused")
+
+// CreateError 表示factory创建资源失败，Acquire、Warmup等返回的factory错误都被包装为*CreateError
+// errors.Is和errors.As可以穿过它检查factory返回的原始错误
+type CreateError struct {
+	Err         error         // factory最后一次返回的错误
+	Attempts    uint          // 调用factory的次数，包括重试
+	Elapsed     time.Duration // 从第一次调用起经过的时间
+	BreakerOpen bool          // 返回时熔断器是否处于断开状态
+}
+
+func (e *CreateError) Error() string {
+	if e.BreakerOpen {
+		return fmt.Sprintf("pool: create failed after %d attempts in %v (breaker open): %v", e.Attempts, e.Elapsed, e.Err)
+	}
+	return fmt.Sprintf("pool: create failed after %d attempts in %v: %v", e.Attempts, e.Elapsed, e.Err)
+}
+
+func (e *CreateError) Unwrap() error { return e.Err }
+
+// ErrQueueFull 表示排队等待资源的Acquire已经达到WithMaxWaiters设置的上限
+var ErrQueueFull = errors.New("Acquire queue is full")
+
+// ErrDoubleRelease 表示Release或Discard了一个已经放回池里的资源
+var ErrDoubleRelease = errors.New("Resource has already been returned to the pool")
+
+// ErrForeignResource 表示Release或Discard了一个不是从本池获取的资源
+var ErrForeignResource = errors.New("Resource does not belong to the pool")
+
+// ErrUnidentifiable 表示T是接口类型时factory返回的资源的动态类型无法区分，见Pool
+var ErrUnidentifiable = errors.New("Resource cannot be told apart from other resources")
+
+// ErrPoolShuttingDown 表示池正在BeginShutdown开始的关闭过程中，并且没有剩余的空闲资源
+var ErrPoolShuttingDown = errors.New("Pool is shutting down")
+
+// ErrCloseTimeout 表示closer在WithCloseTimeout设置的时间内没有返回
+var ErrCloseTimeout = errors.New("Resource close timed out")
+
+// ErrCreateRateLimited 表示非阻塞模式下factory调用超过WithCreateRateLimit设置的速率
+var ErrCreateRateLimited = errors.New("Resource creation rate limit exceeded")
+
 // New 创建一个用来管理资源的池
-// 这个池需要一个可以分配新资源的函数以及一规定池的大小
-func New(fn func() (io.Closer, error), size uint) (*Pool, error) {
-	if size <= 0 {
-		return nil, errors.New("Size Value Too Small")
+// 这个池需要一个可以分配新资源的函数，其它设置通过Option传入
+func New[T any](fn func() (T, error), opts ...Option) (*Pool[T], error) {
+	return NewContext(ignoreContext(fn), opts...)
+}
+
+// NewContext 与New相同，但分配新资源的函数接收一个ctx，
+// 通过AcquireContext创建资源时传入的就是调用者的ctx
+func NewContext[T any](fn func(context.Context) (T, error), opts ...Option) (*Pool[T], error) {
+	var s settings
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return newPool(fn, s)
+}
+
+// NewFromConfig 使用cfg创建一个用来管理资源的池
+// opts在cfg之后生效，可以用来设置与资源类型相关的函数
+func NewFromConfig[T any](fn func() (T, error), cfg Config, opts ...Option) (*Pool[T], error) {
+	s := settings{Config: cfg}
+	for _, opt := range opts {
+		opt(&s)
 	}
-	return &Pool{
-		factory:   fn,
-		resources: make(chan io.Closer, size),
-	}, nil
+	return newPool(ignoreContext(fn), s)
+}
+
+// ignoreContext 把不接收ctx的factory转换为接收ctx的形式
+func ignoreContext[T any](fn func() (T, error)) func(context.Context) (T, error) {
+	if fn == nil {
+		return nil
+	}
+	return func(context.Context) (T, error) { return fn() }
+}
+
+func newPool[T any](fn func(context.Context) (T, error), s settings) (*Pool[T], error) {
+	if t := identity.Of[T](); !identity.Supported(t) {
+		return nil, fmt.Errorf("%w: resources of type %v cannot be told apart, use a pointer", ErrInvalidConfig, t)
+	}
+	cfg := s.Config.withDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if s.chaos != nil {
+		if err := s.chaos.validate(); err != nil {
+			return nil, err
+		}
+	}
+	clock := s.clock
+	if clock == nil {
+		clock = realClock{}
+	}
+	labels := copyTags(s.labels)
+	logger := newLabeledLogger(cfg.Logger, cfg.Name, labels)
+	_, nop := cfg.Logger.(nopLogger)
+	factories, err := funcOption[[]func(context.Context) (T, error)](s.factories, "factories")
+	if err != nil {
+		return nil, err
+	}
+	var fo *failover[T]
+	if factories != nil {
+		// 分别保护每个factory，一个factory panic时仍会尝试后面的factory
+		safe := make([]func(context.Context) (T, error), len(factories))
+		for i, f := range factories {
+			safe[i] = recoverFactory(logger, f)
+		}
+		fo = newFailover(safe, cfg.FailbackInterval, logger, clock)
+		fn = fo.create
+	}
+	balancer, err := funcOption[*Balancer[T]](s.balancer, "balancer")
+	if err != nil {
+		return nil, err
+	}
+	if balancer != nil {
+		if fo != nil {
+			return nil, fmt.Errorf("%w: WithBalancer cannot be used with WithFactories", ErrInvalidConfig)
+		}
+		fn = balancer.create
+	}
+	if fn == nil {
+		return nil, fmt.Errorf("%w: nil factory", ErrInvalidConfig)
+	}
+	closer, err := funcOption[func(T) error](s.closer, "closer")
+	if err != nil {
+		return nil, err
+	}
+	if closer == nil {
+		closer = closeIfCloser[T]
+	}
+	validator, err := ctxFuncOption(s.validator, "validator", func(fn func(T) bool) func(context.Context, T) bool {
+		return func(_ context.Context, r T) bool { return fn(r) }
+	})
+	if err != nil {
+		return nil, err
+	}
+	onCreate, err := ctxFuncOption(s.onCreate, "OnCreate hook", func(fn func(T, Stats) error) func(context.Context, T, Stats) error {
+		return func(_ context.Context, r T, s Stats) error { return fn(r, s) }
+	})
+	if err != nil {
+		return nil, err
+	}
+	onAcquire, err := ctxFuncOption(s.onAcquire, "OnAcquire hook", func(fn func(T, Stats) error) func(context.Context, T, Stats) error {
+		return func(_ context.Context, r T, s Stats) error { return fn(r, s) }
+	})
+	if err != nil {
+		return nil, err
+	}
+	onRelease, err := funcOption[func(T, Stats) error](s.onRelease, "OnRelease hook")
+	if err != nil {
+		return nil, err
+	}
+	onClose, err := funcOption[func(T, Stats)](s.onClose, "OnClose hook")
+	if err != nil {
+		return nil, err
+	}
+	onOutcome, err := funcOption[func(T, Outcome) error](s.onOutcome, "OnOutcome hook")
+	if err != nil {
+		return nil, err
+	}
+	ping, err := funcOption[func(T) error](s.ping, "keepalive ping")
+	if err != nil {
+		return nil, err
+	}
+	reset, err := ctxFuncOption(s.reset, "reset func", func(fn func(T) error) func(context.Context, T) error {
+		return func(_ context.Context, r T) error { return fn(r) }
+	})
+	if err != nil {
+		return nil, err
+	}
+	binder, err := funcOption[func(context.Context, T) error](s.binder, "binder")
+	if err != nil {
+		return nil, err
+	}
+	sanitize, err := funcOption[func(T) error](s.sanitize, "sanitizer")
+	if err != nil {
+		return nil, err
+	}
+	weight, err := funcOption[func(T) uint](s.weight, "weight func")
+	if err != nil {
+		return nil, err
+	}
+	score, err := funcOption[func(T) float64](s.score, "score func")
+	if err != nil {
+		return nil, err
+	}
+	size, err := funcOption[func(T) int](s.size, "size func")
+	if err != nil {
+		return nil, err
+	}
+	isClosed, err := funcOption[func(T) bool](s.isClosed, "closed check")
+	if err != nil {
+		return nil, err
+	}
+	p := &Pool[T]{
+		factory:           fn,
+		clock:             clock,
+		failover:          fo,
+		balancer:          balancer,
+		closer:            closer,
+		closeTimeout:      cfg.CloseTimeout,
+		closeParallel:     cfg.CloseConcurrency,
+		warmupParallel:    cfg.WarmupConcurrency,
+		validator:         validator,
+		onCreate:          onCreate,
+		onAcquire:         onAcquire,
+		onRelease:         onRelease,
+		onClose:           onClose,
+		onOutcome:         onOutcome,
+		ping:              ping,
+		reset:             reset,
+		sanitize:          sanitize,
+		binder:            binder,
+		bindRetries:       cfg.BindRetries,
+		weight:            weight,
+		score:             score,
+		size:              size,
+		maxIdleBytes:      cfg.MaxIdleBytes,
+		isClosed:          isClosed,
+		maxWeight:         cfg.MaxTotalWeight,
+		maxIdle:           cfg.MaxIdle,
+		minIdle:           cfg.MinIdle,
+		maxTotal:          cfg.MaxTotal,
+		nonBlocking:       cfg.NonBlocking,
+		acquireTimeout:    cfg.AcquireTimeout,
+		validateOnRelease: cfg.ValidateOnRelease,
+		validateIdle:      cfg.ValidateIfIdleLongerThan,
+		validationBudget:  cfg.ValidationBudget,
+		overflow:          cfg.OverflowPolicy,
+		replaceDiscarded:  cfg.ReplaceDiscarded,
+		eagerReplenish:    cfg.EagerReplenish,
+		idleTimeout:       cfg.IdleTimeout,
+		compactIdle:       cfg.CompactIdle,
+		strict:            cfg.Strict,
+		maxLifetime:       cfg.MaxLifetime,
+		lifetimeJitter:    cfg.LifetimeJitter,
+		maxUses:           cfg.MaxUses,
+		maxSharers:        cfg.MaxSharers,
+		reserved:          cfg.HighPriorityReserve,
+		overflowN:         cfg.OverflowSize,
+		overflowTTL:       cfg.OverflowTTL,
+		maxWaiters:        cfg.MaxWaiters,
+		factoryAttempts:   cfg.FactoryAttempts,
+		retryBackoff:      cfg.FactoryBackoff,
+		singleflight:      cfg.SingleflightCreates,
+		runtimeTrace:      cfg.RuntimeTrace,
+		quarantineN:       cfg.QuarantineRetries,
+		quarantineBackoff: cfg.QuarantineBackoff,
+		lingerTimeout:     cfg.LingerTimeout,
+		reuse:             cfg.ReuseStrategy,
+		selection:         s.selection,
+		logger:            logger,
+		logging:           !nop,
+		name:              cfg.Name,
+		labels:            labels,
+		idle:              newStore[T](cfg.IdleStore, cfg.MaxIdle),
+		inUse:             make(identity.Map[T, *entry[T]]),
+		tracer:            s.tracer,
+		leakTimeout:       cfg.LeakTimeout,
+		maxCheckout:       cfg.MaxCheckoutDuration,
+		reclaimAbandoned:  cfg.ReclaimAbandoned,
+		discardOverdue:    cfg.DiscardOverdue,
+		onOverdue:         s.onOverdue,
+		onLeak:            s.onLeak,
+		onEvent:           s.onEvent,
+		onError:           s.onError,
+		slowAcquire:       cfg.SlowAcquireThreshold,
+		onSlowAcquire:     s.onSlow,
+		starvation:        cfg.StarvationThreshold,
+		onStarved:         s.onStarved,
+		done:              make(chan struct{}),
+		config:            cfg,
+	}
+	p.recoverPanics()
+	if s.chaos != nil {
+		p.chaos = newChaos(*s.chaos)
+	}
+	p.overridable = s.testOverrides
+	if s.createSem != nil {
+		p.createSem = s.createSem
+	} else if cfg.MaxConcurrentCreates > 0 {
+		p.createSem = make(chan struct{}, cfg.MaxConcurrentCreates)
+	}
+	if s.createLim != nil {
+		p.createLim = s.createLim
+	} else if cfg.CreateRate > 0 {
+		p.createLim = rate.NewLimiter(cfg.CreateRate, cfg.CreateBurst)
+	}
+	if cfg.BreakerThreshold > 0 {
+		p.breaker = &breaker{threshold: cfg.BreakerThreshold, cooldown: cfg.BreakerCooldown}
+	}
+	if cfg.AutoscaleMax > 0 {
+		p.scaler = &autoscaler{min: cfg.AutoscaleMin, max: cfg.AutoscaleMax}
+		go p.autoscale(cfg.AutoscaleInterval)
+	}
+	if cfg.IdleTimeout > 0 || cfg.MaxLifetime > 0 || cfg.MinIdle > 0 || cfg.CompactIdle || cfg.OverflowSize > 0 && cfg.OverflowTTL > 0 {
+		p.reaping = true
+		go p.reaper(cfg.ReapInterval)
+	}
+	if cfg.LeakTimeout > 0 {
+		go p.leakDetector()
+	}
+	if cfg.StarvationThreshold > 0 {
+		go p.starvationWatch()
+	}
+	if cfg.CheckoutProfile {
+		p.profile = newCheckoutProfile(cfg.Name)
+		p.profiled = make(map[*checkoutKey]*entry[T])
+	}
+	if s, ok := p.idle.(*lockFreeStore[T]); ok && p.lockFreeEligible() {
+		p.lf = s
+	}
+	if cfg.KeepaliveInterval > 0 && ping != nil {
+		go p.keepalive(cfg.KeepaliveInterval)
+	}
+	if s.schedule != nil {
+		interval := s.schedInt
+		if interval <= 0 {
+			interval = DefaultScheduleInterval
+		}
+		p.schedule = s.schedule
+		p.applySchedule(clock.Now())
+		go p.scheduler(interval)
+	}
+	if s.stateStore != nil {
+		p.stateStore = s.stateStore
+		p.loadHints(s.stateStore)
+	}
+	if s.statsSink != nil {
+		interval := s.statsInt
+		if interval <= 0 {
+			interval = DefaultStatsInterval
+		}
+		go p.pushStats(s.statsSink, interval)
+	}
+	return p, nil
+}
+
+// SetMaxTotal 设置池中资源总数(空闲+使用中)的上限，0表示不限制
+// 达到上限后Acquire会等待资源被放回或销毁
+func (p *Pool[T]) SetMaxTotal(n uint) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.maxTotal = n
+	p.broadcast()
+}
+
+// SetBlocking 设置达到上限时Acquire的行为
+// 默认阻塞等待，设为false时立即返回ErrPoolExhausted
+func (p *Pool[T]) SetBlocking(blocking bool) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.nonBlocking = !blocking
+	p.broadcast()
 }
 
 // Acquire 从池中获取一个资源
-func (p *Pool) Acquire() (io.Closer, error) {
-	select {
-	case r, ok := <-p.resources:
-		log.Println("Acquire:", "Shared Resource")
-		if !ok {
-			return nil, ErrPoolClosed
+func (p *Pool[T]) Acquire() (T, error) {
+	return p.AcquireContext(context.Background())
+}
+
+// AcquireContext 从池中获取一个资源
+// 池中没有空闲资源时会创建新资源，创建期间若有资源被放回池里则直接使用它。
+// 资源总数达到上限时以PriorityNormal排队等待资源被放回或销毁，非阻塞模式下返回ErrPoolExhausted。
+// ctx被取消时返回ctx.Err()，超时(包括超过AcquireTimeout)时返回ErrAcquireTimeout，
+// 池关闭时返回ErrPoolClosed
+func (p *Pool[T]) AcquireContext(ctx context.Context) (T, error) {
+	return p.acquire(ctx, PriorityNormal, "", acquireWait)
+}
+
+// TryAcquire 从池中获取一个资源，不等待其它goroutine放回资源
+// 没有空闲资源并且资源总数已达到上限，或有其它goroutine在排队时立即返回ErrPoolExhausted
+func (p *Pool[T]) TryAcquire() (T, error) {
+	return p.tryAcquireContext(context.Background())
+}
+
+// tryAcquireContext 与TryAcquire相同，但创建资源时使用ctx
+func (p *Pool[T]) tryAcquireContext(ctx context.Context) (T, error) {
+	return p.acquire(ctx, PriorityNormal, "", acquireTry)
+}
+
+// acquireMode 决定acquire在没有空闲资源时的行为
+type acquireMode int
+
+const (
+	acquireWait acquireMode = iota // 创建新资源，达到上限时排队等待
+	acquireTry                     // 创建新资源，但不排队等待
+	acquireIdle                    // 只使用空闲或可以共享的资源，不创建也不等待，用于ShardedPool先查看所有分片
+)
+
+// acquire 以优先级prio为消费者consumer获取一个资源，mode决定没有空闲资源时是否创建和等待
+// 不能得到资源而又不等待时返回ErrPoolExhausted
+func (p *Pool[T]) acquire(ctx context.Context, prio Priority, consumer string, mode acquireMode) (res T, err error) {
+	var zero T
+	try := mode != acquireWait
+	p.checkClosedAcquire()
+	if p.lf != nil && consumer == "" {
+		if r, ok := p.acquireParked(ctx); ok {
+			return r, nil
+		}
+		// 排队等待的Acquire让之后的Release改为加锁的路径，把资源交给它们
+		p.lf.contended.Add(1)
+		defer p.lf.contended.Add(-1)
+	}
+	if p.acquireTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = withTimeout(ctx, p.clock, p.acquireTimeout)
+		defer cancel()
+	}
+	var stack []byte
+	if p.leakTimeout > 0 {
+		stack = debug.Stack()
+	}
+	if p.tracer != nil {
+		ctx = p.tracer.TraceAcquireStart(ctx)
+	}
+	if p.chaos != nil {
+		if err := p.chaosDelay(ctx); err != nil {
+			return zero, err
 		}
-		return r, nil
-	default:
-		log.Println("Acquire:", "New Resource")
-		return p.factory()
+	}
+	ctx, endTask := p.startTask(ctx)
+	start := p.clock.Now()
+	var waitStart time.Time
+	var wait chan *entry[T] // 排队等待时用来接收唤醒或Release直接交给它的资源
+	woken := false          // 刚被唤醒，需要消耗一次wakeups
+	queued := false         // 已经排过队，之后不再让位给后来的等待者
+	var last waiter[T]      // 最近一次排队的位置，重新排队时保留
+	outcome := OutcomeError
+	var validated time.Duration // 本次获取检查空闲资源用去的时间
+	var binds uint              // 本次获取中binder失败的次数
+	defer func() {
+		err = p.acquireDone(ctx, start, waitStart, err)
+		if consumer != "" {
+			p.consumed(consumer, res, waitStart, err)
+		}
+		if p.profile != nil && err == nil {
+			// 跳过acquire中的defer，调用栈从acquire开始
+			p.profileCheckout(res, 2)
+		}
+		if errors.Is(err, ErrAcquireTimeout) {
+			outcome = OutcomeTimeout
+		}
+		if p.tracer != nil {
+			p.tracer.TraceAcquireEnd(ctx, outcome, err)
+		}
+		endTask(outcome)
+	}()
+	if p.overridable {
+		if err := p.checkOverride(ctx, try, &waitStart); err != nil {
+			return zero, err
+		}
+	}
+	for {
+		if p.lf != nil && p.lf.parked.Load() > 0 {
+			p.m.Lock()
+			p.unpark()
+			p.unlock()
+		}
+		p.m.Lock()
+		if woken {
+			p.wakeups--
+			woken = false
+		}
+		// 池关闭后总是返回ErrPoolClosed，即使ctx也已经结束
+		if p.closed {
+			p.m.Unlock()
+			return zero, ErrPoolClosed
+		}
+		if err := ctx.Err(); err != nil {
+			p.wakeWaiters()
+			p.m.Unlock()
+			return zero, err
+		}
+		if p.paused && (p.nonBlocking || try) {
+			p.m.Unlock()
+			return zero, ErrPoolPaused
+		}
+		// 有优先级不低于自己的goroutine在排队时，新来的Acquire排到它们后面，避免抢走先来者的资源
+		mustQueue := !queued && !p.nonBlocking &&
+			(p.wakeups > 0 || len(p.waiters) > 0 && p.waiters[0].prio >= prio)
+		mustQueue = mustQueue || p.paused || p.overReserve(prio, 1)
+		if p.shutdown != nil {
+			// 正在关闭时不再排队等待，只使用剩余的空闲资源
+			mustQueue = false
+		}
+		// 验证用完了预算并且可以创建新资源时，不再检查空闲资源而是直接创建
+		canCreate := (p.maxTotal == 0 || p.numOpen < p.maxTotal+p.overflowN) && !p.weightFull()
+		overBudget := p.validationBudget > 0 && validated >= p.validationBudget && canCreate
+		if e := p.share(!mustQueue && p.shutdown == nil); e != nil {
+			p.m.Unlock()
+			if ok, err := p.runAcquireHook(ctx, e.r, &binds); err != nil {
+				return zero, err
+			} else if !ok {
+				continue
+			}
+			if p.abandon(e.r) {
+				return zero, ErrPoolClosed
+			}
+			p.stats.hit()
+			outcome = OutcomeHit
+			if p.logging {
+				p.logger.Println("Acquire:", "Shared Resource")
+			}
+			return e.r, nil
+		}
+		if e := p.popIdleIf(!mustQueue && !overBudget, stack); e != nil {
+			p.m.Unlock()
+			checkStart := p.clock.Now()
+			ok := p.checkIdle(ctx, e)
+			validated += p.clock.Now().Sub(checkStart)
+			if !ok {
+				continue
+			}
+			if ok, err := p.runAcquireHook(ctx, e.r, &binds); err != nil {
+				return zero, err
+			} else if !ok {
+				continue
+			}
+			if p.chaos != nil {
+				p.chaosKill(e.r)
+			}
+			if p.abandon(e.r) {
+				return zero, ErrPoolClosed
+			}
+			p.stats.hit()
+			outcome = OutcomeHit
+			if p.logging {
+				p.logger.Println("Acquire:", "Shared Resource")
+			}
+			return e.r, nil
+		}
+		if p.shutdown != nil {
+			p.m.Unlock()
+			return zero, ErrPoolShuttingDown
+		}
+		if !mustQueue && canCreate && mode != acquireIdle {
+			p.numOpen++
+			notify := p.notifier()
+			p.m.Unlock()
+			r, reused, err := p.create(ctx, notify, stack)
+			if err != nil {
+				return zero, ctxError(ctx, err)
+			}
+			if ok, err := p.runAcquireHook(ctx, r, &binds); err != nil {
+				return zero, err
+			} else if !ok {
+				continue
+			}
+			if p.abandon(r) {
+				return zero, ErrPoolClosed
+			}
+			if reused {
+				p.stats.hit()
+				outcome = OutcomeHit
+				if p.logging {
+					p.logger.Println("Acquire:", "Shared Resource")
+				}
+			} else {
+				p.stats.miss()
+				outcome = OutcomeMiss
+			}
+			return r, nil
+		}
+		if p.nonBlocking || try {
+			p.m.Unlock()
+			return zero, ErrPoolExhausted
+		}
+		if !queued && p.queueFull() {
+			p.m.Unlock()
+			return zero, ErrQueueFull
+		}
+		if wait == nil {
+			wait = make(chan *entry[T], 1)
+		}
+		// 排过队的等待者没有拿到资源时回到同一优先级的最前面，保持原来的顺序
+		last = p.enqueue(waiter[T]{ch: wait, prio: prio, n: 1, direct: true, stack: stack, consumer: consumer, tag: last.tag, since: last.since}, queued)
+		queued = true
+		p.wakeWaiters()
+		p.m.Unlock()
+
+		if waitStart.IsZero() {
+			waitStart = p.clock.Now()
+		}
+		if p.logging {
+			p.logger.Println("Acquire:", "Waiting")
+		}
+		endWait := p.region(ctx, "pool.Acquire.Wait")
+		select {
+		case e := <-wait:
+			endWait()
+			if e == nil {
+				woken = true
+				continue
+			}
+			// Release直接把资源交给了这个等待者
+			if !p.checkIdle(ctx, e) {
+				continue
+			}
+			if ok, err := p.runAcquireHook(ctx, e.r, &binds); err != nil {
+				return zero, err
+			} else if !ok {
+				continue
+			}
+			if p.abandon(e.r) {
+				return zero, ErrPoolClosed
+			}
+			p.stats.hit()
+			outcome = OutcomeHit
+			if p.logging {
+				p.logger.Println("Acquire:", "Handoff Resource")
+			}
+			return e.r, nil
+		case <-ctx.Done():
+			endWait()
+			p.m.Lock()
+			if !p.removeWaiter(wait) {
+				// 已经被唤醒或收到了资源，唤醒是在持有锁时发出的，这时一定已经在wait中
+				if e := <-wait; e != nil {
+					p.handBack(e)
+				} else {
+					// 把这次唤醒交给下一个等待者
+					p.wakeups--
+					p.wakeWaiters()
+				}
+			}
+			p.unlock()
+			// 回到循环开始，池同时被关闭时返回ErrPoolClosed而不是ctx的错误
+			continue
+		}
+	}
+}
+
+// acquireDone 记录一次从start开始、从waitStart开始等待的获取的统计信息和事件，
+// 并把超时错误转换为ErrAcquireTimeout
+func (p *Pool[T]) acquireDone(ctx context.Context, start, waitStart time.Time, err error) error {
+	now := p.clock.Now()
+	if !waitStart.IsZero() {
+		p.stats.waited(now.Sub(waitStart))
+		p.m.Lock()
+		p.waitTimes.add(now.Sub(waitStart))
+		p.m.Unlock()
+		p.emit(Event{Type: AcquireWaited, Time: now, Duration: now.Sub(waitStart), Err: err, Context: ctx})
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		p.stats.timeouts.Add(1)
+		if !errors.Is(err, ErrAcquireTimeout) {
+			err = fmt.Errorf("%w: %w", ErrAcquireTimeout, err)
+		}
+		p.emit(Event{Type: AcquireTimedOut, Time: now, Duration: now.Sub(start), Err: err, Context: ctx})
+	}
+	if d := now.Sub(start); p.onSlowAcquire != nil && p.slowAcquire > 0 && d > p.slowAcquire {
+		p.m.Lock()
+		waiters := len(p.waiters)
+		p.m.Unlock()
+		p.onSlowAcquire(d, waiters)
+	}
+	return p.wrapErr(err)
+}
+
+// ctxError 在ctx已经结束、而factory返回了其它错误时把ctx.Err()附加到err上，
+// 让AcquireTimeout导致的创建失败也满足errors.Is(err, context.DeadlineExceeded)
+func ctxError(ctx context.Context, err error) error {
+	if cerr := ctx.Err(); cerr != nil && !errors.Is(err, cerr) {
+		return fmt.Errorf("%w: %w", cerr, err)
+	}
+	return err
+}
+
+// create 用ctx调用factory创建一个新资源，调用者需要已经占用了一个容量
+// 创建期间若有资源被放回池里则直接使用它(reused为true)，新创建的资源稍后放回池里
+func (p *Pool[T]) create(ctx context.Context, notify <-chan struct{}, stack []byte) (r T, reused bool, err error) {
+	var zero T
+	if p.logging {
+		p.logger.Println("Acquire:", "New Resource")
+	}
+	created := make(chan createResult[T], 1)
+	go func() {
+		r, err := p.createInUse(ctx, stack)
+		created <- createResult[T]{r, err}
+	}()
+
+	for {
+		select {
+		case res := <-created:
+			return res.r, false, res.err
+		case <-notify:
+			p.m.Lock()
+			if p.closed {
+				p.m.Unlock()
+				go p.releaseCreated(created)
+				return zero, false, ErrPoolClosed
+			}
+			// 有goroutine在排队时把放回的资源留给它们
+			e := p.popIdleIf(len(p.waiters) == 0 && p.wakeups == 0, stack)
+			notify = p.notifier()
+			p.m.Unlock()
+			if e == nil || !p.checkIdle(ctx, e) {
+				continue
+			}
+			go p.releaseCreated(created)
+			return e.r, true, nil
+		case <-ctx.Done():
+			go p.releaseCreated(created)
+			return zero, false, ctx.Err()
+		}
+	}
+}
+
+// createInUse 为已经占用的容量创建一个资源并记为使用中，创建失败时释放容量
+func (p *Pool[T]) createInUse(ctx context.Context, stack []byte) (T, error) {
+	gen := p.generation.Load()
+	r, err := p.newResource(ctx)
+	var w uint
+	var score float64
+	var scored bool
+	if err == nil {
+		w = p.weightOf(r)
+		score, scored = p.scoreOf(r)
+	}
+	p.m.Lock()
+	defer p.m.Unlock()
+	if err != nil {
+		p.releaseSlot()
+		return r, err
+	}
+	now := p.clock.Now()
+	p.totalWeight += w
+	e := &entry[T]{r: r, createdAt: now, acquiredAt: now, stack: stack, uses: 1, refs: 1, gen: gen, weight: w,
+		overflow: p.maxTotal > 0 && p.numOpen > p.maxTotal, jitter: p.newJitter()}
+	p.setScore(e, score, scored)
+	p.track(e)
+	p.notePeak()
+	if p.maxSharers > 1 {
+		// 创建期间排队的等待者可以共享这个新资源
+		p.wakeWaiters()
+	}
+	return r, nil
+}
+
+// newResource 调用factory创建一个资源并执行OnCreate钩子，钩子返回错误时关闭资源
+// factory失败时按WithFactoryRetry的设置重试
+func (p *Pool[T]) newResource(ctx context.Context) (T, error) {
+	if p.singleflight {
+		return p.newResourceShared(ctx)
+	}
+	return p.makeResource(ctx)
+}
+
+// makeResource 实现newResource
+func (p *Pool[T]) makeResource(ctx context.Context) (T, error) {
+	start := p.clock.Now()
+	r, err := p.callFactory(ctx)
+	attempts := uint(1)
+	for ; err != nil && attempts < p.factoryAttempts; attempts++ {
+		if errors.Is(err, ErrFactoryUnavailable) || ctx.Err() != nil {
+			break
+		}
+		p.logger.Println("Create:", "Retrying:", err)
+		t := p.clock.NewTimer(p.factoryBackoff(attempts))
+		select {
+		case <-t.C():
+		case <-ctx.Done():
+			t.Stop()
+			var zero T
+			return zero, p.createError(err, attempts, start)
+		}
+		r, err = p.callFactory(ctx)
+	}
+	if err != nil {
+		var zero T
+		return zero, p.createError(err, attempts, start)
+	}
+	if _, ok := identity.Key(r); !ok {
+		p.closeResource(r)
+		var zero T
+		return zero, fmt.Errorf("%w: %T", ErrUnidentifiable, r)
+	}
+	p.stats.created.Add(1)
+	if p.onEvent != nil {
+		now := p.clock.Now()
+		p.emit(Event{Type: ResourceCreated, Time: now, Duration: now.Sub(start), Context: ctx})
+	}
+	if p.onCreate != nil {
+		if err := p.onCreate(ctx, r, p.collectStats(false)); err != nil {
+			p.closeResource(r)
+			var zero T
+			return zero, err
+		}
+	}
+	return r, nil
+}
+
+// createError 把factory的错误包装为*CreateError
+func (p *Pool[T]) createError(err error, attempts uint, start time.Time) error {
+	now := p.clock.Now()
+	return &CreateError{
+		Err:         err,
+		Attempts:    attempts,
+		Elapsed:     now.Sub(start),
+		BreakerOpen: p.breaker != nil && p.breaker.isOpen(now),
+	}
+}
+
+// callFactory 调用一次factory
+// 熔断器断开时不调用factory，直接返回ErrFactoryUnavailable
+// 设置了WithMaxConcurrentCreates时，同时进行的调用达到上限后等待其它调用结束
+// 设置了WithCreateRateLimit时，调用速率达到上限后等待，非阻塞模式下返回ErrCreateRateLimited
+func (p *Pool[T]) callFactory(ctx context.Context) (T, error) {
+	if p.breaker != nil && !p.breaker.allow(p.clock.Now()) {
+		var zero T
+		return zero, ErrFactoryUnavailable
+	}
+	if p.createLim != nil {
+		if err := p.waitCreateRate(ctx); err != nil {
+			var zero T
+			return zero, err
+		}
+	}
+	if p.chaos != nil && p.chaos.hit(p.chaos.FactoryFailureRate) {
+		p.logger.Println("Chaos:", "Factory Failure")
+		var zero T
+		return zero, ErrChaos
+	}
+	if p.createSem != nil {
+		select {
+		case p.createSem <- struct{}{}:
+			defer func() { <-p.createSem }()
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		}
+	}
+	if p.tracer != nil {
+		ctx = p.tracer.TraceCreateStart(ctx)
+	}
+	p.m.Lock()
+	factory := p.factory
+	p.m.Unlock()
+	endFactory := p.region(ctx, "pool.Factory")
+	r, err := factory(ctx)
+	endFactory()
+	if p.breaker != nil {
+		p.breaker.record(err, ctx.Err() != nil, p.clock.Now())
+	}
+	if p.tracer != nil {
+		p.tracer.TraceCreateEnd(ctx, err)
+	}
+	return r, err
+}
+
+// waitCreateRate 等待p.createLim允许一次factory调用，非阻塞模式下不等待
+func (p *Pool[T]) waitCreateRate(ctx context.Context) error {
+	p.m.Lock()
+	nonBlocking := p.nonBlocking
+	p.m.Unlock()
+	if nonBlocking {
+		if !p.createLim.Allow() {
+			return ErrCreateRateLimited
+		}
+		return nil
+	}
+	return p.createLim.Wait(ctx)
+}
+
+// factoryBackoff 返回第n次重试前等待的时间，每次重试翻倍并加上随机抖动
+func (p *Pool[T]) factoryBackoff(n uint) time.Duration {
+	d := p.retryBackoff
+	for i := uint(1); i < n && d < maxRetryBackoff; i++ {
+		d *= 2
+	}
+	if d > maxRetryBackoff {
+		d = maxRetryBackoff
+	}
+	// 在[d/2, d)之间随机等待，避免多个goroutine同时重试
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// runAcquireHook 执行OnAcquire钩子和binder，返回错误时销毁资源并返回false，调用者应重新获取
+// binds是本次获取中binder失败的次数，超过BindRetries时返回ErrBindFailed；
+// binds为nil时binder失败只返回false，用于失败后会回到AcquireContext的快速路径
+func (p *Pool[T]) runAcquireHook(ctx context.Context, r T, binds *uint) (bool, error) {
+	if p.onAcquire != nil {
+		if err := p.onAcquire(ctx, r, p.collectStats(false)); err != nil {
+			p.logger.Println("Acquire:", "OnAcquire Failed:", err)
+			p.Discard(r)
+			return false, nil
+		}
+	}
+	if p.binder != nil {
+		if err := p.binder(ctx, r); err != nil {
+			p.logger.Println("Acquire:", "Bind Failed:", err)
+			p.Discard(r)
+			if binds == nil {
+				return false, nil
+			}
+			if ctx.Err() != nil {
+				return false, ctxError(ctx, err)
+			}
+			if *binds >= p.bindRetries {
+				return false, fmt.Errorf("%w: %w", ErrBindFailed, err)
+			}
+			*binds++
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// checkIdle 检查一个从池中取出的空闲资源，过期或不可用的资源会被销毁
+func (p *Pool[T]) checkIdle(ctx context.Context, e *entry[T]) bool {
+	if !p.stale(ctx, e) {
+		return true
+	}
+	p.m.Lock()
+	p.untrack(e)
+	if e.invalid && p.quarantineN > 0 {
+		p.quarantine(e)
+	} else {
+		p.retire(e)
+	}
+	p.unlock()
+	return false
+}
+
+// stale 判断一个从池中取出的空闲资源是否已经过期或不可用
+func (p *Pool[T]) stale(ctx context.Context, e *entry[T]) bool {
+	if p.expired(e, p.clock.Now()) {
+		p.logger.Println("Acquire:", "Expired Resource")
+		p.stats.lifetimeClosed.Add(1)
+		return true
+	}
+	if p.chaos != nil && p.chaos.hit(p.chaos.ValidationFailureRate) {
+		p.logger.Println("Chaos:", "Validation Failure")
+		return true
+	}
+	if p.validator != nil && p.needsValidation(e) && !p.validator(ctx, e.r) {
+		p.logger.Println("Acquire:", "Invalid Resource")
+		p.unhealthy(e.r)
+		e.invalid = true
+		return true
+	}
+	if e.gen != p.generation.Load() {
+		p.logger.Println("Acquire:", "Invalidated Resource")
+		return true
+	}
+	if p.balancer != nil && p.balancer.draining(e.r) {
+		p.logger.Println("Acquire:", "Draining Resource")
+		return true
+	}
+	return false
+}
+
+// needsValidation 判断取出的空闲资源是否需要用验证函数检查，
+// 设置了WithValidateIfIdleLongerThan时只检查空闲时间超过阈值的资源
+func (p *Pool[T]) needsValidation(e *entry[T]) bool {
+	return p.validateIdle == 0 || p.clock.Now().Sub(e.returnedAt) > p.validateIdle
+}
+
+// Pause 暂停分配资源，之后的Acquire排队等待Resume，非阻塞模式下和TryAcquire返回ErrPoolPaused
+// 空闲资源保留在池里，使用中的资源仍然可以放回
+func (p *Pool[T]) Pause() {
+	p.m.Lock()
+	p.paused = true
+	p.slowPath(true)
+	p.unlock()
+	p.logger.Println("Pause")
+}
+
+// Resume 恢复被Pause暂停的池，唤醒排队等待的Acquire
+func (p *Pool[T]) Resume() {
+	p.m.Lock()
+	p.paused = false
+	p.slowPath(p.closed || p.shutdown != nil)
+	p.wakeWaiters()
+	p.unlock()
+	p.logger.Println("Resume")
+}
+
+// Paused 返回池是否被Pause暂停
+func (p *Pool[T]) Paused() bool {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return p.paused
+}
+
+// InvalidateAll 使池中现有的所有资源失效，用于凭据轮换或配置变更后替换所有资源
+// 空闲资源被立即关闭，使用中和正在创建的资源在放回时被关闭，之后的Acquire会创建新资源
+func (p *Pool[T]) InvalidateAll() {
+	p.m.Lock()
+	p.generation.Add(1)
+	p.unpark()
+	p.destroyIdle()
+	p.unlock()
+	p.logger.Println("InvalidateAll:", "Closing Idle Resources")
+}
+
+// SwapFactory 替换分配新资源的函数，用于证书轮换等需要无停机切换后端的场景
+// 现有的资源都会失效：空闲资源被立即关闭，使用中和正在创建的资源在放回时被关闭，
+// 然后用新的函数重新创建与关闭的空闲资源同样多的资源，返回创建失败的错误
+func (p *Pool[T]) SwapFactory(fn func() (T, error)) error {
+	return p.SwapFactoryContext(context.Background(), ignoreContext(fn))
+}
+
+// SwapFactoryContext 与SwapFactory相同，但新的函数接收ctx，ctx也用于重新创建资源
+func (p *Pool[T]) SwapFactoryContext(ctx context.Context, fn func(context.Context) (T, error)) error {
+	if fn == nil {
+		return fmt.Errorf("%w: nil factory", ErrInvalidConfig)
+	}
+	p.m.Lock()
+	if p.closed {
+		p.m.Unlock()
+		return ErrPoolClosed
+	}
+	p.factory = recoverFactory(p.logger, fn)
+	p.generation.Add(1)
+	n := p.destroyIdle()
+	p.unlock()
+	p.logger.Println("SwapFactory:", "Closing Idle Resources", n)
+	return p.fill(ctx, uint(n))
+}
+
+// Drain 关闭当前所有的空闲资源并返回关闭的数量，池仍然可以使用
+// 之后的Acquire会创建新资源，设置了MinIdle时后台goroutine会重新补足空闲资源
+func (p *Pool[T]) Drain() int {
+	p.m.Lock()
+	p.unpark()
+	n := p.destroyIdle()
+	p.unlock()
+	p.logger.Println("Drain:", "Closing Idle Resources", n)
+	return n
+}
+
+// destroyIdle 销毁所有空闲资源并返回数量，调用者需持有p.m，并用p.unlock解锁
+func (p *Pool[T]) destroyIdle() int {
+	idle := p.idle.Drain()
+	for _, e := range idle {
+		p.retire(e)
+	}
+	return len(idle)
+}
+
+// expired 判断资源是否超过了最长使用时间
+func (p *Pool[T]) expired(e *entry[T], now time.Time) bool {
+	return p.maxLifetime > 0 && now.Sub(e.createdAt) > jittered(p.maxLifetime, e.jitter)
+}
+
+// newJitter 为新资源选择idleTimeout和maxLifetime缩短的比例
+func (p *Pool[T]) newJitter() float64 {
+	if p.lifetimeJitter == 0 {
+		return 0
+	}
+	return p.lifetimeJitter * rand.Float64()
+}
+
+// jittered 返回按比例j缩短后的d
+func jittered(d time.Duration, j float64) time.Duration {
+	return d - time.Duration(float64(d)*j)
+}
+
+// createResult 是一次factory调用的结果
+type createResult[T any] struct {
+	r   T
+	err error
+}
+
+// releaseCreated 等待一个已经无人等待的创建结束，并把结果放回池里
+func (p *Pool[T]) releaseCreated(created <-chan createResult[T]) {
+	res := <-created
+	if res.err == nil {
+		p.Release(res.r)
 	}
 }
 
 // Release 将一个使用后的资源放回池里
-func (p *Pool) Release(r io.Closer) {
+// 有Acquire在排队时资源被直接交给排在最前面的等待者，不经过空闲资源，
+// 空闲资源已满时按OverflowPolicy处理，默认关闭放回的资源
+// 资源已经放回过时返回ErrDoubleRelease，不是从本池获取的资源返回ErrForeignResource，
+// 这两种情况下池的状态不会被改变
+func (p *Pool[T]) Release(r T) error {
+	return p.ReleaseContext(context.Background(), r)
+}
+
+// ReleaseContext 与Release相同，ctx会传给WithValidatorContext设置的验证函数和WithResetFuncContext设置的重置函数
+func (p *Pool[T]) ReleaseContext(ctx context.Context, r T) error {
+	pooled := false
+	if p.tracer != nil {
+		ctx = p.tracer.TraceReleaseStart(ctx)
+		defer func() { p.tracer.TraceReleaseEnd(ctx, pooled) }()
+	}
+	p.checkZero(r)
+	if p.maxSharers > 1 && p.unshare(r) {
+		return nil
+	}
+	// 没有需要在锁外执行的检查和钩子时只加一次锁，减少高并发时对锁的争用
+	fast := p.hookless(r)
+	if fast && p.lf != nil && p.park(r) {
+		return nil
+	}
+	var overdue *CheckoutViolation
+	if !fast {
+		// 先检查一次，避免对不属于调用者的资源执行钩子
+		p.m.Lock()
+		err := p.checkOwned(r)
+		if err == nil {
+			overdue = p.checkin(r)
+		}
+		p.m.Unlock()
+		if err != nil {
+			p.logger.Println("Release", err)
+			return p.misuse(p.wrapErr(err))
+		}
+		if overdue != nil {
+			overdue.Discarded = p.discardOverdue
+			p.reportOverdue(overdue)
+		}
+	}
+
+	gone := p.closedOutside(r)
+	valid := !gone && (!p.validateOnRelease || p.validator == nil || p.validator(ctx, r))
+	invalid := !valid && !gone
+	if invalid {
+		p.unhealthy(r)
+	}
+	if overdue != nil && p.discardOverdue {
+		valid = false
+	}
+	if valid && p.onRelease != nil {
+		if err := p.onRelease(r, p.collectStats(false)); err != nil {
+			p.logger.Println("Release", "OnRelease Failed:", err)
+			valid = false
+		}
+	}
+	if valid {
+		if err := p.resetResource(ctx, r); err != nil {
+			p.logger.Println("Release", "Reset Failed:", err)
+			valid = false
+		}
+	}
+	if valid && p.sanitize != nil {
+		if err := p.sanitize(r); err != nil {
+			p.logger.Println("Release", "Sanitize Failed:", err)
+			p.emit(Event{Type: SanitizeFailed, Time: p.clock.Now(), Err: err})
+			valid = false
+		}
+	}
+	var score float64
+	var scored bool
+	if valid && !fast {
+		score, scored = p.scoreOf(r)
+	}
+
 	// 保证本操作和Close操作的安全
+	p.m.Lock()
+	defer p.unlock()
+	// 并发的重复Release可能都通过了上面的检查
+	if err := p.checkOwned(r); err != nil {
+		p.logger.Println("Release", err)
+		return p.misuse(p.wrapErr(err))
+	}
+	if fast {
+		// 没有设置MaxCheckoutDuration，只记录持有的时间
+		p.checkin(r)
+	}
+	e, ok := p.inUse.Get(r)
+	if !ok {
+		// 资源已经在CloseContext超时时被强制关闭
+		return nil
+	}
+	p.untrack(e)
+	now := p.clock.Now()
+	if gone {
+		p.forget(e)
+		return nil
+	}
+	if !valid || e.broken {
+		p.logger.Println("Release", "Invalid Resource")
+		if invalid && !e.broken && p.quarantineN > 0 && !p.closed && p.shutdown == nil {
+			p.quarantine(e)
+			return nil
+		}
+		p.retire(e)
+		return nil
+	}
+	if p.closed || p.shutdown != nil {
+		p.retire(e)
+		return nil
+	}
+	if p.expired(e, now) {
+		p.logger.Println("Release", "Expired")
+		p.stats.lifetimeClosed.Add(1)
+		p.retire(e)
+		return nil
+	}
+	if e.gen != p.generation.Load() {
+		p.logger.Println("Release", "Invalidated")
+		p.retire(e)
+		return nil
+	}
+	if p.balancer != nil && p.balancer.draining(r) {
+		p.logger.Println("Release", "Draining")
+		p.retire(e)
+		return nil
+	}
+	if p.maxUses > 0 && e.uses >= p.maxUses {
+		p.logger.Println("Release", "Max Uses Reached")
+		p.retire(e)
+		return nil
+	}
+	keepOverflow := false
+	switch {
+	case p.maxTotal == 0 || p.numOpen <= p.maxTotal:
+		// 其它资源关闭后，溢出资源成为普通资源
+		e.overflow = false
+	case p.numOpen > p.maxTotal+p.overflowN || e.overflow && p.overflowTTL == 0:
+		// MaxTotal被调小后超出的资源，以及不保留的溢出资源，在放回时关闭
+		p.logger.Println("Release", "Over MaxTotal")
+		p.retire(e)
+		return nil
+	case e.overflow:
+		// 溢出资源保留overflowTTL时间，不受maxIdle限制
+		keepOverflow = true
+	}
+	p.setScore(e, score, scored)
+	if p.handoff(e) {
+		pooled = true
+		p.logger.Println("Release", "Handoff")
+		return nil
+	}
+	if !keepOverflow && uint(p.idleCount()) >= p.maxIdle {
+		switch p.overflow {
+		case BlockOnOverflow:
+			p.logger.Println("Release", "Waiting")
+			for uint(p.idle.Len()) >= p.maxIdle && !p.closed {
+				p.overflowWaiters++
+				notify := p.notifier()
+				p.unlock()
+				<-notify
+				p.m.Lock()
+				p.overflowWaiters--
+			}
+			if p.closed {
+				p.retire(e)
+				return nil
+			}
+		case PanicOnOverflow:
+			p.retire(e)
+			panic(fmt.Sprintf("pool: Release overflows MaxIdle %d", p.maxIdle))
+		default:
+			p.logger.Println("Release", "Closing")
+			p.retire(e)
+			return nil
+		}
+	}
+	e.returnedAt = p.clock.Now()
+	p.idle.Put(e)
+	pooled = true
+	p.fitIdleBytes()
+	p.broadcast()
+	if p.logging {
+		p.logger.Println("Release", "In Queue")
+	}
+	return nil
+}
+
+// Resetter 是可以在两次使用之间重置状态的资源
+// 没有设置WithResetFunc时，Release会对实现了Resetter的资源调用Reset
+type Resetter interface {
+	Reset() error
+}
+
+// hookless 判断Release放回r时是否不需要在加锁前执行任何检查和钩子
+func (p *Pool[T]) hookless(r T) bool {
+	if p.maxCheckout > 0 || p.onRelease != nil || p.reset != nil || p.sanitize != nil || p.score != nil ||
+		p.validateOnRelease && p.validator != nil {
+		return false
+	}
+	if _, ok := any(r).(Scorer); ok {
+		return false
+	}
+	_, ok := any(r).(Resetter)
+	return !ok
+}
+
+// resetResource 在资源放回池里之前重置它
+func (p *Pool[T]) resetResource(ctx context.Context, r T) error {
+	if p.reset != nil {
+		return p.reset(ctx, r)
+	}
+	if rs, ok := any(r).(Resetter); ok {
+		return resetValue(p.logger, rs)
+	}
+	return nil
+}
+
+// resetValue 调用rs.Reset，其中的panic被转换为错误
+func resetValue(logger Logger, rs Resetter) (err error) {
+	defer catch(logger, "Reset", &err)
+	return rs.Reset()
+}
+
+// With 获取一个资源并用它调用fn，fn返回后资源总会被放回池里
+// fn返回错误或panic时资源会被销毁而不是放回池里
+func (p *Pool[T]) With(ctx context.Context, fn func(r T) error) (err error) {
+	r, err := p.AcquireContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			p.Discard(r)
+			panic(v)
+		}
+		if err != nil {
+			p.Discard(r)
+			return
+		}
+		p.Release(r)
+	}()
+	return fn(r)
+}
+
+// Discard 销毁一个使用中的资源，用于调用者发现资源已经损坏、不能再放回池里的情况
+// 设置了WithReplaceDiscarded时，会在后台创建新资源把空闲资源补足到MinIdle
+func (p *Pool[T]) Discard(r T) error {
+	p.checkZero(r)
+	p.m.Lock()
+	if err := p.checkOwned(r); err != nil {
+		p.m.Unlock()
+		p.logger.Println("Discard", err)
+		return p.misuse(p.wrapErr(err))
+	}
+	e, ok := p.inUse.Get(r)
+	if !ok {
+		// 资源已经在CloseContext超时时被强制关闭
+		p.m.Unlock()
+		return nil
+	}
+	if e.refs > 1 {
+		// 还有其它借用者，最后一个借用者放回时再销毁
+		e.refs--
+		e.broken = true
+		p.m.Unlock()
+		p.logger.Println("Discard", "Shared Resource")
+		return nil
+	}
+	overdue := p.checkin(r)
+	p.untrack(e)
+	p.retire(e)
+	p.unlock()
+	p.logger.Println("Discard", "Closing")
+	if overdue != nil {
+		overdue.Discarded = true
+		p.reportOverdue(overdue)
+	}
+
+	if p.replaceDiscarded {
+		p.replenish()
+	}
+	return nil
+}
+
+// checkOwned 检查r是否是本池借出的资源，调用者需要持有锁
+// 池关闭后无法再区分被强制关闭的资源，此时总是返回nil
+func (p *Pool[T]) checkOwned(r T) error {
+	e, ok := p.inUse.Get(r)
+	if ok && !p.claim(e) {
+		// 已经不加锁地放回
+		return ErrDoubleRelease
+	}
+	if ok && !e.lingering || p.closed {
+		return nil
+	} else if ok {
+		return ErrDoubleRelease
+	}
+	for i := 0; i < p.idle.Len(); i++ {
+		e := p.idle.At(i)
+		if identity.Same(e.r, r) {
+			return ErrDoubleRelease
+		}
+	}
+	return ErrForeignResource
+}
+
+// Close 会让资源池停止工作，关闭所有空闲资源，并等待使用中的资源被放回后关闭它们
+// Close开始之后Acquire返回ErrPoolClosed，正在进行的Acquire取得的资源也会被销毁而不是返回给调用者
+// Close可以重复和并发地调用，返回关闭资源时closer返回的所有错误的合并
+func (p *Pool[T]) Close() error {
+	return p.CloseContext(context.Background())
+}
+
+// CloseContext 与Close相同，但最多等待到ctx结束
+// ctx结束时仍未放回的资源会被强制关闭，返回的错误满足errors.Is(err, ctx.Err())
+func (p *Pool[T]) CloseContext(ctx context.Context) (err error) {
+	start := p.clock.Now()
+	p.m.Lock()
+	if !p.closed {
+		// 只有第一次关闭时发出PoolClosed事件
+		var hints WarmHints
+		if p.stateStore != nil {
+			hints = p.hints()
+		}
+		defer func() {
+			if p.stateStore != nil {
+				p.saveHints(p.stateStore, hints)
+			}
+			now := p.clock.Now()
+			p.emit(Event{Type: PoolClosed, Time: now, Duration: now.Sub(start), Err: err})
+		}()
+		p.closed = true
+		close(p.done)
+		p.slowPath(true)
+		// 最早创建的资源最先关闭
+		idle := p.idle.Drain()
+		byAge(idle)
+		for _, e := range idle {
+			p.retire(e)
+		}
+		p.broadcast()
+	}
+	for p.numOpen > 0 {
+		notify := p.notifier()
+		p.unlock()
+		p.logger.Println("Close:", "Waiting")
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			p.m.Lock()
+			p.logger.Println("Close:", "Force Closing")
+			inUse := make([]*entry[T], 0, len(p.inUse))
+			for _, e := range p.inUse {
+				inUse = append(inUse, e)
+			}
+			byAge(inUse)
+			for _, e := range inUse {
+				if !p.claim(e) {
+					// 刚被不加锁地放回，放回它的Release会把它交给加锁的路径关闭
+					continue
+				}
+				p.untrack(e)
+				p.stopLingering(e)
+				p.retire(e)
+			}
+			p.unlock()
+			return p.closeError(ctx.Err())
+		}
+		p.m.Lock()
+	}
+	p.unlock()
+	return p.closeError(nil)
+}
+
+// BeginShutdown 开始逐渐关闭池，用于滚动部署时平稳地停止使用池
+// 之后不再创建新资源，Acquire只使用剩余的空闲资源，没有时返回ErrPoolShuttingDown，放回的资源被关闭，
+// 所有资源都关闭后池随之关闭，返回的通道在这时被关闭，重复调用返回同一个通道
+// 期限到达时可以调用CloseContext立即结束，剩余的空闲资源会被关闭
+func (p *Pool[T]) BeginShutdown() <-chan struct{} {
+	p.m.Lock()
+	defer p.unlock()
+	if p.shutdown == nil {
+		p.shutdown = make(chan struct{})
+		p.logger.Println("BeginShutdown")
+		p.slowPath(true)
+		// 唤醒所有等待者，让它们使用空闲资源或返回错误
+		p.wakeWaiters()
+		go p.finishShutdown(p.shutdown)
+	}
+	return p.shutdown
+}
+
+// finishShutdown 等待所有资源被关闭或池被关闭，然后关闭池和done
+func (p *Pool[T]) finishShutdown(done chan struct{}) {
+	p.m.Lock()
+	for p.numOpen > 0 && !p.closed {
+		notify := p.notifier()
+		p.m.Unlock()
+		<-notify
+		p.m.Lock()
+	}
+	p.m.Unlock()
+	p.Close()
+	close(done)
+}
+
+// closeError 返回err与关闭池期间closer返回的错误的合并
+func (p *Pool[T]) closeError(err error) error {
 	p.m.Lock()
 	defer p.m.Unlock()
-	if p.closed {
-		r.Close()
+	if len(p.closeErrs) == 0 {
+		return p.wrapErr(err)
+	}
+	return p.wrapErr(errors.Join(append([]error{err}, p.closeErrs...)...))
+}
+
+// Shutdown 与CloseContext相同
+func (p *Pool[T]) Shutdown(ctx context.Context) error {
+	return p.CloseContext(ctx)
+}
+
+// popIdle 按ReuseStrategy取出一个空闲资源并记为使用中，没有空闲资源时返回nil
+// stack是开启泄漏检测时获取资源的调用栈，调用者需持有p.m
+func (p *Pool[T]) popIdle(stack []byte) *entry[T] {
+	if p.idle.Len() == 0 {
+		return nil
+	}
+	i := 0
+	switch {
+	case p.selection != nil:
+		i = p.selectIdle()
+	case p.scoring:
+		i = p.bestScored()
+	case p.reuse == LIFO:
+		i = p.idle.Len() - 1
+	}
+	return p.checkout(i, stack)
+}
+
+// checkout 取出第i个空闲资源并记为使用中，调用者需持有p.m
+func (p *Pool[T]) checkout(i int, stack []byte) *entry[T] {
+	return p.lend(p.takeIdle(i), stack)
+}
+
+// lend 把e记为借出的资源，调用者需持有p.m
+func (p *Pool[T]) lend(e *entry[T], stack []byte) *entry[T] {
+	p.track(e)
+	p.notePeak()
+	e.acquiredAt = p.clock.Now()
+	e.uses++
+	e.refs = 1
+	e.stack = stack
+	e.consumer = ""
+	e.sized = false
+	if e.profKey != nil {
+		p.unprofile(e)
+	}
+	e.leakReported = false
+	if p.overflowWaiters > 0 {
+		p.broadcast()
+	}
+	return e
+}
+
+// abandon 在池已经开始关闭时销毁刚取得的资源r并返回true，
+// 这样Close开始之后，即使是正在创建或检查资源的Acquire也不会再返回资源
+func (p *Pool[T]) abandon(r T) bool {
+	select {
+	case <-p.done:
+	default:
+		return false
+	}
+	p.logger.Println("Acquire:", "Pool Closed")
+	p.Discard(r)
+	return true
+}
+
+// abandonAll 与abandon相同，池已经开始关闭时销毁AcquireN刚取得的所有资源rs并返回true
+func (p *Pool[T]) abandonAll(rs []T) bool {
+	select {
+	case <-p.done:
+	default:
+		return false
+	}
+	p.logger.Println("AcquireN:", "Pool Closed")
+	for _, r := range rs {
+		p.Discard(r)
+	}
+	return true
+}
+
+// handoff 把刚放回的资源e直接交给排在最前面的等待者，没有可以直接接收资源的等待者时返回false
+// 在持有锁时完成交接，等待者同时超时也不会丢失资源，调用者需持有p.m
+func (p *Pool[T]) handoff(e *entry[T]) bool {
+	if len(p.waiters) == 0 || p.wakeups > 0 || p.paused {
+		// 已被唤醒的等待者会来取空闲资源，先把资源留给它们
+		return false
+	}
+	w := p.waiters[0]
+	if !w.direct || p.overReserve(w.prio, 1) {
+		return false
+	}
+	p.waiters[0] = waiter[T]{}
+	p.waiters = p.waiters[1:]
+	p.served(w)
+	w.ch <- p.lend(e, w.stack)
+	return true
+}
+
+// handBack 把交给已经超时的等待者的资源e交给下一个等待者或放回空闲资源中，调用者需持有p.m
+func (p *Pool[T]) handBack(e *entry[T]) {
+	p.untrack(e)
+	e.uses--
+	p.putBack(e)
+}
+
+// putBack 把不再使用的资源e交给排在最前面的等待者或放回空闲资源中，不能放回时销毁它，调用者需持有p.m
+func (p *Pool[T]) putBack(e *entry[T]) {
+	if p.closed || p.shutdown != nil || e.gen != p.generation.Load() {
+		p.retire(e)
+		return
+	}
+	if p.handoff(e) {
+		return
+	}
+	if uint(p.idleCount()) >= p.maxIdle && !e.overflow {
+		p.retire(e)
 		return
 	}
+	e.returnedAt = p.clock.Now()
+	p.idle.Put(e)
+	p.fitIdleBytes()
+	p.broadcast()
+}
+
+// popIdleIf 在ok为true时调用popIdle，否则返回nil
+func (p *Pool[T]) popIdleIf(ok bool, stack []byte) *entry[T] {
+	if !ok {
+		return nil
+	}
+	return p.popIdle(stack)
+}
+
+// evictIdle 关闭最早放回池中的空闲资源，没有空闲资源时返回false
+func (p *Pool[T]) evictIdle() bool {
+	p.m.Lock()
+	defer p.unlock()
+	if p.idle.Len() == 0 {
+		return false
+	}
+	p.retire(p.takeIdle(0))
+	return true
+}
+
+// takeIdle 从空闲资源中移除并返回第i个，调用者需持有p.m
+func (p *Pool[T]) takeIdle(i int) *entry[T] {
+	return p.idle.Get(i)
+}
+
+// retire 记录e的存活时间和使用时间，然后像destroy一样销毁它，调用者需持有p.m，并用p.unlock解锁
+func (p *Pool[T]) retire(e *entry[T]) {
+	p.usage.add(p.clock.Now().Sub(e.createdAt), e.busy)
+	p.totalWeight -= e.weight
+	p.destroy(e.r)
+}
+
+// destroy 释放一个资源占用的容量，并把资源记下来等p.unlock解锁后关闭
+// 调用者需持有p.m，并用p.unlock解锁
+func (p *Pool[T]) destroy(r T) {
+	p.pendingClose = append(p.pendingClose, r)
+	p.releaseSlot()
+	if p.eagerReplenish {
+		p.destroyed = true
+	}
+}
+
+// unlock 释放p.m，然后关闭持有锁期间被destroy的资源，
+// 避免耗时的Close和钩子函数在持有锁时执行
+func (p *Pool[T]) unlock() {
+	pending := p.pendingClose
+	p.pendingClose = nil
+	forgotten := p.forgotten
+	p.forgotten = nil
+	destroyed := p.destroyed
+	p.destroyed = false
+	closed := p.closed
+	p.m.Unlock()
+	errs := p.closeResources(pending)
+	if closed && len(errs) > 0 {
+		// 关闭池期间的错误由CloseContext返回
+		p.m.Lock()
+		p.closeErrs = append(p.closeErrs, errs...)
+		p.m.Unlock()
+	}
+	for _, e := range forgotten {
+		p.dropResource(e)
+	}
+	if destroyed {
+		p.replenish()
+	}
+}
+
+// closeResources 关闭rs中的资源，设置了CloseConcurrency时并发地关闭，返回closer的错误
+func (p *Pool[T]) closeResources(rs []T) []error {
+	if p.closeParallel > 1 && len(rs) > 1 {
+		return p.closeConcurrently(rs)
+	}
+	var errs []error
+	for _, r := range rs {
+		if err := p.closeResource(r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// closeConcurrently 用至多CloseConcurrency个goroutine关闭rs中的资源，
+// 与closeResources分开，让每次解锁都会经过的串行路径不必在堆上分配errs
+func (p *Pool[T]) closeConcurrently(rs []T) []error {
+	var errs []error
+	var m sync.Mutex
+	var g errgroup.Group
+	g.SetLimit(int(p.closeParallel))
+	for _, r := range rs {
+		r := r
+		g.Go(func() error {
+			if err := p.closeResource(r); err != nil {
+				m.Lock()
+				errs = append(errs, err)
+				m.Unlock()
+			}
+			return nil
+		})
+	}
+	g.Wait()
+	return errs
+}
+
+// closeResource 使用closer关闭一个资源，返回closer的错误
+func (p *Pool[T]) closeResource(r T) (err error) {
+	p.stats.closed.Add(1)
+	if p.onEvent != nil {
+		defer func() { p.emit(Event{Type: ResourceDestroyed, Time: p.clock.Now(), Err: err}) }()
+	}
+	if p.onClose != nil {
+		p.onClose(r, p.collectStats(false))
+	}
+	if p.closer != nil {
+		if err = p.callCloser(r); err != nil {
+			p.logger.Println("Close:", "Closer Failed:", err)
+			p.reportError(err, SourceClose)
+		}
+	}
+	if p.failover != nil {
+		p.failover.forget(r)
+	}
+	if p.balancer != nil {
+		p.balancer.closed(r)
+	}
+	return err
+}
+
+// callCloser 调用closer，设置了CloseTimeout时在另一个goroutine中调用，超时后返回ErrCloseTimeout
+func (p *Pool[T]) callCloser(r T) error {
+	if p.closeTimeout <= 0 {
+		return p.closer(r)
+	}
+	done := make(chan error, 1)
+	go func() { done <- p.closer(r) }()
+	t := p.clock.NewTimer(p.closeTimeout)
+	defer t.Stop()
 	select {
-	case p.resources <- r:
-		log.Println("Release", "In Queue")
+	case err := <-done:
+		return err
+	case <-t.C():
+		return ErrCloseTimeout
+	}
+}
+
+// closeIfCloser 是未设置closer时使用的closer，资源实现了io.Closer时调用它的Close
+func closeIfCloser[T any](r T) error {
+	if c, ok := any(r).(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// byAge 按创建时间从早到晚排序es
+func byAge[T any](es []*entry[T]) {
+	sort.SliceStable(es, func(i, j int) bool { return es[i].createdAt.Before(es[j].createdAt) })
+}
+
+// releaseSlot 释放一个资源占用的容量并唤醒等待者，调用者需持有p.m
+func (p *Pool[T]) releaseSlot() {
+	p.numOpen--
+	p.broadcast()
+}
+
+// broadcast 唤醒所有等待资源或容量的goroutine，调用者需持有p.m
+func (p *Pool[T]) broadcast() {
+	// 没有goroutine取过notify时不需要关闭它，也不必为下一次创建新的channel
+	if p.notify != nil {
+		close(p.notify)
+		p.notify = nil
+	}
+	p.wakeWaiters()
+}
+
+// notifier 返回下一次broadcast时关闭的channel，调用者需持有p.m
+func (p *Pool[T]) notifier() chan struct{} {
+	if p.notify == nil {
+		p.notify = make(chan struct{})
+	}
+	return p.notify
+}
+
+// wakeWaiters 按排队的顺序唤醒等待的Acquire，每个空闲资源或剩余容量只留给一个等待者，
+// 池关闭或切换到非阻塞模式时唤醒所有等待者，调用者需持有p.m
+func (p *Pool[T]) wakeWaiters() {
+	for len(p.waiters) > 0 {
+		w := p.waiters[0]
+		if p.paused && !p.closed {
+			return
+		}
+		if !p.closed && p.shutdown == nil && !p.nonBlocking && (!p.fits(w) || p.overReserve(w.prio, w.n)) {
+			return
+		}
+		p.waiters[0] = waiter[T]{}
+		p.waiters = p.waiters[1:]
+		p.served(w)
+		p.wakeups += w.n
+		w.ch <- nil
+	}
+}
+
+// fits 判断空闲资源、剩余容量和可以共享的次数是否足够再唤醒等待者w，
+// AcquireN的等待者不共享资源，只计入空闲资源和剩余容量，
+// AcquireWithMinWeight的等待者只在有足够重的空闲资源或可以腾出容量时唤醒，调用者需持有p.m
+func (p *Pool[T]) fits(w waiter[T]) bool {
+	switch {
+	case w.weight > 0:
+		return p.hasWeighted(w.weight) || p.canMakeRoom()
+	case w.direct:
+		return p.available(p.wakeups + w.n)
 	default:
-		log.Println("Release", "Closing")
-		r.Close()
+		return p.batchAvailable(p.wakeups + w.n)
+	}
+}
+
+// available 判断空闲资源数、剩余容量与可以共享的次数之和是否至少为n，调用者需持有p.m
+func (p *Pool[T]) available(n uint) bool {
+	if p.maxTotal == 0 && !p.weightFull() || uint(p.idle.Len()) >= n {
+		return true
 	}
+	free := uint(p.idle.Len()) + p.shareable()
+	if p.weightFull() {
+		return free >= n
+	}
+	if p.numOpen < p.maxTotal+p.overflowN {
+		free += p.maxTotal + p.overflowN - p.numOpen
+	}
+	return free >= n
 }
 
-// Close 会让资源池停止工作，并关闭所有的现有的资源
-func (p *Pool) Close() {
+// queueFull 判断等待队列是否已经达到maxWaiters，达到时计入Stats.QueueFullCount，调用者需持有p.m
+func (p *Pool[T]) queueFull() bool {
+	if p.maxWaiters == 0 || uint(len(p.waiters)) < p.maxWaiters {
+		return false
+	}
+	p.stats.queueFull.Add(1)
+	return true
+}
+
+// Waiting 返回正在排队等待资源的Acquire数
+func (p *Pool[T]) Waiting() int {
 	p.m.Lock()
 	defer p.m.Unlock()
-	if p.closed {
-		return
+	return len(p.waiters)
+}
+
+// removeWaiter 把w从等待队列中移除，w已经被唤醒时返回false，调用者需持有p.m
+func (p *Pool[T]) removeWaiter(w chan *entry[T]) bool {
+	for i, c := range p.waiters {
+		if c.ch == w {
+			copy(p.waiters[i:], p.waiters[i+1:])
+			p.waiters[len(p.waiters)-1] = waiter[T]{}
+			p.waiters = p.waiters[:len(p.waiters)-1]
+			return true
+		}
 	}
-	p.closed = true
+	return false
+}
 
-	close(p.resources)
+// canShare 判断使用中的资源e是否还可以再借给一个Acquire，调用者需持有p.m
+func (p *Pool[T]) canShare(e *entry[T], now time.Time) bool {
+	return e.refs > 0 && e.refs < p.maxSharers && !e.broken && !e.overflow && !e.lingering && !e.leased &&
+		e.gen == p.generation.Load() && !p.expired(e, now) && (p.maxUses == 0 || e.uses < p.maxUses)
+}
+
+// share 在共享模式下把借用者最少的、还可以共享的使用中资源再借出一次，ok为false或没有这样的资源时返回nil
+// 调用者需持有p.m
+func (p *Pool[T]) share(ok bool) *entry[T] {
+	if !ok || p.maxSharers <= 1 {
+		return nil
+	}
+	now := p.clock.Now()
+	var best *entry[T]
+	for _, e := range p.inUse {
+		if p.canShare(e, now) && (best == nil || e.refs < best.refs) {
+			best = e
+		}
+	}
+	if best != nil {
+		best.refs++
+		best.uses++
+	}
+	return best
+}
 
-	for r := range p.resources {
-		r.Close()
+// shareable 返回使用中的资源还可以共享的总次数，调用者需持有p.m
+func (p *Pool[T]) shareable() uint {
+	if p.maxSharers <= 1 {
+		return 0
+	}
+	now := p.clock.Now()
+	var n uint
+	for _, e := range p.inUse {
+		if p.canShare(e, now) {
+			n += p.maxSharers - e.refs
+		}
+	}
+	return n
+}
+
+// unshare 在r还有其它借用者时只减少它的借用者数并返回true，最后一个借用者放回时返回false
+func (p *Pool[T]) unshare(r T) bool {
+	p.m.Lock()
+	defer p.m.Unlock()
+	e, ok := p.inUse.Get(r)
+	if !ok || e.refs <= 1 {
+		return false
 	}
+	e.refs--
+	p.broadcast()
+	p.logger.Println("Release", "Shared Resource")
+	return true
 }
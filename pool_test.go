@@ -0,0 +1,113 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestAcquireReleaseAllocs 检查没有设置日志、钩子和跟踪时，从空闲资源获取并放回不分配内存
+func TestAcquireReleaseAllocs(t *testing.T) {
+	type resource struct{ n int }
+	p, err := New(func() (*resource, error) { return &resource{}, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	r, err := p.Acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Release(r)
+
+	ctx := context.Background()
+	use := func(r *resource) error { r.n++; return nil }
+	tests := []struct {
+		name string
+		run  func()
+	}{
+		{"Acquire", func() {
+			r, _ := p.Acquire()
+			p.Release(r)
+		}},
+		{"AcquireContext", func() {
+			r, _ := p.AcquireContext(ctx)
+			p.Release(r)
+		}},
+		{"TryAcquire", func() {
+			r, _ := p.TryAcquire()
+			p.Release(r)
+		}},
+		{"AcquireWithPriority", func() {
+			r, _ := p.AcquireWithPriority(ctx, PriorityHigh)
+			p.Release(r)
+		}},
+		{"With", func() { p.With(ctx, use) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run() // 第一次执行可能初始化池的内部状态
+			if n := testing.AllocsPerRun(1000, tt.run); n > 0 {
+				t.Errorf("%.1f allocs/op, want 0", n)
+			}
+		})
+	}
+}
+
+// TestSliceResources 检查不可比较的切片资源用底层数组识别
+func TestSliceResources(t *testing.T) {
+	p, err := New(func() ([]byte, error) { return make([]byte, 0, 64), nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	a, err := p.Acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := p.Acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a = append(a, "hello"...) // 没有超过容量，底层数组不变
+	tests := []struct {
+		name string
+		r    []byte
+		want error
+	}{
+		{"resliced", a, nil},
+		{"second", b, nil},
+		{"double release", a, ErrDoubleRelease},
+		{"foreign", make([]byte, 0, 64), ErrForeignResource},
+	}
+	for _, tt := range tests {
+		if err := p.Release(tt.r); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Release = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	if s := p.Stats(); s.Idle != 2 || s.InUse != 0 {
+		t.Errorf("Idle = %d, InUse = %d, want 2, 0", s.Idle, s.InUse)
+	}
+}
+
+// TestUnidentifiableResources 检查无法区分的资源类型在New或创建资源时被拒绝
+func TestUnidentifiableResources(t *testing.T) {
+	type holder struct{ b []byte }
+	if _, err := New(func() (holder, error) { return holder{}, nil }); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("New with a struct holding a slice = %v, want ErrInvalidConfig", err)
+	}
+
+	var closed int
+	p, err := New(func() (any, error) { return holder{}, nil },
+		WithCloser(func(any) error { closed++; return nil }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if _, err := p.Acquire(); !errors.Is(err, ErrUnidentifiable) {
+		t.Errorf("Acquire = %v, want ErrUnidentifiable", err)
+	}
+	if closed != 1 {
+		t.Errorf("closer called %d times, want 1", closed)
+	}
+}
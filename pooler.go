@@ -0,0 +1,50 @@
+package pool
+
+import "context"
+
+// Pooler 是资源池的公共接口，*Pool、*ShardedPool、*Composite、*Singleton和KeyedPool.ForKey返回的视图都实现了它，
+// pooltest.FakePool也实现了它，使用者可以面向Pooler编写代码并在测试时替换实现
+type Pooler[T any] interface {
+	Acquire() (T, error)
+	AcquireContext(ctx context.Context) (T, error)
+	Release(r T) error
+	Discard(r T) error
+	Stats() Stats
+	Close() error
+}
+
+var (
+	_ Pooler[int] = (*Pool[int])(nil)
+	_ Pooler[int] = (*ShardedPool[int])(nil)
+	_ Pooler[int] = keyView[string, int]{}
+	_ Pooler[int] = (*Composite[int])(nil)
+	_ Pooler[int] = (*Singleton[int])(nil)
+)
+
+// ForKey 返回把KeyedPool中key对应的子池当作Pooler使用的视图
+// 视图的Close不做任何事情，KeyedPool需要用它自己的Close关闭
+func (kp *KeyedPool[K, T]) ForKey(key K) Pooler[T] {
+	return keyView[K, T]{kp: kp, key: key}
+}
+
+// keyView 是ForKey返回的视图
+type keyView[K comparable, T any] struct {
+	kp  *KeyedPool[K, T]
+	key K
+}
+
+func (v keyView[K, T]) Acquire() (T, error) {
+	return v.kp.Acquire(context.Background(), v.key)
+}
+
+func (v keyView[K, T]) AcquireContext(ctx context.Context) (T, error) {
+	return v.kp.Acquire(ctx, v.key)
+}
+
+func (v keyView[K, T]) Release(r T) error { return v.kp.Release(v.key, r) }
+
+func (v keyView[K, T]) Discard(r T) error { return v.kp.Discard(v.key, r) }
+
+func (v keyView[K, T]) Stats() Stats { return v.kp.Stats(v.key) }
+
+func (v keyView[K, T]) Close() error { return nil }
@@ -0,0 +1,168 @@
+// Package poolotel 使用OpenTelemetry为资源池提供追踪和指标
+//
+//	t, err := poolotel.NewTracer("db")
+//	p, err := pool.New(factory, pool.WithTracer(t))
+package poolotel
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/lazysheep666/pool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentation 是创建Tracer和Meter时使用的名字
+const instrumentation = "github.com/lazysheep666/pool/poolotel"
+
+// 指标和span上使用的属性
+var (
+	poolNameKey = attribute.Key("pool.name")
+	outcomeKey  = attribute.Key("pool.outcome")
+	pooledKey   = attribute.Key("pool.pooled")
+)
+
+// Tracer 实现了pool.Tracer，为获取、创建和放回操作创建span并记录指标
+type Tracer struct {
+	tracer trace.Tracer
+	attrs  []attribute.KeyValue // pool.name和WithLabels设置的属性
+
+	acquireDuration metric.Float64Histogram
+	createDuration  metric.Float64Histogram
+	releases        metric.Int64Counter
+}
+
+// Option 用于配置NewTracer创建的Tracer
+type Option func(*options)
+
+type options struct {
+	tp     trace.TracerProvider
+	mp     metric.MeterProvider
+	labels map[string]string
+}
+
+// WithTracerProvider 设置创建span使用的TracerProvider，默认使用全局的TracerProvider
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *options) { o.tp = tp }
+}
+
+// WithMeterProvider 设置记录指标使用的MeterProvider，默认使用全局的MeterProvider
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(o *options) { o.mp = mp }
+}
+
+// WithLabels 设置附加在所有span和指标上的属性，通常与资源池的pool.WithLabels相同，
+// 属性名为pool.label.<key>
+func WithLabels(labels map[string]string) Option {
+	return func(o *options) { o.labels = labels }
+}
+
+// NewTracer 创建一个Tracer，name作为pool.name属性附加在所有span和指标上
+func NewTracer(name string, opts ...Option) (*Tracer, error) {
+	o := options{tp: otel.GetTracerProvider(), mp: otel.GetMeterProvider()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	meter := o.mp.Meter(instrumentation)
+	t := &Tracer{
+		tracer: o.tp.Tracer(instrumentation),
+		attrs:  []attribute.KeyValue{poolNameKey.String(name)},
+	}
+	keys := make([]string, 0, len(o.labels))
+	for k := range o.labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		t.attrs = append(t.attrs, attribute.String("pool.label."+k, o.labels[k]))
+	}
+	var err error
+	if t.acquireDuration, err = meter.Float64Histogram("pool.acquire.duration",
+		metric.WithDescription("Duration of pool acquisitions."), metric.WithUnit("s")); err != nil {
+		return nil, err
+	}
+	if t.createDuration, err = meter.Float64Histogram("pool.create.duration",
+		metric.WithDescription("Duration of resource creation."), metric.WithUnit("s")); err != nil {
+		return nil, err
+	}
+	if t.releases, err = meter.Int64Counter("pool.releases",
+		metric.WithDescription("Number of resources released to the pool.")); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// attributes 返回附加了t.attrs和kv的指标属性
+func (t *Tracer) attributes(kv ...attribute.KeyValue) metric.MeasurementOption {
+	attrs := make([]attribute.KeyValue, 0, len(t.attrs)+len(kv))
+	attrs = append(attrs, t.attrs...)
+	return metric.WithAttributes(append(attrs, kv...)...)
+}
+
+// startKey 是保存在ctx中的操作开始时间的键
+type startKey struct{}
+
+// start 开始一个span并在ctx中记录开始时间
+func (t *Tracer) start(ctx context.Context, name string) context.Context {
+	ctx, _ = t.tracer.Start(ctx, name, trace.WithAttributes(t.attrs...))
+	return context.WithValue(ctx, startKey{}, time.Now())
+}
+
+// end 结束ctx中的span并返回操作持续的时间
+func (t *Tracer) end(ctx context.Context, err error, attrs ...attribute.KeyValue) time.Duration {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attrs...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+	start, _ := ctx.Value(startKey{}).(time.Time)
+	return time.Since(start)
+}
+
+// TraceAcquireStart 实现pool.Tracer
+func (t *Tracer) TraceAcquireStart(ctx context.Context) context.Context {
+	return t.start(ctx, "pool.acquire")
+}
+
+// TraceAcquireEnd 实现pool.Tracer
+func (t *Tracer) TraceAcquireEnd(ctx context.Context, outcome pool.AcquireOutcome, err error) {
+	o := outcomeKey.String(outcome.String())
+	d := t.end(ctx, err, o)
+	t.acquireDuration.Record(ctx, d.Seconds(), t.attributes(o))
+}
+
+// TraceCreateStart 实现pool.Tracer
+func (t *Tracer) TraceCreateStart(ctx context.Context) context.Context {
+	return t.start(ctx, "pool.create")
+}
+
+// TraceCreateEnd 实现pool.Tracer
+func (t *Tracer) TraceCreateEnd(ctx context.Context, err error) {
+	o := outcomeKey.String("ok")
+	if err != nil {
+		o = outcomeKey.String("error")
+	}
+	d := t.end(ctx, err, o)
+	t.createDuration.Record(ctx, d.Seconds(), t.attributes(o))
+}
+
+// TraceReleaseStart 实现pool.Tracer
+func (t *Tracer) TraceReleaseStart(ctx context.Context) context.Context {
+	return t.start(ctx, "pool.release")
+}
+
+// TraceReleaseEnd 实现pool.Tracer
+func (t *Tracer) TraceReleaseEnd(ctx context.Context, pooled bool) {
+	p := pooledKey.Bool(pooled)
+	t.end(ctx, nil, p)
+	t.releases.Add(ctx, 1, t.attributes(p))
+}
+
+var _ pool.Tracer = (*Tracer)(nil)
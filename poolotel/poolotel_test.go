@@ -0,0 +1,202 @@
+package poolotel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/lazysheep666/pool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	mnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+	tnoop "go.opentelemetry.io/otel/trace/noop"
+)
+
+// recorder 记录结束的span和指标的测量值，实现了TracerProvider和MeterProvider
+type recorder struct {
+	embedded.TracerProvider
+	mnoop.MeterProvider
+
+	mu      sync.Mutex
+	spans   []*span       // 按结束的顺序
+	records []measurement // 按记录的顺序
+}
+
+// span 是记录了属性和状态的trace.Span
+type span struct {
+	tnoop.Span
+	rec    *recorder
+	name   string
+	attrs  []attribute.KeyValue
+	status codes.Code
+}
+
+// measurement 是一次指标的测量
+type measurement struct {
+	name  string
+	attrs attribute.Set
+}
+
+func (r *recorder) Tracer(string, ...trace.TracerOption) trace.Tracer { return &tracer{rec: r} }
+
+func (r *recorder) Meter(string, ...metric.MeterOption) metric.Meter { return &meter{rec: r} }
+
+// record 记录名为name的指标的一次测量
+func (r *recorder) record(name string, attrs attribute.Set) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, measurement{name, attrs})
+}
+
+type tracer struct {
+	embedded.Tracer
+	rec *recorder
+}
+
+func (t *tracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	cfg := trace.NewSpanStartConfig(opts...)
+	s := &span{rec: t.rec, name: name, attrs: cfg.Attributes()}
+	return trace.ContextWithSpan(ctx, s), s
+}
+
+func (s *span) SetAttributes(kv ...attribute.KeyValue) { s.attrs = append(s.attrs, kv...) }
+
+func (s *span) SetStatus(code codes.Code, _ string) { s.status = code }
+
+func (s *span) End(...trace.SpanEndOption) {
+	s.rec.mu.Lock()
+	defer s.rec.mu.Unlock()
+	s.rec.spans = append(s.rec.spans, s)
+}
+
+// attr 返回span上名为key的属性值
+func (s *span) attr(key attribute.Key) string {
+	for _, kv := range s.attrs {
+		if kv.Key == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+type meter struct {
+	mnoop.Meter
+	rec *recorder
+}
+
+func (m *meter) Float64Histogram(name string, _ ...metric.Float64HistogramOption) (metric.Float64Histogram, error) {
+	return &histogram{rec: m.rec, name: name}, nil
+}
+
+func (m *meter) Int64Counter(name string, _ ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	return &counter{rec: m.rec, name: name}, nil
+}
+
+type histogram struct {
+	mnoop.Float64Histogram
+	rec  *recorder
+	name string
+}
+
+func (h *histogram) Record(_ context.Context, _ float64, opts ...metric.RecordOption) {
+	h.rec.record(h.name, metric.NewRecordConfig(opts).Attributes())
+}
+
+type counter struct {
+	mnoop.Int64Counter
+	rec  *recorder
+	name string
+}
+
+func (c *counter) Add(_ context.Context, _ int64, opts ...metric.AddOption) {
+	c.rec.record(c.name, metric.NewAddConfig(opts).Attributes())
+}
+
+func TestTracer(t *testing.T) {
+	errCreate := errors.New("create failed")
+	tests := []struct {
+		name string
+		fail bool // factory是否失败
+		// run 使用资源池，失败时返回错误
+		run func(p *pool.Pool[int]) error
+		// want 是依次结束的span的名字和结果属性，也是依次记录的指标
+		want []string
+	}{
+		{"miss and release", false, func(p *pool.Pool[int]) error {
+			r, err := p.Acquire()
+			if err != nil {
+				return err
+			}
+			return p.Release(r)
+		}, []string{"pool.create ok", "pool.acquire miss", "pool.release true"}},
+		{"hit", false, func(p *pool.Pool[int]) error {
+			for i := 0; i < 2; i++ {
+				r, err := p.Acquire()
+				if err != nil {
+					return err
+				}
+				p.Release(r)
+			}
+			return nil
+		}, []string{"pool.create ok", "pool.acquire miss", "pool.release true", "pool.acquire hit", "pool.release true"}},
+		{"create error", true, func(p *pool.Pool[int]) error {
+			if _, err := p.Acquire(); !errors.Is(err, errCreate) {
+				return fmt.Errorf("Acquire = %v, want %v", err, errCreate)
+			}
+			return nil
+		}, []string{"pool.create error", "pool.acquire error"}},
+	}
+	// metrics 是每个span对应的指标名
+	metrics := map[string]string{"pool.create": "pool.create.duration", "pool.acquire": "pool.acquire.duration", "pool.release": "pool.releases"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recorder{}
+			tr, err := NewTracer("db", WithTracerProvider(rec), WithMeterProvider(rec), WithLabels(map[string]string{"region": "eu"}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := pool.New(func() (int, error) {
+				if tt.fail {
+					return 0, errCreate
+				}
+				return 1, nil
+			}, pool.WithTracer(tr))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer p.Close()
+			if err := tt.run(p); err != nil {
+				t.Fatal(err)
+			}
+			rec.mu.Lock()
+			defer rec.mu.Unlock()
+			if len(rec.spans) != len(tt.want) || len(rec.records) != len(tt.want) {
+				t.Fatalf("got %d spans and %d measurements, want %d", len(rec.spans), len(rec.records), len(tt.want))
+			}
+			for i, want := range tt.want {
+				s, m := rec.spans[i], rec.records[i]
+				result := s.attr(outcomeKey)
+				if s.name == "pool.release" {
+					result = s.attr(pooledKey)
+				}
+				if got := s.name + " " + result; got != want {
+					t.Errorf("span %d = %q, want %q", i, got, want)
+				}
+				if s.attr(poolNameKey) != "db" || s.attr("pool.label.region") != "eu" {
+					t.Errorf("span %d attributes %v, want pool.name and pool.label.region", i, s.attrs)
+				}
+				if failed := s.status == codes.Error; failed != (result == "error") {
+					t.Errorf("span %d status %v, want an error status only for failures", i, s.status)
+				}
+				if v, _ := m.attrs.Value(poolNameKey); m.name != metrics[s.name] || v.AsString() != "db" {
+					t.Errorf("measurement %d = %s %v, want %s for pool db", i, m.name, m.attrs.ToSlice(), metrics[s.name])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+// Package poolprom 把资源池的统计信息导出为Prometheus指标
+package poolprom
+
+import (
+	"sync"
+
+	"github.com/lazysheep666/pool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// StatsProvider 是可以提供统计信息的资源池，*pool.Pool实现了这个接口
+type StatsProvider interface {
+	Stats() pool.Stats
+}
+
+// labeler 是带有标签的资源池，*pool.Pool用WithLabels设置的标签实现了这个接口
+type labeler interface {
+	Labels() map[string]string
+}
+
+// namer 是带有名字的资源池，*pool.Pool用WithName设置的名字实现了这个接口
+type namer interface {
+	Name() string
+}
+
+// Collector 实现了prometheus.Collector，导出一个或多个命名资源池的指标
+// 每个指标都带有值为资源池名字的pool标签，以及NewCollector指定的标签
+type Collector struct {
+	mu     sync.Mutex
+	pools  map[string]StatsProvider
+	labels []string
+
+	idle        *prometheus.Desc
+	inUse       *prometheus.Desc
+	waiting     *prometheus.Desc
+	created     *prometheus.Desc
+	closed      *prometheus.Desc
+	acquires    *prometheus.Desc
+	hits        *prometheus.Desc
+	misses      *prometheus.Desc
+	waits       *prometheus.Desc
+	timeouts    *prometheus.Desc
+	queueFull   *prometheus.Desc
+	overdue     *prometheus.Desc
+	checkout    *prometheus.Desc
+	waitSeconds *prometheus.Desc
+	starved     *prometheus.Desc
+	maxWaitAge  *prometheus.Desc
+}
+
+// NewCollector 创建一个指标名以namespace为前缀的Collector
+// labels是从资源池的WithLabels中导出的标签名，资源池没有设置某个标签时它的值为空
+func NewCollector(namespace string, labels ...string) *Collector {
+	variable := append([]string{"pool"}, labels...)
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "pool", name), help, variable, nil)
+	}
+	return &Collector{
+		pools:       make(map[string]StatsProvider),
+		labels:      labels,
+		idle:        desc("idle", "Number of idle resources."),
+		inUse:       desc("in_use", "Number of resources currently in use."),
+		waiting:     desc("waiting", "Number of acquisitions currently waiting for a resource."),
+		created:     desc("created_total", "Total number of resources created."),
+		closed:      desc("closed_total", "Total number of resources closed."),
+		acquires:    desc("acquires_total", "Total number of successful acquisitions."),
+		hits:        desc("acquire_hits_total", "Total number of acquisitions served by an idle resource."),
+		misses:      desc("acquire_misses_total", "Total number of acquisitions served by a new resource."),
+		waits:       desc("acquire_waits_total", "Total number of acquisitions that waited for capacity."),
+		timeouts:    desc("acquire_timeouts_total", "Total number of acquisitions that timed out."),
+		queueFull:   desc("acquire_queue_full_total", "Total number of acquisitions rejected because the wait queue was full."),
+		overdue:     desc("checkout_overdue_total", "Total number of checkouts held longer than MaxCheckoutDuration."),
+		checkout:    desc("checkout_seconds_total", "Total time resources were held by callers."),
+		waitSeconds: desc("acquire_wait_seconds", "Time spent waiting for capacity."),
+		starved:     desc("acquire_starved_total", "Total number of acquisitions that waited longer than StarvationThreshold."),
+		maxWaitAge:  desc("max_waiter_age_seconds", "Time the longest waiting acquisition has been waiting."),
+	}
+}
+
+// Register 以name为名字导出p的指标，同名的资源池会被替换
+// name为空时使用WithName设置的名字
+func (c *Collector) Register(name string, p StatsProvider) {
+	if n, ok := p.(namer); ok && name == "" {
+		name = n.Name()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pools[name] = p
+}
+
+// Unregister 停止导出名为name的资源池的指标
+func (c *Collector) Unregister(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pools, name)
+}
+
+// Describe 实现prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.idle
+	ch <- c.inUse
+	ch <- c.waiting
+	ch <- c.created
+	ch <- c.closed
+	ch <- c.acquires
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.waits
+	ch <- c.timeouts
+	ch <- c.queueFull
+	ch <- c.overdue
+	ch <- c.checkout
+	ch <- c.waitSeconds
+	ch <- c.starved
+	ch <- c.maxWaitAge
+}
+
+// Collect 实现prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	pools := make(map[string]StatsProvider, len(c.pools))
+	for name, p := range c.pools {
+		pools[name] = p
+	}
+	c.mu.Unlock()
+
+	for name, p := range pools {
+		s := p.Stats()
+		values := c.values(name, p)
+		gauge := func(d *prometheus.Desc, v float64) {
+			ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, v, values...)
+		}
+		counter := func(d *prometheus.Desc, v uint64) {
+			ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, float64(v), values...)
+		}
+		gauge(c.idle, float64(s.Idle))
+		gauge(c.inUse, float64(s.InUse))
+		gauge(c.waiting, float64(s.Waiting))
+		counter(c.created, s.TotalCreated)
+		counter(c.closed, s.TotalClosed)
+		counter(c.acquires, s.AcquireCount)
+		counter(c.hits, s.Hits)
+		counter(c.misses, s.Misses)
+		counter(c.waits, s.AcquireWaitCount)
+		counter(c.timeouts, s.AcquireTimeoutCount)
+		counter(c.queueFull, s.QueueFullCount)
+		counter(c.overdue, s.OverdueCount)
+		counter(c.starved, s.StarvedCount)
+		gauge(c.maxWaitAge, s.MaxWaiterAge.Seconds())
+		ch <- prometheus.MustNewConstMetric(c.checkout, prometheus.CounterValue, s.CheckoutDuration.Seconds(), values...)
+		buckets := make(map[float64]uint64, len(pool.WaitBuckets))
+		var cumulative uint64
+		for i, le := range pool.WaitBuckets {
+			cumulative += s.AcquireWaitBuckets[i]
+			buckets[le.Seconds()] = cumulative
+		}
+		ch <- prometheus.MustNewConstHistogram(c.waitSeconds,
+			s.AcquireWaitCount, s.AcquireWaitDuration.Seconds(), buckets, values...)
+	}
+}
+
+// values 返回名为name的资源池p的标签值，顺序与NewCollector中的标签相同
+func (c *Collector) values(name string, p StatsProvider) []string {
+	values := make([]string, 1, len(c.labels)+1)
+	values[0] = name
+	var labels map[string]string
+	if l, ok := p.(labeler); ok {
+		labels = l.Labels()
+	}
+	for _, k := range c.labels {
+		values = append(values, labels[k])
+	}
+	return values
+}
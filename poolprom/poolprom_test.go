@@ -0,0 +1,154 @@
+package poolprom
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lazysheep666/pool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// fakePool 是返回固定统计信息的StatsProvider
+type fakePool struct {
+	stats  pool.Stats
+	name   string
+	labels map[string]string
+}
+
+func (f *fakePool) Stats() pool.Stats         { return f.stats }
+func (f *fakePool) Name() string              { return f.name }
+func (f *fakePool) Labels() map[string]string { return f.labels }
+
+func TestCollector(t *testing.T) {
+	stats := pool.Stats{Idle: 2, InUse: 3, TotalCreated: 7, AcquireCount: 11, CheckoutDuration: 1500 * time.Millisecond}
+	tests := []struct {
+		name string
+		// register 把资源池注册到c
+		register func(c *Collector)
+		metrics  []string // 比较的指标
+		want     string
+	}{
+		{"gauges and counters with labels", func(c *Collector) {
+			c.Register("db", &fakePool{stats: stats, labels: map[string]string{"region": "eu"}})
+		}, []string{"app_pool_idle", "app_pool_in_use", "app_pool_created_total", "app_pool_checkout_seconds_total"}, `
+# HELP app_pool_checkout_seconds_total Total time resources were held by callers.
+# TYPE app_pool_checkout_seconds_total counter
+app_pool_checkout_seconds_total{pool="db",region="eu",zone=""} 1.5
+# HELP app_pool_created_total Total number of resources created.
+# TYPE app_pool_created_total counter
+app_pool_created_total{pool="db",region="eu",zone=""} 7
+# HELP app_pool_idle Number of idle resources.
+# TYPE app_pool_idle gauge
+app_pool_idle{pool="db",region="eu",zone=""} 2
+# HELP app_pool_in_use Number of resources currently in use.
+# TYPE app_pool_in_use gauge
+app_pool_in_use{pool="db",region="eu",zone=""} 3
+`},
+		{"empty name uses pool name", func(c *Collector) {
+			c.Register("", &fakePool{stats: stats, name: "cache"})
+		}, []string{"app_pool_idle"}, `
+# HELP app_pool_idle Number of idle resources.
+# TYPE app_pool_idle gauge
+app_pool_idle{pool="cache",region="",zone=""} 2
+`},
+		{"same name replaced", func(c *Collector) {
+			c.Register("db", &fakePool{stats: stats})
+			c.Register("db", &fakePool{stats: pool.Stats{Idle: 5}})
+		}, []string{"app_pool_idle"}, `
+# HELP app_pool_idle Number of idle resources.
+# TYPE app_pool_idle gauge
+app_pool_idle{pool="db",region="",zone=""} 5
+`},
+		{"unregistered pool dropped", func(c *Collector) {
+			c.Register("db", &fakePool{stats: stats})
+			c.Register("cache", &fakePool{stats: stats})
+			c.Unregister("db")
+		}, []string{"app_pool_idle"}, `
+# HELP app_pool_idle Number of idle resources.
+# TYPE app_pool_idle gauge
+app_pool_idle{pool="cache",region="",zone=""} 2
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCollector("app", "region", "zone")
+			tt.register(c)
+			if err := testutil.CollectAndCompare(c, strings.NewReader(tt.want), tt.metrics...); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// TestCollectorWaitHistogram 检查等待时间的直方图使用pool.WaitBuckets并且是累积的
+func TestCollectorWaitHistogram(t *testing.T) {
+	var s pool.Stats
+	s.AcquireWaitCount = 3
+	s.AcquireWaitDuration = 12 * time.Millisecond
+	s.AcquireWaitBuckets[0], s.AcquireWaitBuckets[2] = 1, 2
+	c := NewCollector("app")
+	c.Register("db", &fakePool{stats: s})
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(c); err != nil {
+		t.Fatal(err)
+	}
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() != "app_pool_acquire_wait_seconds" {
+			continue
+		}
+		h := mf.GetMetric()[0].GetHistogram()
+		if h.GetSampleCount() != 3 || h.GetSampleSum() != 0.012 {
+			t.Errorf("count = %d, sum = %v, want 3, 0.012", h.GetSampleCount(), h.GetSampleSum())
+		}
+		if bs := h.GetBucket(); len(bs) != len(pool.WaitBuckets) {
+			t.Fatalf("got %d buckets, want %d", len(bs), len(pool.WaitBuckets))
+		}
+		for i, b := range h.GetBucket() {
+			want := uint64(1)
+			if i >= 2 {
+				want = 3
+			}
+			if b.GetUpperBound() != pool.WaitBuckets[i].Seconds() || b.GetCumulativeCount() != want {
+				t.Errorf("bucket %d: le = %v, count = %d, want %v, %d", i, b.GetUpperBound(), b.GetCumulativeCount(), pool.WaitBuckets[i].Seconds(), want)
+			}
+		}
+		return
+	}
+	t.Fatal("no acquire_wait_seconds metric")
+}
+
+// TestCollectorPool 检查Collector导出*pool.Pool的名字、标签和统计信息
+func TestCollectorPool(t *testing.T) {
+	p, err := pool.New(func() (int, error) { return 1, nil },
+		pool.WithName("db"), pool.WithLabels(map[string]string{"region": "eu"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	r, err := p.Acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Release(r); err != nil {
+		t.Fatal(err)
+	}
+	c := NewCollector("app", "region")
+	c.Register("", p)
+	want := `
+# HELP app_pool_acquire_misses_total Total number of acquisitions served by a new resource.
+# TYPE app_pool_acquire_misses_total counter
+app_pool_acquire_misses_total{pool="db",region="eu"} 1
+# HELP app_pool_idle Number of idle resources.
+# TYPE app_pool_idle gauge
+app_pool_idle{pool="db",region="eu"} 1
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(want), "app_pool_idle", "app_pool_acquire_misses_total"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,195 @@
+// Package poolstatsd 把资源池的统计信息推送到StatsD或DogStatsD
+//
+//	sink, err := poolstatsd.New("127.0.0.1:8125", poolstatsd.WithTags("env:prod"))
+//	p, err := pool.New(factory, pool.WithName("db"), pool.WithStatsSink(sink, 10*time.Second))
+//
+// 资源数等当前值作为gauge推送，累计计数作为两次推送之间的增量以counter推送，
+// 这段时间内平均的等待时间作为timing推送；默认使用DogStatsD的标签，所有指标都带有pool标签和池的WithLabels标签，
+// WithoutTags时改为把池的名字放进指标名中，用于不支持标签的StatsD
+package poolstatsd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lazysheep666/pool"
+)
+
+// DefaultPrefix 是没有设置WithPrefix时指标名的前缀
+const DefaultPrefix = "pool."
+
+// maxPacket 是一个UDP包中最多写入的字节数，保证不超过常见网络的MTU
+const maxPacket = 1432
+
+// Option 用于配置New创建的Sink
+type Option func(*Sink)
+
+// WithPrefix 设置指标名的前缀，默认为DefaultPrefix
+func WithPrefix(prefix string) Option {
+	return func(s *Sink) { s.prefix = prefix }
+}
+
+// WithTags 设置附加在每个指标上的DogStatsD标签，例如"env:prod"
+func WithTags(tags ...string) Option {
+	return func(s *Sink) { s.tags = append(s.tags, tags...) }
+}
+
+// WithoutTags 设置不使用DogStatsD的标签，池的名字放进指标名中，例如pool.db.idle
+func WithoutTags() Option {
+	return func(s *Sink) { s.plain = true }
+}
+
+// Sink 实现了pool.StatsSink，可以同时被多个池使用
+type Sink struct {
+	w      io.Writer
+	closer io.Closer
+	prefix string
+	tags   []string
+	plain  bool
+
+	mu   sync.Mutex            // 保护last，并让多个池的包不会交错写入w
+	last map[string]pool.Stats // 每个池上一次推送的统计信息，用来计算累计值的增量
+}
+
+var _ pool.StatsSink = (*Sink)(nil)
+
+// New 创建一个把指标以UDP发送到addr的Sink
+func New(addr string, opts ...Option) (*Sink, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := NewWriter(conn, opts...)
+	s.closer = conn
+	return s, nil
+}
+
+// NewWriter 创建一个把指标写入w的Sink，每次Write是一个完整的包，用于其它传输方式和测试
+func NewWriter(w io.Writer, opts ...Option) *Sink {
+	s := &Sink{w: w, prefix: DefaultPrefix, last: make(map[string]pool.Stats)}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Close 关闭New创建的连接
+func (s *Sink) Close() error {
+	if s.closer != nil {
+		return s.closer.Close()
+	}
+	return nil
+}
+
+// PushStats 实现pool.StatsSink
+func (s *Sink) PushStats(name string, labels map[string]string, st pool.Stats) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev := s.last[name]
+	s.last[name] = st
+
+	prefix, suffix := s.prefix, s.tagSuffix(name, labels)
+	if s.plain && name != "" {
+		prefix += sanitize(name) + "."
+	}
+	var lines []string
+	gauge := func(metric string, v uint) {
+		lines = append(lines, fmt.Sprintf("%s%s:%d|g%s", prefix, metric, v, suffix))
+	}
+	counter := func(metric string, cur, old uint64) {
+		// 计数变小说明池被重新创建过，这时推送全部的值
+		d := cur - old
+		if cur < old {
+			d = cur
+		}
+		if d > 0 {
+			lines = append(lines, fmt.Sprintf("%s%s:%d|c%s", prefix, metric, d, suffix))
+		}
+	}
+	gauge("idle", st.Idle)
+	gauge("in_use", st.InUse)
+	gauge("waiting", st.Waiting)
+	gauge("quarantined", st.Quarantined)
+	counter("created", st.TotalCreated, prev.TotalCreated)
+	counter("closed", st.TotalClosed, prev.TotalClosed)
+	counter("acquires", st.AcquireCount, prev.AcquireCount)
+	counter("acquire_hits", st.Hits, prev.Hits)
+	counter("acquire_misses", st.Misses, prev.Misses)
+	counter("acquire_waits", st.AcquireWaitCount, prev.AcquireWaitCount)
+	counter("acquire_timeouts", st.AcquireTimeoutCount, prev.AcquireTimeoutCount)
+	counter("acquire_queue_full", st.QueueFullCount, prev.QueueFullCount)
+	counter("checkout_overdue", st.OverdueCount, prev.OverdueCount)
+	counter("acquire_starved", st.StarvedCount, prev.StarvedCount)
+	gauge("max_waiter_age_ms", uint(st.MaxWaiterAge/time.Millisecond))
+	if st.AcquireWaitCount > prev.AcquireWaitCount {
+		waits := st.AcquireWaitCount - prev.AcquireWaitCount
+		avg := (st.AcquireWaitDuration - prev.AcquireWaitDuration) / time.Duration(waits)
+		lines = append(lines, fmt.Sprintf("%sacquire_wait:%g|ms%s", prefix, float64(avg)/float64(time.Millisecond), suffix))
+	}
+	return s.write(lines)
+}
+
+// tagSuffix 返回附加在每一行后面的DogStatsD标签，标签按名字排序
+func (s *Sink) tagSuffix(name string, labels map[string]string) string {
+	if s.plain {
+		return ""
+	}
+	tags := append([]string(nil), s.tags...)
+	if name != "" {
+		tags = append(tags, "pool:"+name)
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		tags = append(tags, k+":"+labels[k])
+	}
+	if len(tags) == 0 {
+		return ""
+	}
+	return "|#" + strings.Join(tags, ",")
+}
+
+// write 把lines按maxPacket分成若干个包写入
+func (s *Sink) write(lines []string) error {
+	var buf bytes.Buffer
+	flush := func() error {
+		if buf.Len() == 0 {
+			return nil
+		}
+		_, err := s.w.Write(buf.Bytes())
+		buf.Reset()
+		return err
+	}
+	for _, line := range lines {
+		if buf.Len() > 0 && buf.Len()+1+len(line) > maxPacket {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(line)
+	}
+	return flush()
+}
+
+// sanitize 把名字中StatsD指标名不允许的字符替换为下划线
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', '#', ' ', '\n':
+			return '_'
+		}
+		return r
+	}, name)
+}
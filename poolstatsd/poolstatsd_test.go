@@ -0,0 +1,233 @@
+package poolstatsd
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lazysheep666/pool"
+	"github.com/lazysheep666/pool/pooltest"
+)
+
+// packets 记录每次Write写入的包
+type packets struct {
+	mu  sync.Mutex
+	got []string
+	err error
+}
+
+func (p *packets) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return 0, p.err
+	}
+	p.got = append(p.got, string(b))
+	return len(b), nil
+}
+
+// lines 返回所有包中的行
+func (p *packets) lines() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var lines []string
+	for _, pkt := range p.got {
+		lines = append(lines, strings.Split(pkt, "\n")...)
+	}
+	return lines
+}
+
+func TestPushStats(t *testing.T) {
+	st := pool.Stats{
+		Idle: 2, InUse: 1, TotalCreated: 3, AcquireCount: 5, Hits: 2, Misses: 3,
+		AcquireWaitCount: 2, AcquireWaitDuration: 30 * time.Millisecond, MaxWaiterAge: 1500 * time.Microsecond,
+	}
+	first := []string{
+		"idle:2|g", "in_use:1|g", "waiting:0|g", "quarantined:0|g",
+		"created:3|c", "acquires:5|c", "acquire_hits:2|c", "acquire_misses:3|c", "acquire_waits:2|c",
+		"max_waiter_age_ms:1|g", "acquire_wait:15|ms",
+	}
+	tests := []struct {
+		name   string
+		opts   []Option
+		pool   string
+		labels map[string]string
+		want   []string
+	}{
+		{"dogstatsd tags", []Option{WithTags("env:prod")}, "db", map[string]string{"zone": "b", "app": "api"},
+			lines("pool.", first, "|#env:prod,pool:db,app:api,zone:b")},
+		{"unnamed pool without tags", nil, "", nil, lines("pool.", first, "")},
+		{"plain statsd", []Option{WithoutTags(), WithTags("env:prod")}, "db:main", map[string]string{"app": "api"},
+			lines("pool.db_main.", first, "")},
+		{"custom prefix", []Option{WithPrefix("svc.")}, "db", nil, lines("svc.", first, "|#pool:db")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w packets
+			if err := NewWriter(&w, tt.opts...).PushStats(tt.pool, tt.labels, st); err != nil {
+				t.Fatal(err)
+			}
+			if len(w.got) != 1 {
+				t.Errorf("wrote %d packets, want 1", len(w.got))
+			}
+			compare(t, w.lines(), tt.want)
+		})
+	}
+}
+
+// TestPushStatsDeltas 检查累计值按两次推送之间的增量推送，计数变小时推送全部的值
+func TestPushStatsDeltas(t *testing.T) {
+	steps := []struct {
+		name string
+		st   pool.Stats
+		want []string
+	}{
+		{"first push", pool.Stats{TotalCreated: 3, AcquireCount: 4}, []string{"created:3|c", "acquires:4|c"}},
+		{"unchanged", pool.Stats{TotalCreated: 3, AcquireCount: 4}, nil},
+		{"increase", pool.Stats{TotalCreated: 5, AcquireCount: 4, AcquireWaitCount: 4, AcquireWaitDuration: 8 * time.Millisecond},
+			[]string{"created:2|c", "acquire_waits:4|c", "acquire_wait:2|ms"}},
+		{"pool recreated", pool.Stats{TotalCreated: 1, AcquireCount: 1}, []string{"created:1|c", "acquires:1|c"}},
+	}
+	var w packets
+	s := NewWriter(&w, WithoutTags())
+	for _, step := range steps {
+		w.got = nil
+		if err := s.PushStats("", nil, step.st); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, l := range w.lines() {
+			if !strings.HasSuffix(l, "|g") {
+				got = append(got, strings.TrimPrefix(l, DefaultPrefix))
+			}
+		}
+		compare(t, got, step.want)
+	}
+}
+
+// TestPacketSize 检查很长的行被分到多个不超过maxPacket的包中，每个包只包含完整的行
+func TestPacketSize(t *testing.T) {
+	var w packets
+	s := NewWriter(&w, WithTags(strings.Repeat("t", 200)))
+	if err := s.PushStats("db", nil, pool.Stats{TotalCreated: 1, AcquireCount: 1, Hits: 1, Misses: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.got) < 2 {
+		t.Fatalf("wrote %d packets, want the lines split over several", len(w.got))
+	}
+	for _, pkt := range w.got {
+		if len(pkt) > maxPacket {
+			t.Errorf("packet of %d bytes, want at most %d", len(pkt), maxPacket)
+		}
+		for _, l := range strings.Split(pkt, "\n") {
+			if !strings.HasPrefix(l, DefaultPrefix) || !strings.HasSuffix(l, ",pool:db") {
+				t.Errorf("partial line %q", l)
+			}
+		}
+	}
+	if n := len(w.lines()); n != 9 {
+		t.Errorf("wrote %d lines, want 9", n)
+	}
+}
+
+// TestPushStatsWriteError 检查写入失败时返回错误
+func TestPushStatsWriteError(t *testing.T) {
+	errWrite := errors.New("write failed")
+	w := packets{err: errWrite}
+	if err := NewWriter(&w).PushStats("db", nil, pool.Stats{}); !errors.Is(err, errWrite) {
+		t.Errorf("PushStats = %v, want %v", err, errWrite)
+	}
+}
+
+// TestNew 检查New通过UDP发送指标
+func TestNew(t *testing.T) {
+	lis, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	s, err := New(lis.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.PushStats("db", nil, pool.Stats{Idle: 1}); err != nil {
+		t.Fatal(err)
+	}
+	lis.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, maxPacket)
+	n, _, err := lis.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Split(string(buf[:n]), "\n")[0]; got != "pool.idle:1|g|#pool:db" {
+		t.Errorf("first line %q, want pool.idle:1|g|#pool:db", got)
+	}
+	if _, err := New("127.0.0.1:bogus"); err == nil {
+		t.Error("New with an invalid address succeeded")
+	}
+}
+
+// TestStatsSink 检查池每隔interval推送一次统计信息，关闭时再推送一次
+func TestStatsSink(t *testing.T) {
+	var w packets
+	clock := pooltest.NewFakeClock(time.Time{})
+	p, err := pool.New(func() (int, error) { return 1, nil }, pool.WithName("db"),
+		pool.WithClock(clock), pool.WithStatsSink(NewWriter(&w), 10*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := p.Acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.BlockUntil(1)
+	clock.Advance(10 * time.Second)
+	waitPackets(t, &w, 1)
+	p.Release(r)
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	waitPackets(t, &w, 2)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !strings.Contains(w.got[0], "pool.in_use:1|g|#pool:db") || !strings.Contains(w.got[1], "pool.in_use:0|g|#pool:db") {
+		t.Errorf("packets %q, want in_use 1 then 0", w.got)
+	}
+}
+
+// waitPackets 等待w收到n个包
+func waitPackets(t *testing.T, w *packets, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		w.mu.Lock()
+		got := len(w.got)
+		w.mu.Unlock()
+		if got >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d packets, want %d", got, n)
+		}
+	}
+}
+
+// lines 给每个指标加上前缀和后缀
+func lines(prefix string, metrics []string, suffix string) []string {
+	out := make([]string, len(metrics))
+	for i, m := range metrics {
+		out[i] = prefix + m + suffix
+	}
+	return out
+}
+
+// compare 逐行比较got和want
+func compare(t *testing.T, got, want []string) {
+	t.Helper()
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got lines\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,182 @@
+// Package pooltest 提供测试资源池时使用的工具
+//
+//	clock := pooltest.NewFakeClock(time.Now())
+//	p, err := pool.New(factory, pool.WithClock(clock), pool.WithIdleTimeout(time.Minute))
+//	clock.BlockUntil(1) // 等待回收goroutine创建它的Ticker
+//	clock.Advance(2 * time.Minute)
+package pooltest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lazysheep666/pool"
+)
+
+// FakeClock 是只在调用Advance或Set时才前进的时钟，实现了pool.Clock
+// 时间前进时，到期的Timer和Ticker向它们的通道发送当时的时间，通道已满时丢弃，与time包相同
+type FakeClock struct {
+	m       sync.Mutex
+	now     time.Time
+	waiters []*fakeWaiter // 未到期的Timer和Ticker
+	changed chan struct{} // waiters变化时关闭，用来唤醒BlockUntil
+}
+
+var _ pool.Clock = (*FakeClock)(nil)
+
+// fakeWaiter 是FakeClock上的一个Timer或Ticker
+type fakeWaiter struct {
+	clock  *FakeClock
+	c      chan time.Time
+	when   time.Time
+	period time.Duration // Ticker的周期，Timer为0
+}
+
+// NewFakeClock 创建一个从now开始的FakeClock
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now, changed: make(chan struct{})}
+}
+
+// Now 返回FakeClock当前的时间
+func (c *FakeClock) Now() time.Time {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.now
+}
+
+// Since 返回从t到FakeClock当前时间经过的时间
+func (c *FakeClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// NewTimer 创建一个在d之后到期的Timer
+func (c *FakeClock) NewTimer(d time.Duration) pool.Timer {
+	w := &fakeWaiter{clock: c, c: make(chan time.Time, 1)}
+	c.m.Lock()
+	defer c.m.Unlock()
+	w.when = c.now.Add(d)
+	c.schedule(w)
+	return (*fakeTimer)(w)
+}
+
+// NewTicker 创建一个每隔d到期一次的Ticker，d必须为正数
+func (c *FakeClock) NewTicker(d time.Duration) pool.Ticker {
+	if d <= 0 {
+		panic("pooltest: non-positive interval for NewTicker")
+	}
+	w := &fakeWaiter{clock: c, c: make(chan time.Time, 1), period: d}
+	c.m.Lock()
+	defer c.m.Unlock()
+	w.when = c.now.Add(d)
+	c.schedule(w)
+	return (*fakeTicker)(w)
+}
+
+// Advance 让时间前进d，并触发这期间到期的Timer和Ticker
+func (c *FakeClock) Advance(d time.Duration) {
+	c.m.Lock()
+	now := c.now.Add(d)
+	c.m.Unlock()
+	c.Set(now)
+}
+
+// Set 把时间设置为t，并触发t之前到期的Timer和Ticker，t早于当前时间时只修改时间
+func (c *FakeClock) Set(t time.Time) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.now = t
+	for len(c.waiters) > 0 && !c.waiters[0].when.After(t) {
+		w := c.waiters[0]
+		c.remove(w)
+		select {
+		case w.c <- w.when:
+		default:
+		}
+		if w.period > 0 {
+			// 与time.Ticker一样，错过的触发只发送一次
+			for !w.when.After(t) {
+				w.when = w.when.Add(w.period)
+			}
+			c.schedule(w)
+		}
+	}
+}
+
+// BlockUntil 阻塞直到FakeClock上有至少n个未到期的Timer和Ticker，
+// 用来等待被测试的goroutine开始等待时间，然后再调用Advance
+func (c *FakeClock) BlockUntil(n int) {
+	for {
+		c.m.Lock()
+		if len(c.waiters) >= n {
+			c.m.Unlock()
+			return
+		}
+		changed := c.changed
+		c.m.Unlock()
+		<-changed
+	}
+}
+
+// schedule 按到期时间把w加入waiters，调用者需持有c.m
+func (c *FakeClock) schedule(w *fakeWaiter) {
+	i := sort.Search(len(c.waiters), func(i int) bool { return c.waiters[i].when.After(w.when) })
+	c.waiters = append(c.waiters, nil)
+	copy(c.waiters[i+1:], c.waiters[i:])
+	c.waiters[i] = w
+	c.notify()
+}
+
+// remove 把w从waiters中移除，w不在其中时返回false，调用者需持有c.m
+func (c *FakeClock) remove(w *fakeWaiter) bool {
+	for i, x := range c.waiters {
+		if x == w {
+			copy(c.waiters[i:], c.waiters[i+1:])
+			c.waiters[len(c.waiters)-1] = nil
+			c.waiters = c.waiters[:len(c.waiters)-1]
+			c.notify()
+			return true
+		}
+	}
+	return false
+}
+
+// notify 唤醒BlockUntil，调用者需持有c.m
+func (c *FakeClock) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// fakeTimer 实现了pool.Timer
+type fakeTimer fakeWaiter
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	c := t.clock
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.remove((*fakeWaiter)(t))
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	c := t.clock
+	c.m.Lock()
+	defer c.m.Unlock()
+	active := c.remove((*fakeWaiter)(t))
+	t.when = c.now.Add(d)
+	c.schedule((*fakeWaiter)(t))
+	return active
+}
+
+// fakeTicker 实现了pool.Ticker
+type fakeTicker fakeWaiter
+
+func (t *fakeTicker) C() <-chan time.Time { return t.c }
+
+func (t *fakeTicker) Stop() {
+	c := t.clock
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.remove((*fakeWaiter)(t))
+}
@@ -0,0 +1,91 @@
+package pooltest
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Factory 包装一个factory，用来向pool.Pool或FakePool注入创建失败和延迟
+//
+//	f := pooltest.NewFactory(dial)
+//	f.FailNext(errors.New("connection refused"))
+//	p, err := pool.NewContext(f.Func())
+type Factory[T any] struct {
+	fn func(context.Context) (T, error)
+
+	m         sync.Mutex
+	failures  []error       // 之后的调用依次返回的错误
+	failEvery int           // 每failEvery次调用失败一次，0表示不失败
+	everyErr  error         // failEvery次调用失败时返回的错误
+	latency   time.Duration // 每次调用的延迟
+	calls     int
+}
+
+// NewFactory 包装fn
+func NewFactory[T any](fn func(context.Context) (T, error)) *Factory[T] {
+	return &Factory[T]{fn: fn}
+}
+
+// Func 返回可以交给pool.NewContext或NewFakePool的factory
+func (f *Factory[T]) Func() func(context.Context) (T, error) {
+	return f.call
+}
+
+// FailNext 让之后的len(errs)次调用依次返回errs中的错误，nil表示这一次调用被包装的factory
+func (f *Factory[T]) FailNext(errs ...error) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	f.failures = append(f.failures, errs...)
+}
+
+// FailEvery 让每n次调用中的最后一次返回err，n为0时取消
+func (f *Factory[T]) FailEvery(n int, err error) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	f.failEvery = n
+	f.everyErr = err
+}
+
+// SetLatency 设置每次调用前等待的时间，等待期间ctx结束时返回ctx.Err()
+func (f *Factory[T]) SetLatency(d time.Duration) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	f.latency = d
+}
+
+// Calls 返回累计调用的次数
+func (f *Factory[T]) Calls() int {
+	f.m.Lock()
+	defer f.m.Unlock()
+	return f.calls
+}
+
+func (f *Factory[T]) call(ctx context.Context) (T, error) {
+	var zero T
+	f.m.Lock()
+	f.calls++
+	var err error
+	if len(f.failures) > 0 {
+		err = f.failures[0]
+		f.failures = f.failures[1:]
+	} else if f.failEvery > 0 && f.calls%f.failEvery == 0 {
+		err = f.everyErr
+	}
+	latency := f.latency
+	f.m.Unlock()
+
+	if latency > 0 {
+		t := time.NewTimer(latency)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return zero, ctx.Err()
+		}
+	}
+	if err != nil {
+		return zero, err
+	}
+	return f.fn(ctx)
+}
@@ -0,0 +1,88 @@
+package pooltest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lazysheep666/pool"
+)
+
+func TestFactory(t *testing.T) {
+	boom := errors.New("boom")
+	tests := []struct {
+		name  string
+		setup func(f *Factory[int])
+		calls int
+		want  []error // 每次调用的错误
+	}{
+		{"no failures", func(f *Factory[int]) {}, 2, []error{nil, nil}},
+		{"fail next in order", func(f *Factory[int]) { f.FailNext(boom, nil, boom) }, 4, []error{boom, nil, boom, nil}},
+		{"fail every third call", func(f *Factory[int]) { f.FailEvery(3, boom) }, 6, []error{nil, nil, boom, nil, nil, boom}},
+		{"fail next before fail every", func(f *Factory[int]) {
+			f.FailEvery(2, boom)
+			f.FailNext(context.Canceled)
+		}, 3, []error{context.Canceled, boom, nil}},
+		{"fail every cancelled", func(f *Factory[int]) {
+			f.FailEvery(1, boom)
+			f.FailEvery(0, nil)
+		}, 2, []error{nil, nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFactory(func(context.Context) (int, error) { return 1, nil })
+			tt.setup(f)
+			fn := f.Func()
+			for i := 0; i < tt.calls; i++ {
+				if _, err := fn(context.Background()); !errors.Is(err, tt.want[i]) || (err == nil) != (tt.want[i] == nil) {
+					t.Errorf("call %d = %v, want %v", i+1, err, tt.want[i])
+				}
+			}
+			if f.Calls() != tt.calls {
+				t.Errorf("Calls = %d, want %d", f.Calls(), tt.calls)
+			}
+		})
+	}
+}
+
+// TestFactoryLatency 检查延迟期间ctx结束时返回ctx.Err()，不调用被包装的factory
+func TestFactoryLatency(t *testing.T) {
+	var wrapped int
+	f := NewFactory(func(context.Context) (int, error) {
+		wrapped++
+		return 1, nil
+	})
+	f.SetLatency(time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if _, err := f.Func()(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("call = %v, want DeadlineExceeded", err)
+	}
+	if wrapped != 0 {
+		t.Errorf("wrapped factory called %d times, want 0", wrapped)
+	}
+}
+
+// TestFactoryWithPool 检查用Factory注入的失败通过pool.Pool返回给调用者
+func TestFactoryWithPool(t *testing.T) {
+	refused := errors.New("connection refused")
+	f := NewFactory(func(context.Context) (int, error) { return 1, nil })
+	f.FailNext(refused)
+	p, err := pool.NewContext(f.Func(), pool.WithClock(NewFakeClock(time.Time{})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if _, err := p.Acquire(); !errors.Is(err, refused) {
+		t.Fatalf("Acquire = %v, want %v", err, refused)
+	}
+	r, err := p.Acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Release(r)
+	if f.Calls() != 2 {
+		t.Errorf("Calls = %d, want 2", f.Calls())
+	}
+}
@@ -0,0 +1,231 @@
+package pooltest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lazysheep666/pool"
+	"github.com/lazysheep666/pool/internal/identity"
+)
+
+// FakePool 是用于测试资源池使用者的假资源池，方法的含义与pool.Pool相同
+// Acquire依次使用放回的资源、Script设置的资源和Factory创建的资源，都没有时返回pool.ErrPoolExhausted
+// 可以用FailAcquire和SetLatency设置Acquire的错误和延迟，测试结束时用AssertAllReleased检查资源都被放回
+type FakePool[T any] struct {
+	m        sync.Mutex
+	factory  func(context.Context) (T, error)
+	script   []T
+	idle     []T
+	inUse    identity.Map[T, T]
+	failures []error       // 之后的Acquire依次返回的错误
+	latency  time.Duration // 每次Acquire的延迟
+	closed   bool
+	stats    pool.Stats
+}
+
+var (
+	_ pool.Pooler[int] = (*FakePool[int])(nil)
+	_ pool.Managed     = (*FakePool[int])(nil)
+)
+
+// NewFakePool 创建一个FakePool，factory在没有空闲资源和脚本资源时创建资源，可以为nil
+func NewFakePool[T any](factory func(context.Context) (T, error)) *FakePool[T] {
+	return &FakePool[T]{factory: factory, inUse: make(identity.Map[T, T])}
+}
+
+// Script 设置之后的Acquire依次返回的资源，在放回的资源之后、factory之前使用
+func (p *FakePool[T]) Script(rs ...T) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.script = append(p.script, rs...)
+}
+
+// FailAcquire 让之后的len(errs)次Acquire依次返回errs中的错误，nil表示这一次正常获取
+func (p *FakePool[T]) FailAcquire(errs ...error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.failures = append(p.failures, errs...)
+}
+
+// SetLatency 设置每次Acquire在获取资源前等待的时间，TryAcquire不等待
+func (p *FakePool[T]) SetLatency(d time.Duration) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.latency = d
+}
+
+// Acquire 从池中获取一个资源
+func (p *FakePool[T]) Acquire() (T, error) {
+	return p.AcquireContext(context.Background())
+}
+
+// AcquireContext 从池中获取一个资源，等待SetLatency设置的延迟时ctx结束会返回ctx.Err()
+func (p *FakePool[T]) AcquireContext(ctx context.Context) (T, error) {
+	p.m.Lock()
+	latency := p.latency
+	p.m.Unlock()
+	if latency > 0 {
+		t := time.NewTimer(latency)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			var zero T
+			return zero, ctx.Err()
+		}
+	}
+	return p.acquire(ctx)
+}
+
+// TryAcquire 从池中获取一个资源，不等待SetLatency设置的延迟
+func (p *FakePool[T]) TryAcquire() (T, error) {
+	return p.acquire(context.Background())
+}
+
+func (p *FakePool[T]) acquire(ctx context.Context) (T, error) {
+	var zero T
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.closed {
+		return zero, pool.ErrPoolClosed
+	}
+	if len(p.failures) > 0 {
+		err := p.failures[0]
+		p.failures = p.failures[1:]
+		if err != nil {
+			return zero, err
+		}
+	}
+	var r T
+	switch {
+	case len(p.idle) > 0:
+		r = p.idle[0]
+		p.idle = p.idle[1:]
+		p.stats.Hits++
+	case len(p.script) > 0:
+		r = p.script[0]
+		p.script = p.script[1:]
+		p.stats.Misses++
+		p.stats.TotalCreated++
+	case p.factory != nil:
+		// 创建期间不持有锁，factory可以调用FakePool的方法
+		p.m.Unlock()
+		created, err := p.factory(ctx)
+		p.m.Lock()
+		if err != nil {
+			return zero, err
+		}
+		r = created
+		p.stats.Misses++
+		p.stats.TotalCreated++
+	default:
+		return zero, pool.ErrPoolExhausted
+	}
+	p.inUse.Set(r, r)
+	p.stats.AcquireCount++
+	return r, nil
+}
+
+// Release 把资源放回池里，资源已经放回过或不是从本池获取时返回与pool.Pool相同的错误
+func (p *FakePool[T]) Release(r T) error {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if err := p.take(r); err != nil {
+		return err
+	}
+	if !p.closed {
+		p.idle = append(p.idle, r)
+	}
+	return nil
+}
+
+// Discard 销毁一个使用中的资源
+func (p *FakePool[T]) Discard(r T) error {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if err := p.take(r); err != nil {
+		return err
+	}
+	p.stats.TotalClosed++
+	return nil
+}
+
+// take 把r从使用中的资源中移除，调用者需持有p.m
+func (p *FakePool[T]) take(r T) error {
+	if _, ok := p.inUse.Get(r); ok {
+		p.inUse.Delete(r)
+		return nil
+	}
+	for _, x := range p.idle {
+		if identity.Same(x, r) {
+			return pool.ErrDoubleRelease
+		}
+	}
+	return pool.ErrForeignResource
+}
+
+// With 获取一个资源并用它调用fn，fn返回错误或panic时销毁资源，否则放回池里
+func (p *FakePool[T]) With(ctx context.Context, fn func(r T) error) (err error) {
+	r, err := p.AcquireContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			p.Discard(r)
+			panic(v)
+		}
+		if err != nil {
+			p.Discard(r)
+			return
+		}
+		p.Release(r)
+	}()
+	return fn(r)
+}
+
+// Stats 返回池的统计信息，只统计了资源数和获取次数
+func (p *FakePool[T]) Stats() pool.Stats {
+	p.m.Lock()
+	defer p.m.Unlock()
+	s := p.stats
+	s.Idle = uint(len(p.idle))
+	s.InUse = uint(len(p.inUse))
+	return s
+}
+
+// Outstanding 返回还没有放回的资源
+func (p *FakePool[T]) Outstanding() []T {
+	p.m.Lock()
+	defer p.m.Unlock()
+	rs := make([]T, 0, len(p.inUse))
+	for _, r := range p.inUse {
+		rs = append(rs, r)
+	}
+	return rs
+}
+
+// AssertAllReleased 在还有资源没有放回或销毁时让测试失败
+func (p *FakePool[T]) AssertAllReleased(t testing.TB) {
+	t.Helper()
+	if rs := p.Outstanding(); len(rs) > 0 {
+		t.Errorf("pooltest: %d resources were not released: %v", len(rs), rs)
+	}
+}
+
+// Close 关闭池，之后Acquire返回pool.ErrPoolClosed
+func (p *FakePool[T]) Close() error {
+	return p.CloseContext(context.Background())
+}
+
+// CloseContext 与Close相同，不等待使用中的资源
+func (p *FakePool[T]) CloseContext(ctx context.Context) error {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.closed = true
+	p.stats.TotalClosed += uint64(len(p.idle))
+	p.idle = nil
+	return nil
+}
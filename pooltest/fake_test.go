@@ -0,0 +1,168 @@
+package pooltest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lazysheep666/pool"
+)
+
+// res 是测试使用的资源
+type res struct{ id int }
+
+func TestFakePool(t *testing.T) {
+	ctx := context.Background()
+	boom := errors.New("boom")
+	// counter 返回依次创建id从100开始的资源的factory
+	counter := func() func(context.Context) (*res, error) {
+		n := 99
+		return func(context.Context) (*res, error) {
+			n++
+			return &res{n}, nil
+		}
+	}
+	tests := []struct {
+		name    string
+		factory func(context.Context) (*res, error)
+		// run 使用p，返回依次获取到的资源的id，获取失败时记为-1
+		run  func(t *testing.T, p *FakePool[*res]) []int
+		want []int
+	}{
+		{"released before script before factory", counter(), func(t *testing.T, p *FakePool[*res]) []int {
+			p.Script(&res{1}, &res{2})
+			a, _ := p.Acquire()
+			p.Release(a)
+			var ids []int
+			for i := 0; i < 4; i++ {
+				r, err := p.Acquire()
+				if err != nil {
+					t.Fatal(err)
+				}
+				ids = append(ids, r.id)
+				defer p.Release(r)
+			}
+			return ids
+		}, []int{1, 2, 100, 101}},
+		{"exhausted without factory", nil, func(t *testing.T, p *FakePool[*res]) []int {
+			p.Script(&res{1})
+			r, _ := p.Acquire()
+			defer p.Release(r)
+			if _, err := p.Acquire(); !errors.Is(err, pool.ErrPoolExhausted) {
+				t.Errorf("Acquire = %v, want ErrPoolExhausted", err)
+			}
+			return []int{r.id}
+		}, []int{1}},
+		{"injected failures in order", counter(), func(t *testing.T, p *FakePool[*res]) []int {
+			p.FailAcquire(boom, nil, boom)
+			var ids []int
+			for i := 0; i < 4; i++ {
+				r, err := p.Acquire()
+				if err != nil {
+					if !errors.Is(err, boom) {
+						t.Fatalf("Acquire = %v, want %v", err, boom)
+					}
+					ids = append(ids, -1)
+					continue
+				}
+				ids = append(ids, r.id)
+				p.Release(r)
+			}
+			return ids
+		}, []int{-1, 100, -1, 100}},
+		{"latency ends with ctx", counter(), func(t *testing.T, p *FakePool[*res]) []int {
+			p.SetLatency(time.Hour)
+			ctx, cancel := context.WithTimeout(ctx, time.Millisecond)
+			defer cancel()
+			if _, err := p.AcquireContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("AcquireContext = %v, want DeadlineExceeded", err)
+			}
+			// TryAcquire不等待
+			r, err := p.TryAcquire()
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.Release(r)
+			return []int{r.id}
+		}, []int{100}},
+		{"returned resources checked", counter(), func(t *testing.T, p *FakePool[*res]) []int {
+			r, _ := p.Acquire()
+			p.Release(r)
+			if err := p.Release(r); !errors.Is(err, pool.ErrDoubleRelease) {
+				t.Errorf("second Release = %v, want ErrDoubleRelease", err)
+			}
+			if err := p.Discard(&res{7}); !errors.Is(err, pool.ErrForeignResource) {
+				t.Errorf("Discard of a foreign resource = %v, want ErrForeignResource", err)
+			}
+			return []int{r.id}
+		}, []int{100}},
+		{"With discards on error", counter(), func(t *testing.T, p *FakePool[*res]) []int {
+			if err := p.With(ctx, func(*res) error { return boom }); !errors.Is(err, boom) {
+				t.Errorf("With = %v, want %v", err, boom)
+			}
+			var id int
+			p.With(ctx, func(r *res) error {
+				id = r.id
+				return nil
+			})
+			if s := p.Stats(); s.TotalClosed != 1 || s.Idle != 1 {
+				t.Errorf("TotalClosed = %d, Idle = %d, want 1, 1", s.TotalClosed, s.Idle)
+			}
+			return []int{id}
+		}, []int{101}},
+		{"closed", counter(), func(t *testing.T, p *FakePool[*res]) []int {
+			r, _ := p.Acquire()
+			p.Release(r)
+			p.Close()
+			if _, err := p.Acquire(); !errors.Is(err, pool.ErrPoolClosed) {
+				t.Errorf("Acquire after Close = %v, want ErrPoolClosed", err)
+			}
+			if s := p.Stats(); s.TotalClosed != 1 || s.Idle != 0 {
+				t.Errorf("TotalClosed = %d, Idle = %d, want 1, 0", s.TotalClosed, s.Idle)
+			}
+			return []int{r.id}
+		}, []int{100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewFakePool(tt.factory)
+			got := tt.run(t, p)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got resources %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got resources %v, want %v", got, tt.want)
+				}
+			}
+			p.AssertAllReleased(t)
+		})
+	}
+}
+
+// TestAssertAllReleased 检查AssertAllReleased报告没有放回的资源
+func TestAssertAllReleased(t *testing.T) {
+	p := NewFakePool[*res](nil)
+	p.Script(&res{1})
+	r, _ := p.Acquire()
+	var ft fakeTB
+	p.AssertAllReleased(&ft)
+	if !ft.failed {
+		t.Error("AssertAllReleased passed with a resource checked out")
+	}
+	if s := p.Stats(); s.InUse != 1 || len(p.Outstanding()) != 1 {
+		t.Errorf("InUse = %d, Outstanding = %v, want the checked out resource", s.InUse, p.Outstanding())
+	}
+	p.Release(r)
+}
+
+// fakeTB 记录测试是否失败
+type fakeTB struct {
+	testing.TB
+	failed bool
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(string, ...any) { f.failed = true }
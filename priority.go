@@ -0,0 +1,71 @@
+package pool
+
+import (
+	"context"
+	"time"
+)
+
+// Priority 是Acquire排队等待时的优先级，资源被放回或容量被释放时优先唤醒优先级高的等待者
+type Priority int
+
+const (
+	// PriorityLow 用于后台任务等可以等待的请求
+	PriorityLow Priority = iota
+	// PriorityNormal 是Acquire和AcquireContext使用的优先级
+	PriorityNormal
+	// PriorityHigh 用于交互请求等对延迟敏感的请求，可以使用WithHighPriorityReserve保留的容量
+	PriorityHigh
+)
+
+// waiter 是一个排队等待资源的Acquire
+// 被唤醒时从ch收到nil，表示需要重新检查池；direct为true的等待者也可能直接收到Release交给它的资源
+type waiter[T any] struct {
+	ch     chan *entry[T]
+	prio   Priority
+	n      uint      // 需要的资源数
+	direct bool      // 是否接受Release直接交给它的资源
+	weight uint      // AcquireWithMinWeight要求的最小权重，0表示任意资源
+	stack  []byte    // 开启泄漏检测时等待者获取资源的调用栈
+	since  time.Time // 开始排队的时间
+
+	consumer string // AcquireAs的消费者，匿名的Acquire为空
+	tag      uint64 // 公平调度的虚拟完成时间，同一优先级按它从小到大排列
+}
+
+// AcquireWithPriority 与AcquireContext相同，但以优先级prio排队等待
+// 优先级高的等待者总是先于优先级低的等待者得到资源，同一优先级按到达的顺序，有多个消费者时见AcquireAs
+func (p *Pool[T]) AcquireWithPriority(ctx context.Context, prio Priority) (T, error) {
+	return p.acquire(ctx, prio, "", acquireWait)
+}
+
+// enqueue 把w按优先级和公平调度的虚拟完成时间排队，front为true时排到完成时间相同的等待者前面
+// 返回排队的w，重新排队时把它的tag和since传回来以保留原来的位置，调用者需持有p.m
+func (p *Pool[T]) enqueue(w waiter[T], front bool) waiter[T] {
+	if w.since.IsZero() {
+		w.since = p.clock.Now()
+	}
+	if w.tag == 0 {
+		w.tag = p.fairTag(w.consumer, w.n)
+	}
+	i := len(p.waiters)
+	for j, c := range p.waiters {
+		if c.prio < w.prio || c.prio == w.prio && (c.tag > w.tag || front && c.tag == w.tag) {
+			i = j
+			break
+		}
+	}
+	p.waiters = append(p.waiters, waiter[T]{})
+	copy(p.waiters[i+1:], p.waiters[i:])
+	p.waiters[i] = w
+	return w
+}
+
+// overReserve 判断优先级为prio的Acquire再取得n个资源是否会占用留给PriorityHigh的容量
+// 调用者需持有p.m
+func (p *Pool[T]) overReserve(prio Priority, n uint) bool {
+	if prio >= PriorityHigh || p.reserved == 0 || p.maxTotal == 0 {
+		return false
+	}
+	inUse := p.numOpen - uint(p.idle.Len())
+	return inUse+n+p.reserved > p.maxTotal
+}
@@ -0,0 +1,125 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lazysheep666/pool"
+)
+
+// startPriority 在新的goroutine中以优先级prio获取一个资源，排队后才返回，结果发送到返回的通道
+func startPriority(t *testing.T, p *pool.Pool[*tracked], prio pool.Priority) <-chan acquired {
+	t.Helper()
+	before := p.Waiting()
+	c := make(chan acquired, 1)
+	go func() {
+		r, err := p.AcquireWithPriority(context.Background(), prio)
+		c <- acquired{r, err}
+	}()
+	eventually(t, "AcquireWithPriority to queue", func() bool { return p.Waiting() > before })
+	return c
+}
+
+func TestPriorityOrder(t *testing.T) {
+	const low, normal, high = pool.PriorityLow, pool.PriorityNormal, pool.PriorityHigh
+	tests := []struct {
+		name  string
+		prios []pool.Priority // 依次排队的等待者的优先级
+		want  []int           // 依次得到资源的等待者
+	}{
+		{"same priority in arrival order", []pool.Priority{normal, normal, normal}, []int{0, 1, 2}},
+		{"higher priority first", []pool.Priority{low, normal, high}, []int{2, 1, 0}},
+		{"arrival order within priority", []pool.Priority{low, high, normal, high, low}, []int{1, 3, 2, 0, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := newHarnessPool(t, pool.WithMaxTotal(1))
+			r := acquire(t, p)
+			results := make([]<-chan acquired, len(tt.prios))
+			for i, prio := range tt.prios {
+				results[i] = startPriority(t, p, prio)
+			}
+			// 只有一个资源，每个等待者得到资源后放回，交给下一个，顺序错误时等待者得不到资源
+			for _, i := range tt.want {
+				release(t, p, r)
+				res := result(t, results[i])
+				if res.err != nil {
+					t.Fatalf("waiter %d: %v", i, res.err)
+				}
+				r = res.r
+			}
+			release(t, p, r)
+		})
+	}
+}
+
+func TestHighPriorityReserve(t *testing.T) {
+	const normal, high = pool.PriorityNormal, pool.PriorityHigh
+	// step 以优先级prio获取一个资源，返回want
+	type step struct {
+		prio pool.Priority
+		want error
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"normal stops at reserve", []step{
+			{normal, nil},
+			{normal, nil},
+			{normal, pool.ErrPoolExhausted},
+		}},
+		{"high uses reserve", []step{
+			{normal, nil},
+			{normal, nil},
+			{high, nil},
+			{high, pool.ErrPoolExhausted},
+		}},
+		{"high takes any capacity", []step{
+			{high, nil},
+			{high, nil},
+			{normal, pool.ErrPoolExhausted},
+			{high, nil},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := newHarnessPool(t, pool.WithMaxTotal(3), pool.WithHighPriorityReserve(1), pool.WithBlocking(false))
+			var held []*tracked
+			for i, s := range tt.steps {
+				r, err := p.AcquireWithPriority(context.Background(), s.prio)
+				if !errors.Is(err, s.want) || (s.want == nil) != (err == nil) {
+					t.Fatalf("step %d: AcquireWithPriority(%v) = %v, want %v", i, s.prio, err, s.want)
+				}
+				if err == nil {
+					held = append(held, r)
+				}
+			}
+			for _, r := range held {
+				release(t, p, r)
+			}
+		})
+	}
+}
+
+// TestHighPriorityReserveWaiter 检查等待中的普通Acquire不占用保留的容量，PriorityHigh的Acquire直接得到资源
+func TestHighPriorityReserveWaiter(t *testing.T) {
+	p, _ := newHarnessPool(t, pool.WithMaxTotal(2), pool.WithHighPriorityReserve(1))
+	a := acquire(t, p)
+	c := startWaiter(t, p, context.Background())
+	r, err := p.AcquireWithPriority(context.Background(), pool.PriorityHigh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Waiting() != 1 {
+		t.Errorf("Waiting = %d, want the normal Acquire still waiting", p.Waiting())
+	}
+	release(t, p, r)
+	release(t, p, a)
+	res := result(t, c)
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	release(t, p, res.r)
+}
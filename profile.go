@@ -0,0 +1,80 @@
+package pool
+
+import (
+	"fmt"
+	"runtime/pprof"
+	"sync"
+)
+
+// profileNames 保证多个池注册到runtime/pprof的名字不重复
+var profileNames sync.Mutex
+
+// checkoutKey 是一次借出在pprof.Profile中的键，不能是零大小的类型，否则不同的指针可能相等
+type checkoutKey struct{ _ byte }
+
+// newCheckoutProfile 创建并注册名为pool.checkouts(有名字的池为pool.checkouts.名字)的pprof.Profile，
+// 已经存在时在名字后面加上序号
+func newCheckoutProfile(name string) *pprof.Profile {
+	base := "pool.checkouts"
+	if name != "" {
+		base += "." + name
+	}
+	profileNames.Lock()
+	defer profileNames.Unlock()
+	n := base
+	for i := 2; pprof.Lookup(n) != nil; i++ {
+		n = fmt.Sprintf("%s.%d", base, i)
+	}
+	return pprof.NewProfile(n)
+}
+
+// Profile 返回记录了正在使用的资源的pprof.Profile，每个样本是一个借出的资源，调用栈是获取它的调用栈，
+// 用go tool pprof查看哪些代码持有最多的资源：
+//
+//	p.Profile().WriteTo(f, 0)
+//	go tool pprof -top f
+//
+// Profile同时注册在runtime/pprof中，名字见Profile().Name()，可以通过net/http/pprof访问；共享的资源只记录第一个借用者，
+// AcquireN等批量获取的资源不被记录，没有设置WithCheckoutProfile时返回nil
+func (p *Pool[T]) Profile() *pprof.Profile {
+	if p.profile == nil {
+		return nil
+	}
+	p.m.Lock()
+	defer p.m.Unlock()
+	// 不经过checkin离开使用中的资源(强制关闭、回收等)在这里移除
+	for key, e := range p.profiled {
+		if cur, ok := p.inUse.Get(e.r); !ok || cur != e || e.profKey != key {
+			p.profile.Remove(key)
+			delete(p.profiled, key)
+			if e.profKey == key {
+				e.profKey = nil
+			}
+		}
+	}
+	return p.profile
+}
+
+// profileCheckout 把刚获取的资源r加入Profile，skip与runtime.Caller的skip相同，决定调用栈从哪一层开始
+func (p *Pool[T]) profileCheckout(r T, skip int) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	e, _ := p.inUse.Get(r)
+	if e == nil || e.refs != 1 || e.profKey != nil {
+		return
+	}
+	key := &checkoutKey{}
+	e.profKey = key
+	p.profiled[key] = e
+	p.profile.Add(key, skip+1)
+}
+
+// unprofile 把e从Profile中移除，调用者需持有p.m
+func (p *Pool[T]) unprofile(e *entry[T]) {
+	if e.profKey == nil {
+		return
+	}
+	p.profile.Remove(e.profKey)
+	delete(p.profiled, e.profKey)
+	e.profKey = nil
+}
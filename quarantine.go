@@ -0,0 +1,49 @@
+package pool
+
+import "context"
+
+// quarantine 隔离没有通过验证函数的资源e，在后台按退避时间重新验证它，调用者需持有p.m
+// 隔离的资源仍然计入numOpen
+func (p *Pool[T]) quarantine(e *entry[T]) {
+	p.logger.Println("Quarantine:", "Resource Quarantined")
+	p.quarantined++
+	go p.retryQuarantined(e)
+}
+
+// retryQuarantined 等待退避时间后重新验证隔离的资源e，通过时把它放回池里，
+// 重试quarantineN次仍然失败或池被关闭时销毁它
+func (p *Pool[T]) retryQuarantined(e *entry[T]) {
+	delay := p.quarantineBackoff
+	for attempt := uint(1); ; attempt++ {
+		t := p.clock.NewTimer(delay)
+		select {
+		case <-t.C():
+		case <-p.done:
+			t.Stop()
+			p.m.Lock()
+			p.quarantined--
+			p.retire(e)
+			p.unlock()
+			return
+		}
+		ok := p.validator(context.Background(), e.r)
+		p.m.Lock()
+		if ok {
+			p.quarantined--
+			e.invalid = false
+			p.logger.Println("Quarantine:", "Resource Recovered")
+			p.putBack(e)
+			p.unlock()
+			return
+		}
+		if attempt >= p.quarantineN || p.closed {
+			p.quarantined--
+			p.logger.Println("Quarantine:", "Closing")
+			p.retire(e)
+			p.unlock()
+			return
+		}
+		p.m.Unlock()
+		delay *= 2
+	}
+}
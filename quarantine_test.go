@@ -0,0 +1,106 @@
+package pool_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lazysheep666/pool"
+)
+
+func TestQuarantine(t *testing.T) {
+	const retries, backoff = 2, time.Second
+	tests := []struct {
+		name string
+		// run 在资源1被隔离之后推进时钟，可以在其中让它恢复
+		run func(t *testing.T, p *pool.Pool[*tracked], h *harness, checks *atomic.Int64, recover func())
+		// 最后关闭的资源数和空闲资源数
+		wantClosed int64
+		wantIdle   uint
+	}{
+		{"recovers on first retry", func(t *testing.T, p *pool.Pool[*tracked], h *harness, checks *atomic.Int64, recover func()) {
+			h.clock.BlockUntil(1)
+			recover()
+			h.clock.Advance(backoff)
+		}, 0, 2},
+		{"backoff doubles", func(t *testing.T, p *pool.Pool[*tracked], h *harness, checks *atomic.Int64, recover func()) {
+			h.clock.BlockUntil(1)
+			h.clock.Advance(backoff)
+			eventually(t, "first retry", func() bool { return checks.Load() == 2 })
+			h.clock.BlockUntil(1)
+			h.clock.Advance(2*backoff - time.Millisecond)
+			if checks.Load() != 2 {
+				t.Errorf("second retry before %v", 2*backoff)
+			}
+			recover()
+			h.clock.Advance(time.Millisecond)
+		}, 0, 2},
+		{"closed after retries", func(t *testing.T, p *pool.Pool[*tracked], h *harness, checks *atomic.Int64, recover func()) {
+			h.clock.BlockUntil(1)
+			h.clock.Advance(backoff)
+			h.clock.BlockUntil(1)
+			h.clock.Advance(2 * backoff)
+		}, 1, 1},
+		{"closed with pool", func(t *testing.T, p *pool.Pool[*tracked], h *harness, checks *atomic.Int64, recover func()) {
+			h.clock.BlockUntil(1)
+			if err := p.Close(); err != nil {
+				t.Fatal(err)
+			}
+		}, 2, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bad, checks atomic.Int64
+			bad.Store(1)
+			p, h := newHarnessPool(t,
+				pool.WithQuarantine(retries, backoff),
+				pool.WithValidator(func(r *tracked) bool {
+					if r.id != bad.Load() {
+						return true
+					}
+					checks.Add(1)
+					return false
+				}),
+			)
+			release(t, p, acquire(t, p))
+			// 资源1没有通过验证并被隔离，Acquire改为创建资源2
+			r := acquire(t, p)
+			if r.id != 2 {
+				t.Fatalf("got resource %d, want a new resource", r.id)
+			}
+			release(t, p, r)
+			if s := p.Stats(); s.Quarantined != 1 || s.Idle != 1 || s.InUse != 0 {
+				t.Fatalf("Quarantined = %d, Idle = %d, InUse = %d, want 1, 1, 0", s.Quarantined, s.Idle, s.InUse)
+			}
+			tt.run(t, p, h, &checks, func() { bad.Store(0) })
+			eventually(t, "quarantine to end", func() bool { return p.Stats().Quarantined == 0 })
+			if s := p.Stats(); h.closed.Load() != tt.wantClosed || s.Idle != tt.wantIdle {
+				t.Errorf("closed = %d, Idle = %d, want %d, %d", h.closed.Load(), s.Idle, tt.wantClosed, tt.wantIdle)
+			}
+		})
+	}
+}
+
+// TestQuarantineOnRelease 检查WithValidateOnRelease时放回的资源没有通过验证也被隔离
+func TestQuarantineOnRelease(t *testing.T) {
+	var healthy atomic.Bool
+	healthy.Store(true)
+	p, h := newHarnessPool(t,
+		pool.WithQuarantine(1, time.Second),
+		pool.WithValidateOnRelease(true),
+		pool.WithValidator(func(*tracked) bool { return healthy.Load() }),
+	)
+	r := acquire(t, p)
+	healthy.Store(false)
+	release(t, p, r)
+	if s := p.Stats(); s.Quarantined != 1 || s.Idle != 0 {
+		t.Fatalf("Quarantined = %d, Idle = %d, want 1, 0", s.Quarantined, s.Idle)
+	}
+	h.clock.BlockUntil(1)
+	healthy.Store(true)
+	h.clock.Advance(time.Second)
+	eventually(t, "resource to recover", func() bool { return p.Stats().Idle == 1 })
+	if h.closed.Load() != 0 {
+		t.Errorf("closed %d resources, want 0", h.closed.Load())
+	}
+}
@@ -0,0 +1,142 @@
+package pool
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ParseQuery 把URL查询参数转换为设置Config中对应字段的Option，用于从连接串创建池
+// 参数名是字段名的小写下划线形式，例如max_total=50&idle_timeout=30s，
+// 时间使用time.ParseDuration的格式，OverflowPolicy可以是discard、block或panic，ReuseStrategy可以是fifo或lifo，IdleStore可以是slice、ring或lockfree，
+// 未知的参数或不合法的值返回ErrInvalidConfig
+func ParseQuery(q url.Values) (Option, error) {
+	type field struct {
+		index int
+		value reflect.Value
+	}
+	var fields []field
+	for key, values := range q {
+		i, ok := queryFields()[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown parameter %q", ErrInvalidConfig, key)
+		}
+		v, err := parseQueryValue(reflect.TypeOf(Config{}).Field(i).Type, values[len(values)-1])
+		if err != nil {
+			return nil, fmt.Errorf("%w: parameter %q: %v", ErrInvalidConfig, key, err)
+		}
+		fields = append(fields, field{i, v})
+	}
+	return func(s *settings) {
+		c := reflect.ValueOf(&s.Config).Elem()
+		for _, f := range fields {
+			c.Field(f.index).Set(f.value)
+		}
+	}, nil
+}
+
+// overflowNames、reuseNames和storeNames 是OverflowPolicy、ReuseStrategy和IdleStore在参数和配置文件中的名字
+var (
+	overflowNames = map[string]OverflowPolicy{"discard": DiscardOverflow, "block": BlockOnOverflow, "panic": PanicOnOverflow}
+	reuseNames    = map[string]ReuseStrategy{"fifo": FIFO, "lifo": LIFO}
+	storeNames    = map[string]IdleStore{"slice": SliceStore, "ring": RingStore, "lockfree": LockFreeStore}
+)
+
+// queryFields 返回参数名到Config字段下标的映射，只包含ParseQuery支持的类型的字段
+// 参数名取字段的json标签，没有标签时使用字段名的小写下划线形式
+func queryFields() map[string]int {
+	t := reflect.TypeOf(Config{})
+	fields := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = snakeCase(f.Name)
+		}
+		switch f.Type.Kind() {
+		case reflect.Bool, reflect.Int, reflect.Int64, reflect.Uint, reflect.Float64, reflect.String:
+			fields[name] = i
+		}
+	}
+	return fields
+}
+
+// parseQueryValue 把s解析为类型t的值
+func parseQueryValue(t reflect.Type, s string) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+	switch t {
+	case reflect.TypeOf(time.Duration(0)):
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return v, err
+		}
+		v.SetInt(int64(d))
+		return v, nil
+	case reflect.TypeOf(OverflowPolicy(0)):
+		if p, ok := overflowNames[strings.ToLower(s)]; ok {
+			v.SetInt(int64(p))
+			return v, nil
+		}
+		if _, err := strconv.Atoi(s); err != nil {
+			return v, fmt.Errorf("unknown value %q, want discard, block or panic", s)
+		}
+	case reflect.TypeOf(ReuseStrategy(0)):
+		if r, ok := reuseNames[strings.ToLower(s)]; ok {
+			v.SetInt(int64(r))
+			return v, nil
+		}
+		if _, err := strconv.Atoi(s); err != nil {
+			return v, fmt.Errorf("unknown value %q, want fifo or lifo", s)
+		}
+	case reflect.TypeOf(IdleStore(0)):
+		if r, ok := storeNames[strings.ToLower(s)]; ok {
+			v.SetInt(int64(r))
+			return v, nil
+		}
+		if _, err := strconv.Atoi(s); err != nil {
+			return v, fmt.Errorf("unknown value %q, want slice or ring", s)
+		}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		v.SetBool(b)
+		return v, err
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		v.SetInt(n)
+		return v, err
+	case reflect.Uint:
+		n, err := strconv.ParseUint(s, 10, 0)
+		v.SetUint(n)
+		return v, err
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		v.SetFloat(f)
+		return v, err
+	default:
+		v.SetString(s)
+		return v, nil
+	}
+}
+
+// snakeCase 把MaxTotal、OverflowTTL这样的字段名转换为max_total、overflow_ttl
+func snakeCase(name string) string {
+	rs := []rune(name)
+	var b strings.Builder
+	for i, r := range rs {
+		if unicode.IsUpper(r) && i > 0 &&
+			(!unicode.IsUpper(rs[i-1]) || i+1 < len(rs) && unicode.IsLower(rs[i+1])) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
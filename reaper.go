@@ -0,0 +1,112 @@
+package pool
+
+import (
+	"math"
+	"time"
+)
+
+// reaper 每隔interval回收一次过期的空闲资源并把空闲资源补足到MinIdle，直到池被关闭
+func (p *Pool[T]) reaper(interval time.Duration) {
+	ticker := p.clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			p.reap(p.clock.Now())
+			p.replenish()
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// reap 关闭超过maxLifetime的空闲资源，以及空闲时间超过idleTimeout的资源，
+// 后者至少保留minIdle个，空闲时间超过overflowTTL的溢出资源总会被关闭，设置了CompactIdle时再按需求收缩空闲资源
+func (p *Pool[T]) reap(now time.Time) {
+	start := p.clock.Now()
+	p.m.Lock()
+	if p.closed {
+		p.m.Unlock()
+		return
+	}
+	// 暂存的资源移回空闲资源后才会按空闲时间回收
+	p.unpark()
+	expired := 0
+	// left 是还没有检查的资源数，kept 是已经保留的资源数
+	left, kept := p.idle.Len(), 0
+	p.idle.Retain(func(e *entry[T]) bool {
+		left--
+		idleExpired := p.idleTimeout > 0 && now.Sub(e.returnedAt) > jittered(p.idleTimeout, e.jitter) &&
+			uint(left+kept) >= p.minIdle
+		overflowExpired := e.overflow && p.numOpen > p.maxTotal && now.Sub(e.returnedAt) > p.overflowTTL
+		if idleExpired || overflowExpired || p.expired(e, now) {
+			switch {
+			case p.expired(e, now):
+				p.stats.lifetimeClosed.Add(1)
+			case idleExpired:
+				p.stats.idleClosed.Add(1)
+			}
+			p.retire(e)
+			expired++
+			return false
+		}
+		kept++
+		return true
+	})
+	if p.compactIdle {
+		expired += p.compact()
+	}
+	p.unlock()
+
+	if expired > 0 {
+		p.logger.Println("Reap:", "Closed", expired, "Idle Resources")
+	}
+	if p.onEvent != nil {
+		end := p.clock.Now()
+		p.emit(Event{Type: ReaperRun, Time: end, Duration: end.Sub(start), Count: expired})
+	}
+}
+
+// demandWeight 是compact计算需求的移动平均时最近一次峰值的权重
+const demandWeight = 0.2
+
+// notePeak 记录同时使用的资源数的峰值，调用者需持有p.m
+func (p *Pool[T]) notePeak() {
+	if n := uint(len(p.inUse)); n > p.peakInUse {
+		p.peakInUse = n
+	}
+	if n := uint(len(p.inUse)); n > p.maxInUse {
+		p.maxInUse = n
+	}
+}
+
+// compact 用上一个回收间隔内使用中资源数的峰值更新需求的移动平均，
+// 关闭超出需求的空闲资源的一半，返回关闭的资源数，调用者需持有p.m，并用p.unlock解锁
+func (p *Pool[T]) compact() int {
+	inUse := uint(len(p.inUse))
+	peak := p.peakInUse
+	p.peakInUse = inUse
+	if p.demand == 0 {
+		p.demand = float64(peak)
+	} else {
+		p.demand = demandWeight*float64(peak) + (1-demandWeight)*p.demand
+	}
+	target := uint(math.Ceil(p.demand))
+	if target > inUse {
+		target -= inUse
+	} else {
+		target = 0
+	}
+	if target < p.minIdle {
+		target = p.minIdle
+	}
+	if uint(p.idle.Len()) <= target {
+		return 0
+	}
+	n := (uint(p.idle.Len()) - target + 1) / 2
+	for i := uint(0); i < n; i++ {
+		p.retire(p.takeIdle(0))
+	}
+	p.stats.compactionClosed.Add(uint64(n))
+	return int(n)
+}
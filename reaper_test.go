@@ -0,0 +1,88 @@
+package pool_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lazysheep666/pool"
+)
+
+func TestReaper(t *testing.T) {
+	const idleTimeout, interval = time.Minute, 10 * time.Second
+	tests := []struct {
+		name    string
+		minIdle uint
+		// run 放回资源并用reap推进时钟
+		run        func(t *testing.T, p *pool.Pool[*tracked], h *harness, reap func(time.Duration))
+		wantClosed int64
+		wantIdle   uint
+	}{
+		{"before idle timeout", 0, func(t *testing.T, p *pool.Pool[*tracked], h *harness, reap func(time.Duration)) {
+			a, b := acquire(t, p), acquire(t, p)
+			release(t, p, a)
+			release(t, p, b)
+			reap(idleTimeout - time.Second)
+		}, 0, 2},
+		{"after idle timeout", 0, func(t *testing.T, p *pool.Pool[*tracked], h *harness, reap func(time.Duration)) {
+			a, b := acquire(t, p), acquire(t, p)
+			release(t, p, a)
+			release(t, p, b)
+			reap(idleTimeout + time.Second)
+		}, 2, 0},
+		{"measured from release", 0, func(t *testing.T, p *pool.Pool[*tracked], h *harness, reap func(time.Duration)) {
+			a, b := acquire(t, p), acquire(t, p)
+			release(t, p, a)
+			reap(40 * time.Second)
+			release(t, p, b)
+			reap(30 * time.Second)
+		}, 1, 1},
+		{"reuse resets idle time", 0, func(t *testing.T, p *pool.Pool[*tracked], h *harness, reap func(time.Duration)) {
+			release(t, p, acquire(t, p))
+			reap(40 * time.Second)
+			release(t, p, acquire(t, p))
+			reap(40 * time.Second)
+		}, 0, 1},
+		{"keeps MinIdle", 1, func(t *testing.T, p *pool.Pool[*tracked], h *harness, reap func(time.Duration)) {
+			a, b := acquire(t, p), acquire(t, p)
+			release(t, p, a)
+			release(t, p, b)
+			reap(idleTimeout + time.Second)
+		}, 1, 1},
+		{"checked out resources untouched", 0, func(t *testing.T, p *pool.Pool[*tracked], h *harness, reap func(time.Duration)) {
+			r := acquire(t, p)
+			reap(idleTimeout + time.Second)
+			release(t, p, r)
+		}, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var runs atomic.Int64
+			p, h := newHarnessPool(t,
+				pool.WithIdleTimeout(idleTimeout),
+				pool.WithReapInterval(interval),
+				pool.WithMinIdle(tt.minIdle),
+				pool.WithEventSink(func(e pool.Event) {
+					if e.Type == pool.ReaperRun {
+						runs.Add(1)
+					}
+				}),
+			)
+			h.clock.BlockUntil(1)
+			// reap 推进时钟d，等待后台goroutine完成由此触发的一次回收
+			reap := func(d time.Duration) {
+				want := runs.Load() + 1
+				h.clock.Advance(d)
+				eventually(t, "reaper run", func() bool { return runs.Load() >= want })
+			}
+			tt.run(t, p, h, reap)
+			s := p.Stats()
+			if h.closed.Load() != tt.wantClosed || s.IdleClosed != uint64(tt.wantClosed) {
+				t.Errorf("closed = %d, IdleClosed = %d, want %d", h.closed.Load(), s.IdleClosed, tt.wantClosed)
+			}
+			if s.Idle != tt.wantIdle {
+				t.Errorf("Idle = %d, want %d", s.Idle, tt.wantIdle)
+			}
+		})
+	}
+}
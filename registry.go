@@ -0,0 +1,202 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Managed 是可以被Registry管理的资源池，*Pool和*ShardedPool都实现了这个接口
+type Managed interface {
+	Stats() Stats
+	CloseContext(ctx context.Context) error
+}
+
+// Registry 按名字管理一组资源池，用于统一关闭和导出统计信息
+type Registry struct {
+	mu    sync.Mutex
+	pools map[string]Managed
+	deps  map[string]map[string]bool // 每个资源池依赖的资源池，见DependsOn
+}
+
+// NewRegistry 创建一个空的Registry
+func NewRegistry() *Registry {
+	return &Registry{pools: make(map[string]Managed), deps: make(map[string]map[string]bool)}
+}
+
+// Register 以name为名字注册p，name已被使用时返回错误
+func (reg *Registry) Register(name string, p Managed) error {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if _, ok := reg.pools[name]; ok {
+		return fmt.Errorf("pool %q already registered", name)
+	}
+	reg.pools[name] = p
+	return nil
+}
+
+// Unregister 取消注册name对应的资源池，不会关闭它，与它有关的依赖关系一起被删除
+func (reg *Registry) Unregister(name string) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	delete(reg.pools, name)
+	delete(reg.deps, name)
+	for _, deps := range reg.deps {
+		delete(deps, name)
+	}
+}
+
+// DependsOn 声明名为a的资源池依赖名为b的资源池，例如HTTP客户端的池依赖DNS解析器的池，
+// CloseAll会在a关闭之后才关闭b；a或b没有注册，或者这个依赖会形成环时返回错误
+func (reg *Registry) DependsOn(a, b string) error {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	for _, name := range []string{a, b} {
+		if _, ok := reg.pools[name]; !ok {
+			return fmt.Errorf("pool %q not registered", name)
+		}
+	}
+	if a == b || reg.reaches(b, a) {
+		return fmt.Errorf("pool %q depending on %q would form a cycle", a, b)
+	}
+	if reg.deps[a] == nil {
+		reg.deps[a] = make(map[string]bool)
+	}
+	reg.deps[a][b] = true
+	return nil
+}
+
+// reaches 判断from是否直接或间接地依赖to，调用者需持有reg.mu
+func (reg *Registry) reaches(from, to string) bool {
+	seen := map[string]bool{from: true}
+	stack := []string{from}
+	for len(stack) > 0 {
+		name := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for dep := range reg.deps[name] {
+			if dep == to {
+				return true
+			}
+			if !seen[dep] {
+				seen[dep] = true
+				stack = append(stack, dep)
+			}
+		}
+	}
+	return false
+}
+
+// Get 返回name对应的资源池
+func (reg *Registry) Get(name string) (Managed, bool) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	p, ok := reg.pools[name]
+	return p, ok
+}
+
+// Names 按字母顺序返回所有已注册的名字
+func (reg *Registry) Names() []string {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	names := make([]string, 0, len(reg.pools))
+	for name := range reg.pools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Stats 返回每个资源池的统计信息
+func (reg *Registry) Stats() map[string]Stats {
+	stats := make(map[string]Stats)
+	for name, p := range reg.snapshot() {
+		stats[name] = p.Stats()
+	}
+	return stats
+}
+
+// TotalStats 返回所有资源池统计信息的总和
+func (reg *Registry) TotalStats() Stats {
+	var total Stats
+	for _, p := range reg.snapshot() {
+		total = total.add(p.Stats())
+	}
+	return total
+}
+
+// CloseAll 按DependsOn声明的依赖关系分批关闭所有资源池：一个资源池在依赖它的资源池都关闭之后才开始关闭，
+// 同一批中的资源池并发地关闭，关闭失败不影响后面的批次
+// 最多等待到ctx结束，返回所有关闭失败的错误
+func (reg *Registry) CloseAll(ctx context.Context) error {
+	pools, dependents, deps := reg.graph()
+	var errs []error
+	for len(pools) > 0 {
+		var batch []string
+		for name := range pools {
+			if dependents[name] == 0 {
+				batch = append(batch, name)
+			}
+		}
+		sort.Strings(batch)
+		errs = append(errs, closeBatch(ctx, pools, batch)...)
+		for _, name := range batch {
+			delete(pools, name)
+			for _, dep := range deps[name] {
+				dependents[dep]--
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// closeBatch 并发地关闭pools中名字在batch里的资源池，返回关闭失败的错误
+func closeBatch(ctx context.Context, pools map[string]Managed, batch []string) []error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+	for _, name := range batch {
+		wg.Add(1)
+		go func(name string, p Managed) {
+			defer wg.Done()
+			if err := p.CloseContext(ctx); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("pool %q: %w", name, err))
+				mu.Unlock()
+			}
+		}(name, pools[name])
+	}
+	wg.Wait()
+	return errs
+}
+
+// graph 在同一次加锁中返回已注册资源池的副本、依赖每个资源池的资源池数和每个资源池依赖的资源池
+func (reg *Registry) graph() (map[string]Managed, map[string]int, map[string][]string) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	pools := make(map[string]Managed, len(reg.pools))
+	for name, p := range reg.pools {
+		pools[name] = p
+	}
+	dependents := make(map[string]int)
+	deps := make(map[string][]string)
+	for name, ds := range reg.deps {
+		for dep := range ds {
+			dependents[dep]++
+			deps[name] = append(deps[name], dep)
+		}
+	}
+	return pools, dependents, deps
+}
+
+// snapshot 返回已注册资源池的副本，避免持有锁时调用资源池的方法
+func (reg *Registry) snapshot() map[string]Managed {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	pools := make(map[string]Managed, len(reg.pools))
+	for name, p := range reg.pools {
+		pools[name] = p
+	}
+	return pools
+}
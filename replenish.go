@@ -0,0 +1,160 @@
+package pool
+
+import (
+	"context"
+	"errors"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Warmup 并发地创建资源，直到空闲资源达到MinIdle，同时创建的资源数受WarmupConcurrency限制
+// 设置了WithStateStore时目标是MinIdle和上次关闭时保存的提示中较大的一个
+// ctx会传给factory，ctx结束时立即返回ctx.Err()，尚未完成并且成功的资源仍会放入池中
+func (p *Pool[T]) Warmup(ctx context.Context) error {
+	p.m.Lock()
+	target := p.minIdle
+	if p.warmHint > target {
+		target = p.warmHint
+	}
+	var n uint
+	if idle := uint(p.idle.Len()) + p.creatingIdle; idle < target {
+		n = target - idle
+	}
+	p.m.Unlock()
+	return p.fill(ctx, n)
+}
+
+// Fill 并发地创建n个资源放入池中，受MaxIdle和MaxTotal限制
+// 返回所有创建失败的错误
+func (p *Pool[T]) Fill(n uint) error {
+	return p.fill(context.Background(), n)
+}
+
+// fill 占用至多n个容量并发地创建资源放入空闲资源中
+func (p *Pool[T]) fill(ctx context.Context, n uint) error {
+	p.m.Lock()
+	if p.closed {
+		p.m.Unlock()
+		return ErrPoolClosed
+	}
+	if p.shutdown != nil {
+		p.m.Unlock()
+		return ErrPoolShuttingDown
+	}
+	if idle := uint(p.idle.Len()) + p.creatingIdle; idle+n > p.maxIdle {
+		n = 0
+		if idle < p.maxIdle {
+			n = p.maxIdle - idle
+		}
+	}
+	if p.weightFull() {
+		n = 0
+	}
+	if p.maxTotal > 0 && p.numOpen+n > p.maxTotal {
+		n = 0
+		if p.numOpen < p.maxTotal {
+			n = p.maxTotal - p.numOpen
+		}
+	}
+	p.numOpen += n
+	p.creatingIdle += n
+	p.m.Unlock()
+
+	errs := make(chan error, n)
+	go func() {
+		var g errgroup.Group
+		if p.warmupParallel > 0 {
+			g.SetLimit(int(p.warmupParallel))
+		}
+		for i := uint(0); i < n; i++ {
+			g.Go(func() error {
+				errs <- p.createIdle(ctx)
+				return nil
+			})
+		}
+	}()
+	var failed []error
+	for i := uint(0); i < n; i++ {
+		select {
+		case err := <-errs:
+			if err != nil {
+				failed = append(failed, err)
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return errors.Join(failed...)
+}
+
+// replenish 在后台创建新资源，直到空闲资源达到minIdle或资源总数达到上限
+// 同一时间只有一个goroutine在补充
+func (p *Pool[T]) replenish() {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.replenishing || p.closed || p.shutdown != nil || uint(p.idle.Len())+p.creatingIdle >= p.minIdle {
+		return
+	}
+	p.replenishing = true
+	go p.fillIdle()
+}
+
+// fillIdle 逐个创建资源放入空闲资源中，直到空闲资源达到minIdle、
+// 资源总数达到上限、池被关闭或创建失败
+func (p *Pool[T]) fillIdle() {
+	for {
+		p.m.Lock()
+		if p.closed || p.shutdown != nil || uint(p.idle.Len())+p.creatingIdle >= p.minIdle ||
+			(p.maxTotal > 0 && p.numOpen >= p.maxTotal) || p.weightFull() {
+			p.replenishing = false
+			p.m.Unlock()
+			return
+		}
+		p.numOpen++
+		p.creatingIdle++
+		p.m.Unlock()
+
+		if err := p.createIdle(context.Background()); err != nil {
+			p.m.Lock()
+			p.replenishing = false
+			p.m.Unlock()
+			p.logger.Println("Replenish:", err)
+			p.reportError(err, SourceReplenish)
+			return
+		}
+	}
+}
+
+// createIdle 为已经占用的容量(同时计入creatingIdle)创建一个资源并放入空闲资源中
+// 池已关闭或空闲资源已满时新资源会被直接销毁
+func (p *Pool[T]) createIdle(ctx context.Context) error {
+	gen := p.generation.Load()
+	r, err := p.newResource(ctx)
+	var w uint
+	var score float64
+	var scored bool
+	if err == nil {
+		w = p.weightOf(r)
+		score, scored = p.scoreOf(r)
+	}
+
+	p.m.Lock()
+	defer p.unlock()
+	p.creatingIdle--
+	if err != nil {
+		p.releaseSlot()
+		return err
+	}
+	if p.closed || p.shutdown != nil || uint(p.idle.Len()) >= p.maxIdle || gen != p.generation.Load() {
+		p.destroy(r)
+		return nil
+	}
+	now := p.clock.Now()
+	p.totalWeight += w
+	e := &entry[T]{r: r, createdAt: now, returnedAt: now, gen: gen, weight: w, jitter: p.newJitter()}
+	p.setScore(e, score, scored)
+	p.idle.Put(e)
+	p.fitIdleBytes()
+	p.broadcast()
+	return nil
+}
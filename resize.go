@@ -0,0 +1,94 @@
+package pool
+
+import "fmt"
+
+// Resize 在运行时调整MaxIdle和MaxTotal，参数的含义与Config中相同
+// 增大时等待的Acquire立即可以创建资源；减小时超出的空闲资源被关闭，超出的使用中资源在放回时关闭
+func (p *Pool[T]) Resize(maxIdle, maxTotal uint) error {
+	p.reconfig.Lock()
+	defer p.reconfig.Unlock()
+	p.m.Lock()
+	cfg := p.liveConfig()
+	p.m.Unlock()
+	cfg.MaxIdle, cfg.MaxTotal = maxIdle, maxTotal
+	return p.updateConfig(cfg)
+}
+
+// UpdateConfig 在运行时应用新的配置，不需要重建池，已有的资源继续使用
+// 可以修改的字段有MaxIdle、MinIdle、MaxTotal、MaxIdleBytes、MaxWaiters、NonBlocking、IdleTimeout、CompactIdle、MaxUses、
+// OverflowPolicy、ReuseStrategy、HighPriorityReserve和EagerReplenish，ReapInterval只在后台回收尚未启动时生效，
+// 其它字段与当前配置不同时返回ErrInvalidConfig，Logger被忽略
+func (p *Pool[T]) UpdateConfig(cfg Config) error {
+	p.reconfig.Lock()
+	defer p.reconfig.Unlock()
+	return p.updateConfig(cfg)
+}
+
+// updateConfig 检查并应用cfg，调用者需持有p.reconfig
+func (p *Pool[T]) updateConfig(cfg Config) error {
+	cfg = cfg.withDefaults()
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	p.m.Lock()
+	if p.closed {
+		p.m.Unlock()
+		return ErrPoolClosed
+	}
+	if cfg.fixed() != p.config.fixed() {
+		p.m.Unlock()
+		return fmt.Errorf("%w: only size limits and idle policies can be changed at runtime", ErrInvalidConfig)
+	}
+	cfg.Logger = p.config.Logger
+	p.config = cfg
+	p.maxIdle = cfg.MaxIdle
+	if p.lf != nil {
+		p.lf.limit.Store(int64(cfg.MaxIdle))
+	}
+	p.minIdle = cfg.MinIdle
+	p.maxTotal = cfg.MaxTotal
+	p.maxIdleBytes = cfg.MaxIdleBytes
+	p.maxWaiters = cfg.MaxWaiters
+	p.nonBlocking = cfg.NonBlocking
+	p.idleTimeout = cfg.IdleTimeout
+	p.compactIdle = cfg.CompactIdle
+	p.maxUses = cfg.MaxUses
+	p.overflow = cfg.OverflowPolicy
+	p.reuse = cfg.ReuseStrategy
+	p.reserved = cfg.HighPriorityReserve
+	p.eagerReplenish = cfg.EagerReplenish
+	// 关闭超出新上限的空闲资源，最早放回的先关闭
+	for uint(p.idle.Len()) > p.maxIdle || p.maxTotal > 0 && p.numOpen > p.maxTotal && p.idle.Len() > 0 {
+		p.retire(p.takeIdle(0))
+	}
+	p.fitIdleBytes()
+	if !p.reaping && (cfg.IdleTimeout > 0 || cfg.MinIdle > 0 || cfg.CompactIdle) {
+		p.reaping = true
+		go p.reaper(cfg.ReapInterval)
+	}
+	p.broadcast()
+	p.unlock()
+	p.logger.Println("UpdateConfig:", "MaxIdle", cfg.MaxIdle, "MaxTotal", cfg.MaxTotal)
+	p.replenish()
+	return nil
+}
+
+// liveConfig 返回池当前使用的配置，包括SetMaxTotal等在创建后修改的值，调用者需持有p.m
+func (p *Pool[T]) liveConfig() Config {
+	cfg := p.config
+	cfg.MaxTotal = p.maxTotal
+	cfg.NonBlocking = p.nonBlocking
+	return cfg
+}
+
+// fixed 返回去掉了可以在运行时修改的字段的配置，用来比较两个配置中不能修改的部分
+func (c Config) fixed() Config {
+	c.MaxIdle, c.MinIdle, c.MaxTotal, c.MaxWaiters, c.MaxIdleBytes = 0, 0, 0, 0, 0
+	c.NonBlocking, c.EagerReplenish = false, false
+	c.IdleTimeout, c.ReapInterval, c.CompactIdle = 0, 0, false
+	c.MaxUses = 0
+	c.OverflowPolicy, c.ReuseStrategy = 0, 0
+	c.HighPriorityReserve = 0
+	c.Logger = nil
+	return c
+}
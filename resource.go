@@ -0,0 +1,91 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync/atomic"
+)
+
+// ErrResourceReleased 表示PooledResource已经被放回池里或销毁
+var ErrResourceReleased = errors.New("Resource has already been released")
+
+// PooledResource 包装一个从池中获取的资源，Close会把资源放回池里而不是关闭它
+type PooledResource[T any] struct {
+	pool *Pool[T]
+	r    T
+	done atomic.Bool
+}
+
+// AcquireResource 与AcquireContext相同，但返回包装后的资源
+// 使用完后调用Close放回池里，资源损坏时调用Destroy
+// 设置了WithReclaimAbandoned时，没有Close或Destroy就被垃圾回收的PooledResource中的资源会被关闭
+func (p *Pool[T]) AcquireResource(ctx context.Context) (*PooledResource[T], error) {
+	r, err := p.AcquireContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pr := &PooledResource[T]{pool: p, r: r}
+	if p.reclaimAbandoned {
+		runtime.SetFinalizer(pr, (*PooledResource[T]).abandoned)
+	}
+	return pr, nil
+}
+
+// abandoned 在pr没有Close或Destroy就被垃圾回收时调用
+func (pr *PooledResource[T]) abandoned() {
+	if pr.done.CompareAndSwap(false, true) {
+		pr.pool.reclaim(pr.r)
+	}
+}
+
+// Value 返回被包装的资源，Close或Destroy之后不应再使用它
+func (pr *PooledResource[T]) Value() T {
+	return pr.r
+}
+
+// Close 把资源放回池里，重复调用时返回ErrResourceReleased
+func (pr *PooledResource[T]) Close() error {
+	if !pr.done.CompareAndSwap(false, true) {
+		return ErrResourceReleased
+	}
+	runtime.SetFinalizer(pr, nil)
+	return pr.pool.Release(pr.r)
+}
+
+// Destroy 销毁资源而不是放回池里，重复调用或在Close之后调用时返回ErrResourceReleased
+func (pr *PooledResource[T]) Destroy() error {
+	if !pr.done.CompareAndSwap(false, true) {
+		return ErrResourceReleased
+	}
+	runtime.SetFinalizer(pr, nil)
+	return pr.pool.Discard(pr.r)
+}
+
+// ReleaseFunc 把AcquireFunc获取的资源放回池里，只有第一次调用有效，之后的调用什么也不做
+type ReleaseFunc func()
+
+// AcquireFunc 与AcquireContext相同，但同时返回放回资源的函数，调用者不需要另外保存池的引用
+//
+//	r, release, err := p.AcquireFunc(ctx)
+//	if err != nil {
+//		return err
+//	}
+//	defer release()
+//
+// 需要在资源损坏时销毁它时使用AcquireFuncs
+func (p *Pool[T]) AcquireFunc(ctx context.Context) (r T, release ReleaseFunc, err error) {
+	r, release, _, err = p.AcquireFuncs(ctx)
+	return r, release, err
+}
+
+// AcquireFuncs 与AcquireFunc相同，但还返回销毁资源的函数，release和discard中只有第一次调用的一个有效，
+// 因此可以在defer release()之后，在资源损坏时调用discard
+func (p *Pool[T]) AcquireFuncs(ctx context.Context) (r T, release, discard ReleaseFunc, err error) {
+	pr, err := p.AcquireResource(ctx)
+	if err != nil {
+		var zero T
+		return zero, nil, nil, err
+	}
+	return pr.r, func() { pr.Close() }, func() { pr.Destroy() }, nil
+}
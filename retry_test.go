@@ -0,0 +1,90 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lazysheep666/pool"
+)
+
+func TestFactoryRetry(t *testing.T) {
+	const attempts, backoff = 3, time.Second
+	tests := []struct {
+		name string
+		// run 在factory每次等待重试时推进时钟，可以在其中改变factory是否失败
+		run func(t *testing.T, f *flaky, cancel context.CancelFunc)
+		// Acquire返回的错误，nil表示成功，和factory被调用的次数
+		wantErr      error
+		wantAttempts uint
+	}{
+		{"succeeds on retry", func(t *testing.T, f *flaky, cancel context.CancelFunc) {
+			f.clock.BlockUntil(1)
+			f.fail.Store(false)
+			f.clock.Advance(backoff)
+		}, nil, 2},
+		{"gives up after attempts", func(t *testing.T, f *flaky, cancel context.CancelFunc) {
+			f.clock.BlockUntil(1)
+			f.clock.Advance(backoff)
+			f.clock.BlockUntil(1)
+			f.clock.Advance(2 * backoff)
+		}, errFlaky, 3},
+		{"backoff doubles with jitter", func(t *testing.T, f *flaky, cancel context.CancelFunc) {
+			// 第n次重试前等待[backoff*2^(n-1)/2, backoff*2^(n-1)]
+			f.clock.BlockUntil(1)
+			f.clock.Advance(backoff/2 - time.Millisecond)
+			if f.calls.Load() != 1 {
+				t.Errorf("retried %d times before half the backoff, want 0", f.calls.Load()-1)
+			}
+			f.clock.Advance(backoff/2 + time.Millisecond)
+			f.clock.BlockUntil(1)
+			if f.calls.Load() != 2 {
+				t.Fatalf("factory called %d times after the first backoff, want 2", f.calls.Load())
+			}
+			f.clock.Advance(backoff - time.Millisecond)
+			if f.calls.Load() != 2 {
+				t.Errorf("second retry before its backoff of at least %v", backoff)
+			}
+			f.fail.Store(false)
+			f.clock.Advance(backoff + time.Millisecond)
+		}, nil, 3},
+		{"canceled while backing off", func(t *testing.T, f *flaky, cancel context.CancelFunc) {
+			f.clock.BlockUntil(1)
+			cancel()
+		}, context.Canceled, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, f := newFlakyPool(t, pool.WithFactoryRetry(attempts, backoff))
+			f.fail.Store(true)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			c := startAcquire(p, ctx)
+			tt.run(t, f, cancel)
+			res := result(t, c)
+			if !errors.Is(res.err, tt.wantErr) || (tt.wantErr == nil) != (res.err == nil) {
+				t.Fatalf("Acquire = %v, want %v", res.err, tt.wantErr)
+			}
+			if res.err == nil {
+				release(t, p, res.r)
+			}
+			if ce := (*pool.CreateError)(nil); errors.As(res.err, &ce) && ce.Attempts != tt.wantAttempts {
+				t.Errorf("CreateError.Attempts = %d, want %d", ce.Attempts, tt.wantAttempts)
+			}
+			if f.calls.Load() != int64(tt.wantAttempts) {
+				t.Errorf("factory called %d times, want %d", f.calls.Load(), tt.wantAttempts)
+			}
+		})
+	}
+}
+
+// startAcquire 在新的goroutine中用ctx获取一个资源，结果发送到返回的通道
+func startAcquire(p *pool.Pool[*tracked], ctx context.Context) <-chan acquired {
+	c := make(chan acquired, 1)
+	go func() {
+		r, err := p.AcquireContext(ctx)
+		c <- acquired{r, err}
+	}()
+	return c
+}
@@ -0,0 +1,33 @@
+package pool
+
+import (
+	"context"
+	"runtime/trace"
+)
+
+// startTask 在开启WithRuntimeTrace并且正在记录执行跟踪时为一次Acquire开始一个runtime/trace任务，
+// 返回的函数记录结果并结束任务
+func (p *Pool[T]) startTask(ctx context.Context) (context.Context, func(AcquireOutcome)) {
+	if !p.runtimeTrace || !trace.IsEnabled() {
+		return ctx, endNoTask
+	}
+	ctx, task := trace.NewTask(ctx, "pool.Acquire")
+	if p.name != "" {
+		trace.Log(ctx, "pool", p.name)
+	}
+	return ctx, func(o AcquireOutcome) {
+		trace.Log(ctx, "outcome", o.String())
+		task.End()
+	}
+}
+
+// endNoTask 是没有开始任务时startTask返回的函数，使用包级的函数避免每次获取分配一个闭包
+func endNoTask(AcquireOutcome) {}
+
+// region 在开启WithRuntimeTrace并且正在记录执行跟踪时开始一个名为name的runtime/trace区域，返回结束它的函数
+func (p *Pool[T]) region(ctx context.Context, name string) func() {
+	if !p.runtimeTrace || !trace.IsEnabled() {
+		return func() {}
+	}
+	return trace.StartRegion(ctx, name).End
+}
@@ -0,0 +1,364 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNoPrimary 表示RWPool中没有可以写入的主节点，例如主节点切换期间所有节点都报告自己不是主节点
+var ErrNoPrimary = errors.New("No primary available")
+
+// DefaultPrimaryCheckTimeout 是RWPool检查一个节点是否为主节点的最长时间
+const DefaultPrimaryCheckTimeout = 5 * time.Second
+
+// RWNode 是RWPool中的一个节点，Pool中的资源都连接到同一个数据库实例
+type RWNode[T any] struct {
+	Name string
+	Pool *Pool[T]
+}
+
+// RWOption 用于配置NewRW创建的RWPool
+type RWOption func(*rwSettings)
+
+type rwSettings struct {
+	primaryCheck  any
+	checkInterval time.Duration
+	readPrimary   bool
+}
+
+// WithPrimaryCheck 设置判断资源连接的节点是否为主节点的函数，例如PostgreSQL中执行
+// SELECT NOT pg_is_in_recovery()，设置后AcquireWrite在拿到的资源不在主节点上时重新查找主节点
+func WithPrimaryCheck[T any](fn func(ctx context.Context, r T) (bool, error)) RWOption {
+	return func(s *rwSettings) { s.primaryCheck = fn }
+}
+
+// WithPrimaryCheckInterval 设置在后台检查主节点是否变化的间隔，需要同时设置WithPrimaryCheck，0表示不在后台检查
+func WithPrimaryCheckInterval(d time.Duration) RWOption {
+	return func(s *rwSettings) { s.checkInterval = d }
+}
+
+// WithReadFromPrimary 设置没有从节点时AcquireRead是否使用主节点，默认为true
+func WithReadFromPrimary(ok bool) RWOption {
+	return func(s *rwSettings) { s.readPrimary = ok }
+}
+
+// RWPool 在一个主节点和若干个从节点的池之上实现读写分离，
+// AcquireWrite从主节点获取资源，AcquireRead在从节点之间选择使用中的资源最少的节点，
+// 设置WithPrimaryCheck后可以跟随主节点的切换，RWPool拥有所有节点的池，Close时关闭它们
+type RWPool[T any] struct {
+	nodes        []RWNode[T]
+	primaryCheck func(context.Context, T) (bool, error)
+	readPrimary  bool
+
+	primary atomic.Int64  // 主节点在nodes中的下标，-1表示没有主节点
+	next    atomic.Uint64 // 从节点负载相同时轮流选择
+	owner   sync.Map      // 使用中的资源所属的节点的池
+
+	m         sync.Mutex // 同一时间只有一个goroutine在查找主节点
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+var _ Pooler[int] = (*RWPool[int])(nil)
+
+// NewRW 创建一个RWPool，nodes[0]是最初的主节点，其余是从节点
+func NewRW[T any](nodes []RWNode[T], opts ...RWOption) (*RWPool[T], error) {
+	s := rwSettings{readPrimary: true}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("%w: no nodes", ErrInvalidConfig)
+	}
+	names := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		if n.Pool == nil {
+			return nil, fmt.Errorf("%w: node %q has nil pool", ErrInvalidConfig, n.Name)
+		}
+		if names[n.Name] {
+			return nil, fmt.Errorf("%w: duplicate node %q", ErrInvalidConfig, n.Name)
+		}
+		names[n.Name] = true
+	}
+	check, err := funcOption[func(context.Context, T) (bool, error)](s.primaryCheck, "primary check")
+	if err != nil {
+		return nil, err
+	}
+	if s.checkInterval < 0 {
+		return nil, fmt.Errorf("%w: negative PrimaryCheckInterval", ErrInvalidConfig)
+	}
+	if s.checkInterval > 0 && check == nil {
+		return nil, fmt.Errorf("%w: PrimaryCheckInterval requires WithPrimaryCheck", ErrInvalidConfig)
+	}
+	rw := &RWPool[T]{
+		nodes:        append([]RWNode[T](nil), nodes...),
+		primaryCheck: check,
+		readPrimary:  s.readPrimary,
+		done:         make(chan struct{}),
+	}
+	if s.checkInterval > 0 {
+		go rw.watchPrimary(s.checkInterval)
+	}
+	return rw, nil
+}
+
+// Primary 返回当前主节点的名字，没有主节点时返回空字符串
+func (rw *RWPool[T]) Primary() string {
+	if i := rw.primary.Load(); i >= 0 {
+		return rw.nodes[i].Name
+	}
+	return ""
+}
+
+// Promote 把名为name的节点设置为主节点，原来的主节点成为从节点，用于外部的故障切换通知
+func (rw *RWPool[T]) Promote(name string) error {
+	for i, n := range rw.nodes {
+		if n.Name == name {
+			rw.setPrimary(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: unknown node %q", ErrInvalidConfig, name)
+}
+
+// setPrimary 把第i个节点设置为主节点
+func (rw *RWPool[T]) setPrimary(i int) {
+	if old := rw.primary.Swap(int64(i)); old != int64(i) && i >= 0 {
+		rw.nodes[i].Pool.logger.Println("RWPool:", "Primary", rw.nodes[i].Name)
+	}
+}
+
+// Acquire 与AcquireWrite相同，RWPool作为Pooler使用时所有操作都在主节点上进行
+func (rw *RWPool[T]) Acquire() (T, error) {
+	return rw.AcquireWrite(context.Background())
+}
+
+// AcquireContext 与AcquireWrite相同
+func (rw *RWPool[T]) AcquireContext(ctx context.Context) (T, error) {
+	return rw.AcquireWrite(ctx)
+}
+
+// AcquireWrite 从主节点获取一个资源
+// 设置了WithPrimaryCheck时检查资源是否仍在主节点上，不在时重新查找主节点再获取，
+// 找不到主节点时返回ErrNoPrimary
+func (rw *RWPool[T]) AcquireWrite(ctx context.Context) (T, error) {
+	var zero T
+	for attempt := 0; attempt < 2; attempt++ {
+		i := int(rw.primary.Load())
+		if i < 0 {
+			if i = rw.findPrimary(ctx); i < 0 {
+				return zero, ErrNoPrimary
+			}
+		}
+		p := rw.nodes[i].Pool
+		r, err := p.AcquireContext(ctx)
+		if err != nil {
+			return zero, err
+		}
+		if rw.primaryCheck == nil {
+			rw.owner.Store(r, p)
+			return r, nil
+		}
+		ok, err := rw.primaryCheck(ctx, r)
+		if err == nil && ok {
+			rw.owner.Store(r, p)
+			return r, nil
+		}
+		if err != nil {
+			p.Discard(r)
+		} else {
+			p.Release(r)
+		}
+		if ctx.Err() != nil {
+			return zero, ctx.Err()
+		}
+		// 主节点已经切换，重新查找
+		rw.primary.CompareAndSwap(int64(i), -1)
+	}
+	return zero, ErrNoPrimary
+}
+
+// AcquireRead 从使用中的资源最少的从节点获取一个资源，没有从节点时按WithReadFromPrimary使用主节点
+// 一个从节点获取失败时依次尝试其它从节点，都失败时返回最后一个错误
+func (rw *RWPool[T]) AcquireRead(ctx context.Context) (T, error) {
+	var zero T
+	replicas := rw.replicas()
+	if len(replicas) == 0 {
+		if !rw.readPrimary {
+			return zero, fmt.Errorf("%w: no replicas", ErrNoBackend)
+		}
+		return rw.AcquireWrite(ctx)
+	}
+	var err error
+	for _, p := range replicas {
+		var r T
+		if r, err = p.AcquireContext(ctx); err == nil {
+			rw.owner.Store(r, p)
+			return r, nil
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return zero, err
+}
+
+// replicas 返回当前的从节点，按使用中和等待的资源数从少到多排列，负载相同时轮流排在前面
+func (rw *RWPool[T]) replicas() []*Pool[T] {
+	primary := int(rw.primary.Load())
+	type load struct {
+		p *Pool[T]
+		n uint
+	}
+	loads := make([]load, 0, len(rw.nodes))
+	start := int(rw.next.Add(1) % uint64(len(rw.nodes)))
+	for k := range rw.nodes {
+		i := (start + k) % len(rw.nodes)
+		if i == primary {
+			continue
+		}
+		s := rw.nodes[i].Pool.Stats()
+		loads = append(loads, load{p: rw.nodes[i].Pool, n: s.InUse + s.Waiting})
+	}
+	// 节点数很少，插入排序保持轮流的顺序
+	for i := 1; i < len(loads); i++ {
+		for j := i; j > 0 && loads[j].n < loads[j-1].n; j-- {
+			loads[j], loads[j-1] = loads[j-1], loads[j]
+		}
+	}
+	pools := make([]*Pool[T], len(loads))
+	for i, l := range loads {
+		pools[i] = l.p
+	}
+	return pools
+}
+
+// findPrimary 用primaryCheck依次检查每个节点，把第一个报告自己是主节点的节点设置为主节点并返回它的下标，
+// 没有设置primaryCheck时使用nodes[0]，找不到时返回-1
+func (rw *RWPool[T]) findPrimary(ctx context.Context) int {
+	if rw.primaryCheck == nil {
+		rw.primary.CompareAndSwap(-1, 0)
+		return int(rw.primary.Load())
+	}
+	rw.m.Lock()
+	defer rw.m.Unlock()
+	for i, n := range rw.nodes {
+		if rw.isPrimary(ctx, n.Pool) {
+			rw.setPrimary(i)
+			return i
+		}
+	}
+	rw.primary.Store(-1)
+	return -1
+}
+
+// isPrimary 从p获取一个资源检查它是否连接到主节点
+func (rw *RWPool[T]) isPrimary(ctx context.Context, p *Pool[T]) bool {
+	ctx, cancel := context.WithTimeout(ctx, DefaultPrimaryCheckTimeout)
+	defer cancel()
+	r, err := p.AcquireContext(ctx)
+	if err != nil {
+		return false
+	}
+	ok, err := rw.primaryCheck(ctx, r)
+	if err != nil {
+		p.Discard(r)
+		return false
+	}
+	p.Release(r)
+	return ok
+}
+
+// watchPrimary 每隔interval检查一次主节点，主节点不再是主节点时重新查找
+func (rw *RWPool[T]) watchPrimary(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-rw.done:
+			return
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			select {
+			case <-rw.done:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		if i := rw.primary.Load(); i < 0 || !rw.isPrimary(ctx, rw.nodes[i].Pool) {
+			rw.findPrimary(ctx)
+		}
+		cancel()
+	}
+}
+
+// pool 返回资源所属的节点的池，并把资源从使用中的记录里删除
+func (rw *RWPool[T]) pool(r T) (*Pool[T], error) {
+	if p, ok := rw.owner.LoadAndDelete(r); ok {
+		return p.(*Pool[T]), nil
+	}
+	return nil, ErrForeignResource
+}
+
+// Release 把资源放回它所属的节点的池，错误的含义与Pool.Release相同
+func (rw *RWPool[T]) Release(r T) error {
+	p, err := rw.pool(r)
+	if err != nil {
+		return err
+	}
+	return p.Release(r)
+}
+
+// Discard 销毁一个使用中的资源
+func (rw *RWPool[T]) Discard(r T) error {
+	p, err := rw.pool(r)
+	if err != nil {
+		return err
+	}
+	return p.Discard(r)
+}
+
+// Stats 返回所有节点统计信息的总和
+func (rw *RWPool[T]) Stats() Stats {
+	var s Stats
+	for _, n := range rw.nodes {
+		s = s.add(n.Pool.Stats())
+	}
+	return s
+}
+
+// NodeStats 返回每个节点的统计信息，以节点的名字为键
+func (rw *RWPool[T]) NodeStats() map[string]Stats {
+	stats := make(map[string]Stats, len(rw.nodes))
+	for _, n := range rw.nodes {
+		stats[n.Name] = n.Pool.Stats()
+	}
+	return stats
+}
+
+// Close 关闭所有节点的池，并等待使用中的资源被放回，返回所有节点的错误的合并
+func (rw *RWPool[T]) Close() error {
+	return rw.CloseContext(context.Background())
+}
+
+// CloseContext 与Close相同，但最多等待到ctx结束，之后仍未放回的资源会被强制关闭
+func (rw *RWPool[T]) CloseContext(ctx context.Context) error {
+	rw.closeOnce.Do(func() { close(rw.done) })
+	var wg sync.WaitGroup
+	errs := make([]error, len(rw.nodes))
+	for i, n := range rw.nodes {
+		wg.Add(1)
+		go func(i int, p *Pool[T]) {
+			defer wg.Done()
+			errs[i] = p.CloseContext(ctx)
+		}(i, n.Pool)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
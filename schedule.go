@@ -0,0 +1,86 @@
+package pool
+
+import "time"
+
+// DefaultScheduleInterval 是WithSchedule未设置间隔时检查时间表的间隔
+const DefaultScheduleInterval = time.Minute
+
+// Capacity 是时间表要求的池容量，字段含义与Config中相同，MaxIdle为0时使用默认值
+type Capacity struct {
+	MinIdle  uint
+	MaxIdle  uint
+	MaxTotal uint
+}
+
+// Schedule 返回池在now时应该使用的容量
+type Schedule func(now time.Time) Capacity
+
+// Window 是每天的一个时间段，From和To是从当天零点(now所在时区)开始的时间，
+// To小于From时表示时间段跨过零点，例如From为22小时、To为6小时表示夜间
+type Window struct {
+	From, To time.Duration
+	Capacity
+}
+
+// contains 返回now是否在时间段内
+func (w Window) contains(now time.Time) bool {
+	y, m, d := now.Date()
+	t := now.Sub(time.Date(y, m, d, 0, 0, 0, 0, now.Location()))
+	if w.From <= w.To {
+		return t >= w.From && t < w.To
+	}
+	return t >= w.From || t < w.To
+}
+
+// Daily 返回按每天的时间段切换容量的Schedule，now在多个时间段内时使用最前面的一个，
+// 不在任何时间段内时使用def
+//
+//	pool.WithSchedule(pool.Daily(
+//		pool.Capacity{MinIdle: 20, MaxTotal: 200},
+//		pool.Window{From: 22 * time.Hour, To: 6 * time.Hour, Capacity: pool.Capacity{MinIdle: 1, MaxIdle: 2, MaxTotal: 10}},
+//	), 0)
+func Daily(def Capacity, windows ...Window) Schedule {
+	windows = append([]Window(nil), windows...)
+	return func(now time.Time) Capacity {
+		for _, w := range windows {
+			if w.contains(now) {
+				return w.Capacity
+			}
+		}
+		return def
+	}
+}
+
+// scheduler 每隔interval按时间表调整一次容量，直到池被关闭
+func (p *Pool[T]) scheduler(interval time.Duration) {
+	ticker := p.clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			p.applySchedule(p.clock.Now())
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// applySchedule 在时间表要求的容量变化时调整MinIdle、MaxIdle和MaxTotal，超出的空闲资源被关闭
+func (p *Pool[T]) applySchedule(now time.Time) {
+	c := p.schedule(now)
+	if p.scheduled != nil && *p.scheduled == c {
+		return
+	}
+	p.reconfig.Lock()
+	defer p.reconfig.Unlock()
+	p.m.Lock()
+	cfg := p.liveConfig()
+	p.m.Unlock()
+	cfg.MinIdle, cfg.MaxIdle, cfg.MaxTotal = c.MinIdle, c.MaxIdle, c.MaxTotal
+	if err := p.updateConfig(cfg); err != nil {
+		p.logger.Println("Schedule:", err)
+		p.reportError(err, SourceSchedule)
+		return
+	}
+	p.scheduled = &c
+}
@@ -0,0 +1,46 @@
+package pool
+
+// Scorer 是可以报告自己当前质量的资源，分数越高越好，例如按RTT或服务端是否正在GOAWAY计算的连接质量
+// 没有设置WithScoreFunc时，池在创建资源后、每次放回时和keepalive的ping成功后调用Score，
+// 没有设置SelectionPolicy时Acquire优先使用分数最高的空闲资源，分数相同时按ReuseStrategy选择
+type Scorer interface {
+	Score() float64
+}
+
+// scoreOf 返回资源r的分数，资源没有分数时ok为false，计算分数的函数panic时分数为0
+func (p *Pool[T]) scoreOf(r T) (s float64, ok bool) {
+	var err error
+	defer func() {
+		if err != nil {
+			s = 0
+		}
+	}()
+	defer catch(p.logger, "score func", &err)
+	if p.score != nil {
+		return p.score(r), true
+	}
+	if sr, ok := any(r).(Scorer); ok {
+		return sr.Score(), true
+	}
+	return 0, false
+}
+
+// setScore 记录e的分数，调用者需持有p.m
+func (p *Pool[T]) setScore(e *entry[T], s float64, ok bool) {
+	if ok {
+		e.score = s
+		p.scoring = true
+	}
+}
+
+// bestScored 返回分数最高的空闲资源的下标，分数相同时按ReuseStrategy选择，调用者需持有p.m
+func (p *Pool[T]) bestScored() int {
+	best := 0
+	for i := 1; i < p.idle.Len(); i++ {
+		s, b := p.idle.At(i).score, p.idle.At(best).score
+		if s > b || s == b && p.reuse == LIFO {
+			best = i
+		}
+	}
+	return best
+}
@@ -0,0 +1,83 @@
+package pool
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// IdleResource 描述一个空闲资源，供SelectionPolicy选择
+type IdleResource struct {
+	CreatedAt  time.Time // 创建的时间
+	ReturnedAt time.Time // 最近一次放回池中的时间
+	Uses       uint      // 被获取的次数
+	Weight     uint      // 资源的权重，见WithWeightFunc
+	Score      float64   // 资源最近一次计算的分数，见Scorer
+}
+
+// SelectionPolicy 决定Acquire使用哪个空闲资源，设置后代替ReuseStrategy和按Scorer的分数选择
+// Select在持有池的锁时调用，应该尽快返回
+type SelectionPolicy interface {
+	// Select 返回要使用的资源在idle中的下标，idle按放回的时间从早到晚排列，至少有一个元素
+	// 调用返回后idle会被复用，不应保留它
+	Select(idle []IdleResource) int
+}
+
+var (
+	// NewestFirst 优先使用最晚创建的资源，它们最不可能已经被服务端关闭
+	NewestFirst SelectionPolicy = newestFirst{}
+	// LeastUsed 优先使用被获取次数最少的资源，让使用次数在资源之间保持均匀
+	LeastUsed SelectionPolicy = leastUsed{}
+)
+
+type newestFirst struct{}
+
+func (newestFirst) Select(idle []IdleResource) int {
+	best := 0
+	for i, r := range idle {
+		if r.CreatedAt.After(idle[best].CreatedAt) {
+			best = i
+		}
+	}
+	return best
+}
+
+type leastUsed struct{}
+
+func (leastUsed) Select(idle []IdleResource) int {
+	best := 0
+	for i, r := range idle {
+		if r.Uses < idle[best].Uses {
+			best = i
+		}
+	}
+	return best
+}
+
+// RoundRobin 返回一个依次轮流使用各个位置的空闲资源的SelectionPolicy，每个池应该使用自己的实例
+func RoundRobin() SelectionPolicy {
+	return &roundRobin{}
+}
+
+type roundRobin struct {
+	n atomic.Uint64
+}
+
+func (rr *roundRobin) Select(idle []IdleResource) int {
+	return int((rr.n.Add(1) - 1) % uint64(len(idle)))
+}
+
+// selectIdle 用p.selection选择一个空闲资源，返回它在空闲资源中的下标，调用者需持有p.m
+func (p *Pool[T]) selectIdle() int {
+	buf := p.selectBuf[:0]
+	for i := 0; i < p.idle.Len(); i++ {
+		e := p.idle.At(i)
+		buf = append(buf, IdleResource{CreatedAt: e.createdAt, ReturnedAt: e.returnedAt, Uses: e.uses, Weight: e.weight, Score: e.score})
+	}
+	p.selectBuf = buf
+	i := p.selection.Select(buf)
+	if i < 0 || i >= p.idle.Len() {
+		// 不合法的下标按FIFO处理
+		return 0
+	}
+	return i
+}
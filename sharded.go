@@ -0,0 +1,343 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ShardedPool 把资源分散到多个子池(分片)中，减少高并发时对单个锁的争用
+// 它的方法与Pool相同，Acquire优先使用轮到的分片，分片中没有空闲资源时
+// 先从其它分片取空闲资源，所有分片都达到上限时在轮到的分片上等待
+type ShardedPool[T any] struct {
+	shards []*Pool[T]
+	next   atomic.Uint64
+	owner  sync.Map // 使用中的资源所属的分片
+
+	stateStore StateStore // 整个池共用的容量提示，不交给分片，见WithStateStore
+	statsSink  StatsSink  // 接收所有分片合计的StatsSink，不交给分片
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+// NewSharded 创建一个有n个分片的资源池，n为0时使用runtime.GOMAXPROCS(0)
+// MaxTotal、MaxIdle、MinIdle、自动调整的范围和WithSchedule的容量会平均分配到各个分片，
+// 分片数不会超过设置的MaxTotal、MaxIdle和AutoscaleMin；
+// WithStatsSink推送所有分片的合计，WithStateStore为整个池保存一份容量提示
+func NewSharded[T any](fn func() (T, error), n int, opts ...Option) (*ShardedPool[T], error) {
+	return NewShardedContext(ignoreContext(fn), n, opts...)
+}
+
+// NewShardedContext 与NewSharded相同，但分配新资源的函数接收一个ctx
+func NewShardedContext[T any](fn func(context.Context) (T, error), n int, opts ...Option) (*ShardedPool[T], error) {
+	var s settings
+	for _, opt := range opts {
+		opt(&s)
+	}
+	if err := s.Config.withDefaults().Validate(); err != nil {
+		return nil, err
+	}
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	if s.MaxTotal > 0 && uint(n) > s.MaxTotal {
+		n = int(s.MaxTotal)
+	}
+	if s.MaxIdle > 0 && uint(n) > s.MaxIdle {
+		n = int(s.MaxIdle)
+	}
+	if s.AutoscaleMax > 0 && uint(n) > s.AutoscaleMin {
+		n = int(s.AutoscaleMin)
+	}
+
+	if s.MaxConcurrentCreates > 0 {
+		// 所有分片共用同一个限制
+		s.createSem = make(chan struct{}, s.MaxConcurrentCreates)
+	}
+	if s.CreateRate > 0 {
+		burst := s.CreateBurst
+		if burst == 0 {
+			burst = 1
+		}
+		s.createLim = rate.NewLimiter(s.CreateRate, burst)
+	}
+	sp := &ShardedPool[T]{shards: make([]*Pool[T], n), stateStore: s.stateStore, statsSink: s.statsSink, done: make(chan struct{})}
+	for i := range sp.shards {
+		ss := s
+		ss.MaxTotal = split(s.MaxTotal, n, i)
+		ss.MaxIdle = split(s.MaxIdle, n, i)
+		ss.MinIdle = split(s.MinIdle, n, i)
+		ss.AutoscaleMin = split(s.AutoscaleMin, n, i)
+		ss.AutoscaleMax = split(s.AutoscaleMax, n, i)
+		if s.schedule != nil {
+			ss.schedule = splitSchedule(s.schedule, n, i)
+		}
+		// 推送统计信息和保存容量提示由ShardedPool对整个池进行
+		ss.statsSink = nil
+		ss.stateStore = nil
+		p, err := newPool(fn, ss)
+		if err != nil {
+			for _, p := range sp.shards[:i] {
+				p.Close()
+			}
+			return nil, err
+		}
+		sp.shards[i] = p
+	}
+	if sp.stateStore != nil {
+		sp.loadHints()
+	}
+	if s.statsSink != nil {
+		interval := s.statsInt
+		if interval <= 0 {
+			interval = DefaultStatsInterval
+		}
+		go sp.pushStats(interval)
+	}
+	return sp, nil
+}
+
+// splitSchedule 返回把schedule的每个容量平均分成n份后第i份的时间表
+func splitSchedule(schedule Schedule, n, i int) Schedule {
+	return func(now time.Time) Capacity {
+		c := schedule(now)
+		return Capacity{MinIdle: split(c.MinIdle, n, i), MaxIdle: split(c.MaxIdle, n, i), MaxTotal: split(c.MaxTotal, n, i)}
+	}
+}
+
+// loadHints 读取整个池的容量提示，平均分配给各个分片，只在NewShardedContext中调用
+func (sp *ShardedPool[T]) loadHints() {
+	first := sp.shards[0]
+	h, err := sp.stateStore.LoadHints(first.name)
+	if err != nil {
+		first.logger.Println("State:", "Load Failed:", err)
+		first.reportError(err, SourceState)
+		return
+	}
+	n := len(sp.shards)
+	for i, p := range sp.shards {
+		p.m.Lock()
+		p.warmHint = split(h.target(), n, i)
+		p.demand = h.Demand / float64(n)
+		p.m.Unlock()
+	}
+}
+
+// WarmHints 返回整个池的容量提示，PeakInUse是各分片峰值之和，可能大于同时使用的资源数的真实峰值
+func (sp *ShardedPool[T]) WarmHints() WarmHints {
+	var h WarmHints
+	for _, p := range sp.shards {
+		ph := p.WarmHints()
+		h.PeakInUse += ph.PeakInUse
+		h.Demand += ph.Demand
+		h.SavedAt = ph.SavedAt
+	}
+	return h
+}
+
+// pushStats 每隔interval把所有分片的合计推送给statsSink，直到池被关闭，关闭后的最后一次推送由CloseContext进行
+func (sp *ShardedPool[T]) pushStats(interval time.Duration) {
+	ticker := sp.shards[0].clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			sp.pushStatsOnce()
+		case <-sp.done:
+			return
+		}
+	}
+}
+
+// pushStatsOnce 把所有分片的合计推送给statsSink一次
+func (sp *ShardedPool[T]) pushStatsOnce() {
+	first := sp.shards[0]
+	if err := sp.statsSink.PushStats(first.name, first.labels, sp.Stats()); err != nil {
+		first.logger.Println("Stats:", "Push Failed:", err)
+		first.reportError(err, SourceStats)
+	}
+}
+
+// split 返回把total平均分成n份后第i份的大小
+func split(total uint, n, i int) uint {
+	v := total / uint(n)
+	if uint(i) < total%uint(n) {
+		v++
+	}
+	return v
+}
+
+// Acquire 从池中获取一个资源
+func (sp *ShardedPool[T]) Acquire() (T, error) {
+	return sp.AcquireContext(context.Background())
+}
+
+// AcquireContext 从池中获取一个资源
+// 依次尝试轮到的分片和其它分片的空闲资源，然后在有剩余容量的分片上创建新资源，
+// 所有分片都达到上限时在轮到的分片上等待，ctx的含义与Pool.AcquireContext相同
+func (sp *ShardedPool[T]) AcquireContext(ctx context.Context) (T, error) {
+	i := sp.pick()
+	r, err := sp.acquireAny(ctx, i)
+	if !errors.Is(err, ErrPoolExhausted) {
+		return r, err
+	}
+	p := sp.shards[i]
+	r, err = p.AcquireContext(ctx)
+	if err == nil {
+		sp.owner.Store(r, p)
+	}
+	return r, err
+}
+
+// TryAcquire 从池中获取一个资源，不等待其它goroutine放回资源
+// 所有分片都没有空闲资源并且达到上限时返回ErrPoolExhausted
+func (sp *ShardedPool[T]) TryAcquire() (T, error) {
+	return sp.tryAcquireContext(context.Background())
+}
+
+// tryAcquireContext 与TryAcquire相同，但创建资源时使用ctx
+func (sp *ShardedPool[T]) tryAcquireContext(ctx context.Context) (T, error) {
+	return sp.acquireAny(ctx, sp.pick())
+}
+
+// acquireAny 从第i个分片开始依次尝试每个分片的空闲资源，
+// 然后尝试在有剩余容量的分片上创建新资源，所有分片都达到上限时返回ErrPoolExhausted
+func (sp *ShardedPool[T]) acquireAny(ctx context.Context, i int) (T, error) {
+	for j := range sp.shards {
+		p := sp.shards[(i+j)%len(sp.shards)]
+		r, err := p.acquire(ctx, PriorityNormal, "", acquireIdle)
+		if err == nil {
+			sp.owner.Store(r, p)
+			return r, nil
+		}
+		if !errors.Is(err, ErrPoolExhausted) {
+			return r, err
+		}
+	}
+	for j := range sp.shards {
+		p := sp.shards[(i+j)%len(sp.shards)]
+		r, err := p.acquire(ctx, PriorityNormal, "", acquireTry)
+		if err == nil {
+			sp.owner.Store(r, p)
+			return r, nil
+		}
+		if !errors.Is(err, ErrPoolExhausted) {
+			return r, err
+		}
+	}
+	var zero T
+	return zero, ErrPoolExhausted
+}
+
+// pick 轮流选择一个分片
+func (sp *ShardedPool[T]) pick() int {
+	return int(sp.next.Add(1) % uint64(len(sp.shards)))
+}
+
+// shard 返回资源所属的分片，并把资源从使用中的记录里删除
+func (sp *ShardedPool[T]) shard(r T) *Pool[T] {
+	if p, ok := sp.owner.LoadAndDelete(r); ok {
+		return p.(*Pool[T])
+	}
+	// 不在使用中的资源交给空闲资源中有它的分片，让它返回ErrDoubleRelease
+	for _, p := range sp.shards {
+		p.m.Lock()
+		err := p.checkOwned(r)
+		p.m.Unlock()
+		if errors.Is(err, ErrDoubleRelease) {
+			return p
+		}
+	}
+	return sp.shards[0]
+}
+
+// Release 把资源放回它所属的分片，错误的含义与Pool.Release相同
+func (sp *ShardedPool[T]) Release(r T) error {
+	return sp.shard(r).Release(r)
+}
+
+// Discard 销毁一个使用中的资源
+func (sp *ShardedPool[T]) Discard(r T) error {
+	return sp.shard(r).Discard(r)
+}
+
+// With 获取一个资源并用它调用fn，行为与Pool.With相同
+func (sp *ShardedPool[T]) With(ctx context.Context, fn func(r T) error) (err error) {
+	r, err := sp.AcquireContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			sp.Discard(r)
+			panic(v)
+		}
+		if err != nil {
+			sp.Discard(r)
+			return
+		}
+		sp.Release(r)
+	}()
+	return fn(r)
+}
+
+// Warmup 在每个分片上创建资源，直到空闲资源达到MinIdle
+func (sp *ShardedPool[T]) Warmup(ctx context.Context) error {
+	var errs []error
+	for _, p := range sp.shards {
+		if err := p.Warmup(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Stats 返回所有分片统计信息的总和
+func (sp *ShardedPool[T]) Stats() Stats {
+	var s Stats
+	for _, p := range sp.shards {
+		s = s.add(p.Stats())
+	}
+	return s
+}
+
+// Close 关闭所有分片，并等待使用中的资源被放回，返回所有分片的错误的合并
+func (sp *ShardedPool[T]) Close() error {
+	return sp.CloseContext(context.Background())
+}
+
+// CloseContext 与Close相同，但最多等待到ctx结束，之后仍未放回的资源会被强制关闭
+func (sp *ShardedPool[T]) CloseContext(ctx context.Context) error {
+	first := false
+	sp.closeOnce.Do(func() {
+		first = true
+		close(sp.done)
+	})
+	var wg sync.WaitGroup
+	errs := make([]error, len(sp.shards))
+	for i, p := range sp.shards {
+		wg.Add(1)
+		go func(i int, p *Pool[T]) {
+			defer wg.Done()
+			errs[i] = p.CloseContext(ctx)
+		}(i, p)
+	}
+	wg.Wait()
+	if first && sp.statsSink != nil {
+		sp.pushStatsOnce()
+	}
+	if first && sp.stateStore != nil {
+		sp.shards[0].saveHints(sp.stateStore, sp.WarmHints())
+	}
+	return errors.Join(errs...)
+}
+
+// Shutdown 与CloseContext相同
+func (sp *ShardedPool[T]) Shutdown(ctx context.Context) error {
+	return sp.CloseContext(ctx)
+}
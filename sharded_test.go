@@ -0,0 +1,154 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingTracer 记录每种结果的Acquire次数
+type countingTracer struct {
+	mu       sync.Mutex
+	outcomes map[AcquireOutcome]int
+}
+
+func (t *countingTracer) TraceAcquireStart(ctx context.Context) context.Context { return ctx }
+func (t *countingTracer) TraceAcquireEnd(ctx context.Context, o AcquireOutcome, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.outcomes == nil {
+		t.outcomes = make(map[AcquireOutcome]int)
+	}
+	t.outcomes[o]++
+}
+func (t *countingTracer) TraceCreateStart(ctx context.Context) context.Context  { return ctx }
+func (t *countingTracer) TraceCreateEnd(ctx context.Context, err error)         {}
+func (t *countingTracer) TraceReleaseStart(ctx context.Context) context.Context { return ctx }
+func (t *countingTracer) TraceReleaseEnd(ctx context.Context, pooled bool)      {}
+
+// TestShardedIdleAcquireUsesAcquire 检查从分片的空闲资源获取与Pool.Acquire走同一条路径
+func TestShardedIdleAcquireUsesAcquire(t *testing.T) {
+	boom := errors.New("boom")
+	var tracer countingTracer
+	sp, err := NewSharded(func() (*int, error) { return new(int), nil }, 2, WithTracer(&tracer), WithTestOverrides())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sp.Close()
+	r, _ := sp.Acquire()
+	sp.Release(r)
+	tracer.outcomes = nil
+
+	r, err = sp.Acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp.Release(r)
+	if n := tracer.outcomes[OutcomeHit]; n != 1 {
+		t.Fatalf("traced %d hits, want 1 (%v)", n, tracer.outcomes)
+	}
+
+	for _, p := range sp.shards {
+		p.SetTestOverride(TestOverride{InjectedAcquireErr: boom})
+	}
+	if _, err := sp.Acquire(); !errors.Is(err, boom) {
+		t.Fatalf("got %v, want the injected error", err)
+	}
+}
+
+// recordingSink 记录推送的统计信息
+type recordingSink struct {
+	mu     sync.Mutex
+	pushes []Stats
+}
+
+func (s *recordingSink) PushStats(name string, labels map[string]string, st Stats) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pushes = append(s.pushes, st)
+	return nil
+}
+
+// memoryStore 是保存在内存中的StateStore
+type memoryStore struct {
+	mu    sync.Mutex
+	hints map[string]WarmHints
+	saves int
+}
+
+func (s *memoryStore) LoadHints(name string) (WarmHints, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hints[name], nil
+}
+
+func (s *memoryStore) SaveHints(name string, h WarmHints) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.hints == nil {
+		s.hints = make(map[string]WarmHints)
+	}
+	s.hints[name] = h
+	s.saves++
+	return nil
+}
+
+// TestShardedPoolLevelSettings 检查统计信息推送、容量提示和时间表按整个池处理，而不是每个分片一份
+func TestShardedPoolLevelSettings(t *testing.T) {
+	tests := []struct {
+		name   string
+		shards int
+	}{
+		{"one shard", 1},
+		{"four shards", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sink recordingSink
+			store := &memoryStore{}
+			schedule := func(time.Time) Capacity { return Capacity{MaxIdle: 8, MaxTotal: 8} }
+			opts := []Option{WithName("sharded"), WithStatsSink(&sink, time.Hour), WithStateStore(store), WithSchedule(schedule, time.Hour)}
+			sp, err := NewSharded(func() (*int, error) { return new(int), nil }, tt.shards, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var held []*int
+			for {
+				r, err := sp.TryAcquire()
+				if err != nil {
+					break
+				}
+				held = append(held, r)
+			}
+			if len(held) != 8 {
+				t.Fatalf("acquired %d resources, want the scheduled MaxTotal 8", len(held))
+			}
+			for _, r := range held {
+				sp.Release(r)
+			}
+			sp.Close()
+			sp.Close()
+			if len(sink.pushes) != 1 || sink.pushes[0].TotalCreated != 8 {
+				t.Fatalf("got %d pushes, want one push of the whole pool", len(sink.pushes))
+			}
+			if store.saves != 1 || store.hints["sharded"].PeakInUse != 8 {
+				t.Fatalf("got %d saves with %+v, want one save with PeakInUse 8", store.saves, store.hints["sharded"])
+			}
+
+			// 下一次创建时提示平均分配给分片，Warmup一共预热8个资源
+			sp, err = NewSharded(func() (*int, error) { return new(int), nil }, tt.shards, WithName("sharded"), WithStateStore(store), WithMaxIdle(8))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer sp.Close()
+			if err := sp.Warmup(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if s := sp.Stats(); s.Idle != 8 {
+				t.Fatalf("warmed up %d resources, want 8", s.Idle)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lazysheep666/pool"
+)
+
+func TestSharing(t *testing.T) {
+	// ids 返回rs中资源的id
+	ids := func(rs ...*tracked) []int64 {
+		out := make([]int64, len(rs))
+		for i, r := range rs {
+			out[i] = r.id
+		}
+		return out
+	}
+	tests := []struct {
+		name    string
+		sharers uint
+		opts    []pool.Option
+		run     func(t *testing.T, p *pool.Pool[*tracked], h *harness)
+		// 最后的统计
+		wantCreated, wantClosed int64
+		wantIdle                uint
+	}{
+		{"shared up to the limit", 2, nil, func(t *testing.T, p *pool.Pool[*tracked], h *harness) {
+			rs := []*tracked{acquire(t, p), acquire(t, p), acquire(t, p), acquire(t, p)}
+			if got, want := ids(rs...), []int64{1, 1, 2, 2}; !reflect.DeepEqual(got, want) {
+				t.Errorf("acquired %v, want %v", got, want)
+			}
+			for _, r := range rs {
+				release(t, p, r)
+			}
+		}, 2, 0, 2},
+		{"idle only after last release", 2, nil, func(t *testing.T, p *pool.Pool[*tracked], h *harness) {
+			a, b := acquire(t, p), acquire(t, p)
+			release(t, p, a)
+			if s := p.Stats(); s.Idle != 0 || s.InUse != 1 {
+				t.Errorf("with one sharer left: Idle = %d, InUse = %d, want 0, 1", s.Idle, s.InUse)
+			}
+			release(t, p, b)
+			if err := p.Release(b); !errors.Is(err, pool.ErrDoubleRelease) {
+				t.Errorf("extra Release = %v, want ErrDoubleRelease", err)
+			}
+		}, 1, 0, 1},
+		{"discarded resource not shared again", 3, nil, func(t *testing.T, p *pool.Pool[*tracked], h *harness) {
+			a, b := acquire(t, p), acquire(t, p)
+			if err := p.Discard(a); err != nil {
+				t.Fatal(err)
+			}
+			if a.closed.Load() {
+				t.Error("discarded resource closed while another sharer holds it")
+			}
+			c := acquire(t, p)
+			if c.id != 2 {
+				t.Errorf("acquired resource %d after Discard, want a new resource 2", c.id)
+			}
+			release(t, p, b)
+			release(t, p, c)
+		}, 2, 1, 1},
+		{"shared instead of waiting", 2, []pool.Option{pool.WithMaxTotal(1)}, func(t *testing.T, p *pool.Pool[*tracked], h *harness) {
+			a, b := acquire(t, p), acquire(t, p)
+			if a != b {
+				t.Fatal("second Acquire did not share the only resource")
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			c := startWaiter(t, p, ctx)
+			release(t, p, a)
+			res := result(t, c)
+			if res.err != nil || res.r != a {
+				t.Fatalf("waiter got %v, %v, want the shared resource", res.r, res.err)
+			}
+			release(t, p, b)
+			release(t, p, res.r)
+			if s := p.Stats(); s.AcquireWaitCount != 1 {
+				t.Errorf("AcquireWaitCount = %d, want 1", s.AcquireWaitCount)
+			}
+		}, 1, 0, 1},
+		{"expired resource not shared", 2, []pool.Option{pool.WithMaxLifetime(time.Minute)}, func(t *testing.T, p *pool.Pool[*tracked], h *harness) {
+			a := acquire(t, p)
+			h.clock.Advance(time.Minute + time.Second)
+			b := acquire(t, p)
+			if b == a {
+				t.Error("expired resource was shared")
+			}
+			release(t, p, a)
+			release(t, p, b)
+		}, 2, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, h := newHarnessPool(t, append([]pool.Option{pool.WithSharing(tt.sharers)}, tt.opts...)...)
+			tt.run(t, p, h)
+			if h.created.Load() != tt.wantCreated || h.closed.Load() != tt.wantClosed {
+				t.Errorf("created %d, closed %d, want %d, %d", h.created.Load(), h.closed.Load(), tt.wantCreated, tt.wantClosed)
+			}
+			if s := p.Stats(); s.Idle != tt.wantIdle || s.InUse != 0 {
+				t.Errorf("Idle = %d, InUse = %d, want %d, 0", s.Idle, s.InUse, tt.wantIdle)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package pool
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// DefaultGracePeriod 是CloseOnSignal和GracefulClose的grace不是正数时等待资源放回的时间
+const DefaultGracePeriod = 30 * time.Second
+
+// CloseOnSignal 在进程收到signals中的任一信号时用GracefulClose关闭p，没有指定signals时使用SIGINT和SIGTERM
+// 收到第一个信号后停止监听，再次收到信号时按信号默认的方式处理，通常会直接结束进程
+// 返回的通道在p关闭后收到GracefulClose的结果，ctx在收到信号前结束时停止监听并关闭通道，不关闭p
+//
+//	done := pool.CloseOnSignal(ctx, p, 10*time.Second)
+//	...
+//	if err := <-done; err != nil {
+//		log.Println("pool:", err)
+//	}
+func CloseOnSignal(ctx context.Context, p Managed, grace time.Duration, signals ...os.Signal) <-chan error {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, signals...)
+	done := make(chan error, 1)
+	go func() {
+		defer signal.Stop(sig)
+		select {
+		case <-sig:
+		case <-ctx.Done():
+			close(done)
+			return
+		}
+		signal.Stop(sig)
+		done <- GracefulClose(p, grace)
+	}()
+	return done
+}
+
+// GracefulClose 逐渐关闭p，最多等待grace，grace不是正数时使用DefaultGracePeriod
+// p实现了BeginShutdown时(例如*Pool)先调用它，不再创建新资源、放回的资源直接关闭，
+// 所有资源都关闭或grace到达后调用CloseContext，这时仍未放回的资源被强制关闭
+// 可以在signal.NotifyContext返回的ctx结束后调用它
+func GracefulClose(p Managed, grace time.Duration) error {
+	if grace <= 0 {
+		grace = DefaultGracePeriod
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	if d, ok := p.(interface{ BeginShutdown() <-chan struct{} }); ok {
+		select {
+		case <-d.BeginShutdown():
+		case <-ctx.Done():
+		}
+	}
+	return p.CloseContext(ctx)
+}
@@ -0,0 +1,86 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Singleton 管理一个至多存在一个、被所有调用者共享的资源，例如嵌入式引擎
+// 第一次Acquire创建资源，之后的Acquire共享同一个资源并增加引用计数，同时到达的Acquire等待它创建完成；
+// 最后一个持有者放回后资源变为空闲，空闲超过idleTTL后被关闭，下一次Acquire重新创建
+type Singleton[T any] struct {
+	p *Pool[T]
+}
+
+// NewSingleton 创建一个用fn创建资源的Singleton，idleTTL为0表示资源空闲时不关闭，直到Close
+// opts中的WithCloser、WithValidator、WithLogger等与New中的含义相同，
+// MaxTotal、MaxIdle、MinIdle和MaxSharers由Singleton决定，不能修改
+func NewSingleton[T any](fn func(context.Context) (T, error), idleTTL time.Duration, opts ...Option) (*Singleton[T], error) {
+	if idleTTL < 0 {
+		return nil, fmt.Errorf("%w: negative idle TTL %v", ErrInvalidConfig, idleTTL)
+	}
+	opts = append(opts[:len(opts):len(opts)], func(s *settings) {
+		s.MaxTotal = 1
+		s.MaxIdle = 1
+		s.MinIdle = 0
+		s.MaxSharers = math.MaxUint32
+		s.IdleTimeout = idleTTL
+		if s.ReapInterval == 0 && idleTTL > 0 {
+			// 默认的回收间隔是idleTTL的四分之一，资源最多在空闲1.25倍idleTTL后关闭
+			s.ReapInterval = idleTTL / 4
+		}
+	})
+	p, err := NewContext(fn, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Singleton[T]{p: p}, nil
+}
+
+// Acquire 返回共享的资源，资源不存在时创建它
+func (s *Singleton[T]) Acquire() (T, error) {
+	return s.p.Acquire()
+}
+
+// AcquireContext 与Acquire相同，ctx的含义与Pool.AcquireContext相同
+func (s *Singleton[T]) AcquireContext(ctx context.Context) (T, error) {
+	return s.p.AcquireContext(ctx)
+}
+
+// Release 放弃调用者对资源的一次引用，每次Acquire都要对应一次Release
+func (s *Singleton[T]) Release(r T) error {
+	return s.p.Release(r)
+}
+
+// Discard 放弃调用者的引用并标记资源已经损坏，其它持有者都放回后资源被关闭，之后的Acquire创建新资源
+func (s *Singleton[T]) Discard(r T) error {
+	return s.p.Discard(r)
+}
+
+// Holders 返回当前持有资源的调用者数
+func (s *Singleton[T]) Holders() uint {
+	s.p.m.Lock()
+	defer s.p.m.Unlock()
+	var n uint
+	for _, e := range s.p.inUse {
+		n += e.refs
+	}
+	return n
+}
+
+// Stats 返回底层池的统计信息
+func (s *Singleton[T]) Stats() Stats {
+	return s.p.Stats()
+}
+
+// Close 关闭Singleton，等待所有持有者放回资源后关闭它
+func (s *Singleton[T]) Close() error {
+	return s.p.Close()
+}
+
+// CloseContext 与Close相同，但最多等待到ctx结束，之后强制关闭资源
+func (s *Singleton[T]) CloseContext(ctx context.Context) error {
+	return s.p.CloseContext(ctx)
+}
@@ -0,0 +1,147 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lazysheep666/pool"
+	"github.com/lazysheep666/pool/pooltest"
+)
+
+func TestSingleton(t *testing.T) {
+	const idleTTL = time.Minute
+	// acquire 从s获取资源，失败时结束测试
+	acquire := func(t *testing.T, s *pool.Singleton[*tracked]) *tracked {
+		t.Helper()
+		r, err := s.Acquire()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	tests := []struct {
+		name string
+		// run 借出和放回资源，用reap推进时钟，返回最后一次Acquire得到的资源的id
+		run func(t *testing.T, s *pool.Singleton[*tracked], reap func(time.Duration)) int64
+		// 最后创建和关闭的资源数
+		wantCreated, wantClosed int64
+	}{
+		{"holders share one resource", func(t *testing.T, s *pool.Singleton[*tracked], reap func(time.Duration)) int64 {
+			a, b := acquire(t, s), acquire(t, s)
+			if a != b || s.Holders() != 2 {
+				t.Errorf("got resources %d and %d with %d holders, want one resource with 2 holders", a.id, b.id, s.Holders())
+			}
+			s.Release(a)
+			if s.Holders() != 1 {
+				t.Errorf("Holders = %d after one Release, want 1", s.Holders())
+			}
+			s.Release(b)
+			return b.id
+		}, 1, 0},
+		{"kept within idle TTL", func(t *testing.T, s *pool.Singleton[*tracked], reap func(time.Duration)) int64 {
+			s.Release(acquire(t, s))
+			reap(idleTTL / 2)
+			r := acquire(t, s)
+			s.Release(r)
+			return r.id
+		}, 1, 0},
+		{"closed after idle TTL", func(t *testing.T, s *pool.Singleton[*tracked], reap func(time.Duration)) int64 {
+			s.Release(acquire(t, s))
+			reap(idleTTL + idleTTL/4)
+			r := acquire(t, s)
+			s.Release(r)
+			return r.id
+		}, 2, 1},
+		{"held resource not closed", func(t *testing.T, s *pool.Singleton[*tracked], reap func(time.Duration)) int64 {
+			r := acquire(t, s)
+			reap(2 * idleTTL)
+			s.Release(r)
+			return r.id
+		}, 1, 0},
+		{"recreated after Discard", func(t *testing.T, s *pool.Singleton[*tracked], reap func(time.Duration)) int64 {
+			a, b := acquire(t, s), acquire(t, s)
+			if err := s.Discard(a); err != nil {
+				t.Fatal(err)
+			}
+			// 另一个持有者放回之前资源保持打开
+			if b.closed.Load() {
+				t.Error("discarded resource closed while still held")
+			}
+			s.Release(b)
+			r := acquire(t, s)
+			s.Release(r)
+			return r.id
+		}, 2, 1},
+		{"concurrent first Acquire", func(t *testing.T, s *pool.Singleton[*tracked], reap func(time.Duration)) int64 {
+			const n = 8
+			var wg sync.WaitGroup
+			rs := make([]*tracked, n)
+			for i := 0; i < n; i++ {
+				i := i
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					r, err := s.Acquire()
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					rs[i] = r
+				}()
+			}
+			wg.Wait()
+			for _, r := range rs {
+				if r != nil {
+					s.Release(r)
+				}
+			}
+			return rs[0].id
+		}, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &harness{clock: pooltest.NewFakeClock(epoch)}
+			var runs atomic.Int64
+			s, err := pool.NewSingleton(func(context.Context) (*tracked, error) { return h.create() }, idleTTL,
+				pool.WithClock(h.clock),
+				pool.WithCloser(h.close),
+				pool.WithEventSink(func(e pool.Event) {
+					if e.Type == pool.ReaperRun {
+						runs.Add(1)
+					}
+				}),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			h.clock.BlockUntil(1)
+			// reap 推进时钟d，等待后台goroutine完成由此触发的一次回收
+			reap := func(d time.Duration) {
+				want := runs.Load() + 1
+				h.clock.Advance(d)
+				eventually(t, "reaper run", func() bool { return runs.Load() >= want })
+			}
+			if id := tt.run(t, s, reap); id != tt.wantCreated {
+				t.Errorf("last Acquire got resource %d, want %d", id, tt.wantCreated)
+			}
+			if h.created.Load() != tt.wantCreated || h.closed.Load() != tt.wantClosed {
+				t.Errorf("created %d, closed %d, want %d, %d", h.created.Load(), h.closed.Load(), tt.wantCreated, tt.wantClosed)
+			}
+			if s.Holders() != 0 {
+				t.Errorf("Holders = %d, want 0", s.Holders())
+			}
+		})
+	}
+}
+
+func TestNewSingletonNegativeTTL(t *testing.T) {
+	_, err := pool.NewSingleton(func(context.Context) (int, error) { return 1, nil }, -time.Second)
+	if !errors.Is(err, pool.ErrInvalidConfig) {
+		t.Fatalf("NewSingleton = %v, want ErrInvalidConfig", err)
+	}
+}
@@ -0,0 +1,63 @@
+package pool
+
+// Sized 是可以报告自己占用的字节数的资源，例如缓冲区，用于MaxIdleBytes
+// 没有设置WithSizeFunc时，池在资源放回空闲资源时调用Size，没有实现Sized的资源大小为0
+type Sized interface {
+	Size() int
+}
+
+// sizeOf 返回资源r占用的字节数，计算大小的函数panic或返回负数时为0
+func (p *Pool[T]) sizeOf(r T) (n uint64) {
+	var err error
+	defer func() {
+		if err != nil {
+			n = 0
+		}
+	}()
+	defer catch(p.logger, "size func", &err)
+	size := 0
+	if p.size != nil {
+		size = p.size(r)
+	} else if sr, ok := any(r).(Sized); ok {
+		size = sr.Size()
+	}
+	if size < 0 {
+		return 0
+	}
+	return uint64(size)
+}
+
+// fitIdleBytes 在空闲资源的总大小超过maxIdleBytes时关闭最大的空闲资源，大小相同时先关闭最早放回的，
+// 空闲资源不超过minIdle个时不再关闭；新放回的资源在这时测量大小，调用者需持有p.m，并用p.unlock解锁
+func (p *Pool[T]) fitIdleBytes() {
+	if p.maxIdleBytes == 0 {
+		return
+	}
+	total := p.idleBytes()
+	for total > p.maxIdleBytes && uint(p.idle.Len()) > p.minIdle {
+		largest := 0
+		for i := 0; i < p.idle.Len(); i++ {
+			if p.idle.At(i).size > p.idle.At(largest).size {
+				largest = i
+			}
+		}
+		e := p.takeIdle(largest)
+		total -= e.size
+		p.retire(e)
+		p.stats.sizeClosed.Add(1)
+	}
+}
+
+// idleBytes 返回空闲资源的总大小，测量还没有测量过的资源，调用者需持有p.m
+func (p *Pool[T]) idleBytes() uint64 {
+	var total uint64
+	for i := 0; i < p.idle.Len(); i++ {
+		e := p.idle.At(i)
+		if !e.sized {
+			e.size = p.sizeOf(e.r)
+			e.sized = true
+		}
+		total += e.size
+	}
+	return total
+}
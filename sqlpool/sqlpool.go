@@ -0,0 +1,205 @@
+// Package sqlpool 让database/sql使用本包的资源池管理驱动连接
+//
+//	c, err := sqlpool.NewConnector(connector, pool.WithMaxTotal(16), pool.WithBreaker(5, time.Second))
+//	db := sql.OpenDB(c)
+//	db.SetMaxIdleConns(0) // 连接由资源池保留，database/sql用完立即放回
+package sqlpool
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+
+	"github.com/lazysheep666/pool"
+)
+
+// Connector 实现了driver.Connector，Connect从资源池获取驱动连接，连接的Close把它放回池里
+// database/sql自己也会保留空闲连接，应该用SetMaxIdleConns(0)关闭它，让池的配置生效
+type Connector struct {
+	c driver.Connector
+	p *pool.Pool[driver.Conn]
+}
+
+var (
+	_ driver.Connector = (*Connector)(nil)
+	_ pool.Managed     = (*Connector)(nil)
+)
+
+// NewConnector 创建一个用c建立驱动连接的Connector
+// 空闲连接实现了driver.Validator时用IsValid检查，实现了driver.SessionResetter时放回前调用ResetSession
+// opts用来设置池的其它配置，其中的WithValidator和WithResetFunc会替换默认的检查和重置
+func NewConnector(c driver.Connector, opts ...pool.Option) (*Connector, error) {
+	if c == nil {
+		return nil, errors.New("sqlpool: nil connector")
+	}
+	opts = append([]pool.Option{
+		pool.WithCloser(func(dc driver.Conn) error { return dc.Close() }),
+		pool.WithValidator(valid),
+		pool.WithResetFunc(resetSession),
+	}, opts...)
+	p, err := pool.NewContext(c.Connect, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Connector{c: c, p: p}, nil
+}
+
+// valid 检查一个空闲连接是否仍然可用
+func valid(dc driver.Conn) bool {
+	if v, ok := dc.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+// resetSession 在连接放回池里前重置它的会话状态
+func resetSession(dc driver.Conn) error {
+	if sr, ok := dc.(driver.SessionResetter); ok {
+		return sr.ResetSession(context.Background())
+	}
+	return nil
+}
+
+// Connect 从池中获取一个连接
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	dc, err := c.p.AcquireContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: dc, p: c.p}, nil
+}
+
+// Driver 返回底层Connector的驱动
+func (c *Connector) Driver() driver.Driver {
+	return c.c.Driver()
+}
+
+// Stats 返回连接池的统计信息
+func (c *Connector) Stats() pool.Stats {
+	return c.p.Stats()
+}
+
+// Close 关闭连接池，sql.DB.Close会调用它
+func (c *Connector) Close() error {
+	return c.p.CloseContext(context.Background())
+}
+
+// CloseContext 与Close相同，但最多等待到ctx结束
+func (c *Connector) CloseContext(ctx context.Context) error {
+	return c.p.CloseContext(ctx)
+}
+
+// Pool 返回底层的资源池
+func (c *Connector) Pool() *pool.Pool[driver.Conn] {
+	return c.p
+}
+
+// conn 包装一个从池中获取的驱动连接，转发database/sql使用的可选接口
+// 连接上出现driver.ErrBadConn后，Close会销毁连接而不是放回池里
+type conn struct {
+	driver.Conn
+	p    *pool.Pool[driver.Conn]
+	bad  atomic.Bool
+	done atomic.Bool
+}
+
+var (
+	_ driver.ConnBeginTx        = (*conn)(nil)
+	_ driver.ConnPrepareContext = (*conn)(nil)
+	_ driver.ExecerContext      = (*conn)(nil)
+	_ driver.QueryerContext     = (*conn)(nil)
+	_ driver.Pinger             = (*conn)(nil)
+	_ driver.SessionResetter    = (*conn)(nil)
+	_ driver.Validator          = (*conn)(nil)
+	_ driver.NamedValueChecker  = (*conn)(nil)
+)
+
+// check 在err是driver.ErrBadConn时标记连接已损坏
+func (c *conn) check(err error) error {
+	if errors.Is(err, driver.ErrBadConn) {
+		c.bad.Store(true)
+	}
+	return err
+}
+
+// Close 把连接放回池里，连接已损坏时销毁它
+func (c *conn) Close() error {
+	if !c.done.CompareAndSwap(false, true) {
+		return pool.ErrResourceReleased
+	}
+	if c.bad.Load() {
+		return c.p.Discard(c.Conn)
+	}
+	return c.p.Release(c.Conn)
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	s, err := c.Conn.Prepare(query)
+	return s, c.check(err)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if pc, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		s, err := pc.PrepareContext(ctx, query)
+		return s, c.check(err)
+	}
+	return c.Prepare(query)
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if bt, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err := bt.BeginTx(ctx, opts)
+		return tx, c.check(err)
+	}
+	if opts.Isolation != driver.IsolationLevel(0) || opts.ReadOnly {
+		return nil, errors.New("sqlpool: driver does not support non-default transaction options")
+	}
+	// 驱动没有实现ConnBeginTx时只能使用Begin
+	tx, err := c.Conn.Begin()
+	return tx, c.check(err)
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if ec, ok := c.Conn.(driver.ExecerContext); ok {
+		res, err := ec.ExecContext(ctx, query, args)
+		return res, c.check(err)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if qc, ok := c.Conn.(driver.QueryerContext); ok {
+		rows, err := qc.QueryContext(ctx, query, args)
+		return rows, c.check(err)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if pc, ok := c.Conn.(driver.Pinger); ok {
+		return c.check(pc.Ping(ctx))
+	}
+	return nil
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if sr, ok := c.Conn.(driver.SessionResetter); ok {
+		return c.check(sr.ResetSession(ctx))
+	}
+	return nil
+}
+
+func (c *conn) IsValid() bool {
+	if c.bad.Load() {
+		return false
+	}
+	return valid(c.Conn)
+}
+
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
@@ -0,0 +1,241 @@
+package sqlpool
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/lazysheep666/pool"
+)
+
+// fakeConnector 是记录连接状态的驱动，badExec次Exec返回driver.ErrBadConn
+type fakeConnector struct {
+	mu      sync.Mutex
+	conns   []*fakeConn
+	badExec int
+}
+
+func (fc *fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	c := &fakeConn{owner: fc, valid: true}
+	fc.conns = append(fc.conns, c)
+	return c, nil
+}
+
+func (fc *fakeConnector) Driver() driver.Driver { return fakeDriver{} }
+
+// counts 返回创建、关闭和重置会话的次数
+func (fc *fakeConnector) counts() (created, closed, resets int) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	for _, c := range fc.conns {
+		if c.closed {
+			closed++
+		}
+		resets += c.resets
+	}
+	return len(fc.conns), closed, resets
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return nil, errors.New("not supported") }
+
+// fakeConn 没有实现ConnBeginTx，BeginTx退回到Begin
+type fakeConn struct {
+	owner  *fakeConnector
+	closed bool
+	valid  bool
+	resets int
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+
+func (c *fakeConn) Close() error {
+	c.owner.mu.Lock()
+	defer c.owner.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.owner.mu.Lock()
+	defer c.owner.mu.Unlock()
+	if c.owner.badExec > 0 {
+		c.owner.badExec--
+		return nil, driver.ErrBadConn
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return &fakeRows{values: []int64{1, 2}}, nil
+}
+
+func (c *fakeConn) ResetSession(context.Context) error {
+	c.owner.mu.Lock()
+	defer c.owner.mu.Unlock()
+	c.resets++
+	return nil
+}
+
+func (c *fakeConn) IsValid() bool {
+	c.owner.mu.Lock()
+	defer c.owner.mu.Unlock()
+	return c.valid
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+// fakeRows 返回一列values
+type fakeRows struct{ values []int64 }
+
+func (r *fakeRows) Columns() []string { return []string{"n"} }
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0], r.values = r.values[0], r.values[1:]
+	return nil
+}
+
+// openDB 用fc创建经过资源池的sql.DB，database/sql不保留空闲连接
+func openDB(t *testing.T, fc *fakeConnector, opts ...pool.Option) (*sql.DB, *Connector) {
+	t.Helper()
+	c, err := NewConnector(fc, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(c)
+	db.SetMaxIdleConns(0)
+	t.Cleanup(func() { db.Close() })
+	return db, c
+}
+
+func TestOpenDB(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		badExec int
+		run     func(t *testing.T, db *sql.DB, fc *fakeConnector)
+		// 最后期望的创建、关闭和重置会话的次数
+		wantCreated, wantClosed, wantResets int
+	}{
+		{"connection reused", 0, func(t *testing.T, db *sql.DB, fc *fakeConnector) {
+			for i := 0; i < 3; i++ {
+				if _, err := db.ExecContext(ctx, "UPDATE t SET n = 1"); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}, 1, 0, 3},
+		{"query", 0, func(t *testing.T, db *sql.DB, fc *fakeConnector) {
+			rows, err := db.QueryContext(ctx, "SELECT n FROM t")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var sum int64
+			for rows.Next() {
+				var n int64
+				if err := rows.Scan(&n); err != nil {
+					t.Fatal(err)
+				}
+				sum += n
+			}
+			if err := rows.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if sum != 3 {
+				t.Errorf("sum = %d, want 3", sum)
+			}
+		}, 1, 0, 1},
+		{"bad connection destroyed", 1, func(t *testing.T, db *sql.DB, fc *fakeConnector) {
+			// database/sql在ErrBadConn后换一个连接重试
+			if _, err := db.ExecContext(ctx, "UPDATE t SET n = 1"); err != nil {
+				t.Fatal(err)
+			}
+		}, 2, 1, 1},
+		{"invalid idle connection replaced", 0, func(t *testing.T, db *sql.DB, fc *fakeConnector) {
+			if err := db.PingContext(ctx); err != nil {
+				t.Fatal(err)
+			}
+			fc.mu.Lock()
+			fc.conns[0].valid = false
+			fc.mu.Unlock()
+			if err := db.PingContext(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}, 2, 1, 2},
+		{"default transaction through Begin", 0, func(t *testing.T, db *sql.DB, fc *fakeConnector) {
+			tx, err := db.BeginTx(ctx, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tx.Commit(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true}); err == nil {
+				t.Error("read-only BeginTx succeeded on a driver without ConnBeginTx")
+			}
+		}, 1, 0, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := &fakeConnector{badExec: tt.badExec}
+			db, c := openDB(t, fc)
+			tt.run(t, db, fc)
+			created, closed, resets := fc.counts()
+			if created != tt.wantCreated || closed != tt.wantClosed || resets != tt.wantResets {
+				t.Errorf("created %d, closed %d, reset %d connections, want %d, %d, %d",
+					created, closed, resets, tt.wantCreated, tt.wantClosed, tt.wantResets)
+			}
+			if s := c.Stats(); s.InUse != 0 {
+				t.Errorf("InUse = %d after database/sql returned all connections, want 0", s.InUse)
+			}
+			// sql.DB.Close关闭Connector和池中的空闲连接
+			if err := db.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if created, closed, _ := fc.counts(); closed != created {
+				t.Errorf("closed %d of %d connections after db.Close", closed, created)
+			}
+		})
+	}
+}
+
+// TestConnClose 检查直接从Connector获取的连接重复Close时返回ErrResourceReleased
+func TestConnClose(t *testing.T) {
+	c, err := NewConnector(&fakeConnector{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	dc, err := c.Connect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := dc.Close(); !errors.Is(err, pool.ErrResourceReleased) {
+		t.Errorf("second Close = %v, want ErrResourceReleased", err)
+	}
+	if _, ok := c.Driver().(fakeDriver); !ok {
+		t.Errorf("Driver = %T, want the wrapped driver", c.Driver())
+	}
+	if _, err := NewConnector(nil); err == nil {
+		t.Error("NewConnector(nil) succeeded")
+	}
+}
@@ -0,0 +1,186 @@
+// Package sshpool 提供管理SSH客户端的资源池，用于在少量SSH连接上复用大量的会话和SFTP传输
+//
+//	p, err := sshpool.NewClientPool("10.0.0.1:22", &ssh.ClientConfig{
+//		User:            "deploy",
+//		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+//		HostKeyCallback: ssh.FixedHostKey(hostKey),
+//	}, pool.WithMaxTotal(4), sshpool.WithMaxSessions(8))
+//	c, err := p.Get(ctx)
+//	defer c.Close() // 把客户端放回池里
+//	sc, err := sftp.NewClient(c.Client)
+//
+// 设置WithMaxSessions后一个客户端同时借给多个Get，每个Get对应客户端上的一个会话或SFTP子系统
+package sshpool
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/lazysheep666/pool"
+	"golang.org/x/crypto/ssh"
+)
+
+// DefaultKeepaliveTimeout 是检查空闲客户端时等待keepalive请求回复的最长时间
+const DefaultKeepaliveTimeout = 5 * time.Second
+
+// keepaliveRequest 是OpenSSH服务端都支持的全局请求，服务端对未知请求也会回复失败，所以任何回复都说明连接可用
+const keepaliveRequest = "keepalive@openssh.com"
+
+// ClientPool 是一个*ssh.Client的资源池
+// 从池中取出空闲客户端时发送keepalive请求检查连接，没有及时回复的客户端会被关闭并重新获取
+type ClientPool struct {
+	p *pool.Pool[*ssh.Client]
+}
+
+// NewClientPool 创建一个用config连接addr的客户端池，config必须设置HostKeyCallback以验证服务端的主机密钥
+// opts用来设置池的其它配置，其中的WithValidator会替换默认的keepalive检查
+func NewClientPool(addr string, config *ssh.ClientConfig, opts ...pool.Option) (*ClientPool, error) {
+	if config == nil {
+		return nil, errors.New("sshpool: nil client config")
+	}
+	if config.HostKeyCallback == nil {
+		return nil, errors.New("sshpool: missing HostKeyCallback")
+	}
+	dial := func(ctx context.Context) (*ssh.Client, error) {
+		return Dial(ctx, "tcp", addr, config)
+	}
+	opts = append([]pool.Option{
+		pool.WithCloser(func(c *ssh.Client) error { return c.Close() }),
+		pool.WithValidatorContext(Alive),
+	}, opts...)
+	p, err := pool.NewContext(dial, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientPool{p: p}, nil
+}
+
+// WithMaxSessions 设置一个客户端最多同时借给n个Get，用于限制每个SSH连接上的会话数，与pool.WithSharing相同
+// 大多数服务端默认限制每个连接最多10个会话(OpenSSH的MaxSessions)
+func WithMaxSessions(n uint) pool.Option {
+	return pool.WithSharing(n)
+}
+
+// Dial 在ctx结束前建立到addr的SSH连接并完成握手
+func Dial(ctx context.Context, network, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	// 握手不接受ctx，ctx结束时设置连接的超时让它返回
+	done, canceled := make(chan struct{}), make(chan bool, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Now())
+			canceled <- true
+		case <-done:
+			canceled <- false
+		}
+	}()
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	close(done)
+	// 握手因为设置的超时失败时返回ctx.Err()而不是超时错误
+	if <-canceled {
+		err = ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// Get 从池中获取一个客户端，使用完后调用它的Close放回池里
+func (cp *ClientPool) Get(ctx context.Context) (*Client, error) {
+	pr, err := cp.p.AcquireResource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{Client: pr.Value(), pr: pr}, nil
+}
+
+// NewSession 从池中获取一个客户端并在上面打开一个会话，会话的Close同时把客户端放回池里
+// 打开会话失败时客户端被销毁
+func (cp *ClientPool) NewSession(ctx context.Context) (*Session, error) {
+	c, err := cp.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s, err := c.Client.NewSession()
+	if err != nil {
+		c.Destroy()
+		return nil, err
+	}
+	return &Session{Session: s, c: c}, nil
+}
+
+// Stats 返回客户端池的统计信息
+func (cp *ClientPool) Stats() pool.Stats {
+	return cp.p.Stats()
+}
+
+// Close 关闭客户端池，并等待使用中的客户端被放回后关闭它们
+func (cp *ClientPool) Close() error {
+	return cp.p.Close()
+}
+
+// Pool 返回底层的资源池
+func (cp *ClientPool) Pool() *pool.Pool[*ssh.Client] {
+	return cp.p
+}
+
+// Client 是从ClientPool中获取的客户端，Close会把客户端放回池里而不是关闭连接
+type Client struct {
+	*ssh.Client
+	pr *pool.PooledResource[*ssh.Client]
+}
+
+// Close 把客户端放回池里，重复调用时返回pool.ErrResourceReleased
+// 放回之前应当关闭在客户端上打开的会话、SFTP客户端和转发的连接
+func (c *Client) Close() error {
+	return c.pr.Close()
+}
+
+// Destroy 关闭连接而不是放回池里，用于连接出错、状态不可预期的情况
+// 共享的客户端在所有借用者都放回后才关闭
+func (c *Client) Destroy() error {
+	return c.pr.Destroy()
+}
+
+// Session 是NewSession打开的会话，Close关闭会话并把客户端放回池里
+type Session struct {
+	*ssh.Session
+	c *Client
+}
+
+// Close 关闭会话并把客户端放回池里，会话已经结束时ssh返回的io.EOF被忽略
+func (s *Session) Close() error {
+	err := s.Session.Close()
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	return errors.Join(err, s.c.Close())
+}
+
+// Alive 发送keepalive请求检查一个空闲客户端是否仍然可用，DefaultKeepaliveTimeout内没有回复时返回false
+func Alive(ctx context.Context, c *ssh.Client) bool {
+	ctx, cancel := context.WithTimeout(ctx, DefaultKeepaliveTimeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := c.SendRequest(keepaliveRequest, true, nil)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err == nil
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,289 @@
+package sshpool
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lazysheep666/pool"
+	"golang.org/x/crypto/ssh"
+)
+
+// server 是回环地址上的SSH服务端，exec请求把命令原样写回
+type server struct {
+	lis     net.Listener
+	key     ssh.Signer
+	config  *ssh.ServerConfig
+	handled atomic.Int64 // 完成握手的连接数
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func newServer(t *testing.T) *server {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &server{lis: lis, key: newSigner(t), config: &ssh.ServerConfig{NoClientAuth: true}}
+	s.config.AddHostKey(s.key)
+	go s.serve()
+	t.Cleanup(func() {
+		lis.Close()
+		s.dropAll()
+	})
+	return s
+}
+
+// newSigner 生成一个ed25519密钥
+func newSigner(t *testing.T) ssh.Signer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+func (s *server) serve() {
+	for {
+		conn, err := s.lis.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns = append(s.conns, conn)
+		s.mu.Unlock()
+		go s.handle(conn)
+	}
+}
+
+func (s *server) handle(conn net.Conn) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	s.handled.Add(1)
+	// 对keepalive等全局请求回复失败
+	go ssh.DiscardRequests(reqs)
+	for nc := range chans {
+		if nc.ChannelType() != "session" {
+			nc.Reject(ssh.UnknownChannelType, "unsupported")
+			continue
+		}
+		ch, creqs, err := nc.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			for req := range creqs {
+				if req.Type != "exec" {
+					req.Reply(false, nil)
+					continue
+				}
+				var cmd struct{ Command string }
+				ssh.Unmarshal(req.Payload, &cmd)
+				req.Reply(true, nil)
+				ch.Write([]byte(cmd.Command))
+				ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+				ch.Close()
+			}
+		}()
+	}
+}
+
+// dropAll 断开所有连接
+func (s *server) dropAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.conns {
+		c.Close()
+	}
+	s.conns = nil
+}
+
+// clientConfig 返回信任s的主机密钥的客户端配置
+func (s *server) clientConfig() *ssh.ClientConfig {
+	return &ssh.ClientConfig{User: "test", HostKeyCallback: ssh.FixedHostKey(s.key.PublicKey())}
+}
+
+// newPool 创建连接s的客户端池
+func newPool(t *testing.T, s *server, opts ...pool.Option) *ClientPool {
+	t.Helper()
+	cp, err := NewClientPool(s.lis.Addr().String(), s.clientConfig(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cp.Close() })
+	return cp
+}
+
+// get 从cp获取一个客户端，失败时结束测试
+func get(t *testing.T, cp *ClientPool) *Client {
+	t.Helper()
+	c, err := cp.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestClientPool(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		opts []pool.Option
+		run  func(t *testing.T, s *server, cp *ClientPool)
+		// 最后期望建立的连接数
+		wantConns int64
+	}{
+		{"reused after Close", nil, func(t *testing.T, s *server, cp *ClientPool) {
+			a := get(t, cp)
+			a.Close()
+			b := get(t, cp)
+			if b.Client != a.Client {
+				t.Error("Get dialed a new client with a live one idle")
+			}
+			b.Close()
+		}, 1},
+		{"session", nil, func(t *testing.T, s *server, cp *ClientPool) {
+			for i := 0; i < 2; i++ {
+				sess, err := cp.NewSession(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				out, err := sess.Output("echo hi")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(out) != "echo hi" {
+					t.Errorf("Output = %q, want %q", out, "echo hi")
+				}
+				// 会话已经结束，Close忽略io.EOF并放回客户端
+				if err := sess.Close(); err != nil {
+					t.Errorf("Session.Close = %v", err)
+				}
+			}
+		}, 1},
+		{"dead idle client replaced", nil, func(t *testing.T, s *server, cp *ClientPool) {
+			a := get(t, cp)
+			a.Close()
+			s.dropAll()
+			// 连接断开后keepalive请求失败
+			for deadline := time.Now().Add(5 * time.Second); Alive(ctx, a.Client); time.Sleep(time.Millisecond) {
+				if time.Now().After(deadline) {
+					t.Fatal("client still alive after the server dropped it")
+				}
+			}
+			b := get(t, cp)
+			if b.Client == a.Client {
+				t.Error("Get reused a dead client")
+			}
+			b.Close()
+		}, 2},
+		{"sessions share a client", []pool.Option{WithMaxSessions(2)}, func(t *testing.T, s *server, cp *ClientPool) {
+			a, b, c := get(t, cp), get(t, cp), get(t, cp)
+			if a.Client != b.Client || c.Client == a.Client {
+				t.Error("want the first two Gets to share a client and the third to dial")
+			}
+			a.Close()
+			b.Close()
+			c.Close()
+		}, 2},
+		{"double Close", nil, func(t *testing.T, s *server, cp *ClientPool) {
+			c := get(t, cp)
+			c.Close()
+			if err := c.Close(); !errors.Is(err, pool.ErrResourceReleased) {
+				t.Errorf("second Close = %v, want ErrResourceReleased", err)
+			}
+		}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t)
+			cp := newPool(t, s, tt.opts...)
+			tt.run(t, s, cp)
+			if n := s.handled.Load(); n != tt.wantConns {
+				t.Errorf("server accepted %d connections, want %d", n, tt.wantConns)
+			}
+			if st := cp.Stats(); st.InUse != 0 {
+				t.Errorf("InUse = %d, want 0", st.InUse)
+			}
+		})
+	}
+}
+
+func TestDial(t *testing.T) {
+	s := newServer(t)
+	// silent 接受连接但不握手
+	silent, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+	go func() {
+		for {
+			conn, err := silent.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	wrongKey := s.clientConfig()
+	wrongKey.HostKeyCallback = ssh.FixedHostKey(newSigner(t).PublicKey())
+	tests := []struct {
+		name    string
+		addr    string
+		config  *ssh.ClientConfig
+		timeout time.Duration
+		wantErr error // nil表示只检查失败
+		ok      bool
+	}{
+		{"handshake", s.lis.Addr().String(), s.clientConfig(), 5 * time.Second, nil, true},
+		{"wrong host key", s.lis.Addr().String(), wrongKey, 5 * time.Second, nil, false},
+		{"ctx ends during handshake", silent.Addr().String(), s.clientConfig(), 50 * time.Millisecond, context.DeadlineExceeded, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			c, err := Dial(ctx, "tcp", tt.addr, tt.config)
+			if tt.ok {
+				if err != nil {
+					t.Fatal(err)
+				}
+				c.Close()
+				return
+			}
+			if err == nil {
+				c.Close()
+				t.Fatal("Dial succeeded")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Dial = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewClientPoolErrors(t *testing.T) {
+	if _, err := NewClientPool("127.0.0.1:22", nil); err == nil {
+		t.Error("NewClientPool with a nil config succeeded")
+	}
+	if _, err := NewClientPool("127.0.0.1:22", &ssh.ClientConfig{User: "test"}); err == nil {
+		t.Error("NewClientPool without a HostKeyCallback succeeded")
+	}
+}
@@ -0,0 +1,67 @@
+package pool
+
+import "time"
+
+// StarvedWaiter 描述一个排队等待超过StarvationThreshold的Acquire，在它仍在等待时报告
+type StarvedWaiter struct {
+	Since    time.Time     // 开始排队的时间
+	Waited   time.Duration // 已经等待的时间
+	Priority Priority      // 排队的优先级
+	Consumer string        // AcquireAs的消费者，匿名的Acquire为空
+	N        uint          // 需要的资源数
+	Waiters  int           // 正在排队的Acquire数
+}
+
+// starvationWatch 定期检查排队过久的等待者，直到池被关闭
+func (p *Pool[T]) starvationWatch() {
+	interval := p.starvation / 4
+	if interval <= 0 {
+		interval = p.starvation
+	}
+	ticker := p.clock.NewTicker(interval)
+	defer ticker.Stop()
+	last := p.clock.Now()
+	for {
+		select {
+		case <-ticker.C():
+			now := p.clock.Now()
+			p.checkStarvation(last, now)
+			last = now
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// checkStarvation 报告在(last, now]期间等待时间达到starvation的等待者
+// 重新排队的等待者保留原来的since，所以每次排队只在越过阈值的那次检查中被报告一次
+func (p *Pool[T]) checkStarvation(last, now time.Time) {
+	var starved []StarvedWaiter
+	p.m.Lock()
+	for _, w := range p.waiters {
+		due := w.since.Add(p.starvation)
+		if due.After(now) || !due.After(last) {
+			continue
+		}
+		starved = append(starved, StarvedWaiter{
+			Since:    w.since,
+			Waited:   now.Sub(w.since),
+			Priority: w.prio,
+			Consumer: w.consumer,
+			N:        w.n,
+		})
+	}
+	waiters := len(p.waiters)
+	p.m.Unlock()
+
+	for _, s := range starved {
+		s.Waiters = waiters
+		p.stats.starved.Add(1)
+		p.emit(Event{Type: AcquireStarved, Time: now, Duration: s.Waited})
+		if p.onStarved != nil {
+			p.onStarved(s)
+		} else {
+			p.logger.Println("Starvation:", "Acquire waiting for", s.Waited)
+		}
+	}
+}
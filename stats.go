@@ -0,0 +1,382 @@
+package pool
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// UsageSamples 是计算Stats.Lifetime和Stats.BusyTime时保留的最近销毁的资源数
+const UsageSamples = 1024
+
+// WaitBuckets 是Stats.AcquireWaitBuckets中各个桶的上限，
+// 最后一个桶记录超过所有上限的等待
+var WaitBuckets = [...]time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Stats 是池在某一时刻的统计信息
+type Stats struct {
+	Idle        uint   // 空闲资源数
+	InUse       uint   // 使用中的资源数，包括正在创建的资源
+	Waiting     uint   // 正在排队等待资源的Acquire数
+	Quarantined uint   // 没有通过验证、被WithQuarantine隔离等待重试的资源数
+	Lingering   uint   // 被ReleaseDeferred放回、还在等待决定的资源数
+	Weight      uint   // 已创建且尚未销毁的资源的总权重，见WithWeightFunc
+	IdleBytes   uint64 // 空闲资源的总大小，只在设置了MaxIdleBytes时统计
+
+	TotalCreated  uint64 // 累计创建的资源数
+	TotalClosed   uint64 // 累计销毁的资源数
+	ClosedOutside uint64 // 累计在Release时发现已经被调用者直接关闭的资源数，也计入TotalClosed
+	// IdleClosed、LifetimeClosed、CompactionClosed和SizeClosed 是因为超过IdleTimeout、超过MaxLifetime、
+	// 被CompactIdle收缩和超过MaxIdleBytes而关闭的资源数，都计入TotalClosed
+	IdleClosed       uint64
+	LifetimeClosed   uint64
+	CompactionClosed uint64
+	SizeClosed       uint64
+
+	AcquireCount        uint64        // 累计成功获取资源的次数
+	AcquireWaitCount    uint64        // 累计因资源达到上限而等待的次数
+	AcquireWaitDuration time.Duration // 累计等待的时间
+	AcquireTimeoutCount uint64        // 累计因超时而失败的次数
+	QueueFullCount      uint64        // 累计因等待队列已满而失败的次数
+	Hits                uint64        // 获取到空闲资源的次数
+	Misses              uint64        // 获取到新创建资源的次数
+	CheckoutDuration    time.Duration // 累计持有资源的时间，在资源放回或销毁时计入
+	OverdueCount        uint64        // 累计持有时间超过MaxCheckoutDuration的次数
+	StarvedCount        uint64        // 累计排队等待超过StarvationThreshold的次数
+	MaxWaiterAge        time.Duration // 当前排队最久的Acquire已经等待的时间
+
+	// AcquireWaitBuckets 等待时间的分布，第i个桶记录不超过WaitBuckets[i]且超过前一个上限的等待次数
+	AcquireWaitBuckets [len(WaitBuckets) + 1]uint64
+	// WaitTime 是最近至多UsageSamples次等待的时间的分布
+	WaitTime Summary
+
+	// Lifetime 和BusyTime 是最近销毁的至多UsageSamples个资源从创建到销毁的时间和其中被持有的时间，
+	// 以及空闲和使用中的资源从创建到现在的时间和其中被持有的时间的分布
+	Lifetime Summary
+	BusyTime Summary
+	// Utilization 是这些资源被持有的时间占存活时间的百分比
+	Utilization float64
+}
+
+// Summary 是一组时间的分布摘要
+type Summary struct {
+	Count         uint64        // 样本数
+	Total         time.Duration // 样本之和
+	P50, P90, P99 time.Duration // 分位数
+}
+
+// merge 返回s和o合并后的摘要，分位数按样本数加权平均，只是近似值
+func (s Summary) merge(o Summary) Summary {
+	n := s.Count + o.Count
+	if n == 0 {
+		return s
+	}
+	avg := func(a, b time.Duration) time.Duration {
+		return time.Duration((float64(a)*float64(s.Count) + float64(b)*float64(o.Count)) / float64(n))
+	}
+	return Summary{Count: n, Total: s.Total + o.Total, P50: avg(s.P50, o.P50), P90: avg(s.P90, o.P90), P99: avg(s.P99, o.P99)}
+}
+
+// utilization 返回busy占lifetime的百分比
+func utilization(lifetime, busy Summary) float64 {
+	if lifetime.Total <= 0 {
+		return 0
+	}
+	return 100 * float64(busy.Total) / float64(lifetime.Total)
+}
+
+// usageRing 保存最近销毁的UsageSamples个资源的存活时间和使用时间
+type usageRing struct {
+	lifetimes sampleRing
+	busy      sampleRing
+}
+
+// add 记录一个销毁的资源
+func (u *usageRing) add(lifetime, busy time.Duration) {
+	u.lifetimes.add(lifetime)
+	u.busy.add(busy)
+}
+
+// sampleRing 保存最近的UsageSamples个时间样本
+type sampleRing struct {
+	samples [UsageSamples]time.Duration
+	n       int // 累计记录的样本数
+	cached  bool
+	summary Summary // 缓存的摘要，cached为true时有效
+}
+
+// add 记录一个样本
+func (r *sampleRing) add(d time.Duration) {
+	r.samples[r.n%UsageSamples] = d
+	r.n++
+	r.cached = false
+}
+
+// ringSnapshot 是在锁中复制的sampleRing，在锁外计算摘要
+type ringSnapshot struct {
+	samples []time.Duration // 需要重新计算时的样本副本，为nil时summary有效
+	summary Summary
+	n       int // 复制时累计记录的样本数，-1表示混入了存活资源的样本，不缓存
+}
+
+// snapshot 在样本没有变化时返回缓存的摘要，否则复制样本，调用者需持有保护r的锁
+func (r *sampleRing) snapshot() ringSnapshot {
+	if r.cached {
+		return ringSnapshot{summary: r.summary, n: r.n}
+	}
+	return r.copySamples(nil)
+}
+
+// withLive 复制样本并在后面加上live，返回的快照不会被缓存，调用者需持有保护r的锁
+func (r *sampleRing) withLive(live []time.Duration) ringSnapshot {
+	s := r.copySamples(live)
+	s.n = -1
+	return s
+}
+
+// copySamples 返回样本的副本之后加上extra的快照，调用者需持有保护r的锁
+func (r *sampleRing) copySamples(extra []time.Duration) ringSnapshot {
+	n := r.n
+	if n > UsageSamples {
+		n = UsageSamples
+	}
+	samples := make([]time.Duration, 0, n+len(extra))
+	samples = append(append(samples, r.samples[:n]...), extra...)
+	return ringSnapshot{samples: samples, n: r.n}
+}
+
+// resolve 返回快照的摘要，需要时在锁外计算，computed表示是否重新计算了
+func (s *ringSnapshot) resolve() (computed bool) {
+	if s.samples == nil {
+		return false
+	}
+	s.summary = summarize(s.samples)
+	s.samples = nil
+	return true
+}
+
+// cache 在快照之后没有新样本时缓存它的摘要，调用者需持有保护r的锁
+func (r *sampleRing) cache(s ringSnapshot) {
+	if r.n == s.n {
+		r.summary = s.summary
+		r.cached = true
+	}
+}
+
+// summarize 计算ds的摘要，会把ds排序
+func summarize(ds []time.Duration) Summary {
+	if len(ds) == 0 {
+		return Summary{}
+	}
+	sorted := ds
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	s := Summary{Count: uint64(len(sorted))}
+	for _, d := range sorted {
+		s.Total += d
+	}
+	at := func(q float64) time.Duration { return sorted[int(q*float64(len(sorted)-1))] }
+	s.P50, s.P90, s.P99 = at(0.5), at(0.9), at(0.99)
+	return s
+}
+
+// counters 保存Stats中的累计值，使用原子操作更新
+type counters struct {
+	created   atomic.Uint64
+	closed    atomic.Uint64
+	acquired  atomic.Uint64
+	waits     atomic.Uint64
+	waitNanos atomic.Int64
+	buckets   [len(WaitBuckets) + 1]atomic.Uint64
+	timeouts  atomic.Uint64
+	queueFull atomic.Uint64
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+
+	checkoutNanos atomic.Int64
+	overdue       atomic.Uint64
+	starved       atomic.Uint64
+	closedOutside atomic.Uint64
+
+	idleClosed       atomic.Uint64
+	lifetimeClosed   atomic.Uint64
+	compactionClosed atomic.Uint64
+	sizeClosed       atomic.Uint64
+}
+
+// hit 记录一次获取到空闲资源的Acquire
+func (c *counters) hit() {
+	c.acquired.Add(1)
+	c.hits.Add(1)
+}
+
+// miss 记录一次获取到新创建资源的Acquire
+func (c *counters) miss() {
+	c.acquired.Add(1)
+	c.misses.Add(1)
+}
+
+// waited 记录一次持续d的等待
+func (c *counters) waited(d time.Duration) {
+	c.waits.Add(1)
+	c.waitNanos.Add(int64(d))
+	i := 0
+	for i < len(WaitBuckets) && d > WaitBuckets[i] {
+		i++
+	}
+	c.buckets[i].Add(1)
+}
+
+// Stats 返回池当前的统计信息
+func (p *Pool[T]) Stats() Stats {
+	return p.collectStats(true)
+}
+
+// collectStats 返回池当前的统计信息，dists为false时不计算Lifetime、BusyTime、WaitTime和Utilization，
+// 用于在获取和放回时调用的钩子，样本在锁中复制，排序在锁外进行
+func (p *Pool[T]) collectStats(dists bool) Stats {
+	var lifetime, busy, waits ringSnapshot
+	p.m.Lock()
+	idle := uint(p.idle.Len())
+	if p.lf != nil {
+		// 暂存的资源在池看来是使用中的，但它们可以立即被获取
+		idle += uint(p.lf.parked.Load())
+	}
+	open := p.numOpen
+	waiting := uint(len(p.waiters))
+	quarantined := p.quarantined
+	lingering := p.lingering
+	weight := p.totalWeight
+	var idleBytes uint64
+	if p.maxIdleBytes > 0 {
+		idleBytes = p.idleBytes()
+	}
+	if dists {
+		if live := p.idle.Len() + len(p.inUse); live > 0 {
+			// 存活的资源的时间每次都不同，和销毁的资源的样本一起重新计算
+			now := p.clock.Now()
+			lifetimes, busyTimes := make([]time.Duration, 0, live), make([]time.Duration, 0, live)
+			for i := 0; i < p.idle.Len(); i++ {
+				e := p.idle.At(i)
+				lifetimes, busyTimes = append(lifetimes, now.Sub(e.createdAt)), append(busyTimes, e.busy)
+			}
+			for _, e := range p.inUse {
+				lifetimes, busyTimes = append(lifetimes, now.Sub(e.createdAt)), append(busyTimes, p.busyTime(e, now))
+			}
+			lifetime, busy = p.usage.lifetimes.withLive(lifetimes), p.usage.busy.withLive(busyTimes)
+		} else {
+			lifetime, busy = p.usage.lifetimes.snapshot(), p.usage.busy.snapshot()
+		}
+		waits = p.waitTimes.snapshot()
+	}
+	var maxAge time.Duration
+	if len(p.waiters) > 0 {
+		now := p.clock.Now()
+		for _, w := range p.waiters {
+			if age := now.Sub(w.since); age > maxAge {
+				maxAge = age
+			}
+		}
+	}
+	p.m.Unlock()
+
+	if dists {
+		// 三个结果都计算过时只需要再加锁一次来缓存它们
+		computed := lifetime.resolve()
+		computed = busy.resolve() || computed
+		computed = waits.resolve() || computed
+		if computed {
+			p.m.Lock()
+			p.usage.lifetimes.cache(lifetime)
+			p.usage.busy.cache(busy)
+			p.waitTimes.cache(waits)
+			p.m.Unlock()
+		}
+	}
+
+	s := Stats{
+		Idle:                idle,
+		InUse:               open - idle - quarantined - lingering,
+		Waiting:             waiting,
+		Quarantined:         quarantined,
+		Lingering:           lingering,
+		Weight:              weight,
+		IdleBytes:           idleBytes,
+		TotalCreated:        p.stats.created.Load(),
+		TotalClosed:         p.stats.closed.Load(),
+		ClosedOutside:       p.stats.closedOutside.Load(),
+		IdleClosed:          p.stats.idleClosed.Load(),
+		LifetimeClosed:      p.stats.lifetimeClosed.Load(),
+		CompactionClosed:    p.stats.compactionClosed.Load(),
+		SizeClosed:          p.stats.sizeClosed.Load(),
+		AcquireCount:        p.stats.acquired.Load(),
+		AcquireWaitCount:    p.stats.waits.Load(),
+		AcquireWaitDuration: time.Duration(p.stats.waitNanos.Load()),
+		AcquireTimeoutCount: p.stats.timeouts.Load(),
+		QueueFullCount:      p.stats.queueFull.Load(),
+		Hits:                p.stats.hits.Load(),
+		Misses:              p.stats.misses.Load(),
+		CheckoutDuration:    time.Duration(p.stats.checkoutNanos.Load()),
+		OverdueCount:        p.stats.overdue.Load(),
+		StarvedCount:        p.stats.starved.Load(),
+		MaxWaiterAge:        maxAge,
+		WaitTime:            waits.summary,
+		Lifetime:            lifetime.summary,
+		BusyTime:            busy.summary,
+		Utilization:         utilization(lifetime.summary, busy.summary),
+	}
+	for i := range s.AcquireWaitBuckets {
+		s.AcquireWaitBuckets[i] = p.stats.buckets[i].Load()
+	}
+	return s
+}
+
+// add 返回s和o相加的结果
+func (s Stats) add(o Stats) Stats {
+	s.Idle += o.Idle
+	s.InUse += o.InUse
+	s.Waiting += o.Waiting
+	s.Quarantined += o.Quarantined
+	s.Lingering += o.Lingering
+	s.Weight += o.Weight
+	s.IdleBytes += o.IdleBytes
+	s.TotalCreated += o.TotalCreated
+	s.TotalClosed += o.TotalClosed
+	s.ClosedOutside += o.ClosedOutside
+	s.IdleClosed += o.IdleClosed
+	s.LifetimeClosed += o.LifetimeClosed
+	s.CompactionClosed += o.CompactionClosed
+	s.SizeClosed += o.SizeClosed
+	s.AcquireCount += o.AcquireCount
+	s.AcquireWaitCount += o.AcquireWaitCount
+	s.AcquireWaitDuration += o.AcquireWaitDuration
+	s.AcquireTimeoutCount += o.AcquireTimeoutCount
+	s.QueueFullCount += o.QueueFullCount
+	s.Hits += o.Hits
+	s.Misses += o.Misses
+	s.CheckoutDuration += o.CheckoutDuration
+	s.OverdueCount += o.OverdueCount
+	s.StarvedCount += o.StarvedCount
+	if o.MaxWaiterAge > s.MaxWaiterAge {
+		s.MaxWaiterAge = o.MaxWaiterAge
+	}
+	for i := range s.AcquireWaitBuckets {
+		s.AcquireWaitBuckets[i] += o.AcquireWaitBuckets[i]
+	}
+	s.Lifetime = s.Lifetime.merge(o.Lifetime)
+	s.BusyTime = s.BusyTime.merge(o.BusyTime)
+	s.WaitTime = s.WaitTime.merge(o.WaitTime)
+	s.Utilization = utilization(s.Lifetime, s.BusyTime)
+	return s
+}
@@ -0,0 +1,135 @@
+package pool
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSummarize(t *testing.T) {
+	ms := func(ns ...int) []time.Duration {
+		ds := make([]time.Duration, len(ns))
+		for i, n := range ns {
+			ds[i] = time.Duration(n) * time.Millisecond
+		}
+		return ds
+	}
+	tests := []struct {
+		name string
+		in   []time.Duration
+		want Summary
+	}{
+		{"empty", nil, Summary{}},
+		{"one", ms(5), Summary{Count: 1, Total: 5 * time.Millisecond, P50: 5 * time.Millisecond, P90: 5 * time.Millisecond, P99: 5 * time.Millisecond}},
+		{"unsorted", ms(9, 1, 5, 3, 7), Summary{Count: 5, Total: 25 * time.Millisecond, P50: 5 * time.Millisecond, P90: 7 * time.Millisecond, P99: 7 * time.Millisecond}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := summarize(tt.in); got != tt.want {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSampleRingCache(t *testing.T) {
+	var r sampleRing
+	for i := 1; i <= 3; i++ {
+		r.add(time.Duration(i))
+	}
+	s := r.snapshot()
+	if !s.resolve() || s.summary.Count != 3 {
+		t.Fatalf("first snapshot %+v", s.summary)
+	}
+	// 快照之后有新样本时不缓存旧的摘要
+	r.add(4)
+	r.cache(s)
+	if r.cached {
+		t.Fatal("cached a stale summary")
+	}
+	s = r.snapshot()
+	s.resolve()
+	r.cache(s)
+	if s2 := r.snapshot(); s2.samples != nil || s2.summary.Count != 4 {
+		t.Fatalf("second snapshot %+v, want cached summary of 4 samples", s2.summary)
+	}
+	// 超过UsageSamples时只保留最近的样本
+	for i := 0; i < UsageSamples; i++ {
+		r.add(time.Second)
+	}
+	s = r.snapshot()
+	s.resolve()
+	if s.summary.Count != UsageSamples || s.summary.P50 != time.Second {
+		t.Fatalf("full ring %+v", s.summary)
+	}
+}
+
+func TestStatsDistributions(t *testing.T) {
+	var hookStats []Stats
+	var mu sync.Mutex
+	p, err := New(func() (*int, error) { return new(int), nil },
+		WithOnRelease(func(r *int, s Stats) error {
+			mu.Lock()
+			hookStats = append(hookStats, s)
+			mu.Unlock()
+			return nil
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	for i := 0; i < 3; i++ {
+		r, _ := p.Acquire()
+		p.Release(r)
+		// 取出放回的资源并销毁它，记录一个存活时间的样本
+		r, _ = p.Acquire()
+		p.Discard(r)
+	}
+	s := p.Stats()
+	if s.Lifetime.Count != 3 || s.BusyTime.Count != 3 {
+		t.Fatalf("Lifetime %+v BusyTime %+v, want 3 samples", s.Lifetime, s.BusyTime)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	last := hookStats[len(hookStats)-1]
+	if last.AcquireCount != 5 || last.Lifetime.Count != 0 {
+		t.Fatalf("hook got AcquireCount %d Lifetime %+v, want 5 and no distribution", last.AcquireCount, last.Lifetime)
+	}
+}
+
+// TestStatsConcurrent 在不断产生样本的同时调用Stats，应该开启竞争检测运行
+func TestStatsConcurrent(t *testing.T) {
+	p, err := New(func() (*int, error) { return new(int), nil }, WithMaxIdle(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				r, _ := p.Acquire()
+				if j%2 == 0 {
+					p.Discard(r)
+				} else {
+					p.Release(r)
+				}
+				p.Stats()
+			}
+		}()
+	}
+	wg.Wait()
+	// Acquire用了别人放回的资源时，它创建的资源在后台放回
+	for deadline := time.Now().Add(5 * time.Second); p.Stats().InUse != 0; {
+		if time.Now().After(deadline) {
+			t.Fatalf("InUse = %d after all releases, want 0", p.Stats().InUse)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// 销毁的资源和剩下的空闲资源各有一个样本
+	if s := p.Stats(); s.Lifetime.Count != s.TotalClosed+uint64(s.Idle) || s.TotalClosed < 400 {
+		t.Fatalf("Lifetime.Count = %d, TotalClosed = %d, Idle = %d", s.Lifetime.Count, s.TotalClosed, s.Idle)
+	}
+}
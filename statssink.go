@@ -0,0 +1,44 @@
+package pool
+
+import "time"
+
+// DefaultStatsInterval 是WithStatsSink的interval不是正数时推送统计信息的间隔
+const DefaultStatsInterval = 10 * time.Second
+
+// StatsSink 接收WithStatsSink定时推送的统计信息，用于把指标推送到StatsD等不拉取指标的系统，
+// 见poolstatsd包；name和labels是WithName和WithLabels设置的池的名字和标签，不应修改
+type StatsSink interface {
+	PushStats(name string, labels map[string]string, s Stats) error
+}
+
+// WithStatsSink 设置每隔interval把池的统计信息推送给sink，池关闭时再推送一次，
+// interval不是正数时使用DefaultStatsInterval，推送失败时交给WithErrorHandler设置的函数
+func WithStatsSink(sink StatsSink, interval time.Duration) Option {
+	return func(s *settings) {
+		s.statsSink = sink
+		s.statsInt = interval
+	}
+}
+
+// pushStats 每隔interval把统计信息推送给sink，直到池被关闭
+func (p *Pool[T]) pushStats(sink StatsSink, interval time.Duration) {
+	ticker := p.clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			p.pushStatsOnce(sink)
+		case <-p.done:
+			p.pushStatsOnce(sink)
+			return
+		}
+	}
+}
+
+// pushStatsOnce 把当前的统计信息推送给sink一次
+func (p *Pool[T]) pushStatsOnce(sink StatsSink) {
+	if err := sink.PushStats(p.name, p.labels, p.Stats()); err != nil {
+		p.logger.Println("Stats:", "Push Failed:", err)
+		p.reportError(err, SourceStats)
+	}
+}
@@ -0,0 +1,174 @@
+package pool
+
+import "fmt"
+
+// IdleStore 选择保存空闲资源的数据结构，不影响获取和放回的语义，只影响各个操作的开销
+type IdleStore int
+
+const (
+	// SliceStore 用切片保存空闲资源，LIFO取出最近放回的资源只需O(1)，FIFO取出最早放回的资源需要移动其余的资源
+	SliceStore IdleStore = iota
+	// RingStore 用环形缓冲区保存空闲资源，从两端取出都只需O(1)，适合空闲资源很多并使用FIFO的池
+	RingStore
+	// LockFreeStore 在SliceStore之外用一个无锁的环形缓冲区暂存放回的资源，最多MaxIdle个(不超过1024个)，
+	// Release不加锁地把资源放进去，Acquire不加锁地按FIFO取出，减少高并发时对池的锁的争用；
+	// 有Acquire在排队、池暂停或开始关闭时改为加锁的路径，暂存的资源被移回空闲资源。
+	// 不加锁的借出不更新最近获取的时间，不计入CheckoutTime和BusyTime，暂存期间不计算空闲时间，
+	// 后台回收时才移回空闲资源；Stats把暂存的资源计为空闲，Snapshot和Checkouts把它们计为使用中。
+	// 设置了在获取或放回时执行检查或钩子的选项(验证函数、OnAcquire、OnRelease、重置函数、
+	// MaxUses、MaxCheckoutDuration、泄漏检测、共享、权重、事件、日志、Tracer、非默认的OverflowPolicy等)时与SliceStore相同
+	LockFreeStore
+)
+
+// String 返回存储方式的名字
+func (s IdleStore) String() string {
+	switch s {
+	case SliceStore:
+		return "slice"
+	case RingStore:
+		return "ring"
+	case LockFreeStore:
+		return "lockfree"
+	}
+	return fmt.Sprintf("IdleStore(%d)", int(s))
+}
+
+// store 保存空闲资源，按放回的时间从早到晚排列，下标0是最早放回的
+// 所有方法都在持有p.m时调用，新的实现只需要实现这个接口，acquire和release的逻辑不需要修改
+type store[T any] interface {
+	// Len 返回空闲资源数
+	Len() int
+	// At 返回第i个空闲资源
+	At(i int) *entry[T]
+	// Put 把e放到最后，作为最近放回的资源
+	Put(e *entry[T])
+	// Get 移除并返回第i个空闲资源
+	Get(i int) *entry[T]
+	// Drain 移除并按顺序返回所有空闲资源
+	Drain() []*entry[T]
+	// Retain 按顺序对每个空闲资源调用keep，只保留返回true的，保持它们的顺序
+	Retain(keep func(e *entry[T]) bool)
+}
+
+// newStore 返回kind对应的store，maxIdle是LockFreeStore最多暂存的资源数
+func newStore[T any](kind IdleStore, maxIdle uint) store[T] {
+	switch kind {
+	case RingStore:
+		return &ringStore[T]{}
+	case LockFreeStore:
+		return newLockFreeStore[T](maxIdle)
+	}
+	return &sliceStore[T]{}
+}
+
+// sliceStore 用切片实现store
+type sliceStore[T any] struct {
+	es []*entry[T]
+}
+
+func (s *sliceStore[T]) Len() int { return len(s.es) }
+
+func (s *sliceStore[T]) At(i int) *entry[T] { return s.es[i] }
+
+func (s *sliceStore[T]) Put(e *entry[T]) { s.es = append(s.es, e) }
+
+func (s *sliceStore[T]) Get(i int) *entry[T] {
+	e := s.es[i]
+	copy(s.es[i:], s.es[i+1:])
+	s.es[len(s.es)-1] = nil
+	s.es = s.es[:len(s.es)-1]
+	return e
+}
+
+func (s *sliceStore[T]) Drain() []*entry[T] {
+	es := s.es
+	s.es = nil
+	return es
+}
+
+func (s *sliceStore[T]) Retain(keep func(e *entry[T]) bool) {
+	kept := s.es[:0]
+	for _, e := range s.es {
+		if keep(e) {
+			kept = append(kept, e)
+		}
+	}
+	for i := len(kept); i < len(s.es); i++ {
+		s.es[i] = nil
+	}
+	s.es = kept
+}
+
+// ringStore 用环形缓冲区实现store，容量是2的幂，满时翻倍
+type ringStore[T any] struct {
+	buf  []*entry[T]
+	head int // 第0个空闲资源在buf中的位置
+	n    int
+}
+
+func (s *ringStore[T]) Len() int { return s.n }
+
+func (s *ringStore[T]) At(i int) *entry[T] { return s.buf[s.pos(i)] }
+
+// pos 返回第i个空闲资源在buf中的位置
+func (s *ringStore[T]) pos(i int) int { return (s.head + i) & (len(s.buf) - 1) }
+
+func (s *ringStore[T]) Put(e *entry[T]) {
+	if s.n == len(s.buf) {
+		size := len(s.buf) * 2
+		if size == 0 {
+			size = 8
+		}
+		buf := make([]*entry[T], size)
+		for i := 0; i < s.n; i++ {
+			buf[i] = s.At(i)
+		}
+		s.buf, s.head = buf, 0
+	}
+	s.buf[s.pos(s.n)] = e
+	s.n++
+}
+
+func (s *ringStore[T]) Get(i int) *entry[T] {
+	e := s.At(i)
+	// 移动离两端较近的一侧
+	if i < s.n/2 {
+		for j := i; j > 0; j-- {
+			s.buf[s.pos(j)] = s.buf[s.pos(j-1)]
+		}
+		s.buf[s.head] = nil
+		s.head = s.pos(1)
+	} else {
+		for j := i; j < s.n-1; j++ {
+			s.buf[s.pos(j)] = s.buf[s.pos(j+1)]
+		}
+		s.buf[s.pos(s.n-1)] = nil
+	}
+	s.n--
+	return e
+}
+
+func (s *ringStore[T]) Drain() []*entry[T] {
+	es := make([]*entry[T], s.n)
+	for i := range es {
+		es[i] = s.At(i)
+		s.buf[s.pos(i)] = nil
+	}
+	s.head, s.n = 0, 0
+	return es
+}
+
+func (s *ringStore[T]) Retain(keep func(e *entry[T]) bool) {
+	kept := 0
+	for i := 0; i < s.n; i++ {
+		e := s.At(i)
+		if keep(e) {
+			s.buf[s.pos(kept)] = e
+			kept++
+		}
+	}
+	for i := kept; i < s.n; i++ {
+		s.buf[s.pos(i)] = nil
+	}
+	s.n = kept
+}
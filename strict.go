@@ -0,0 +1,62 @@
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+)
+
+// ErrZeroResource 表示放回或销毁的是资源类型的零值，例如nil指针
+var ErrZeroResource = errors.New("Resource is the zero value")
+
+// MisuseError 是严格模式下发现错误用法时panic的值，Stack是发现错误时的调用栈，
+// Acquired是资源被获取时的调用栈，只在设置了LeakTimeout时记录
+type MisuseError struct {
+	Err      error
+	Stack    []byte
+	Acquired []byte
+}
+
+// Error 返回错误用法的描述和调用栈
+func (e *MisuseError) Error() string {
+	msg := fmt.Sprintf("pool: misuse: %v\n%s", e.Err, e.Stack)
+	if e.Acquired != nil {
+		msg += "\nacquired at\n" + string(e.Acquired)
+	}
+	return msg
+}
+
+// Unwrap 返回具体的错误，例如ErrDoubleRelease
+func (e *MisuseError) Unwrap() error {
+	return e.Err
+}
+
+// misuse 在严格模式下对错误的用法panic，否则原样返回err，
+// 只有ErrDoubleRelease、ErrForeignResource、ErrZeroResource被视为错误的用法
+func (p *Pool[T]) misuse(err error) error {
+	if p.strict && (errors.Is(err, ErrDoubleRelease) || errors.Is(err, ErrForeignResource) || errors.Is(err, ErrZeroResource)) {
+		panic(&MisuseError{Err: err, Stack: debug.Stack()})
+	}
+	return err
+}
+
+// checkZero 在严格模式下对放回零值的资源panic
+func (p *Pool[T]) checkZero(r T) {
+	if p.strict && reflect.ValueOf(&r).Elem().IsZero() {
+		p.misuse(p.wrapErr(ErrZeroResource))
+	}
+}
+
+// checkClosedAcquire 在严格模式下对关闭之后开始的Acquire panic，
+// 关闭时正在进行的Acquire仍然返回ErrPoolClosed
+func (p *Pool[T]) checkClosedAcquire() {
+	if !p.strict {
+		return
+	}
+	select {
+	case <-p.done:
+		panic(&MisuseError{Err: p.wrapErr(ErrPoolClosed), Stack: debug.Stack()})
+	default:
+	}
+}
@@ -0,0 +1,67 @@
+package pool
+
+import (
+	"context"
+	"runtime/debug"
+)
+
+// Swap 销毁使用中的资源old，并用它占用的容量创建一个新资源返回给调用者，
+// 用于操作中途发现资源已经损坏、需要换一个资源重试的情况，不会因为其它goroutine在排队而等待
+// 创建失败时old的容量被释放并返回错误；old被共享时只放弃调用者对它的使用，新资源与AcquireContext相同地获取
+// old已经放回过时返回ErrDoubleRelease，不是从本池获取的资源返回ErrForeignResource
+func (p *Pool[T]) Swap(ctx context.Context, old T) (T, error) {
+	var zero T
+	p.checkZero(old)
+	var stack []byte
+	if p.leakTimeout > 0 {
+		stack = debug.Stack()
+	}
+	p.m.Lock()
+	if err := p.checkOwned(old); err != nil {
+		p.m.Unlock()
+		p.logger.Println("Swap", err)
+		return zero, p.misuse(p.wrapErr(err))
+	}
+	e, ok := p.inUse.Get(old)
+	if !ok || p.closed {
+		// 资源已经在CloseContext超时时被强制关闭
+		p.m.Unlock()
+		return zero, ErrPoolClosed
+	}
+	if e.refs > 1 {
+		e.refs--
+		e.broken = true
+		p.m.Unlock()
+		p.logger.Println("Swap", "Shared Resource")
+		return p.AcquireContext(ctx)
+	}
+	overdue := p.checkin(old)
+	p.untrack(e)
+	// 与retire相同，但不释放old占用的容量，新资源直接使用它
+	p.usage.add(p.clock.Now().Sub(e.createdAt), e.busy)
+	p.totalWeight -= e.weight
+	p.pendingClose = append(p.pendingClose, old)
+	p.unlock()
+	p.logger.Println("Swap", "Closing")
+	if overdue != nil {
+		overdue.Discarded = true
+		p.reportOverdue(overdue)
+	}
+
+	r, err := p.createInUse(ctx, stack)
+	if err != nil {
+		return zero, p.wrapErr(ctxError(ctx, err))
+	}
+	if ok, _ := p.runAcquireHook(ctx, r, nil); !ok {
+		// 钩子拒绝了新资源，它占用的容量已经释放
+		return p.AcquireContext(ctx)
+	}
+	if p.abandon(r) {
+		return zero, ErrPoolClosed
+	}
+	if p.profile != nil {
+		p.profileCheckout(r, 1)
+	}
+	p.stats.miss()
+	return r, nil
+}
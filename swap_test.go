@@ -0,0 +1,102 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lazysheep666/pool"
+)
+
+func TestSwap(t *testing.T) {
+	ctx := context.Background()
+	// swap 用Swap替换old，失败时结束测试
+	swap := func(t *testing.T, p *pool.Pool[*tracked], old *tracked) *tracked {
+		t.Helper()
+		r, err := p.Swap(ctx, old)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	tests := []struct {
+		name string
+		opts []pool.Option
+		run  func(t *testing.T, p *pool.Pool[*tracked], f *flaky)
+		// 最后创建和关闭的资源数
+		wantCreated, wantClosed int64
+	}{
+		{"replaces resource", []pool.Option{pool.WithMaxTotal(1)}, func(t *testing.T, p *pool.Pool[*tracked], f *flaky) {
+			old := acquire(t, p)
+			r := swap(t, p, old)
+			if r == old || !old.closed.Load() {
+				t.Errorf("got resource %d, want a new resource with the old one closed", r.id)
+			}
+			if s := p.Stats(); s.InUse != 1 || s.Idle != 0 {
+				t.Errorf("InUse = %d, Idle = %d, want 1, 0", s.InUse, s.Idle)
+			}
+			release(t, p, r)
+		}, 2, 1},
+		{"does not queue behind waiters", []pool.Option{pool.WithMaxTotal(1)}, func(t *testing.T, p *pool.Pool[*tracked], f *flaky) {
+			old := acquire(t, p)
+			c := startWaiter(t, p, ctx)
+			r := swap(t, p, old)
+			if p.Waiting() != 1 {
+				t.Errorf("Waiting = %d, want the waiter still queued", p.Waiting())
+			}
+			release(t, p, r)
+			res := result(t, c)
+			if res.err != nil || res.r != r {
+				t.Fatalf("waiter got %v, %v, want the swapped in resource", res.r, res.err)
+			}
+			release(t, p, res.r)
+		}, 2, 1},
+		{"create failure frees slot", []pool.Option{pool.WithMaxTotal(1)}, func(t *testing.T, p *pool.Pool[*tracked], f *flaky) {
+			old := acquire(t, p)
+			f.fail.Store(true)
+			if _, err := p.Swap(ctx, old); !errors.Is(err, errFlaky) {
+				t.Fatalf("Swap = %v, want %v", err, errFlaky)
+			}
+			f.fail.Store(false)
+			// old的容量已经释放，可以创建新资源
+			release(t, p, acquire(t, p))
+		}, 2, 1},
+		{"shared resource kept for other borrowers", []pool.Option{pool.WithSharing(2)}, func(t *testing.T, p *pool.Pool[*tracked], f *flaky) {
+			old := acquire(t, p)
+			if acquire(t, p) != old {
+				t.Fatal("second Acquire did not share the resource")
+			}
+			r := swap(t, p, old)
+			if r == old || old.closed.Load() {
+				t.Fatalf("got resource %d, want a new resource with the shared one still open", r.id)
+			}
+			release(t, p, r)
+			// 最后一个借用者放回时销毁被放弃的资源
+			release(t, p, old)
+		}, 2, 1},
+		{"returned resource", nil, func(t *testing.T, p *pool.Pool[*tracked], f *flaky) {
+			old := acquire(t, p)
+			release(t, p, old)
+			if _, err := p.Swap(ctx, old); !errors.Is(err, pool.ErrDoubleRelease) {
+				t.Errorf("Swap of an idle resource = %v, want ErrDoubleRelease", err)
+			}
+		}, 1, 0},
+		{"foreign resource", nil, func(t *testing.T, p *pool.Pool[*tracked], f *flaky) {
+			if _, err := p.Swap(ctx, &tracked{}); !errors.Is(err, pool.ErrForeignResource) {
+				t.Errorf("Swap of a foreign resource = %v, want ErrForeignResource", err)
+			}
+		}, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, f := newFlakyPool(t, tt.opts...)
+			tt.run(t, p, f)
+			if f.created.Load() != tt.wantCreated || f.closed.Load() != tt.wantClosed {
+				t.Errorf("created %d, closed %d, want %d, %d", f.created.Load(), f.closed.Load(), tt.wantCreated, tt.wantClosed)
+			}
+			if s := p.Stats(); s.InUse != 0 {
+				t.Errorf("InUse = %d, want 0", s.InUse)
+			}
+		})
+	}
+}
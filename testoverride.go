@@ -0,0 +1,86 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrTestOverrideDisabled 表示池创建时没有设置WithTestOverrides，SetTestOverride不起作用
+var ErrTestOverrideDisabled = errors.New("Test overrides are disabled")
+
+// TestOverride 是集成测试中让池模拟异常状态的设置，零值表示不模拟
+type TestOverride struct {
+	// ForceExhausted 为true时池表现得像已经达到上限：不再使用空闲资源或创建新资源，
+	// 阻塞的Acquire一直等待到ctx结束或这个设置被取消，不阻塞的Acquire返回ErrPoolExhausted
+	ForceExhausted bool
+	// InjectedAcquireErr 不为nil时Acquire直接返回这个错误，优先于ForceExhausted
+	InjectedAcquireErr error
+}
+
+// testOverride 是生效中的TestOverride，changed在它被替换时关闭，唤醒因ForceExhausted等待的Acquire
+type testOverride struct {
+	TestOverride
+	changed chan struct{}
+}
+
+// SetTestOverride 让池按o模拟被占满或获取失败，传入零值恢复正常，用于集成测试验证服务在池饱和时的降级
+// 池创建时没有设置WithTestOverrides时不做任何事并返回ErrTestOverrideDisabled
+func (p *Pool[T]) SetTestOverride(o TestOverride) error {
+	if !p.overridable {
+		return ErrTestOverrideDisabled
+	}
+	var next *testOverride
+	if o.ForceExhausted || o.InjectedAcquireErr != nil {
+		next = &testOverride{TestOverride: o, changed: make(chan struct{})}
+	}
+	if prev := p.override.Swap(next); prev != nil {
+		close(prev.changed)
+	}
+	p.logger.Println("SetTestOverride", o)
+	return nil
+}
+
+// checkOverride 按生效中的TestOverride决定这次获取是否失败，ForceExhausted时等待到设置被取消，
+// 开始等待时设置waitStart，try为true时不等待，没有设置TestOverride时立即返回nil
+func (p *Pool[T]) checkOverride(ctx context.Context, try bool, waitStart *time.Time) error {
+	for {
+		o := p.override.Load()
+		if o == nil {
+			return nil
+		}
+		if o.InjectedAcquireErr != nil {
+			return o.InjectedAcquireErr
+		}
+		if !o.ForceExhausted {
+			return nil
+		}
+		p.m.Lock()
+		try = try || p.nonBlocking
+		closed := p.closed
+		p.m.Unlock()
+		if closed {
+			return ErrPoolClosed
+		}
+		if try {
+			return ErrPoolExhausted
+		}
+		if waitStart.IsZero() {
+			*waitStart = p.clock.Now()
+		}
+		p.overrideWait.Add(1)
+		select {
+		case <-o.changed:
+		case <-p.done:
+		case <-ctx.Done():
+			p.overrideWait.Add(-1)
+			select {
+			case <-p.done:
+				return ErrPoolClosed
+			default:
+				return ctx.Err()
+			}
+		}
+		p.overrideWait.Add(-1)
+	}
+}
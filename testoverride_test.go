@@ -0,0 +1,132 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// sliceError 是动态类型不可比较的错误
+type sliceError struct{ causes []string }
+
+func (e sliceError) Error() string { return "slice error" }
+
+func TestSetTestOverride(t *testing.T) {
+	boom := errors.New("boom")
+	tests := []struct {
+		name     string
+		override TestOverride
+		try      bool
+		want     error
+	}{
+		{"injected error", TestOverride{InjectedAcquireErr: boom}, false, boom},
+		{"uncomparable error", TestOverride{InjectedAcquireErr: sliceError{causes: []string{"a"}}}, false, sliceError{}},
+		{"error wins over exhausted", TestOverride{ForceExhausted: true, InjectedAcquireErr: boom}, true, boom},
+		{"exhausted try", TestOverride{ForceExhausted: true}, true, ErrPoolExhausted},
+		{"exhausted blocks until timeout", TestOverride{ForceExhausted: true}, false, ErrAcquireTimeout},
+		{"zero value", TestOverride{}, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(func() (int, error) { return 1, nil }, WithTestOverrides())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer p.Close()
+			if err := p.SetTestOverride(tt.override); err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			var r int
+			if tt.try {
+				r, err = p.TryAcquire()
+			} else {
+				r, err = p.AcquireContext(ctx)
+			}
+			switch want := tt.want.(type) {
+			case nil:
+				if err != nil {
+					t.Fatalf("got %v, want nil", err)
+				}
+				p.Release(r)
+			case sliceError:
+				if !errors.As(err, &want) {
+					t.Fatalf("got %v, want sliceError", err)
+				}
+			default:
+				if !errors.Is(err, tt.want) {
+					t.Fatalf("got %v, want %v", err, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSetTestOverrideDisabled(t *testing.T) {
+	p, err := New(func() (int, error) { return 1, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if err := p.SetTestOverride(TestOverride{ForceExhausted: true}); !errors.Is(err, ErrTestOverrideDisabled) {
+		t.Fatalf("got %v, want ErrTestOverrideDisabled", err)
+	}
+	r, err := p.TryAcquire()
+	if err != nil {
+		t.Fatalf("override applied on a pool without WithTestOverrides: %v", err)
+	}
+	p.Release(r)
+}
+
+func TestForceExhaustedReleasedByClear(t *testing.T) {
+	p, err := New(func() (int, error) { return 1, nil }, WithTestOverrides())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	p.SetTestOverride(TestOverride{ForceExhausted: true})
+	done := make(chan error)
+	go func() {
+		r, err := p.Acquire()
+		if err == nil {
+			p.Release(r)
+		}
+		done <- err
+	}()
+	waitOverride(p)
+	p.SetTestOverride(TestOverride{})
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if n := p.Stats().AcquireWaitCount; n != 1 {
+		t.Fatalf("AcquireWaitCount = %d, want 1", n)
+	}
+}
+
+func TestForceExhaustedReleasedByClose(t *testing.T) {
+	p, err := New(func() (int, error) { return 1, nil }, WithTestOverrides())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetTestOverride(TestOverride{ForceExhausted: true})
+	done := make(chan error)
+	go func() {
+		_, err := p.Acquire()
+		done <- err
+	}()
+	waitOverride(p)
+	p.Close()
+	if err := <-done; !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("got %v, want ErrPoolClosed", err)
+	}
+}
+
+// waitOverride 等待到有获取因ForceExhausted开始等待
+func waitOverride(p *Pool[int]) {
+	for p.overrideWait.Load() == 0 {
+		runtime.Gosched()
+	}
+}
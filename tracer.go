@@ -0,0 +1,45 @@
+package pool
+
+import "context"
+
+// AcquireOutcome 是一次Acquire的结果
+type AcquireOutcome int
+
+const (
+	// OutcomeHit 获取到空闲资源
+	OutcomeHit AcquireOutcome = iota
+	// OutcomeMiss 获取到新创建的资源
+	OutcomeMiss
+	// OutcomeTimeout 等待或创建资源超时
+	OutcomeTimeout
+	// OutcomeError 因其它错误失败，例如池已关闭或factory返回错误
+	OutcomeError
+)
+
+// String 返回结果的名字
+func (o AcquireOutcome) String() string {
+	switch o {
+	case OutcomeHit:
+		return "hit"
+	case OutcomeMiss:
+		return "miss"
+	case OutcomeTimeout:
+		return "timeout"
+	default:
+		return "error"
+	}
+}
+
+// Tracer 观察资源池的获取、创建和放回操作，用于接入追踪和指标系统
+// 每对Start和End在调用者的goroutine中同步执行，Start返回的ctx会传给对应的End，
+// 获取资源时创建资源所用的ctx来自TraceAcquireStart
+type Tracer interface {
+	TraceAcquireStart(ctx context.Context) context.Context
+	TraceAcquireEnd(ctx context.Context, outcome AcquireOutcome, err error)
+	TraceCreateStart(ctx context.Context) context.Context
+	TraceCreateEnd(ctx context.Context, err error)
+	// Release没有ctx，TraceReleaseStart收到的是context.Background()
+	TraceReleaseStart(ctx context.Context) context.Context
+	// pooled表示资源是否被放回了空闲资源中
+	TraceReleaseEnd(ctx context.Context, pooled bool)
+}
@@ -0,0 +1,108 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+)
+
+// TransferTo 把p中的空闲资源移到dst中，不关闭它们，用于重新加载配置时用新的池接管旧池中的连接，避免冷启动
+//
+//	next, _ := pool.New(factory, newOpts...)
+//	old.TransferTo(next)
+//	old.Close()
+//
+// 移动的资源数受dst的MaxIdle、MaxTotal和MaxTotalWeight限制，设置了验证函数时只移动通过dst的验证函数的资源，
+// 资源保留原来的创建时间，之后按dst的MaxLifetime等设置管理；没有移动的空闲资源留在p中
+// 返回移动的资源数，p或dst已关闭时返回ErrPoolClosed
+func (p *Pool[T]) TransferTo(dst *Pool[T]) (moved int, err error) {
+	if dst == nil || dst == p {
+		return 0, fmt.Errorf("%w: TransferTo needs a different destination pool", ErrInvalidConfig)
+	}
+	// 先在dst中占用容量，避免同时持有两个池的锁
+	dst.m.Lock()
+	if dst.closed || dst.shutdown != nil {
+		dst.m.Unlock()
+		return 0, ErrPoolClosed
+	}
+	room := remaining(dst.maxIdle, uint(dst.idle.Len())+dst.creatingIdle)
+	if dst.maxTotal > 0 {
+		if r := remaining(dst.maxTotal, dst.numOpen); r < room {
+			room = r
+		}
+	}
+	if dst.weightFull() {
+		room = 0
+	}
+	dst.numOpen += room
+	dst.creatingIdle += room
+	dst.m.Unlock()
+
+	p.m.Lock()
+	var taken []*entry[T]
+	if p.closed {
+		err = ErrPoolClosed
+	} else {
+		// 先移动最近放回的资源，它们最可能仍然可用
+		for uint(len(taken)) < room && p.idle.Len() > 0 {
+			taken = append(taken, p.idle.Get(p.idle.Len()-1))
+			p.totalWeight -= taken[len(taken)-1].weight
+			p.releaseSlot()
+		}
+	}
+	p.m.Unlock()
+	for _, e := range taken {
+		if p.failover != nil {
+			p.failover.forget(e.r)
+		}
+		if p.balancer != nil {
+			p.balancer.closed(e.r)
+		}
+	}
+
+	// 在锁外计算权重、分数和验证
+	ctx := context.Background()
+	weights := make([]uint, len(taken))
+	scores := make([]float64, len(taken))
+	scored := make([]bool, len(taken))
+	valid := make([]bool, len(taken))
+	for i, e := range taken {
+		weights[i] = dst.weightOf(e.r)
+		scores[i], scored[i] = dst.scoreOf(e.r)
+		valid[i] = dst.validator == nil || dst.validator(ctx, e.r)
+	}
+
+	dst.m.Lock()
+	defer dst.unlock()
+	dst.creatingIdle -= room
+	// 没有用到的容量还给dst
+	dst.numOpen -= room - uint(len(taken))
+	now := dst.clock.Now()
+	gen := dst.generation.Load()
+	for i, e := range taken {
+		w := weights[i]
+		if !valid[i] || dst.closed || dst.shutdown != nil || uint(dst.idle.Len()) >= dst.maxIdle ||
+			dst.maxWeight > 0 && dst.totalWeight+w > dst.maxWeight {
+			dst.destroy(e.r)
+			continue
+		}
+		dst.totalWeight += w
+		ne := &entry[T]{r: e.r, createdAt: e.createdAt, returnedAt: now, uses: e.uses, gen: gen, weight: w, tags: e.tags, jitter: dst.newJitter()}
+		dst.setScore(ne, scores[i], scored[i])
+		dst.idle.Put(ne)
+		moved++
+	}
+	dst.fitIdleBytes()
+	dst.broadcast()
+	if moved > 0 {
+		dst.logger.Println("TransferTo:", moved, "Resources")
+	}
+	return moved, err
+}
+
+// remaining 返回used距离上限limit还剩下的数量，used超过limit时返回0
+func remaining(limit, used uint) uint {
+	if used >= limit {
+		return 0
+	}
+	return limit - used
+}
@@ -0,0 +1,68 @@
+package pool_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lazysheep666/pool"
+)
+
+// TestUtilization 检查Lifetime、BusyTime和Utilization同时计入销毁的和仍然存活的资源
+func TestUtilization(t *testing.T) {
+	tests := []struct {
+		name string
+		// run 借出、放回和销毁资源并推进时钟，返回仍然借出、检查统计之后放回的资源
+		run             func(t *testing.T, p *pool.Pool[*tracked], h *harness) []*tracked
+		wantCount       uint64
+		wantLife        time.Duration
+		wantBusy        time.Duration
+		wantUtilization float64
+	}{
+		{"no resources", func(t *testing.T, p *pool.Pool[*tracked], h *harness) []*tracked {
+			return nil
+		}, 0, 0, 0, 0},
+		{"idle resource", func(t *testing.T, p *pool.Pool[*tracked], h *harness) []*tracked {
+			r := acquire(t, p)
+			h.clock.Advance(30 * time.Second)
+			release(t, p, r)
+			h.clock.Advance(30 * time.Second)
+			return nil
+		}, 1, time.Minute, 30 * time.Second, 50},
+		{"checked out resource", func(t *testing.T, p *pool.Pool[*tracked], h *harness) []*tracked {
+			r := acquire(t, p)
+			h.clock.Advance(10 * time.Second)
+			return []*tracked{r}
+		}, 1, 10 * time.Second, 10 * time.Second, 100},
+		{"destroyed and live resources", func(t *testing.T, p *pool.Pool[*tracked], h *harness) []*tracked {
+			a, b := acquire(t, p), acquire(t, p)
+			h.clock.Advance(10 * time.Second)
+			if err := p.Discard(a); err != nil {
+				t.Fatal(err)
+			}
+			release(t, p, b)
+			h.clock.Advance(10 * time.Second)
+			c := acquire(t, p)
+			h.clock.Advance(10 * time.Second)
+			return []*tracked{c}
+		}, 2, 40 * time.Second, 30 * time.Second, 75},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, h := newHarnessPool(t)
+			held := tt.run(t, p, h)
+			s := p.Stats()
+			for _, r := range held {
+				release(t, p, r)
+			}
+			if s.Lifetime.Count != tt.wantCount || s.BusyTime.Count != tt.wantCount {
+				t.Errorf("Lifetime.Count = %d, BusyTime.Count = %d, want %d", s.Lifetime.Count, s.BusyTime.Count, tt.wantCount)
+			}
+			if s.Lifetime.Total != tt.wantLife || s.BusyTime.Total != tt.wantBusy {
+				t.Errorf("Lifetime.Total = %v, BusyTime.Total = %v, want %v, %v", s.Lifetime.Total, s.BusyTime.Total, tt.wantLife, tt.wantBusy)
+			}
+			if s.Utilization != tt.wantUtilization {
+				t.Errorf("Utilization = %v, want %v", s.Utilization, tt.wantUtilization)
+			}
+		})
+	}
+}
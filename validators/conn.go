@@ -0,0 +1,82 @@
+package validators
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+// probeTimeout 是无法直接检查socket的连接上探测读等待的时间
+const probeTimeout = time.Millisecond
+
+// DefaultPingTimeout 是ctx没有截止时间时ProtocolPing等待回复的最长时间
+const DefaultPingTimeout = time.Second
+
+// ConnAlive 不发送任何数据地检查一个空闲连接是否仍然可用
+// 对端已经关闭连接、连接出错或连接上有未读的数据时返回false
+// 可以直接检查socket时不会阻塞，否则用一次很短的读探测
+func ConnAlive(c net.Conn) bool {
+	if alive, ok := peek(c); ok {
+		return alive
+	}
+	if err := c.SetReadDeadline(time.Now().Add(probeTimeout)); err != nil {
+		return false
+	}
+	var b [1]byte
+	_, err := c.Read(b[:])
+	c.SetReadDeadline(time.Time{})
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// TLSFresh 返回检查TLS连接的验证函数，握手没有完成，或对端证书在margin之内就会过期时验证失败，
+// 用来在证书轮换前换掉长期保持的连接，不是TLS的连接总是通过
+func TLSFresh(margin time.Duration) func(net.Conn) bool {
+	return func(c net.Conn) bool {
+		tc, ok := c.(*tls.Conn)
+		if !ok {
+			return true
+		}
+		cs := tc.ConnectionState()
+		if !cs.HandshakeComplete {
+			return false
+		}
+		deadline := time.Now().Add(margin)
+		for _, cert := range cs.PeerCertificates {
+			if !deadline.Before(cert.NotAfter) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// ProtocolPing 返回在连接上发送request并检查回复是否为reply的验证函数，用于基于文本协议的服务
+// 读写的截止时间取ctx的截止时间，没有时使用DefaultPingTimeout，检查结束后清除截止时间
+func ProtocolPing(request, reply []byte) func(context.Context, net.Conn) bool {
+	return func(ctx context.Context, c net.Conn) bool {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			deadline = time.Now().Add(DefaultPingTimeout)
+		}
+		if err := c.SetDeadline(deadline); err != nil {
+			return false
+		}
+		defer c.SetDeadline(time.Time{})
+		if _, err := c.Write(request); err != nil {
+			return false
+		}
+		got := make([]byte, len(reply))
+		if _, err := io.ReadFull(c, got); err != nil {
+			return false
+		}
+		return bytes.Equal(got, reply)
+	}
+}
+
+// RedisPing 向Redis连接发送PING并检查回复是否为PONG
+var RedisPing = ProtocolPing([]byte("*1\r\n$4\r\nPING\r\n"), []byte("+PONG\r\n"))
@@ -0,0 +1,223 @@
+package validators
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// loopback 返回一对相连的回环TCP连接，测试结束时关闭它们
+func loopback(t *testing.T) (client, server net.Conn) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, _ := ln.Accept()
+		accepted <- c
+	}()
+	client, err = net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server = <-accepted
+	if server == nil {
+		t.Fatal("accept failed")
+	}
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return client, server
+}
+
+// pipe 返回一对用net.Pipe相连、无法直接检查socket的连接，测试结束时关闭它们
+func pipe(t *testing.T) (client, server net.Conn) {
+	client, server = net.Pipe()
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return client, server
+}
+
+// settled 等待对端的操作到达c，回环连接上的FIN和数据是异步到达的
+func settled(c net.Conn) {
+	for deadline := time.Now().Add(5 * time.Second); ConnAlive(c) && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConnAlive(t *testing.T) {
+	tests := []struct {
+		name string
+		conn func(t *testing.T) (client, server net.Conn)
+		// prepare 在检查之前操作连接，返回true时等待对端的操作到达
+		prepare func(t *testing.T, client, server net.Conn) bool
+		want    bool
+	}{
+		{"live socket", loopback, func(t *testing.T, c, s net.Conn) bool { return false }, true},
+		{"socket closed by peer", loopback, func(t *testing.T, c, s net.Conn) bool {
+			s.Close()
+			return true
+		}, false},
+		{"socket with unread data", loopback, func(t *testing.T, c, s net.Conn) bool {
+			if _, err := s.Write([]byte("x")); err != nil {
+				t.Fatal(err)
+			}
+			return true
+		}, false},
+		{"socket closed locally", loopback, func(t *testing.T, c, s net.Conn) bool {
+			c.Close()
+			return false
+		}, false},
+		{"socket with expired deadline", loopback, func(t *testing.T, c, s net.Conn) bool {
+			c.SetReadDeadline(time.Now().Add(-time.Second))
+			return false
+		}, true},
+		{"live pipe probed by read", pipe, func(t *testing.T, c, s net.Conn) bool { return false }, true},
+		{"pipe closed by peer", pipe, func(t *testing.T, c, s net.Conn) bool {
+			s.Close()
+			return false
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, s := tt.conn(t)
+			if tt.prepare(t, c, s) {
+				settled(c)
+			}
+			if got := ConnAlive(c); got != tt.want {
+				t.Errorf("ConnAlive = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProtocolPing(t *testing.T) {
+	tests := []struct {
+		name string
+		// reply 是服务端收到PING后的回复，nil表示不回复
+		reply []byte
+		want  bool
+	}{
+		{"PONG", []byte("+PONG\r\n"), true},
+		{"wrong reply", []byte("-ERR\r\n\r"), false},
+		{"short reply then close", []byte("+PO"), false},
+		{"no reply", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, s := pipe(t)
+			go func() {
+				req := make([]byte, len("*1\r\n$4\r\nPING\r\n"))
+				if _, err := s.Read(req); err != nil || string(req) != "*1\r\n$4\r\nPING\r\n" {
+					s.Close()
+					return
+				}
+				if tt.reply != nil {
+					s.Write(tt.reply)
+					s.Close()
+				}
+			}()
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			if got := RedisPing(ctx, c); got != tt.want {
+				t.Errorf("RedisPing = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestProtocolPingClearsDeadline 检查ping成功后连接的截止时间被清除，之后的使用不受影响
+func TestProtocolPingClearsDeadline(t *testing.T) {
+	c, s := loopback(t)
+	go func() {
+		buf := make([]byte, 4)
+		s.Read(buf)
+		s.Write([]byte("pong"))
+		// 在ping的截止时间之后再发送数据
+		time.Sleep(30 * time.Millisecond)
+		s.Write([]byte("more"))
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if !ProtocolPing([]byte("ping"), []byte("pong"))(ctx, c) {
+		t.Fatal("ping failed")
+	}
+	buf := make([]byte, 4)
+	if _, err := c.Read(buf); err != nil {
+		t.Fatalf("Read after ping = %v, want the deadline cleared", err)
+	}
+}
+
+// selfSigned 返回一个在notAfter过期的自签名证书
+func selfSigned(t *testing.T, notAfter time.Time) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestTLSFresh(t *testing.T) {
+	cert := selfSigned(t, time.Now().Add(time.Hour))
+	tests := []struct {
+		name      string
+		margin    time.Duration
+		handshake bool
+		plain     bool // 不是TLS连接
+		want      bool
+	}{
+		{"certificate outside margin", 30 * time.Minute, true, false, true},
+		{"certificate within margin", 2 * time.Hour, true, false, false},
+		{"handshake not complete", time.Minute, false, false, false},
+		{"not a TLS connection", 2 * time.Hour, false, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, s := pipe(t)
+			if tt.plain {
+				if got := TLSFresh(tt.margin)(c); got != tt.want {
+					t.Errorf("TLSFresh = %v, want %v", got, tt.want)
+				}
+				return
+			}
+			tc := tls.Client(c, &tls.Config{InsecureSkipVerify: true})
+			if tt.handshake {
+				done := make(chan error, 1)
+				go func() { done <- tls.Server(s, &tls.Config{Certificates: []tls.Certificate{cert}}).Handshake() }()
+				if err := tc.Handshake(); err != nil {
+					t.Fatal(err)
+				}
+				if err := <-done; err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := TLSFresh(tt.margin)(tc); got != tt.want {
+				t.Errorf("TLSFresh = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
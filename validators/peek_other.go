@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package validators
+
+import "net"
+
+// peek 在这个平台上无法直接检查socket，总是返回ok为false
+func peek(net.Conn) (alive, ok bool) {
+	return false, false
+}
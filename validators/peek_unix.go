@@ -0,0 +1,35 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package validators
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// peek 不阻塞地查看socket上是否有数据或EOF，ok为false表示无法直接检查这个连接
+func peek(c net.Conn) (alive, ok bool) {
+	sc, isSys := c.(syscall.Conn)
+	if !isSys {
+		return false, false
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return false, false
+	}
+	var b [1]byte
+	var perr error
+	// Control不受上一个使用者设置的读截止时间影响
+	err = rc.Control(func(fd uintptr) {
+		_, _, perr = syscall.Recvfrom(int(fd), b[:], syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+	})
+	if err != nil {
+		return false, true
+	}
+	if errors.Is(perr, syscall.EAGAIN) || errors.Is(perr, syscall.EWOULDBLOCK) {
+		return true, true
+	}
+	// 出错、EOF或有未读的数据
+	return false, true
+}
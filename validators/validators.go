@@ -0,0 +1,81 @@
+// Package validators 提供常用的资源验证函数和组合它们的方法，
+// 返回的函数可以直接传给pool.WithValidatorContext
+//
+//	p, err := pool.New(factory, pool.WithValidatorContext(validators.All(
+//		validators.NoContext(validators.ConnAlive),
+//		validators.WithTimeout(time.Second, validators.RedisPing),
+//	)))
+package validators
+
+import (
+	"context"
+	"time"
+)
+
+// Pinger 是可以用Ping检查自己是否可用的资源
+type Pinger interface {
+	Ping() error
+}
+
+// ContextPinger 是可以用带ctx的Ping检查自己是否可用的资源，例如*sql.DB和*redis.Client
+type ContextPinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping 用资源自己的Ping方法检查它，资源实现了ContextPinger时传入ctx，
+// 只实现了Pinger时不传ctx，都没有实现时总是返回true
+func Ping[T any](ctx context.Context, r T) bool {
+	switch p := any(r).(type) {
+	case ContextPinger:
+		return p.Ping(ctx) == nil
+	case Pinger:
+		return p.Ping() == nil
+	}
+	return true
+}
+
+// NoContext 把不接收ctx的验证函数转换为接收ctx的形式，以便与其它验证函数组合
+func NoContext[T any](v func(T) bool) func(context.Context, T) bool {
+	return func(_ context.Context, r T) bool { return v(r) }
+}
+
+// All 返回依次执行vs的验证函数，所有验证函数都通过时才通过，遇到第一个失败的验证函数时停止
+func All[T any](vs ...func(context.Context, T) bool) func(context.Context, T) bool {
+	return func(ctx context.Context, r T) bool {
+		for _, v := range vs {
+			if !v(ctx, r) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Any 返回依次执行vs的验证函数，有一个验证函数通过时就通过，vs为空时总是失败
+func Any[T any](vs ...func(context.Context, T) bool) func(context.Context, T) bool {
+	return func(ctx context.Context, r T) bool {
+		for _, v := range vs {
+			if v(ctx, r) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// WithTimeout 返回最多等待d的v，传给v的ctx在d之后结束，v在这之前没有返回时验证失败
+// 不理会ctx的v会在后台继续执行到返回
+func WithTimeout[T any](d time.Duration, v func(context.Context, T) bool) func(context.Context, T) bool {
+	return func(ctx context.Context, r T) bool {
+		ctx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+		ok := make(chan bool, 1)
+		go func() { ok <- v(ctx, r) }()
+		select {
+		case res := <-ok:
+			return res
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
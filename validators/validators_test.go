@@ -0,0 +1,102 @@
+package validators
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// pinger 实现了Pinger
+type pinger struct{ err error }
+
+func (p pinger) Ping() error { return p.err }
+
+// ctxPinger 实现了ContextPinger，记录收到的ctx
+type ctxPinger struct {
+	err error
+	got *context.Context
+}
+
+func (p ctxPinger) Ping(ctx context.Context) error {
+	*p.got = ctx
+	return p.err
+}
+
+func TestPing(t *testing.T) {
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "caller")
+	var got context.Context
+	down := errors.New("down")
+	tests := []struct {
+		name string
+		r    any
+		want bool
+	}{
+		{"Pinger ok", pinger{}, true},
+		{"Pinger failing", pinger{down}, false},
+		{"ContextPinger ok", ctxPinger{nil, &got}, true},
+		{"ContextPinger failing", ctxPinger{down, &got}, false},
+		{"no Ping method", 42, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			if ok := Ping(ctx, tt.r); ok != tt.want {
+				t.Errorf("Ping = %v, want %v", ok, tt.want)
+			}
+			if _, isCtx := tt.r.(ctxPinger); isCtx && (got == nil || got.Value(key{}) != "caller") {
+				t.Error("ContextPinger did not receive the caller's ctx")
+			}
+		})
+	}
+}
+
+func TestCombinators(t *testing.T) {
+	pass := func(context.Context, int) bool { return true }
+	fail := func(context.Context, int) bool { return false }
+	// calls 记录被调用的验证函数
+	var calls []string
+	track := func(name string, v func(context.Context, int) bool) func(context.Context, int) bool {
+		return func(ctx context.Context, r int) bool {
+			calls = append(calls, name)
+			return v(ctx, r)
+		}
+	}
+	slow := func(ctx context.Context, _ int) bool {
+		<-ctx.Done()
+		return true
+	}
+	tests := []struct {
+		name      string
+		v         func(context.Context, int) bool
+		want      bool
+		wantCalls []string
+	}{
+		{"All passes", All(track("a", pass), track("b", pass)), true, []string{"a", "b"}},
+		{"All stops at first failure", All(track("a", fail), track("b", pass)), false, []string{"a"}},
+		{"All empty", All[int](), true, nil},
+		{"Any stops at first success", Any(track("a", fail), track("b", pass), track("c", pass)), true, []string{"a", "b"}},
+		{"Any all failing", Any(track("a", fail), track("b", fail)), false, []string{"a", "b"}},
+		{"Any empty", Any[int](), false, nil},
+		{"NoContext", NoContext(func(r int) bool { return r == 7 }), true, nil},
+		{"WithTimeout in time", WithTimeout(time.Second, track("a", pass)), true, []string{"a"}},
+		{"WithTimeout expired", WithTimeout(time.Millisecond, slow), false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = nil
+			if ok := tt.v(context.Background(), 7); ok != tt.want {
+				t.Errorf("got %v, want %v", ok, tt.want)
+			}
+			if len(calls) != len(tt.wantCalls) {
+				t.Fatalf("called %v, want %v", calls, tt.wantCalls)
+			}
+			for i := range calls {
+				if calls[i] != tt.wantCalls[i] {
+					t.Fatalf("called %v, want %v", calls, tt.wantCalls)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lazysheep666/pool"
+	"github.com/lazysheep666/pool/pooltest"
+)
+
+// startWaiter 在新的goroutine中用ctx获取一个资源，排队后才返回，结果发送到返回的通道
+func startWaiter(t *testing.T, p *pool.Pool[*tracked], ctx context.Context) <-chan acquired {
+	t.Helper()
+	before := p.Waiting()
+	c := make(chan acquired, 1)
+	go func() {
+		r, err := p.AcquireContext(ctx)
+		c <- acquired{r, err}
+	}()
+	eventually(t, "Acquire to queue", func() bool { return p.Waiting() > before })
+	return c
+}
+
+// acquired 是startWaiter中Acquire的结果
+type acquired struct {
+	r   *tracked
+	err error
+}
+
+// result 等待startWaiter的结果，5秒后没有结果时结束测试
+func result(t *testing.T, c <-chan acquired) acquired {
+	t.Helper()
+	select {
+	case res := <-c:
+		return res
+	case <-time.After(5 * time.Second):
+		t.Fatal("Acquire did not return")
+		return acquired{}
+	}
+}
+
+func TestWaitersFIFO(t *testing.T) {
+	const waiters = 4
+	tests := []struct {
+		name   string
+		cancel []int // 放回资源之前取消的等待者
+		want   []int // 依次得到资源的等待者
+	}{
+		{"in arrival order", nil, []int{0, 1, 2, 3}},
+		{"cancelled waiter skipped", []int{1}, []int{0, 2, 3}},
+		{"cancelled head skipped", []int{0, 3}, []int{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := newHarnessPool(t, pool.WithMaxTotal(1))
+			r := acquire(t, p)
+			results := make([]<-chan acquired, waiters)
+			cancels := make([]context.CancelFunc, waiters)
+			for i := range results {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+				cancels[i] = cancel
+				results[i] = startWaiter(t, p, ctx)
+			}
+			for _, i := range tt.cancel {
+				cancels[i]()
+				if res := result(t, results[i]); !errors.Is(res.err, context.Canceled) {
+					t.Fatalf("cancelled waiter %d: err = %v, want context.Canceled", i, res.err)
+				}
+			}
+			if got, want := p.Waiting(), waiters-len(tt.cancel); got != want {
+				t.Fatalf("Waiting = %d after cancelling, want %d", got, want)
+			}
+			// 每个等待者得到资源后放回，交给下一个等待者
+			for _, i := range tt.want {
+				release(t, p, r)
+				res := result(t, results[i])
+				if res.err != nil {
+					t.Fatalf("waiter %d: %v", i, res.err)
+				}
+				r = res.r
+				if r.id != 1 {
+					t.Errorf("waiter %d got resource %d, want the released resource 1", i, r.id)
+				}
+			}
+			release(t, p, r)
+			if s := p.Stats(); s.Waiting != 0 || s.AcquireWaitCount != waiters {
+				t.Errorf("Waiting = %d, AcquireWaitCount = %d, want 0, %d", s.Waiting, s.AcquireWaitCount, waiters)
+			}
+		})
+	}
+}
+
+func TestAcquireTimeout(t *testing.T) {
+	const timeout = time.Second
+	tests := []struct {
+		name         string
+		advance      time.Duration // 排队后推进的时间
+		cancel       bool          // 推进时间后取消调用者的ctx
+		release      bool          // 推进时间后放回资源
+		wantErr      error
+		wantTimeouts uint64
+	}{
+		{"expires", timeout, false, false, pool.ErrAcquireTimeout, 1},
+		{"released in time", timeout - time.Millisecond, false, true, nil, 0},
+		{"caller cancels first", timeout / 2, true, false, context.Canceled, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, h := newHarnessPool(t, pool.WithMaxTotal(1), pool.WithAcquireTimeout(timeout))
+			held := acquire(t, p)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			c := startWaiter(t, p, ctx)
+			h.clock.BlockUntil(1)
+			h.clock.Advance(tt.advance)
+			if tt.cancel {
+				cancel()
+			}
+			if tt.release {
+				release(t, p, held)
+			}
+			res := result(t, c)
+			if !errors.Is(res.err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", res.err, tt.wantErr)
+			}
+			if errors.Is(tt.wantErr, pool.ErrAcquireTimeout) && !errors.Is(res.err, context.DeadlineExceeded) {
+				t.Errorf("err = %v, want it to also match context.DeadlineExceeded", res.err)
+			}
+			if tt.wantErr == context.Canceled && errors.Is(res.err, pool.ErrAcquireTimeout) {
+				t.Errorf("err = %v, a cancelled Acquire is not a timeout", res.err)
+			}
+			if res.err == nil {
+				release(t, p, res.r)
+			} else {
+				release(t, p, held)
+			}
+			if s := p.Stats(); s.AcquireTimeoutCount != tt.wantTimeouts || s.Waiting != 0 {
+				t.Errorf("AcquireTimeoutCount = %d, Waiting = %d, want %d, 0", s.AcquireTimeoutCount, s.Waiting, tt.wantTimeouts)
+			}
+		})
+	}
+}
+
+// TestAcquireTimeoutBoundsCreate 检查AcquireTimeout同样限制创建资源的时间
+func TestAcquireTimeoutBoundsCreate(t *testing.T) {
+	clock := pooltest.NewFakeClock(epoch)
+	started := make(chan struct{})
+	p, err := pool.NewContext(func(ctx context.Context) (*tracked, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, pool.WithClock(clock), pool.WithAcquireTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.Acquire()
+		done <- err
+	}()
+	<-started
+	clock.Advance(time.Second)
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("err = %v, want context.DeadlineExceeded", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Acquire did not return")
+	}
+}
@@ -0,0 +1,146 @@
+package pool
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// WarmHints 是池关闭时保存、下次创建时读取的容量提示，用于部署重启后不从空池开始
+type WarmHints struct {
+	PeakInUse uint      `json:"peak_in_use" yaml:"peak_in_use"` // 池存在期间同时使用的资源数的峰值
+	Demand    float64   `json:"demand" yaml:"demand"`           // 同时使用的资源数的移动平均，只在设置了CompactIdle时统计
+	SavedAt   time.Time `json:"saved_at" yaml:"saved_at"`       // 保存的时间
+}
+
+// target 返回按提示预热的空闲资源数，优先使用Demand，没有统计时使用PeakInUse
+func (h WarmHints) target() uint {
+	if h.Demand > 0 {
+		return uint(math.Ceil(h.Demand))
+	}
+	return h.PeakInUse
+}
+
+// StateStore 保存和读取WithStateStore的容量提示，name是WithName设置的池的名字
+// 没有保存过提示时LoadHints返回零值和nil
+type StateStore interface {
+	LoadHints(name string) (WarmHints, error)
+	SaveHints(name string, h WarmHints) error
+}
+
+// WithStateStore 让池在创建时从store读取上次关闭时保存的容量提示，Warmup据此把空闲资源补足到
+// MinIdle和提示中较大的一个(仍受MaxIdle和MaxTotal限制)，池关闭时保存新的提示
+// 读取或保存失败时写入日志并交给WithErrorHandler设置的函数，不影响池的创建和关闭
+func WithStateStore(store StateStore) Option {
+	return func(s *settings) { s.stateStore = store }
+}
+
+// WithStateFile 与WithStateStore相同，提示以JSON保存在path，见FileStateStore
+func WithStateFile(path string) Option {
+	return WithStateStore(NewFileStateStore(path))
+}
+
+// FileStateStore 把容量提示以JSON保存在一个文件中，多个名字不同的池可以共用一个文件
+type FileStateStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileStateStore 创建把提示保存在path的FileStateStore，文件在第一次保存时创建
+func NewFileStateStore(path string) *FileStateStore {
+	return &FileStateStore{path: path}
+}
+
+// LoadHints 实现StateStore，文件不存在时返回零值
+func (s *FileStateStore) LoadHints(name string) (WarmHints, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all, err := s.read()
+	return all[name], err
+}
+
+// SaveHints 实现StateStore，先写入临时文件再重命名，避免进程中途退出时留下不完整的文件
+func (s *FileStateStore) SaveHints(name string, h WarmHints) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all, err := s.read()
+	if err != nil {
+		return err
+	}
+	if all == nil {
+		all = make(map[string]WarmHints)
+	}
+	all[name] = h
+	data, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// read 读取文件中所有池的提示，调用者需持有s.mu
+func (s *FileStateStore) read() (map[string]WarmHints, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]WarmHints
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// WarmHints 返回池当前的容量提示，池关闭时保存的就是它
+func (p *Pool[T]) WarmHints() WarmHints {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return p.hints()
+}
+
+// hints 返回当前的容量提示，调用者需持有p.m
+func (p *Pool[T]) hints() WarmHints {
+	return WarmHints{PeakInUse: p.maxInUse, Demand: p.demand, SavedAt: p.clock.Now()}
+}
+
+// loadHints 从store读取上次保存的容量提示，设置Warmup的目标和需求的移动平均，只在New中调用
+func (p *Pool[T]) loadHints(store StateStore) {
+	h, err := store.LoadHints(p.name)
+	if err != nil {
+		p.logger.Println("State:", "Load Failed:", err)
+		p.reportError(err, SourceState)
+		return
+	}
+	p.m.Lock()
+	p.warmHint = h.target()
+	p.demand = h.Demand
+	p.m.Unlock()
+}
+
+// saveHints 把h保存到store
+func (p *Pool[T]) saveHints(store StateStore, h WarmHints) {
+	if err := store.SaveHints(p.name, h); err != nil {
+		p.logger.Println("State:", "Save Failed:", err)
+		p.reportError(err, SourceState)
+	}
+}
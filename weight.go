@@ -0,0 +1,264 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"time"
+)
+
+// ErrWeightUnavailable 表示AcquireWithMinWeight新创建的资源的权重小于要求的权重
+var ErrWeightUnavailable = errors.New("No resource with the requested weight")
+
+// Weighted 是有权重的资源，例如连接到不同规格副本的连接
+// 没有设置WithWeightFunc时，池在创建资源后调用一次Weight，没有实现Weighted的资源权重为1
+type Weighted interface {
+	Weight() uint
+}
+
+// minWeightKey 是AcquireWithMinWeight在ctx中保存要求的权重时使用的键
+type minWeightKey struct{}
+
+// MinWeight 返回AcquireWithMinWeight要求的权重，factory可以用它创建足够大的资源，
+// ctx不是来自AcquireWithMinWeight时返回0
+func MinWeight(ctx context.Context) uint {
+	w, _ := ctx.Value(minWeightKey{}).(uint)
+	return w
+}
+
+// weightOf 返回资源r的权重，计算权重的函数panic时权重为1
+func (p *Pool[T]) weightOf(r T) (w uint) {
+	var err error
+	defer func() {
+		if err != nil {
+			w = 1
+		}
+	}()
+	defer catch(p.logger, "weight func", &err)
+	if p.weight != nil {
+		return p.weight(r)
+	}
+	if wr, ok := any(r).(Weighted); ok {
+		return wr.Weight()
+	}
+	return 1
+}
+
+// weightFull 判断已创建资源的总权重是否已经达到maxWeight，调用者需持有p.m
+func (p *Pool[T]) weightFull() bool {
+	return p.maxWeight > 0 && p.totalWeight >= p.maxWeight
+}
+
+// AcquireWithMinWeight 获取一个权重至少为w的资源，w为0时与AcquireContext相同
+// 优先使用满足要求的空闲资源，没有时创建新资源，factory可以用MinWeight(ctx)得到w；
+// 新资源的权重仍然不够时把它放回池里并返回ErrWeightUnavailable
+// 资源数或总权重达到上限时，如果关闭权重不够的空闲资源可以腾出容量，就关闭其中最早放回的资源，
+// 否则与AcquireContext相同地排队等待，非阻塞模式下返回ErrPoolExhausted；
+// Release直接交给排队者的资源不会给它，它被唤醒后重新查看空闲资源
+func (p *Pool[T]) AcquireWithMinWeight(ctx context.Context, w uint) (_ T, err error) {
+	if w == 0 {
+		return p.AcquireContext(ctx)
+	}
+	var zero T
+	p.checkClosedAcquire()
+	if p.acquireTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = withTimeout(ctx, p.clock, p.acquireTimeout)
+		defer cancel()
+	}
+	ctx = context.WithValue(ctx, minWeightKey{}, w)
+	var stack []byte
+	if p.leakTimeout > 0 {
+		stack = debug.Stack()
+	}
+	start := p.clock.Now()
+	var waitStart time.Time
+	var binds uint // 本次获取中binder失败的次数
+	defer func() { err = p.acquireDone(ctx, start, waitStart, err) }()
+	if p.overridable {
+		if err := p.checkOverride(ctx, false, &waitStart); err != nil {
+			return zero, err
+		}
+	}
+	if p.lf != nil {
+		// 与acquire相同，让之后的Release改为加锁的路径
+		p.lf.contended.Add(1)
+		defer p.lf.contended.Add(-1)
+	}
+
+	var wait chan *entry[T]
+	var last waiter[T]
+	woken, queued := false, false
+	for {
+		if p.lf != nil && p.lf.parked.Load() > 0 {
+			p.m.Lock()
+			p.unpark()
+			p.unlock()
+		}
+		p.m.Lock()
+		if woken {
+			p.wakeups--
+			woken = false
+		}
+		// 池关闭后总是返回ErrPoolClosed，即使ctx也已经结束
+		if p.closed {
+			p.m.Unlock()
+			return zero, ErrPoolClosed
+		}
+		if err := ctx.Err(); err != nil {
+			p.wakeWaiters()
+			p.m.Unlock()
+			return zero, err
+		}
+		if p.paused && p.nonBlocking {
+			p.m.Unlock()
+			return zero, ErrPoolPaused
+		}
+		mustQueue := !queued && !p.nonBlocking &&
+			(p.wakeups > 0 || len(p.waiters) > 0 && p.waiters[0].prio >= PriorityNormal)
+		mustQueue = mustQueue || p.paused || p.overReserve(PriorityNormal, 1)
+		if p.shutdown != nil {
+			mustQueue = false
+		}
+		if e := p.popWeightedIf(!mustQueue, w, stack); e != nil {
+			p.m.Unlock()
+			if !p.checkIdle(ctx, e) {
+				continue
+			}
+			if ok, err := p.runAcquireHook(ctx, e.r, &binds); err != nil {
+				return zero, err
+			} else if !ok {
+				continue
+			}
+			if p.abandon(e.r) {
+				return zero, ErrPoolClosed
+			}
+			p.stats.hit()
+			p.logger.Println("Acquire:", "Weighted Resource")
+			return e.r, nil
+		}
+		if p.shutdown != nil {
+			p.m.Unlock()
+			return zero, ErrPoolShuttingDown
+		}
+		if !mustQueue && p.makeRoom() {
+			p.numOpen++
+			p.unlock()
+			p.logger.Println("Acquire:", "New Weighted Resource")
+			r, err := p.createInUse(ctx, stack)
+			if err != nil {
+				return zero, ctxError(ctx, err)
+			}
+			if p.weightOf(r) < w {
+				p.Release(r)
+				return zero, ErrWeightUnavailable
+			}
+			if ok, err := p.runAcquireHook(ctx, r, &binds); err != nil {
+				return zero, err
+			} else if !ok {
+				continue
+			}
+			if p.abandon(r) {
+				return zero, ErrPoolClosed
+			}
+			p.stats.miss()
+			return r, nil
+		}
+		if p.nonBlocking {
+			p.unlock()
+			return zero, ErrPoolExhausted
+		}
+		if !queued && p.queueFull() {
+			p.unlock()
+			return zero, ErrQueueFull
+		}
+		if wait == nil {
+			wait = make(chan *entry[T], 1)
+		}
+		last = p.enqueue(waiter[T]{ch: wait, prio: PriorityNormal, n: 1, weight: w, tag: last.tag, since: last.since}, queued)
+		queued = true
+		p.wakeWaiters()
+		p.unlock()
+
+		if waitStart.IsZero() {
+			waitStart = p.clock.Now()
+		}
+		p.logger.Println("Acquire:", "Waiting")
+		endWait := p.region(ctx, "pool.Acquire.Wait")
+		select {
+		case <-wait:
+			endWait()
+			woken = true
+		case <-ctx.Done():
+			endWait()
+			p.m.Lock()
+			if !p.removeWaiter(wait) {
+				p.wakeups--
+				p.wakeWaiters()
+			}
+			p.m.Unlock()
+			// 回到循环开始，池同时被关闭时返回ErrPoolClosed而不是ctx的错误
+			continue
+		}
+	}
+}
+
+// makeRoom 判断是否可以创建新资源，资源数或总权重达到上限时关闭最早放回的空闲资源腾出容量，
+// 关闭所有空闲资源也腾不出容量时不关闭任何资源并返回false，
+// AcquireWithMinWeight在没有权重足够的空闲资源时调用，调用者需持有p.m，并用p.unlock解锁
+func (p *Pool[T]) makeRoom() bool {
+	if !p.canMakeRoom() {
+		return false
+	}
+	for !p.roomFor(p.numOpen, p.totalWeight) {
+		p.logger.Println("Acquire:", "Evicting Light Resource")
+		p.retire(p.takeIdle(0))
+	}
+	return true
+}
+
+// canMakeRoom 判断关闭所有空闲资源之后是否可以创建新资源，调用者需持有p.m
+func (p *Pool[T]) canMakeRoom() bool {
+	idleWeight := uint(0)
+	for i := 0; i < p.idle.Len(); i++ {
+		idleWeight += p.idle.At(i).weight
+	}
+	return p.roomFor(p.numOpen-uint(p.idle.Len()), p.totalWeight-idleWeight)
+}
+
+// roomFor 判断有open个资源、总权重为weight时是否可以再创建一个资源，调用者需持有p.m
+func (p *Pool[T]) roomFor(open, weight uint) bool {
+	return (p.maxTotal == 0 || open < p.maxTotal+p.overflowN) && (p.maxWeight == 0 || weight < p.maxWeight)
+}
+
+// hasWeighted 判断是否有权重至少为w的空闲资源，调用者需持有p.m
+func (p *Pool[T]) hasWeighted(w uint) bool {
+	for i := 0; i < p.idle.Len(); i++ {
+		if p.idle.At(i).weight >= w {
+			return true
+		}
+	}
+	return false
+}
+
+// popWeightedIf 在ok为true时调用popWeighted，否则返回nil
+func (p *Pool[T]) popWeightedIf(ok bool, w uint, stack []byte) *entry[T] {
+	if !ok {
+		return nil
+	}
+	return p.popWeighted(w, stack)
+}
+
+// popWeighted 按ReuseStrategy的顺序取出第一个权重至少为w的空闲资源，没有时返回nil，调用者需持有p.m
+func (p *Pool[T]) popWeighted(w uint, stack []byte) *entry[T] {
+	for k := 0; k < p.idle.Len(); k++ {
+		i := k
+		if p.reuse == LIFO {
+			i = p.idle.Len() - 1 - k
+		}
+		if p.idle.At(i).weight >= w {
+			return p.checkout(i, stack)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lazysheep666/pool"
+	"github.com/lazysheep666/pool/pooltest"
+)
+
+// newWeightedPool 与newHarnessPool相同，但资源的权重是创建时MinWeight(ctx)要求的权重，至少为1
+func newWeightedPool(t *testing.T, opts ...pool.Option) (*pool.Pool[*tracked], *harness) {
+	t.Helper()
+	h := &harness{clock: pooltest.NewFakeClock(epoch)}
+	opts = append([]pool.Option{
+		pool.WithClock(h.clock),
+		pool.WithCloser(h.close),
+		pool.WithWeightFunc(func(r *tracked) uint { return r.weight }),
+	}, opts...)
+	p, err := pool.NewContext(func(ctx context.Context) (*tracked, error) {
+		r, _ := h.create()
+		r.weight = pool.MinWeight(ctx)
+		if r.weight == 0 {
+			r.weight = 1
+		}
+		return r, nil
+	}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		p.CloseContext(ctx)
+	})
+	return p, h
+}
+
+// startWeighted 在新的goroutine中获取一个权重至少为w的资源，排队后才返回，结果发送到返回的通道
+func startWeighted(t *testing.T, p *pool.Pool[*tracked], w uint) <-chan acquired {
+	t.Helper()
+	before := p.Waiting()
+	c := make(chan acquired, 1)
+	go func() {
+		r, err := p.AcquireWithMinWeight(context.Background(), w)
+		c <- acquired{r, err}
+	}()
+	eventually(t, "AcquireWithMinWeight to queue", func() bool { return p.Waiting() > before })
+	return c
+}
+
+func TestAcquireWithMinWeight(t *testing.T) {
+	ctx := context.Background()
+	// heavy 获取一个权重至少为2的资源，失败时结束测试
+	heavy := func(t *testing.T, p *pool.Pool[*tracked]) *tracked {
+		t.Helper()
+		r, err := p.AcquireWithMinWeight(ctx, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.weight < 2 {
+			t.Errorf("got a resource of weight %d, want at least 2", r.weight)
+		}
+		return r
+	}
+	tests := []struct {
+		name string
+		opts []pool.Option
+		run  func(t *testing.T, p *pool.Pool[*tracked], h *harness)
+		// 最后创建和关闭的资源数
+		wantCreated, wantClosed int64
+	}{
+		{"reuses heavy idle resource", nil, func(t *testing.T, p *pool.Pool[*tracked], h *harness) {
+			release(t, p, heavy(t, p))
+			if r := heavy(t, p); r.id != 1 {
+				t.Errorf("got resource %d, want the idle resource 1", r.id)
+			} else {
+				release(t, p, r)
+			}
+		}, 1, 0},
+		{"skips light idle resource", nil, func(t *testing.T, p *pool.Pool[*tracked], h *harness) {
+			release(t, p, acquire(t, p))
+			release(t, p, heavy(t, p))
+			if s := p.Stats(); s.Idle != 2 {
+				t.Errorf("Idle = %d, want 2", s.Idle)
+			}
+		}, 2, 0},
+		{"evicts light idle resource at MaxTotal", []pool.Option{pool.WithMaxTotal(1)}, func(t *testing.T, p *pool.Pool[*tracked], h *harness) {
+			release(t, p, acquire(t, p))
+			release(t, p, heavy(t, p))
+		}, 2, 1},
+		{"evicts light idle resource at MaxTotalWeight", []pool.Option{pool.WithMaxTotalWeight(2)}, func(t *testing.T, p *pool.Pool[*tracked], h *harness) {
+			a, b := acquire(t, p), acquire(t, p)
+			release(t, p, a)
+			release(t, p, b)
+			r := heavy(t, p)
+			if s := p.Stats(); s.Weight != 3 {
+				t.Errorf("Weight = %d, want 3", s.Weight)
+			}
+			release(t, p, r)
+		}, 3, 1},
+		{"exhausted when nothing can be evicted", []pool.Option{pool.WithMaxTotal(2), pool.WithBlocking(false)}, func(t *testing.T, p *pool.Pool[*tracked], h *harness) {
+			a, b := acquire(t, p), acquire(t, p)
+			release(t, p, a)
+			c := heavy(t, p)
+			// 两个容量都被使用，没有可以关闭的空闲资源
+			if _, err := p.AcquireWithMinWeight(ctx, 2); !errors.Is(err, pool.ErrPoolExhausted) {
+				t.Errorf("AcquireWithMinWeight = %v, want ErrPoolExhausted", err)
+			}
+			release(t, p, b)
+			release(t, p, c)
+		}, 3, 1},
+		{"queues behind earlier waiters", []pool.Option{pool.WithMaxTotal(1)}, func(t *testing.T, p *pool.Pool[*tracked], h *harness) {
+			held := acquire(t, p)
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			first := startWaiter(t, p, ctx)
+			second := startWeighted(t, p, 2)
+			release(t, p, held)
+			res := result(t, first)
+			if res.err != nil || res.r != held {
+				t.Fatalf("first waiter got %v, %v, want the released resource", res.r, res.err)
+			}
+			// 之后放回的轻资源被关闭，给等待的AcquireWithMinWeight腾出容量
+			release(t, p, res.r)
+			res = result(t, second)
+			if res.err != nil || res.r.weight != 2 {
+				t.Fatalf("weighted waiter got %v, %v, want a new resource of weight 2", res.r, res.err)
+			}
+			release(t, p, res.r)
+		}, 2, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, h := newWeightedPool(t, tt.opts...)
+			tt.run(t, p, h)
+			if h.created.Load() != tt.wantCreated || h.closed.Load() != tt.wantClosed {
+				t.Errorf("created %d, closed %d, want %d, %d", h.created.Load(), h.closed.Load(), tt.wantCreated, tt.wantClosed)
+			}
+			if s := p.Stats(); s.Waiting != 0 {
+				t.Errorf("Waiting = %d, want 0", s.Waiting)
+			}
+		})
+	}
+}
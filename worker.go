@@ -0,0 +1,36 @@
+package pool
+
+import (
+	"context"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Run 启动concurrency个worker从jobs中读取任务，每个任务用With获取一个资源执行，
+// 任务返回错误或panic时资源被销毁而不是放回池里
+// jobs被关闭并且所有任务完成后返回nil；任一任务失败或ctx结束时停止读取新任务，等待执行中的任务返回后返回第一个错误
+// concurrency小于1时按1处理
+func (p *Pool[T]) Run(ctx context.Context, jobs <-chan func(r T) error, concurrency int) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	g, ctx := errgroup.WithContext(ctx)
+	for i := 0; i < concurrency; i++ {
+		g.Go(func() error {
+			for {
+				select {
+				case job, ok := <-jobs:
+					if !ok {
+						return nil
+					}
+					if err := p.With(ctx, job); err != nil {
+						return err
+					}
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		})
+	}
+	return g.Wait()
+}
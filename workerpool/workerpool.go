@@ -0,0 +1,213 @@
+// Package workerpool 提供管理worker goroutine的资源池，每个worker独占一份状态，例如cgo库的上下文，
+// 状态只在worker自己的goroutine上创建、使用和释放
+//
+//	p, err := workerpool.NewPool(workerpool.Spec[*C.ctx_t]{
+//		Init:         func(ctx context.Context) (*C.ctx_t, error) { return C.ctx_new(), nil },
+//		Stop:         func(c *C.ctx_t) { C.ctx_free(c) },
+//		LockOSThread: true,
+//	}, pool.WithMaxTotal(4))
+//	err = p.Do(ctx, func(c *C.ctx_t) error { return run(c) })
+//
+// 池关闭时通知所有worker在执行完当前的命令后退出，并等待它们退出至多JoinTimeout
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/lazysheep666/pool"
+)
+
+// DefaultJoinTimeout 是没有设置JoinTimeout时Close等待worker退出的最长时间
+const DefaultJoinTimeout = 5 * time.Second
+
+// ErrWorkerStopped 表示worker已经退出，不能再执行命令
+var ErrWorkerStopped = errors.New("workerpool: worker stopped")
+
+// ErrJoinTimeout 表示Close在JoinTimeout内没有等到worker退出，worker会在当前的命令返回后自己退出
+var ErrJoinTimeout = errors.New("workerpool: worker did not stop in time")
+
+// ErrWorkerPanic 表示命令在worker上panic，返回的错误包括panic的值，worker的状态可能已经损坏
+var ErrWorkerPanic = errors.New("workerpool: command panicked")
+
+// Spec 描述如何启动和停止一个worker
+type Spec[S any] struct {
+	// Init 在worker的goroutine上创建它的状态，返回错误时worker退出，ctx是创建资源时的ctx
+	Init func(ctx context.Context) (S, error)
+	// Stop 在worker退出前在它的goroutine上释放状态，可以为nil
+	Stop func(s S)
+	// LockOSThread 为true时worker固定在一个系统线程上，用于依赖线程局部状态的库
+	LockOSThread bool
+	// JoinTimeout 是Close等待worker退出的最长时间，0表示使用DefaultJoinTimeout
+	JoinTimeout time.Duration
+}
+
+// Worker 是一个拥有状态S的goroutine，命令按提交的顺序在它上面逐个执行
+type Worker[S any] struct {
+	cmds        chan func(S)
+	stop        chan struct{}
+	done        chan struct{}
+	stopOnce    sync.Once
+	joinTimeout time.Duration
+}
+
+// Start 启动一个worker，并等待spec.Init在它的goroutine上返回
+func Start[S any](ctx context.Context, spec Spec[S]) (*Worker[S], error) {
+	if spec.Init == nil {
+		return nil, errors.New("workerpool: nil Init")
+	}
+	w := &Worker[S]{
+		cmds:        make(chan func(S)),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+		joinTimeout: spec.JoinTimeout,
+	}
+	if w.joinTimeout == 0 {
+		w.joinTimeout = DefaultJoinTimeout
+	}
+	started := make(chan error, 1)
+	go w.run(ctx, spec, started)
+	if err := <-started; err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// run 是worker的goroutine
+func (w *Worker[S]) run(ctx context.Context, spec Spec[S], started chan<- error) {
+	defer close(w.done)
+	if spec.LockOSThread {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+	}
+	s, err := spec.Init(ctx)
+	started <- err
+	if err != nil {
+		return
+	}
+	if spec.Stop != nil {
+		defer spec.Stop(s)
+	}
+	for {
+		select {
+		case cmd := <-w.cmds:
+			cmd(s)
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// Do 在worker的goroutine上用它的状态执行fn，并返回fn的错误
+// ctx结束时不再等待并返回ctx.Err()，已经开始的fn仍然会执行完；worker已经退出时返回ErrWorkerStopped
+func (w *Worker[S]) Do(ctx context.Context, fn func(s S) error) error {
+	res := make(chan error, 1)
+	cmd := func(s S) { res <- call(fn, s) }
+	select {
+	case w.cmds <- cmd:
+	case <-w.done:
+		return ErrWorkerStopped
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-res:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// call 调用fn，其中的panic被转换为ErrWorkerPanic
+func call[S any](fn func(S) error, s S) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("%w: %v", ErrWorkerPanic, v)
+		}
+	}()
+	return fn(s)
+}
+
+// Close 通知worker在当前的命令返回后退出，并等待它退出至多JoinTimeout，超时返回ErrJoinTimeout
+// 重复调用时只等待退出
+func (w *Worker[S]) Close() error {
+	w.stopOnce.Do(func() { close(w.stop) })
+	timer := time.NewTimer(w.joinTimeout)
+	defer timer.Stop()
+	select {
+	case <-w.done:
+		return nil
+	case <-timer.C:
+		return ErrJoinTimeout
+	}
+}
+
+// IsClosed 判断worker是否已经退出，实现pool.ClosedChecker
+func (w *Worker[S]) IsClosed() bool {
+	select {
+	case <-w.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Pool 是一个worker的资源池
+// 从池中取出的worker已经退出时被销毁并重新获取；命令panic的worker被销毁，不再放回池里
+type Pool[S any] struct {
+	p *pool.Pool[*Worker[S]]
+}
+
+// NewPool 创建一个按spec启动worker的池
+// opts用来设置池的其它配置，其中的WithValidator会替换默认的检查
+func NewPool[S any](spec Spec[S], opts ...pool.Option) (*Pool[S], error) {
+	if spec.Init == nil {
+		return nil, errors.New("workerpool: nil Init")
+	}
+	start := func(ctx context.Context) (*Worker[S], error) {
+		return Start(ctx, spec)
+	}
+	opts = append([]pool.Option{
+		pool.WithValidator(func(w *Worker[S]) bool { return !w.IsClosed() }),
+	}, opts...)
+	p, err := pool.NewContext(start, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Pool[S]{p: p}, nil
+}
+
+// Do 获取一个worker，在它上面执行fn，然后把它放回池里
+// fn panic或worker已经退出时worker被销毁，ctx结束时返回ctx.Err()，worker执行完fn后仍可以被其它调用者使用
+func (wp *Pool[S]) Do(ctx context.Context, fn func(s S) error) error {
+	w, err := wp.p.AcquireContext(ctx)
+	if err != nil {
+		return err
+	}
+	err = w.Do(ctx, fn)
+	if errors.Is(err, ErrWorkerPanic) || errors.Is(err, ErrWorkerStopped) {
+		wp.p.Discard(w)
+		return err
+	}
+	wp.p.Release(w)
+	return err
+}
+
+// Stats 返回池的统计信息
+func (wp *Pool[S]) Stats() pool.Stats {
+	return wp.p.Stats()
+}
+
+// Close 关闭池，等待使用中的worker被放回，然后停止所有worker
+func (wp *Pool[S]) Close() error {
+	return wp.p.Close()
+}
+
+// Pool 返回底层的资源池
+func (wp *Pool[S]) Pool() *pool.Pool[*Worker[S]] {
+	return wp.p
+}
@@ -0,0 +1,254 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lazysheep666/pool"
+)
+
+// state 是测试worker的状态，记录在它上面执行的命令
+type state struct {
+	id   int64
+	cmds int
+}
+
+// counters 记录启动和停止的worker数
+type counters struct {
+	inits, stops atomic.Int64
+}
+
+// spec 返回创建state的Spec，initErr不为nil时Init失败
+func (c *counters) spec(initErr error) Spec[*state] {
+	return Spec[*state]{
+		Init: func(context.Context) (*state, error) {
+			if initErr != nil {
+				return nil, initErr
+			}
+			return &state{id: c.inits.Add(1)}, nil
+		},
+		Stop:         func(*state) { c.stops.Add(1) },
+		LockOSThread: true,
+	}
+}
+
+func TestWorker(t *testing.T) {
+	ctx := context.Background()
+	boom := errors.New("boom")
+	tests := []struct {
+		name string
+		run  func(t *testing.T, w *Worker[*state])
+	}{
+		{"commands in order on one state", func(t *testing.T, w *Worker[*state]) {
+			for i := 1; i <= 3; i++ {
+				var got int
+				if err := w.Do(ctx, func(s *state) error {
+					s.cmds++
+					got = s.cmds
+					return nil
+				}); err != nil {
+					t.Fatal(err)
+				}
+				if got != i {
+					t.Errorf("command %d saw %d earlier commands", i, got-1)
+				}
+			}
+		}},
+		{"command error", func(t *testing.T, w *Worker[*state]) {
+			if err := w.Do(ctx, func(*state) error { return boom }); !errors.Is(err, boom) {
+				t.Errorf("Do = %v, want %v", err, boom)
+			}
+		}},
+		{"panic converted", func(t *testing.T, w *Worker[*state]) {
+			err := w.Do(ctx, func(*state) error { panic("bad state") })
+			if !errors.Is(err, ErrWorkerPanic) {
+				t.Errorf("Do = %v, want ErrWorkerPanic", err)
+			}
+			// worker在panic之后仍然执行命令
+			if err := w.Do(ctx, func(*state) error { return nil }); err != nil {
+				t.Errorf("Do after a panic = %v", err)
+			}
+		}},
+		{"ctx ends while running", func(t *testing.T, w *Worker[*state]) {
+			ctx, cancel := context.WithCancel(ctx)
+			finished := make(chan struct{})
+			err := w.Do(ctx, func(*state) error {
+				cancel()
+				time.Sleep(10 * time.Millisecond)
+				close(finished)
+				return nil
+			})
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("Do = %v, want context.Canceled", err)
+			}
+			// 已经开始的命令仍然执行完
+			<-finished
+		}},
+		{"stopped", func(t *testing.T, w *Worker[*state]) {
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if !w.IsClosed() {
+				t.Error("IsClosed = false after Close")
+			}
+			if err := w.Do(ctx, func(*state) error { return nil }); !errors.Is(err, ErrWorkerStopped) {
+				t.Errorf("Do after Close = %v, want ErrWorkerStopped", err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c counters
+			w, err := Start(ctx, c.spec(nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.run(t, w)
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if c.inits.Load() != 1 || c.stops.Load() != 1 {
+				t.Errorf("Init called %d times, Stop %d times, want 1, 1", c.inits.Load(), c.stops.Load())
+			}
+		})
+	}
+}
+
+// TestWorkerJoinTimeout 检查Close在JoinTimeout内等不到当前的命令返回时返回ErrJoinTimeout，worker之后自己退出
+func TestWorkerJoinTimeout(t *testing.T) {
+	var c counters
+	spec := c.spec(nil)
+	spec.JoinTimeout = 20 * time.Millisecond
+	w, err := Start(context.Background(), spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	running, unblock := make(chan struct{}), make(chan struct{})
+	go w.Do(context.Background(), func(*state) error {
+		close(running)
+		<-unblock
+		return nil
+	})
+	<-running
+	if err := w.Close(); !errors.Is(err, ErrJoinTimeout) {
+		t.Fatalf("Close = %v, want ErrJoinTimeout", err)
+	}
+	if w.IsClosed() || c.stops.Load() != 0 {
+		t.Fatal("worker stopped while its command was running")
+	}
+	close(unblock)
+	// 重复调用时只等待退出
+	if err := w.Close(); err != nil {
+		t.Fatalf("second Close = %v", err)
+	}
+	if !w.IsClosed() || c.stops.Load() != 1 {
+		t.Errorf("IsClosed = %v, Stop called %d times, want true, 1", w.IsClosed(), c.stops.Load())
+	}
+}
+
+func TestStartErrors(t *testing.T) {
+	errInit := errors.New("init failed")
+	var c counters
+	if _, err := Start(context.Background(), c.spec(errInit)); !errors.Is(err, errInit) {
+		t.Errorf("Start = %v, want %v", err, errInit)
+	}
+	if c.stops.Load() != 0 {
+		t.Error("Stop called for a worker whose Init failed")
+	}
+	if _, err := Start(context.Background(), Spec[*state]{}); err == nil {
+		t.Error("Start with a nil Init succeeded")
+	}
+	if _, err := NewPool(Spec[*state]{}); err == nil {
+		t.Error("NewPool with a nil Init succeeded")
+	}
+}
+
+func TestPool(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		run  func(t *testing.T, wp *Pool[*state], c *counters)
+		// 最后期望启动的worker数
+		wantInits int64
+	}{
+		{"worker reused", func(t *testing.T, wp *Pool[*state], c *counters) {
+			for i := 0; i < 3; i++ {
+				if err := wp.Do(ctx, func(s *state) error {
+					s.cmds++
+					return nil
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			wp.Do(ctx, func(s *state) error {
+				if s.cmds != 3 {
+					t.Errorf("worker ran %d earlier commands, want 3", s.cmds)
+				}
+				return nil
+			})
+		}, 1},
+		{"panicked worker destroyed", func(t *testing.T, wp *Pool[*state], c *counters) {
+			if err := wp.Do(ctx, func(*state) error { panic("bad state") }); !errors.Is(err, ErrWorkerPanic) {
+				t.Fatalf("Do = %v, want ErrWorkerPanic", err)
+			}
+			if c.stops.Load() != 1 {
+				t.Errorf("Stop called %d times after the panic, want 1", c.stops.Load())
+			}
+			wp.Do(ctx, func(s *state) error {
+				if s.id != 2 {
+					t.Errorf("Do ran on worker %d, want a new worker", s.id)
+				}
+				return nil
+			})
+		}, 2},
+		{"stopped idle worker replaced", func(t *testing.T, wp *Pool[*state], c *counters) {
+			var w *Worker[*state]
+			if err := wp.Pool().With(ctx, func(got *Worker[*state]) error {
+				w = got
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			w.Close()
+			wp.Do(ctx, func(s *state) error {
+				if s.id != 2 {
+					t.Errorf("Do ran on worker %d, want a new worker", s.id)
+				}
+				return nil
+			})
+		}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c counters
+			wp, err := NewPool(c.spec(nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.run(t, wp, &c)
+			if err := wp.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if c.inits.Load() != tt.wantInits || c.stops.Load() != tt.wantInits {
+				t.Errorf("Init called %d times, Stop %d times, want %d", c.inits.Load(), c.stops.Load(), tt.wantInits)
+			}
+		})
+	}
+}
+
+// TestPoolInitError 检查Init失败时Do返回它的错误
+func TestPoolInitError(t *testing.T) {
+	errInit := errors.New("init failed")
+	var c counters
+	wp, err := NewPool(c.spec(errInit), pool.WithBlocking(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wp.Close()
+	if err := wp.Do(context.Background(), func(*state) error { return nil }); !errors.Is(err, errInit) {
+		t.Errorf("Do = %v, want %v", err, errInit)
+	}
+}
@@ -0,0 +1,131 @@
+// Package wspool 提供管理WebSocket连接的资源池
+//
+//	p, err := wspool.NewConnPool(dialer, "wss://example.com/stream", pool.WithMaxTotal(32))
+//	c, err := p.Get(ctx)
+//	defer c.Close() // 把连接放回池里
+//
+// 包不依赖具体的WebSocket实现，Dialer和Conn只需要少量的方法，可以用几行代码包装gorilla/websocket或nhooyr.io/websocket，
+// 例如nhooyr.io/websocket的连接已经有Ping(ctx)，只需要把Close包装成正常关闭：
+//
+//	type nhooyrConn struct{ *websocket.Conn }
+//
+//	func (c nhooyrConn) Close() error { return c.Conn.Close(websocket.StatusNormalClosure, "") }
+//
+// gorilla/websocket的连接需要用WriteControl发送ping，并在SetPongHandler中等待pong
+package wspool
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lazysheep666/pool"
+)
+
+// DefaultPingTimeout 是检查空闲连接时等待pong的最长时间
+const DefaultPingTimeout = 5 * time.Second
+
+// DefaultReconnectAttempts 和DefaultReconnectBackoff 是建立连接失败时的重试次数和第一次重试前的等待时间
+const (
+	DefaultReconnectAttempts = 3
+	DefaultReconnectBackoff  = 100 * time.Millisecond
+)
+
+// Conn 是一个WebSocket连接，Ping发送ping并等待对端的pong，Close正常关闭连接
+type Conn interface {
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// Dialer 建立到url的WebSocket连接
+type Dialer interface {
+	Dial(ctx context.Context, url string) (Conn, error)
+}
+
+// DialerFunc 让普通函数实现Dialer
+type DialerFunc func(ctx context.Context, url string) (Conn, error)
+
+// Dial 调用f
+func (f DialerFunc) Dial(ctx context.Context, url string) (Conn, error) {
+	return f(ctx, url)
+}
+
+// ConnPool 是一个WebSocket连接的资源池
+// 从池中取出空闲连接时用ping检查它是否仍然可用，没有及时收到pong的连接会被关闭并重新获取；
+// 建立连接失败时按DefaultReconnectAttempts重试，出错后Destroy的连接会在后台被新连接替换
+type ConnPool struct {
+	p *pool.Pool[Conn]
+}
+
+// NewConnPool 创建一个用dialer连接url的连接池
+// opts用来设置池的其它配置，其中的WithValidator、WithFactoryRetry和WithReplaceDiscarded会替换默认的设置
+func NewConnPool(dialer Dialer, url string, opts ...pool.Option) (*ConnPool, error) {
+	if dialer == nil {
+		return nil, errors.New("wspool: nil dialer")
+	}
+	if url == "" {
+		return nil, errors.New("wspool: empty url")
+	}
+	dial := func(ctx context.Context) (Conn, error) {
+		return dialer.Dial(ctx, url)
+	}
+	opts = append([]pool.Option{
+		pool.WithCloser(func(c Conn) error { return c.Close() }),
+		pool.WithValidatorContext(Healthy),
+		pool.WithFactoryRetry(DefaultReconnectAttempts, DefaultReconnectBackoff),
+		pool.WithReplaceDiscarded(true),
+	}, opts...)
+	p, err := pool.NewContext(dial, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &ConnPool{p: p}, nil
+}
+
+// Get 从池中获取一个连接，使用完后调用它的Close放回池里
+func (cp *ConnPool) Get(ctx context.Context) (*PooledConn, error) {
+	pr, err := cp.p.AcquireResource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &PooledConn{Conn: pr.Value(), pr: pr}, nil
+}
+
+// Stats 返回连接池的统计信息
+func (cp *ConnPool) Stats() pool.Stats {
+	return cp.p.Stats()
+}
+
+// Close 关闭连接池，并等待使用中的连接被放回后关闭它们
+func (cp *ConnPool) Close() error {
+	return cp.p.Close()
+}
+
+// Pool 返回底层的资源池
+func (cp *ConnPool) Pool() *pool.Pool[Conn] {
+	return cp.p
+}
+
+// PooledConn 是从ConnPool中获取的连接，Close会把连接放回池里
+// 需要使用具体实现的读写方法时，对Conn做类型断言
+type PooledConn struct {
+	Conn
+	pr *pool.PooledResource[Conn]
+}
+
+// Close 把连接放回池里，重复调用时返回pool.ErrResourceReleased
+func (c *PooledConn) Close() error {
+	return c.pr.Close()
+}
+
+// Destroy 关闭连接而不是放回池里，用于读写出错、连接状态不可预期的情况
+func (c *PooledConn) Destroy() error {
+	return c.pr.Destroy()
+}
+
+// Healthy 用ping检查一个空闲连接是否仍然可用，DefaultPingTimeout内没有收到pong时返回false
+func Healthy(ctx context.Context, c Conn) bool {
+	ctx, cancel := context.WithTimeout(ctx, DefaultPingTimeout)
+	defer cancel()
+	return c.Ping(ctx) == nil
+}
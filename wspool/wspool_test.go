@@ -0,0 +1,252 @@
+package wspool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lazysheep666/pool"
+	"github.com/lazysheep666/pool/pooltest"
+)
+
+var errPing = errors.New("ping failed")
+
+// fakeConn 记录ping和关闭，dead时ping失败，hang时ping等到ctx结束
+type fakeConn struct {
+	pings  atomic.Int64
+	dead   atomic.Bool
+	hang   atomic.Bool
+	closed atomic.Bool
+}
+
+func (c *fakeConn) Ping(ctx context.Context) error {
+	c.pings.Add(1)
+	if c.hang.Load() {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if c.dead.Load() {
+		return errPing
+	}
+	return nil
+}
+
+func (c *fakeConn) Close() error {
+	c.closed.Store(true)
+	return nil
+}
+
+// fakeDialer 记录建立的连接，前failures次Dial失败
+type fakeDialer struct {
+	mu       sync.Mutex
+	conns    []*fakeConn
+	failures int
+	calls    int
+	url      string
+}
+
+var errDial = errors.New("dial failed")
+
+func (d *fakeDialer) Dial(ctx context.Context, url string) (Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.calls++
+	d.url = url
+	if d.failures > 0 {
+		d.failures--
+		return nil, errDial
+	}
+	c := &fakeConn{}
+	d.conns = append(d.conns, c)
+	return c, nil
+}
+
+// dialed 返回Dial的调用次数和建立的连接数
+func (d *fakeDialer) dialed() (calls, conns int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.calls, len(d.conns)
+}
+
+// get 从cp获取一个连接，失败时结束测试
+func get(t *testing.T, cp *ConnPool) *PooledConn {
+	t.Helper()
+	c, err := cp.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestConnPool(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []pool.Option
+		run  func(t *testing.T, cp *ConnPool, d *fakeDialer)
+		// 最后期望建立的连接数
+		wantConns int
+	}{
+		{"idle connection pinged and reused", nil, func(t *testing.T, cp *ConnPool, d *fakeDialer) {
+			a := get(t, cp)
+			a.Close()
+			b := get(t, cp)
+			if b.Conn != a.Conn {
+				t.Error("Get dialed a new connection with a live one idle")
+			}
+			if n := d.conns[0].pings.Load(); n != 1 {
+				t.Errorf("idle connection pinged %d times, want 1", n)
+			}
+			b.Close()
+		}, 1},
+		{"dead idle connection replaced", nil, func(t *testing.T, cp *ConnPool, d *fakeDialer) {
+			a := get(t, cp)
+			a.Close()
+			d.conns[0].dead.Store(true)
+			b := get(t, cp)
+			if b.Conn == a.Conn {
+				t.Error("Get reused a connection that failed its ping")
+			}
+			if !d.conns[0].closed.Load() {
+				t.Error("dead connection not closed")
+			}
+			b.Close()
+		}, 2},
+		{"double Close", nil, func(t *testing.T, cp *ConnPool, d *fakeDialer) {
+			c := get(t, cp)
+			c.Close()
+			if err := c.Close(); !errors.Is(err, pool.ErrResourceReleased) {
+				t.Errorf("second Close = %v, want ErrResourceReleased", err)
+			}
+		}, 1},
+		{"destroyed connection replaced in the background", []pool.Option{pool.WithMinIdle(1)}, func(t *testing.T, cp *ConnPool, d *fakeDialer) {
+			c := get(t, cp)
+			if err := c.Destroy(); err != nil {
+				t.Fatal(err)
+			}
+			if !d.conns[0].closed.Load() {
+				t.Error("destroyed connection not closed")
+			}
+			waitIdle(t, cp, 1)
+		}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &fakeDialer{}
+			cp, err := NewConnPool(d, "ws://example/stream", tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cp.Close()
+			tt.run(t, cp, d)
+			if _, conns := d.dialed(); conns != tt.wantConns {
+				t.Errorf("dialed %d connections, want %d", conns, tt.wantConns)
+			}
+			if d.url != "ws://example/stream" {
+				t.Errorf("dialed %q", d.url)
+			}
+			if s := cp.Stats(); s.InUse != 0 {
+				t.Errorf("InUse = %d, want 0", s.InUse)
+			}
+		})
+	}
+}
+
+// TestReconnect 检查建立连接失败时按DefaultReconnectAttempts重试
+func TestReconnect(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		wantCalls int
+		wantErr   bool
+	}{
+		{"first attempt", 0, 1, false},
+		{"after retries", DefaultReconnectAttempts - 1, DefaultReconnectAttempts, false},
+		{"all attempts fail", DefaultReconnectAttempts, DefaultReconnectAttempts, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := pooltest.NewFakeClock(time.Time{})
+			d := &fakeDialer{failures: tt.failures}
+			cp, err := NewConnPool(d, "ws://example", pool.WithClock(clock))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cp.Close()
+			done := make(chan error, 1)
+			go func() {
+				c, err := cp.Get(context.Background())
+				if err == nil {
+					c.Close()
+				}
+				done <- err
+			}()
+			// 推进时钟越过每次重试前的等待
+			for i := 1; i < DefaultReconnectAttempts && i <= tt.failures; i++ {
+				clock.BlockUntil(1)
+				clock.Advance(time.Minute)
+			}
+			err = <-done
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Get = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, errDial) {
+				t.Errorf("Get = %v, want %v", err, errDial)
+			}
+			if calls, _ := d.dialed(); calls != tt.wantCalls {
+				t.Errorf("Dial called %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestHealthy(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name string
+		ctx  context.Context
+		conn func() *fakeConn
+		want bool
+	}{
+		{"pong", context.Background(), func() *fakeConn { return &fakeConn{} }, true},
+		{"ping failed", context.Background(), func() *fakeConn {
+			c := &fakeConn{}
+			c.dead.Store(true)
+			return c
+		}, false},
+		{"no pong before ctx ends", cancelled, func() *fakeConn {
+			c := &fakeConn{}
+			c.hang.Store(true)
+			return c
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Healthy(tt.ctx, tt.conn()); got != tt.want {
+				t.Errorf("Healthy = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewConnPoolErrors(t *testing.T) {
+	if _, err := NewConnPool(nil, "ws://example"); err == nil {
+		t.Error("NewConnPool with a nil dialer succeeded")
+	}
+	if _, err := NewConnPool(DialerFunc(func(context.Context, string) (Conn, error) { return nil, errDial }), ""); err == nil {
+		t.Error("NewConnPool with an empty url succeeded")
+	}
+}
+
+// waitIdle 等待cp中有n个空闲连接
+func waitIdle(t *testing.T, cp *ConnPool, n uint) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); cp.Stats().Idle != n; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Idle = %d, want %d", cp.Stats().Idle, n)
+		}
+	}
+}